@@ -1,41 +1,429 @@
 package api
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
-	"github.com/joho/godotenv"
+	"github.com/mzampetakis/prods-api/api/cache"
+	"github.com/mzampetakis/prods-api/api/config"
 	"github.com/mzampetakis/prods-api/api/controllers"
+	"github.com/mzampetakis/prods-api/api/controllers/dtos"
+	"github.com/mzampetakis/prods-api/api/controllers/middlewares"
+	"github.com/mzampetakis/prods-api/api/health"
+	"github.com/mzampetakis/prods-api/api/idempotency"
+	"github.com/mzampetakis/prods-api/api/logging"
+	"github.com/mzampetakis/prods-api/api/metrics"
+	"github.com/mzampetakis/prods-api/api/ratelimit"
 	"github.com/mzampetakis/prods-api/api/repositories"
 	"github.com/mzampetakis/prods-api/api/services"
+	"github.com/mzampetakis/prods-api/api/storage"
+	"github.com/mzampetakis/prods-api/api/tlsconfig"
+	"github.com/mzampetakis/prods-api/api/tracing"
 	"github.com/sirupsen/logrus"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
-func init() {
-	loadEnvVars()
+// Run serves the API with the provided configuration until it receives SIGINT or SIGTERM
+func Run(cfg *config.Config) error {
+	if err := logging.Configure(cfg.Log.Level, cfg.Log.Format); err != nil {
+		return err
+	}
+	db, err := connectDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	db.StatementTimeout = cfg.DB.StatementTimeout
+	tracer, shutdownTracer, err := newTracer(cfg.Tracing)
+	if err != nil {
+		return fmt.Errorf("could not initialize tracing: %s", err.Error())
+	}
+	tracing.SetTracer(tracer)
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracer(shutdownCtx); err != nil {
+			logrus.Warnf("Could not export the remaining spans: %s", err.Error())
+		}
+	}()
+	apiMetrics := metrics.New()
+	apiMetrics.RegisterDB(db.DB)
+	datastore := newCachedDatastore(db, cfg.DB)
+	if cachedDatastore, ok := datastore.(*repositories.CachedDatastore); ok {
+		apiMetrics.RegisterCache("datastore", func() (uint64, uint64) {
+			stats := cachedDatastore.Stats()
+			return stats.Hits, stats.Misses
+		})
+	}
+	sv, err := newService(cfg, datastore)
+	if err != nil {
+		return err
+	}
+	cacheClient := newCacheClient(cfg.Cache)
+	if cacheClient != nil {
+		sv.Cache = cacheClient
+		apiMetrics.RegisterCache("response", cacheClient.Stats)
+	}
+	rateLimiter, err := newRateLimiter(cfg.RateLimit, cfg.Cache)
+	if err != nil {
+		return err
+	}
+	idempotencyKeys, err := newIdempotencyKeys(cfg.Idempotency, cfg.RateLimit, cfg.Cache)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signalContext()
+	defer stop()
+	var workers sync.WaitGroup
+	startLinkChecker(ctx, &workers, cfg.ImageURLs.LinkCheckInterval, db, sv.Cache)
+	h := controllers.Handler{
+		AppServices:  &services.TracedService{FunctionalitiesIface: sv},
+		Cache:        cacheClient,
+		CachePolicy:  middlewares.CachePolicy{MaxAge: cfg.HTTPCache.MaxAge, RouteMaxAges: cfg.HTTPCache.RouteMaxAges},
+		Metrics:      apiMetrics,
+		AccessLog:    accessLogOptions(cfg.Log),
+		HealthChecks: healthChecks(db, cacheClient, cfg.Health.CheckTimeout),
+		RateLimiter:  rateLimiter,
+		Idempotency:  idempotencyKeys,
+		BodyLimits: middlewares.BodyLimits{
+			MaxBytes:          cfg.Server.MaxBodyBytes,
+			MaxMultipartBytes: cfg.Server.MaxUploadBodyBytes,
+		},
+		StatementTimeouts: middlewares.StatementTimeoutPolicy{RouteTimeouts: cfg.DB.RouteStatementTimeouts},
+		CORS: middlewares.CORSOptions{
+			AllowedOrigins:   cfg.CORS.AllowedOrigins,
+			AllowedMethods:   cfg.CORS.AllowedMethods,
+			AllowedHeaders:   cfg.CORS.AllowedHeaders,
+			ExposedHeaders:   cfg.CORS.ExposedHeaders,
+			AllowCredentials: cfg.CORS.AllowCredentials,
+			MaxAge:           cfg.CORS.MaxAge,
+		},
+		ClientPrincipals: cfg.TLS.ClientPrincipals,
+		Compression:      compressionOptions(cfg.Compression),
+		Versions:         versionOptions(cfg.API),
+		Currency:         cfg.API.Currency,
+		Locales:          cfg.Locales.Locales(),
+		SecurityHeaders: middlewares.SecurityHeadersOptions{
+			HSTSMaxAge:                   cfg.Security.HSTSMaxAge,
+			HSTSIncludeSubdomains:        cfg.Security.HSTSIncludeSubdomains,
+			ContentTypeNosniff:           cfg.Security.ContentTypeNosniff,
+			FrameOptions:                 cfg.Security.FrameOptions,
+			ReferrerPolicy:               cfg.Security.ReferrerPolicy,
+			ContentSecurityPolicy:        cfg.Security.ContentSecurityPolicy,
+			SwaggerContentSecurityPolicy: cfg.Security.SwaggerContentSecurityPolicy,
+		},
+	}
+	serverCfg := serverConfig(cfg.Server)
+	if cfg.TLS.Enabled() {
+		if serverCfg.TLS, err = tlsconfig.ServerConfig(tlsOptions(cfg.TLS)); err != nil {
+			return err
+		}
+	}
+	serverErr := h.ServerRun(ctx, serverCfg)
 
+	// the server has stopped, stop the background workers before releasing the resources they use
+	stop()
+	workers.Wait()
+	if cacheClient != nil {
+		if err = cacheClient.Close(); err != nil {
+			logrus.Warnf("Could not close the cache backend: %s", err.Error())
+		}
+	}
+	if rateLimiter != nil {
+		if closer, ok := rateLimiter.Store.(io.Closer); ok {
+			if err = closer.Close(); err != nil {
+				logrus.Warnf("Could not close the rate limit store: %s", err.Error())
+			}
+		}
+	}
+	if idempotencyKeys != nil {
+		if closer, ok := idempotencyKeys.Store.(io.Closer); ok {
+			if err = closer.Close(); err != nil {
+				logrus.Warnf("Could not close the idempotency store: %s", err.Error())
+			}
+		}
+	}
+	return serverErr
 }
-func loadEnvVars() {
-	if err := godotenv.Load(".env"); err != nil {
-		logrus.Warn(".env file not found")
+
+// signalContext returns a context that is cancelled on SIGINT or SIGTERM
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			logrus.Infof("Received %s", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+	return ctx, cancel
+}
+
+func serverConfig(cfg config.ServerConfig) controllers.ServerConfig {
+	return controllers.ServerConfig{
+		Addr:              ":" + strconv.Itoa(cfg.Port),
+		Prefix:            cfg.APIPrefix,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		ShutdownTimeout:   cfg.ShutdownTimeout,
+	}
+}
+
+func tlsOptions(cfg config.TLSConfig) tlsconfig.Options {
+	return tlsconfig.Options{
+		CertFile:       cfg.CertFile,
+		KeyFile:        cfg.KeyFile,
+		ClientCAFile:   cfg.ClientCAFile,
+		ClientAuth:     cfg.ClientAuth,
+		ReloadInterval: cfg.ReloadInterval,
+		DisableHTTP2:   !cfg.HTTP2,
+	}
+}
+
+// healthChecks creates the dependency checks of the readiness and status endpoints
+func healthChecks(db *repositories.DB, cacheClient *cache.Client, timeout time.Duration) []health.Check {
+	checks := []health.Check{
+		{Name: "db", Timeout: timeout, Check: db.PingContext},
+		{Name: "migrations", Timeout: timeout, Check: func(ctx context.Context) error {
+			pending, err := db.PendingMigrations(ctx)
+			if err != nil {
+				return err
+			}
+			if len(pending) > 0 {
+				return fmt.Errorf("missing tables: %s", strings.Join(pending, ", "))
+			}
+			return nil
+		}},
 	}
+	if cacheClient != nil {
+		checks = append(checks, health.Check{Name: "cache", Timeout: timeout, Check: cacheClient.Ping})
+	}
+	return checks
 }
 
-func Run() {
-	dbConnectionURL := fmt.Sprintf("%s:%s@tcp(%s:3306)/%s?charset=utf8&parseTime=True&loc=Local", os.Getenv("MYSQL_USER"), os.Getenv("MYSQL_PASSWORD"), os.Getenv("MYSQL_HOST"), os.Getenv("MYSQL_DATABASE"))
-	db, err := repositories.NewDB("mysql", dbConnectionURL)
+// accessLogOptions redacts the configured headers on top of the default sensitive ones
+func accessLogOptions(cfg config.LogConfig) middlewares.AccessLogOptions {
+	return middlewares.AccessLogOptions{
+		Headers:          cfg.Headers,
+		SensitiveHeaders: append(append([]string{}, logging.DefaultSensitiveHeaders...), cfg.RedactHeaders...),
+	}
+}
+
+// newTracer creates the tracer with the configured exporter: otlp sends the spans to an OpenTelemetry collector,
+// stdout and file write them as JSON, while with none the trace context is only propagated.
+// The returned shutdown func exports the remaining spans and then closes the file of the file exporter.
+func newTracer(cfg config.TracingConfig) (*tracing.Tracer, func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	var file *os.File
+	var err error
+	switch cfg.Exporter {
+	case "otlp":
+		exporter, err = tracing.NewOTLPExporter(cfg.OTLPEndpoint)
+	case "stdout":
+		exporter, err = tracing.NewWriterExporter(os.Stdout)
+	case "file":
+		file, err = os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, nil, err
+		}
+		exporter, err = tracing.NewWriterExporter(file)
+	}
 	if err != nil {
-		logrus.Errorf("Could not connect ot DB: %s", err.Error())
-		return
+		if file != nil {
+			file.Close()
+		}
+		return nil, nil, err
 	}
-	defer db.Close()
-	if os.Getenv("MIGRATE_DB") == "true" {
-		db.MigrateDB()
+	tracer := tracing.NewTracer(cfg.ServiceName, exporter)
+	shutdown := func(ctx context.Context) error {
+		err := tracer.Shutdown(ctx)
+		if file != nil {
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		return err
+	}
+	return tracer, shutdown, nil
+}
+
+// newService creates the services of the API on top of the provided datastore
+func newService(cfg *config.Config, datastore repositories.DatastoreIface) (*services.Service, error) {
+	blobStorage, err := newBlobStorage(cfg.Media, cfg.S3)
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize media storage: %s", err.Error())
+	}
+	return &services.Service{
+		DB:      datastore,
+		Storage: blobStorage,
+		Images: services.ImageOptions{
+			MaxSize:        cfg.Media.MaxUploadSize,
+			MaxPixels:      cfg.Media.MaxImagePixels,
+			ThumbnailSizes: cfg.Media.ThumbnailSizes,
+		},
+		Pages: services.PageOptions{
+			DefaultLimit:  cfg.Pagination.DefaultLimit,
+			MaxLimit:      cfg.Pagination.MaxLimit,
+			RejectOverMax: cfg.Pagination.OverMax == "reject",
+		},
+		AllowedImageHosts: cfg.ImageURLs.AllowedHosts,
+		Locales:           cfg.Locales.Locales(),
+	}, nil
+}
+
+// newBlobStorage creates the configured media storage (local or s3)
+func newBlobStorage(cfg config.MediaConfig, s3 config.S3Config) (storage.BlobStorage, error) {
+	if cfg.Storage == "s3" {
+		return storage.NewS3Storage(storage.S3Config{
+			Endpoint:        s3.Endpoint,
+			Region:          s3.Region,
+			Bucket:          s3.Bucket,
+			AccessKeyID:     s3.AccessKeyID,
+			SecretAccessKey: s3.SecretAccessKey,
+		}), nil
+	}
+	return storage.NewLocalStorage(cfg.LocalPath)
+}
+
+// newCachedDatastore decorates the datastore with an in-process cache of the entity reads.
+// A non positive TTL disables the cache.
+func newCachedDatastore(db repositories.DatastoreIface, cfg config.DBConfig) repositories.DatastoreIface {
+	if cfg.CacheTTL <= 0 {
+		return db
+	}
+	return repositories.NewCachedDatastore(db, cfg.CacheTTL, cfg.CacheMaxEntries)
+}
+
+// newCacheClient creates the response cache with the configured backend (memory, redis or none).
+// A nil client means that caching is disabled.
+func newCacheClient(cfg config.CacheConfig) *cache.Client {
+	if cfg.TTL <= 0 {
+		return nil
+	}
+	var backend cache.Backend
+	switch cfg.Backend {
+	case "redis":
+		backend = cache.NewRedisBackend(cache.RedisConfig{Addr: cfg.RedisAddr, Password: cfg.RedisPassword, DB: cfg.RedisDB})
+	case "memory":
+		backend = cache.NewMemoryBackend(cfg.MaxEntries)
+	default:
+		return nil
+	}
+	return cache.NewClient(backend, cfg.TTL, cfg.RefreshKey)
+}
+
+// newRateLimiter creates the rate limiter with the configured store (memory, redis or none).
+// A nil limiter means that rate limiting is disabled.
+func newRateLimiter(cfg config.RateLimitConfig, cacheCfg config.CacheConfig) (*ratelimit.Limiter, error) {
+	quotas, err := cfg.ParseQuotas()
+	if err != nil {
+		return nil, err
+	}
+	trustedProxies, err := ratelimit.ParseNetworks(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	var store ratelimit.Store
+	switch cfg.Store {
+	case "redis":
+		store = ratelimit.NewRedisStore(ratelimit.RedisConfig{Addr: cacheCfg.RedisAddr, Password: cacheCfg.RedisPassword, DB: cacheCfg.RedisDB})
+	case "memory":
+		store = ratelimit.NewMemoryStore()
+	default:
+		return nil, nil
+	}
+	return &ratelimit.Limiter{
+		Store:          store,
+		Quotas:         quotas,
+		APIKeys:        cfg.APIKeys,
+		APIKeyHeader:   cfg.APIKeyHeader,
+		TrustedProxies: trustedProxies,
+	}, nil
+}
+
+// compressionOptions returns the options of the response compression, without encodings when it is disabled
+func compressionOptions(cfg config.CompressionConfig) middlewares.CompressionOptions {
+	if !cfg.Enabled {
+		return middlewares.CompressionOptions{}
+	}
+	return middlewares.CompressionOptions{
+		Encodings:   cfg.Encodings,
+		MinSize:     cfg.MinSize,
+		GzipLevel:   cfg.GzipLevel,
+		BrotliLevel: cfg.BrotliLevel,
+	}
+}
+
+// versionOptions returns the default version of the API and the deprecation of v1
+func versionOptions(cfg config.APIConfig) middlewares.VersionOptions {
+	options := middlewares.VersionOptions{Default: cfg.DefaultVersion, Deprecations: map[int]middlewares.Deprecation{}}
+	if cfg.V1Deprecated {
+		// the dates have been validated with the configuration
+		date, _ := config.ParseDate(cfg.V1DeprecationDate)
+		sunset, _ := config.ParseDate(cfg.V1Sunset)
+		options.Deprecations[dtos.V1] = middlewares.Deprecation{Date: date, Sunset: sunset, Link: cfg.V1DeprecationLink}
+	}
+	return options
+}
+
+// newIdempotencyKeys creates the idempotency keys with the configured store (memory, redis or none).
+// Nil keys mean that the idempotency keys are ignored. The keys of the requests without a principal are scoped
+// to their client the way the rate limiter identifies it, by its API key or address, even when rate limiting is disabled.
+func newIdempotencyKeys(cfg config.IdempotencyConfig, rateLimitCfg config.RateLimitConfig, cacheCfg config.CacheConfig) (*idempotency.Keys, error) {
+	var store idempotency.Store
+	switch cfg.Store {
+	case "redis":
+		store = idempotency.NewRedisStore(idempotency.RedisConfig{Addr: cacheCfg.RedisAddr, Password: cacheCfg.RedisPassword, DB: cacheCfg.RedisDB})
+	case "memory":
+		store = idempotency.NewMemoryStore()
+	default:
+		return nil, nil
+	}
+	trustedProxies, err := ratelimit.ParseNetworks(rateLimitCfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	clients := &ratelimit.Limiter{APIKeys: rateLimitCfg.APIKeys, APIKeyHeader: rateLimitCfg.APIKeyHeader, TrustedProxies: trustedProxies}
+	client := func(r *http.Request) string {
+		client, _ := clients.Client(r)
+		return client
+	}
+	return &idempotency.Keys{Store: store, Header: cfg.Header, TTL: cfg.TTL, LockTTL: cfg.LockTTL, Wait: cfg.Wait, Client: client}, nil
+}
+
+// startLinkChecker starts the background check of the image URLs every interval.
+// A non positive interval disables the check.
+func startLinkChecker(ctx context.Context, workers *sync.WaitGroup, interval time.Duration, db repositories.DatastoreIface, invalidator cache.Invalidator) {
+	if interval <= 0 {
+		return
 	}
-	if os.Getenv("SEED_DATA") == "true" {
-		db.SeedData()
+	checker := &services.LinkChecker{
+		DB:          db,
+		Client:      services.NewLinkCheckClient(10 * time.Second),
+		Interval:    interval,
+		Timeout:     10 * time.Second,
+		Concurrency: 5,
+		Cache:       invalidator,
 	}
-	sv := &services.Service{DB: db}
-	h := controllers.Handler{AppServices: sv}
-	h.ServerRun(":"+os.Getenv("SERVER_PORT"), os.Getenv("API_PREFIX"))
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		checker.Run(ctx)
+	}()
 }
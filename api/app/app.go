@@ -2,8 +2,11 @@ package app
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"net/http"
+	"strings"
+	"time"
 )
 
 // Filter is used in all GET listings
@@ -12,6 +15,60 @@ type Filter struct {
 	Limit         int    `schema:"limit"`
 	SortBy        string `schema:"sortby"`
 	SortDirection string `schema:"sortdirection"`
+	// The bounds of the creation and update timestamps of the listed entities, as parsed by ParseTimeRange
+	CreatedFrom string `schema:"created_from"`
+	CreatedTo   string `schema:"created_to"`
+	UpdatedFrom string `schema:"updated_from"`
+	UpdatedTo   string `schema:"updated_to"`
+	// Created and Updated are the parsed ranges of the timestamps
+	Created TimeRange `schema:"-"`
+	Updated TimeRange `schema:"-"`
+}
+
+// TimeRange is a range of timestamps that includes From and excludes Before. Zero bounds leave the range open.
+type TimeRange struct {
+	From   time.Time
+	Before time.Time
+}
+
+// ParseTimeRange parses the inclusive bounds of a range, each either an RFC 3339 date-time or a date in UTC,
+// e.g. 2020-05-25. A date to includes the whole day and a date-time to includes its whole second, which is
+// the precision of the stored timestamps. Empty bounds leave the range open.
+func ParseTimeRange(from, to string) (TimeRange, error) {
+	var r TimeRange
+	var err error
+	if from != "" {
+		if r.From, _, err = parseTimeBound(from); err != nil {
+			return TimeRange{}, err
+		}
+	}
+	if to != "" {
+		var date bool
+		if r.Before, date, err = parseTimeBound(to); err != nil {
+			return TimeRange{}, err
+		}
+		if date {
+			r.Before = r.Before.AddDate(0, 0, 1)
+		} else {
+			r.Before = r.Before.Truncate(time.Second).Add(time.Second)
+		}
+	}
+	if !r.From.IsZero() && !r.Before.IsZero() && !r.From.Before(r.Before) {
+		return TimeRange{}, fmt.Errorf("%s is after %s", from, to)
+	}
+	return r, nil
+}
+
+// parseTimeBound parses a date-time or a date and reports whether it is a date
+func parseTimeBound(value string) (time.Time, bool, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%s is neither an RFC 3339 date-time nor a date", value)
+	}
+	return t.UTC(), false, nil
 }
 
 const (
@@ -19,6 +76,46 @@ const (
 	DESC string = "DESC"
 )
 
+// Selection is used in all GET endpoints to pick the returned fields and the embedded relationships
+type Selection struct {
+	Fields      string `schema:"fields"`
+	Expand      string `schema:"expand"`
+	ExpandLimit int    `schema:"expand_limit"`
+	// Locales are the translations of the content to return, in order of preference, before falling back to the
+	// content of the default locale
+	Locales []string `schema:"-"`
+}
+
+// FieldsList returns the requested fields. An empty list means all fields.
+func (s Selection) FieldsList() []string {
+	return splitList(s.Fields)
+}
+
+// ExpandList returns the requested relationships to embed
+func (s Selection) ExpandList() []string {
+	return splitList(s.Expand)
+}
+
+// Expands reports whether the relationship with the provided name is requested
+func (s Selection) Expands(relationship string) bool {
+	for _, expand := range s.ExpandList() {
+		if expand == relationship {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(list string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // Error is the way we pass and stack our errors
 type Error struct {
 	// Machine-readable error code.
@@ -29,53 +126,94 @@ type Error struct {
 	Op string
 	// Detailed Error Responce
 	Err error
+	// Field level errors
+	Details []FieldError
 }
 
+// FieldError describes a rule that a request field violates
+type FieldError struct {
+	Field   string
+	Rule    string
+	Message string
+}
+
+// Generic error codes
 const (
-	ECONFLICT    = "conflict"  // action cannot be performed
-	EINTERNAL    = "internal"  // internal error
-	EINVALID     = "invalid"   // validation failed
-	ENOTFOUND    = "not_found" // entity does not exist
-	ENOTACCEPTED = "not_accepted"
+	ECONFLICT    = "conflict"     // action cannot be performed
+	EINTERNAL    = "internal"     // internal error
+	EINVALID     = "invalid"      // validation failed
+	ENOTFOUND    = "not_found"    // entity does not exist
+	ENOTACCEPTED = "not_accepted" // response format is not accepted
+	ERATELIMITED = "rate_limited" // client exceeded its quota of requests
+	ETIMEOUT     = "timeout"      // operation did not complete in time
 )
 
+// StatusCode returns the HTTP status of the error's code as defined in the ErrorCatalogue
 func StatusCode(err error) int {
-	switch ErrorCode(err) {
-	case ECONFLICT:
-		return http.StatusConflict
-	case EINTERNAL:
-		return http.StatusInternalServerError
-	case EINVALID:
-		return http.StatusBadRequest
-	case ENOTFOUND:
-		return http.StatusNotFound
-	case ENOTACCEPTED:
-		return http.StatusNotAcceptable
-	default:
-		return http.StatusInternalServerError
-	}
+	return ErrorDefinitionOf(err).Status
 }
 
+// ErrorCode returns the first code of the error chain. Internal errors caused by an expired deadline,
+// such as the statement timeouts that the repositories wrap, are reported as timeouts.
 func ErrorCode(err error) string {
 	if err == nil {
 		return ""
-	} else if e, ok := err.(*Error); ok && e.Code != "" {
+	}
+	code := errorCode(err)
+	if code == EINTERNAL && errors.Is(err, context.DeadlineExceeded) {
+		return ETIMEOUT
+	}
+	return code
+}
+
+func errorCode(err error) string {
+	if e, ok := err.(*Error); ok && e.Code != "" {
 		return e.Code
 	} else if ok && e.Err != nil {
-		return ErrorCode(e.Err)
+		return errorCode(e.Err)
 	}
 	return EINTERNAL
 }
 
+// ErrorMessage returns the first human-readable message of the error chain.
+// Errors without a message get the title of their code, unless they are internal.
 func ErrorMessage(err error) string {
 	if err == nil {
 		return ""
-	} else if e, ok := err.(*Error); ok && e.Message != "" {
+	}
+	if message := errorMessage(err); message != "" {
+		return message
+	}
+	if definition := ErrorDefinitionOf(err); definition.Code != EINTERNAL {
+		return definition.Title + "."
+	}
+	return "An internal error has occurred. Please contact technical support."
+}
+
+func errorMessage(err error) string {
+	if e, ok := err.(*Error); ok && e.Message != "" {
 		return e.Message
 	} else if ok && e.Err != nil {
-		return ErrorMessage(e.Err)
+		return errorMessage(e.Err)
 	}
-	return "An internal error has occurred. Please contact technical support."
+	return ""
+}
+
+// ErrorDetails returns the field level errors of the first error in the chain that has any
+func ErrorDetails(err error) []FieldError {
+	if err == nil {
+		return nil
+	} else if e, ok := err.(*Error); ok && len(e.Details) > 0 {
+		return e.Details
+	} else if ok && e.Err != nil {
+		return ErrorDetails(e.Err)
+	}
+	return nil
+}
+
+// Unwrap returns the underlying error, so that errors.Is and errors.As inspect the whole chain
+func (e *Error) Unwrap() error {
+	return e.Err
 }
 
 // Error returns the string representation of the error message.
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"net/http"
+	"sort"
+)
+
+// Fine grained error codes. Each one belongs to one of the generic error codes (its class),
+// which is what legacy error responses expose. See docs/errors.md for the documented catalogue.
+const (
+	EINVALIDID             = "invalid_id"              // path ID is not a valid number
+	EINVALIDJSON           = "invalid_json"            // request body is not valid JSON
+	EBODYTOOLARGE          = "body_too_large"          // request body exceeds the maximum size
+	EUNSUPPORTEDENCODING   = "unsupported_encoding"    // request body has an unsupported Content-Encoding
+	EINVALIDLIMIT          = "invalid_limit"           // limit exceeds the maximum page size
+	EINVALIDIDEMPOTENCYKEY = "invalid_idempotency_key" // Idempotency-Key header is too long
+	EIDEMPOTENCYMISMATCH   = "idempotency_key_reused"  // Idempotency-Key was used by a different request
+	EIDEMPOTENCYINFLIGHT   = "idempotency_in_progress" // request with the Idempotency-Key is still in progress
+	EINVALIDSORTFIELD      = "invalid_sort_field"      // sortby is not a field of the listed entity
+	EINVALIDSORTDIRECTION  = "invalid_sort_direction"  // sortdirection is not ASC or DESC
+	EINVALIDTIMERANGE      = "invalid_time_range"      // a timestamp range filter is invalid
+	EINVALIDLOCALE         = "invalid_locale"          // locale is not a supported locale of translations
+	EINVALIDFIELD          = "invalid_field"           // fields contains an unknown field
+	EINVALIDEXPAND         = "invalid_expand"          // expand contains an unsupported relationship
+	EINVALIDIMAGE          = "invalid_image"           // uploaded image is not a supported image
+	EIMAGETOOLARGE         = "image_too_large"         // uploaded image exceeds the maximum size
+	EPRODUCTNOTFOUND       = "product_not_found"       // product does not exist
+	ECATEGORYNOTFOUND      = "category_not_found"      // category does not exist
+	EIMAGENOTFOUND         = "image_not_found"         // product image does not exist
+	EMEDIANOTFOUND         = "media_not_found"         // stored media does not exist
+	ETRANSLATIONNOTFOUND   = "translation_not_found"   // translation to the locale does not exist
+)
+
+// ErrorDefinition describes an error code of the catalogue
+type ErrorDefinition struct {
+	Code string
+	// Class is the generic error code that the code belongs to
+	Class  string
+	Status int
+	Title  string
+}
+
+// ErrorCatalogue holds the definition of every error code that the API responds with
+var ErrorCatalogue = map[string]ErrorDefinition{
+	ECONFLICT:    {Code: ECONFLICT, Class: ECONFLICT, Status: http.StatusConflict, Title: "Conflict"},
+	EINTERNAL:    {Code: EINTERNAL, Class: EINTERNAL, Status: http.StatusInternalServerError, Title: "Internal error"},
+	EINVALID:     {Code: EINVALID, Class: EINVALID, Status: http.StatusBadRequest, Title: "Validation failed"},
+	ENOTFOUND:    {Code: ENOTFOUND, Class: ENOTFOUND, Status: http.StatusNotFound, Title: "Not found"},
+	ENOTACCEPTED: {Code: ENOTACCEPTED, Class: ENOTACCEPTED, Status: http.StatusNotAcceptable, Title: "Not acceptable"},
+	ERATELIMITED: {Code: ERATELIMITED, Class: ERATELIMITED, Status: http.StatusTooManyRequests, Title: "Rate limit exceeded"},
+	ETIMEOUT:     {Code: ETIMEOUT, Class: ETIMEOUT, Status: http.StatusServiceUnavailable, Title: "Timeout"},
+
+	EINVALIDID:             {Code: EINVALIDID, Class: EINVALID, Status: http.StatusBadRequest, Title: "Invalid ID"},
+	EINVALIDJSON:           {Code: EINVALIDJSON, Class: EINVALID, Status: http.StatusBadRequest, Title: "Invalid JSON body"},
+	EBODYTOOLARGE:          {Code: EBODYTOOLARGE, Class: EINVALID, Status: http.StatusRequestEntityTooLarge, Title: "Request body too large"},
+	EUNSUPPORTEDENCODING:   {Code: EUNSUPPORTEDENCODING, Class: EINVALID, Status: http.StatusUnsupportedMediaType, Title: "Unsupported content encoding"},
+	EINVALIDLIMIT:          {Code: EINVALIDLIMIT, Class: EINVALID, Status: http.StatusBadRequest, Title: "Invalid limit"},
+	EINVALIDIDEMPOTENCYKEY: {Code: EINVALIDIDEMPOTENCYKEY, Class: EINVALID, Status: http.StatusBadRequest, Title: "Invalid idempotency key"},
+	EIDEMPOTENCYMISMATCH:   {Code: EIDEMPOTENCYMISMATCH, Class: EINVALID, Status: http.StatusUnprocessableEntity, Title: "Idempotency key reused"},
+	EIDEMPOTENCYINFLIGHT:   {Code: EIDEMPOTENCYINFLIGHT, Class: ECONFLICT, Status: http.StatusConflict, Title: "Idempotent request in progress"},
+	EINVALIDSORTFIELD:      {Code: EINVALIDSORTFIELD, Class: EINVALID, Status: http.StatusBadRequest, Title: "Invalid sort field"},
+	EINVALIDSORTDIRECTION:  {Code: EINVALIDSORTDIRECTION, Class: EINVALID, Status: http.StatusBadRequest, Title: "Invalid sort direction"},
+	EINVALIDTIMERANGE:      {Code: EINVALIDTIMERANGE, Class: EINVALID, Status: http.StatusBadRequest, Title: "Invalid time range"},
+	EINVALIDLOCALE:         {Code: EINVALIDLOCALE, Class: EINVALID, Status: http.StatusBadRequest, Title: "Invalid locale"},
+	EINVALIDFIELD:          {Code: EINVALIDFIELD, Class: EINVALID, Status: http.StatusBadRequest, Title: "Invalid field"},
+	EINVALIDEXPAND:         {Code: EINVALIDEXPAND, Class: EINVALID, Status: http.StatusBadRequest, Title: "Invalid expand"},
+	EINVALIDIMAGE:          {Code: EINVALIDIMAGE, Class: EINVALID, Status: http.StatusBadRequest, Title: "Invalid image"},
+	EIMAGETOOLARGE:         {Code: EIMAGETOOLARGE, Class: EINVALID, Status: http.StatusRequestEntityTooLarge, Title: "Image too large"},
+	EPRODUCTNOTFOUND:       {Code: EPRODUCTNOTFOUND, Class: ENOTFOUND, Status: http.StatusNotFound, Title: "Product not found"},
+	ECATEGORYNOTFOUND:      {Code: ECATEGORYNOTFOUND, Class: ENOTFOUND, Status: http.StatusNotFound, Title: "Category not found"},
+	EIMAGENOTFOUND:         {Code: EIMAGENOTFOUND, Class: ENOTFOUND, Status: http.StatusNotFound, Title: "Image not found"},
+	EMEDIANOTFOUND:         {Code: EMEDIANOTFOUND, Class: ENOTFOUND, Status: http.StatusNotFound, Title: "Media not found"},
+	ETRANSLATIONNOTFOUND:   {Code: ETRANSLATIONNOTFOUND, Class: ENOTFOUND, Status: http.StatusNotFound, Title: "Translation not found"},
+}
+
+// ErrorDefinitionOf returns the catalogue definition of the error's code.
+// Unknown codes are treated as internal errors.
+func ErrorDefinitionOf(err error) ErrorDefinition {
+	if definition, ok := ErrorCatalogue[ErrorCode(err)]; ok {
+		return definition
+	}
+	return ErrorCatalogue[EINTERNAL]
+}
+
+// ErrorClass returns the generic error code that the error's code belongs to
+func ErrorClass(err error) string {
+	if err == nil {
+		return ""
+	}
+	return ErrorDefinitionOf(err).Class
+}
+
+// ErrorCodes returns all the error codes of the catalogue sorted
+func ErrorCodes() []string {
+	codes := make([]string, 0, len(ErrorCatalogue))
+	for code := range ErrorCatalogue {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"strings"
+)
+
+// Locales configures the locales of the titles and descriptions of products and categories
+type Locales struct {
+	// Default is the locale of the content stored on the products and categories themselves
+	Default string
+	// Supported are the locales of the content, including the default one. The others are stored as translations.
+	Supported []string
+}
+
+// Supports returns the supported locale that matches the provided one case insensitively
+func (l Locales) Supports(locale string) (string, bool) {
+	for _, supported := range l.Supported {
+		if strings.EqualFold(supported, locale) {
+			return supported, true
+		}
+	}
+	return "", false
+}
+
+// Translated returns the supported locales other than the default one
+func (l Locales) Translated() []string {
+	translated := make([]string, 0, len(l.Supported))
+	for _, supported := range l.Supported {
+		if !strings.EqualFold(supported, l.Default) {
+			translated = append(translated, supported)
+		}
+	}
+	return translated
+}
+
+// Chain returns the fallback chain of the requested locales, which are in order of preference. Each supported
+// locale is followed by its language when that is supported too (e.g. de-AT by de) and the chain ends with the
+// default locale. Locales that are not supported are skipped.
+func (l Locales) Chain(requested ...string) []string {
+	chain := make([]string, 0, len(requested)+1)
+	add := func(locale string) {
+		if supported, ok := l.Supports(locale); ok && !containsFold(chain, supported) {
+			chain = append(chain, supported)
+		}
+	}
+	for _, locale := range requested {
+		add(locale)
+		if i := strings.Index(locale, "-"); i > 0 {
+			add(locale[:i])
+		}
+	}
+	if !containsFold(chain, l.Default) {
+		chain = append(chain, l.Default)
+	}
+	return chain
+}
+
+// Translations returns the locales of the chain that precede the default locale, whose content is stored as
+// translations. Locales after the default one are never used as the content of the default locale always exists.
+func (l Locales) Translations(chain []string) []string {
+	translations := make([]string, 0, len(chain))
+	for _, locale := range chain {
+		if strings.EqualFold(locale, l.Default) {
+			break
+		}
+		translations = append(translations, locale)
+	}
+	return translations
+}
+
+func containsFold(items []string, item string) bool {
+	for _, i := range items {
+		if strings.EqualFold(i, item) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+// Package auth carries the authenticated principal of a request to the layers that authorise it
+package auth
+
+import "context"
+
+// Principal is an authenticated client of the API
+type Principal struct {
+	// Name identifies the principal and is used as its role, e.g. for the rate limit quotas
+	Name string
+	// Subject is the credential that authenticated the principal, e.g. the subject of its client certificate
+	Subject string
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of the context that carries the principal
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the principal of the context, if there is one
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
@@ -0,0 +1,48 @@
+// Package cache provides tag aware caches with pluggable backends, used by the server side response cache
+package cache
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// Backend stores cached responses under keys and groups them by tags so that they can be invalidated together
+type Backend interface {
+	// Get returns the value stored under the key. The bool reports whether the key was found.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores the value under the key for ttl and attaches the provided tags to it
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error
+	// Invalidate removes all the values that have any of the provided tags
+	Invalidate(ctx context.Context, tags ...string) error
+}
+
+// Invalidator drops cached responses when the data they contain changes
+type Invalidator interface {
+	Invalidate(ctx context.Context, tags ...string) error
+}
+
+// Pinger is implemented by the backends that depend on a remote server
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Tags of the cached responses
+const (
+	// ProductsTag is attached to every response that lists products or embeds them
+	ProductsTag = "products"
+	// CategoriesTag is attached to every response that lists categories or embeds them
+	CategoriesTag = "categories"
+	// ReportsTag is attached to every report response
+	ReportsTag = "reports"
+)
+
+// ProductTag is attached to the responses of a single product and its sub resources
+func ProductTag(productID int64) string {
+	return "product:" + strconv.FormatInt(productID, 10)
+}
+
+// CategoryTag is attached to the responses of a single category
+func CategoryTag(categoryID int64) string {
+	return "category:" + strconv.FormatInt(categoryID, 10)
+}
@@ -0,0 +1,270 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMemoryBackend_EvictsLeastRecentlyUsed(t *testing.T) {
+	//Prepare
+	ctx := context.Background()
+	backend := NewMemoryBackend(2)
+	backend.Set(ctx, "a", []byte("a"), time.Minute, nil)
+	backend.Set(ctx, "b", []byte("b"), time.Minute, nil)
+
+	//Act
+	backend.Get(ctx, "a")
+	backend.Set(ctx, "c", []byte("c"), time.Minute, nil)
+
+	//Assert
+	if _, found, _ := backend.Get(ctx, "b"); found {
+		t.Errorf("Expected least recently used entry b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, found, _ := backend.Get(ctx, key); !found {
+			t.Errorf("Expected entry %s to be cached", key)
+		}
+	}
+}
+
+func TestMemoryBackend_Invalidate(t *testing.T) {
+	//Prepare
+	ctx := context.Background()
+	backend := NewMemoryBackend(10)
+	backend.Set(ctx, "/products/5", []byte("product"), time.Minute, []string{ProductTag(5)})
+	backend.Set(ctx, "/products", []byte("products"), time.Minute, []string{ProductsTag})
+	backend.Set(ctx, "/categories", []byte("categories"), time.Minute, []string{CategoriesTag})
+
+	//Act
+	backend.Invalidate(ctx, ProductTag(5), ProductsTag)
+
+	//Assert
+	if backend.Len() != 1 {
+		t.Errorf("Expected 1 cached entry but got %d", backend.Len())
+	}
+	if _, found, _ := backend.Get(ctx, "/categories"); !found {
+		t.Errorf("Expected /categories to remain cached")
+	}
+}
+
+func TestRequestTags(t *testing.T) {
+	tests := map[string]struct {
+		url          string
+		expectedTags []string
+	}{
+		"Products list":          {url: "/api/products", expectedTags: []string{ProductsTag}},
+		"Single product":         {url: "/api/products/5", expectedTags: []string{ProductTag(5)}},
+		"Product images":         {url: "/api/products/5/images", expectedTags: []string{ProductTag(5)}},
+		"Product with category":  {url: "/api/products/5?expand=category", expectedTags: []string{CategoriesTag, ProductTag(5)}},
+		"Category with products": {url: "/api/categories/2?expand=products", expectedTags: []string{CategoryTag(2), ProductsTag}},
+		"Broken images report":   {url: "/api/reports/broken-images", expectedTags: []string{ReportsTag}},
+		"Missing translations report": {url: "/api/reports/missing-translations",
+			expectedTags: []string{CategoriesTag, ProductsTag, ReportsTag}},
+		"Product translation":     {url: "/api/products/5/translations/el", expectedTags: []string{ProductTag(5)}},
+		"Categories list":         {url: "/api/categories?offset=10", expectedTags: []string{CategoriesTag}},
+		"Path without a resource": {url: "/api/", expectedTags: []string{}},
+	}
+
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			tags := RequestTags(httptest.NewRequest(http.MethodGet, tc.url, nil))
+			if !reflect.DeepEqual(tags, tc.expectedTags) {
+				t.Errorf("Expected tags %v but got %v", tc.expectedTags, tags)
+			}
+		})
+	}
+}
+
+func TestClient_Middleware(t *testing.T) {
+	//Prepare
+	calls := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"calls":%d}`, calls)
+	})
+	client := NewClient(NewMemoryBackend(10), time.Minute, "refresh")
+	server := client.Middleware(handler)
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		return w
+	}
+
+	//Act
+	first := get("/api/products/5")
+	cached := get("/api/products/5")
+	client.Invalidate(context.Background(), ProductTag(5))
+	invalidated := get("/api/products/5")
+	refreshed := get("/api/products/5?refresh")
+
+	//Assert
+	if first.Header().Get("X-Cache") != "MISS" || first.Body.String() != `{"calls":1}` {
+		t.Errorf("Expected first response to be a miss but got %s %s", first.Header().Get("X-Cache"), first.Body.String())
+	}
+	if cached.Header().Get("X-Cache") != "HIT" || cached.Body.String() != `{"calls":1}` {
+		t.Errorf("Expected second response to be a hit but got %s %s", cached.Header().Get("X-Cache"), cached.Body.String())
+	}
+	if cached.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected cached Content-Type application/json but got %s", cached.Header().Get("Content-Type"))
+	}
+	if invalidated.Body.String() != `{"calls":2}` {
+		t.Errorf("Expected invalidated response to be served by the handler but got %s", invalidated.Body.String())
+	}
+	if refreshed.Body.String() != `{"calls":3}` {
+		t.Errorf("Expected refreshed response to be served by the handler but got %s", refreshed.Body.String())
+	}
+}
+
+type failingBackend struct {
+	calls int
+}
+
+func (b *failingBackend) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	b.calls++
+	return nil, false, errors.New("connection refused")
+}
+
+func (b *failingBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	b.calls++
+	return errors.New("connection refused")
+}
+
+func (b *failingBackend) Invalidate(ctx context.Context, tags ...string) error {
+	b.calls++
+	return errors.New("connection refused")
+}
+
+func TestClient_Middleware_WhenBackendDown_Bypasses(t *testing.T) {
+	//Prepare
+	backend := &failingBackend{}
+	client := NewClient(backend, time.Minute, "")
+	server := client.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	//Act
+	responses := make([]*httptest.ResponseRecorder, 0)
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/products", nil))
+		responses = append(responses, w)
+	}
+
+	//Assert
+	for _, w := range responses {
+		if w.Code != http.StatusOK || w.Body.String() != "ok" {
+			t.Errorf("Expected response to be served by the handler but got %d %s", w.Code, w.Body.String())
+		}
+	}
+	if backend.calls != 1 {
+		t.Errorf("Expected the backend to be bypassed after the first failure but got %d calls", backend.calls)
+	}
+}
+
+func TestClient_Middleware_RespectsRequestCacheControl(t *testing.T) {
+	tests := map[string]struct {
+		cacheControl     string
+		expectedBody     string
+		expectedAfterHit string
+	}{
+		"no-cache refreshes the cached response": {cacheControl: "no-cache", expectedBody: `{"calls":2}`, expectedAfterHit: `{"calls":2}`},
+		"no-store bypasses the cache":            {cacheControl: "no-store", expectedBody: `{"calls":2}`, expectedAfterHit: `{"calls":1}`},
+		"max-age uses the cached response":       {cacheControl: "max-age=0, private", expectedBody: `{"calls":1}`, expectedAfterHit: `{"calls":1}`},
+	}
+
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Prepare
+			calls := 0
+			server := NewClient(NewMemoryBackend(10), time.Minute, "").Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				fmt.Fprintf(w, `{"calls":%d}`, calls)
+			}))
+			server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/products", nil))
+			r := httptest.NewRequest(http.MethodGet, "/api/products", nil)
+			r.Header.Set("Cache-Control", tc.cacheControl)
+			w := httptest.NewRecorder()
+			afterHit := httptest.NewRecorder()
+
+			//Act
+			server.ServeHTTP(w, r)
+			server.ServeHTTP(afterHit, httptest.NewRequest(http.MethodGet, "/api/products", nil))
+
+			//Assert
+			if w.Body.String() != tc.expectedBody {
+				t.Errorf("Expected body %s but got %s", tc.expectedBody, w.Body.String())
+			}
+			if afterHit.Body.String() != tc.expectedAfterHit {
+				t.Errorf("Expected next cached body %s but got %s", tc.expectedAfterHit, afterHit.Body.String())
+			}
+		})
+	}
+}
+
+func TestClient_Middleware_DoesNotCacheOuterHeaders(t *testing.T) {
+	//Prepare
+	client := NewClient(NewMemoryBackend(10), time.Minute, "")
+	server := client.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{}`))
+	}))
+	get := func(origin string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/products/5", nil))
+		return w
+	}
+
+	//Act
+	get("https://a.example.com")
+	cached := get("https://b.example.com")
+
+	//Assert
+	if cached.Header().Get("X-Cache") != "HIT" || cached.Header().Get("ETag") != `"v1"` {
+		t.Fatalf("Expected a hit with the handler's headers but got %v", cached.Header())
+	}
+	if origin := cached.Header().Get("Access-Control-Allow-Origin"); origin != "https://b.example.com" {
+		t.Errorf("Expected the outer header of the request but got %s", origin)
+	}
+}
+
+func TestClient_Middleware_CachesEncodingsSeparately(t *testing.T) {
+	//Prepare
+	client := NewClient(NewMemoryBackend(10), time.Minute, "")
+	server := client.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") == "gzip" {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write([]byte("compressed"))
+			return
+		}
+		w.Write([]byte("plain"))
+	}))
+	get := func(acceptEncoding string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/products", nil)
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+		server.ServeHTTP(w, r)
+		return w
+	}
+
+	//Act
+	get("gzip")
+	get("")
+	compressed := get("gzip")
+	plain := get("")
+
+	//Assert
+	if compressed.Header().Get("X-Cache") != "HIT" || compressed.Header().Get("Content-Encoding") != "gzip" || compressed.Body.String() != "compressed" {
+		t.Errorf("Expected the cached gzip variant but got %v %s", compressed.Header(), compressed.Body.String())
+	}
+	if plain.Header().Get("X-Cache") != "HIT" || plain.Header().Get("Content-Encoding") != "" || plain.Body.String() != "plain" {
+		t.Errorf("Expected the cached plain variant but got %v %s", plain.Header(), plain.Body.String())
+	}
+}
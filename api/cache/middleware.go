@@ -0,0 +1,242 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mzampetakis/prods-api/api/logging"
+	"github.com/sirupsen/logrus"
+)
+
+// Client caches the successful GET responses and serves them until they expire or get invalidated.
+// When the backend fails, the cache is bypassed for BypassFor and requests are served directly.
+type Client struct {
+	Backend Backend
+	TTL     time.Duration
+	// RefreshKey is a query parameter that forces the response to be refreshed in the cache
+	RefreshKey string
+	// BypassFor is how long the cache is bypassed after a backend failure
+	BypassFor time.Duration
+	// Tagger returns the tags of the response of a request
+	Tagger func(r *http.Request) []string
+
+	mu          sync.Mutex
+	bypassUntil time.Time
+	hits        uint64
+	misses      uint64
+}
+
+// Stats returns the number of GET requests served from the cache (hits) and by the handler (misses)
+func (c *Client) Stats() (hits uint64, misses uint64) {
+	return atomic.LoadUint64(&c.hits), atomic.LoadUint64(&c.misses)
+}
+
+func NewClient(backend Backend, ttl time.Duration, refreshKey string) *Client {
+	return &Client{
+		Backend:    backend,
+		TTL:        ttl,
+		RefreshKey: refreshKey,
+		BypassFor:  10 * time.Second,
+		Tagger:     RequestTags,
+	}
+}
+
+// cachedResponse is the stored form of a response
+type cachedResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Middleware serves GET requests from the cache and caches the successful responses.
+// Requests with Cache-Control: no-cache or the refresh key refresh the cached response
+// and requests with Cache-Control: no-store bypass the cache.
+func (c *Client) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || c.bypassed() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key := c.key(r)
+		// no-cache revalidates the cached response by refreshing it while no-store neither reads nor stores it
+		requestCacheControl := strings.ToLower(r.Header.Get("Cache-Control"))
+		noStore := strings.Contains(requestCacheControl, "no-store")
+		_, refresh := r.URL.Query()[c.RefreshKey]
+		refresh = (refresh && c.RefreshKey != "") || noStore || strings.Contains(requestCacheControl, "no-cache")
+		if !refresh {
+			value, found, err := c.Backend.Get(r.Context(), key)
+			if err != nil {
+				c.backendFailed(err)
+			} else if found {
+				if response, err := decodeResponse(value); err == nil {
+					atomic.AddUint64(&c.hits, 1)
+					writeResponse(w, response, "HIT")
+					return
+				}
+			}
+		}
+
+		atomic.AddUint64(&c.misses, 1)
+		recorder := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		// the headers set before the handler, e.g. CORS and rate limit headers, vary per client and are not cached
+		outerHeader := w.Header().Clone()
+		w.Header().Set("X-Cache", "MISS")
+		next.ServeHTTP(recorder, r)
+		if recorder.statusCode != http.StatusOK || noStore || c.bypassed() {
+			return
+		}
+		value, err := encodeResponse(cachedResponse{StatusCode: recorder.statusCode, Header: handlerHeader(w.Header(), outerHeader), Body: recorder.body.Bytes()})
+		if err != nil {
+			logging.FromContext(r.Context()).Warnf("Could not encode response for cache: %s", err.Error())
+			return
+		}
+		if err = c.Backend.Set(r.Context(), key, value, c.TTL, c.Tagger(r)); err != nil {
+			c.backendFailed(err)
+		}
+	})
+}
+
+// Ping checks that the cache backend is reachable. Backends that are not Pingers are always reachable.
+func (c *Client) Ping(ctx context.Context) error {
+	if pinger, ok := c.Backend.(Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// Close releases the connections of the cache backend, if any
+func (c *Client) Close() error {
+	if closer, ok := c.Backend.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Invalidate removes the cached responses with any of the provided tags
+func (c *Client) Invalidate(ctx context.Context, tags ...string) error {
+	if err := c.Backend.Invalidate(ctx, tags...); err != nil {
+		c.backendFailed(err)
+		return err
+	}
+	return nil
+}
+
+// key identifies a response by its path, its sorted query without the refresh key, its accepted format,
+// its accepted encodings and its accepted languages, so that the compressed and the localized variants
+// of a response are cached separately
+func (c *Client) key(r *http.Request) string {
+	query := r.URL.Query()
+	query.Del(c.RefreshKey)
+	return r.URL.Path + "?" + query.Encode() + "|" + r.Header.Get("Accept") + "|" + r.Header.Get("Accept-Encoding") +
+		"|" + r.Header.Get("Accept-Language")
+}
+
+func (c *Client) bypassed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Now().Before(c.bypassUntil)
+}
+
+func (c *Client) backendFailed(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bypassUntil = time.Now().Add(c.BypassFor)
+	logrus.Warnf("Cache backend failed, bypassing cache for %s: %s", c.BypassFor, err.Error())
+}
+
+// RequestTags tags a response by the resources of its path and the relationships it expands.
+// E.g. /products/5 is tagged with product:5 and /categories?expand=products with categories and products.
+// The missing translations report is tagged with products and categories as it changes along with them.
+func RequestTags(r *http.Request) []string {
+	tags := make([]string, 0)
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	for i, segment := range segments {
+		var id int64
+		var err error = strconv.ErrSyntax
+		if i+1 < len(segments) {
+			id, err = strconv.ParseInt(segments[i+1], 10, 64)
+		}
+		switch {
+		case segment == "products" && err == nil:
+			tags = append(tags, ProductTag(id))
+		case segment == "products":
+			tags = append(tags, ProductsTag)
+		case segment == "categories" && err == nil:
+			tags = append(tags, CategoryTag(id))
+		case segment == "categories":
+			tags = append(tags, CategoriesTag)
+		case segment == "reports":
+			tags = append(tags, ReportsTag)
+		case segment == "missing-translations":
+			tags = append(tags, ProductsTag, CategoriesTag)
+		}
+	}
+	for _, expand := range strings.Split(r.URL.Query().Get("expand"), ",") {
+		switch strings.TrimSpace(expand) {
+		case "products":
+			tags = append(tags, ProductsTag)
+		case "category":
+			tags = append(tags, CategoriesTag)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+func encodeResponse(response cachedResponse) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(response)
+	return buf.Bytes(), err
+}
+
+func decodeResponse(value []byte) (cachedResponse, error) {
+	var response cachedResponse
+	err := gob.NewDecoder(bytes.NewReader(value)).Decode(&response)
+	return response, err
+}
+
+func writeResponse(w http.ResponseWriter, response cachedResponse, cacheStatus string) {
+	for name, values := range response.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("X-Cache", cacheStatus)
+	w.WriteHeader(response.StatusCode)
+	w.Write(response.Body)
+}
+
+// handlerHeader returns the headers of the response that were set or changed after the outer ones
+func handlerHeader(header http.Header, outer http.Header) http.Header {
+	handler := make(http.Header)
+	for name, values := range header {
+		if strings.Join(outer[name], ",") != strings.Join(values, ",") {
+			handler[name] = values
+		}
+	}
+	return handler
+}
+
+// responseRecorder captures the status code and the body of a response while writing it
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// RedisConfig holds the connection details of the Redis server
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+	// KeyPrefix namespaces the keys of the cache
+	KeyPrefix string
+}
+
+// RedisBackend stores the cached responses in Redis. Each tag is a set holding the keys it is attached to.
+type RedisBackend struct {
+	client    *redis.Client
+	keyPrefix string
+}
+
+func NewRedisBackend(config RedisConfig) *RedisBackend {
+	if config.KeyPrefix == "" {
+		config.KeyPrefix = "prods-api:cache:"
+	}
+	return &RedisBackend{
+		client: redis.NewClient(&redis.Options{
+			Addr:        config.Addr,
+			Password:    config.Password,
+			DB:          config.DB,
+			DialTimeout: time.Second,
+			ReadTimeout: time.Second,
+			MaxRetries:  0,
+		}),
+		keyPrefix: config.KeyPrefix,
+	}
+}
+
+func (r *RedisBackend) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := r.client.WithContext(ctx).Get(r.keyPrefix + key).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (r *RedisBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	_, err := r.client.WithContext(ctx).TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.Set(r.keyPrefix+key, value, ttl)
+		for _, tag := range tags {
+			pipe.SAdd(r.tagKey(tag), key)
+			// the tag set outlives its keys by a ttl so that it never expires before them
+			pipe.Expire(r.tagKey(tag), 2*ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+func (r *RedisBackend) Invalidate(ctx context.Context, tags ...string) error {
+	client := r.client.WithContext(ctx)
+	for _, tag := range tags {
+		keys, err := client.SMembers(r.tagKey(tag)).Result()
+		if err != nil {
+			return err
+		}
+		toDelete := []string{r.tagKey(tag)}
+		for _, key := range keys {
+			toDelete = append(toDelete, r.keyPrefix+key)
+		}
+		if err = client.Del(toDelete...).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ping checks that Redis is reachable
+func (r *RedisBackend) Ping(ctx context.Context) error {
+	return r.client.WithContext(ctx).Ping().Err()
+}
+
+// Close closes the connections to Redis
+func (r *RedisBackend) Close() error {
+	return r.client.Close()
+}
+
+func (r *RedisBackend) tagKey(tag string) string {
+	return r.keyPrefix + "tag:" + tag
+}
@@ -0,0 +1,347 @@
+package api
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/config"
+	"github.com/mzampetakis/prods-api/api/logging"
+	"github.com/mzampetakis/prods-api/api/repositories"
+	"github.com/mzampetakis/prods-api/api/services"
+	"github.com/mzampetakis/prods-api/api/tlsconfig"
+)
+
+// Exit codes of the commands
+const (
+	ExitOK = 0
+	// ExitFailure is returned when a command fails, e.g. the DB is unreachable or some rows could not be imported
+	ExitFailure = 1
+	// ExitUsage is returned for unknown commands, invalid flags and invalid configuration
+	ExitUsage = 2
+	// ExitPending is returned by migrate status when some tables of the schema do not exist
+	ExitPending = 3
+)
+
+// command is a subcommand of the CLI. Its run function receives the arguments that follow the command's name.
+type command struct {
+	name        string
+	usage       string
+	description string
+	run         func(name string, args []string) int
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "serve", usage: "serve [flags]", description: "Serve the API (the default command)", run: serveCommand},
+		{name: "migrate", usage: "migrate up|down|status [flags]", description: "Create, drop or list the tables of the DB schema", run: migrateCommand},
+		{name: "seed", usage: "seed [--file data.sql] [flags]", description: "Replace the DB's data with the statements of an SQL file", run: seedCommand},
+		{name: "import", usage: "import [flags] products.csv", description: "Create the products of a CSV file", run: importCommand},
+		{name: "export", usage: "export [--format ndjson|csv] [--output file] [flags] products|categories", description: "Write all products or categories", run: exportCommand},
+		{name: "check-config", usage: "check-config [flags]", description: "Validate the configuration", run: checkConfigCommand},
+		{name: "config", usage: "config print [flags]", description: "Print the effective configuration with the secrets redacted", run: configCommand},
+	}
+}
+
+// Main runs the command of the provided arguments and returns its exit code.
+// Without a command, or when the first argument is a flag, the API is served.
+func Main(args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return serveCommand("serve", args)
+	}
+	if args[0] == "help" {
+		usage(os.Stdout)
+		return ExitOK
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(c.name, args[1:])
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", args[0])
+	usage(os.Stderr)
+	return ExitUsage
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: prods-api <command> [flags] [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %s\n      %s\n", c.usage, c.description)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run prods-api <command> --help for the flags of each command.")
+}
+
+// loadConfig loads the configuration with the command's flags, reporting each invalid setting
+func loadConfig(flags *flag.FlagSet, args []string) (*config.Config, bool) {
+	cfg, err := config.Load(flags, args)
+	if _, ok := err.(*app.Error); err != nil && !ok {
+		// flag errors have already been reported with the usage
+		return nil, false
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, app.ErrorMessage(err))
+		for _, detail := range app.ErrorDetails(err) {
+			fmt.Fprintln(os.Stderr, "  - "+detail.Message)
+		}
+		return nil, false
+	}
+	if err = logging.Configure(cfg.Log.Level, cfg.Log.Format); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return nil, false
+	}
+	return cfg, true
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet("prods-api "+name, flag.ContinueOnError)
+}
+
+// fail reports the error of a command and returns ExitFailure
+func fail(format string, a ...interface{}) int {
+	fmt.Fprintf(os.Stderr, format+"\n", a...)
+	return ExitFailure
+}
+
+func serveCommand(name string, args []string) int {
+	cfg, ok := loadConfig(newFlagSet(name), args)
+	if !ok {
+		return ExitUsage
+	}
+	if err := Run(cfg); err != nil {
+		return fail("%s", err.Error())
+	}
+	return ExitOK
+}
+
+func migrateCommand(name string, args []string) int {
+	if len(args) == 0 || (args[0] != "up" && args[0] != "down" && args[0] != "status") {
+		fmt.Fprintln(os.Stderr, "Usage: prods-api migrate up|down|status [flags]")
+		return ExitUsage
+	}
+	action := args[0]
+	flags := newFlagSet(name + " " + action)
+	force := flags.Bool("force", false, "Confirm dropping all tables (migrate down only)")
+	cfg, ok := loadConfig(flags, args[1:])
+	if !ok {
+		return ExitUsage
+	}
+	if action == "down" && !*force {
+		fmt.Fprintln(os.Stderr, "migrate down drops all tables and their data, run it with --force to confirm")
+		return ExitUsage
+	}
+	db, err := connectDB(cfg)
+	if err != nil {
+		return fail("%s", err.Error())
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	switch action {
+	case "up":
+		created, err := db.MigrateUp(ctx)
+		if err != nil {
+			return fail("Could not migrate the DB: %s", err.Error())
+		}
+		if len(created) == 0 {
+			fmt.Println("The DB schema is up to date")
+		}
+		for _, table := range created {
+			fmt.Println("Created table " + table)
+		}
+	case "down":
+		if err = db.MigrateDown(ctx); err != nil {
+			return fail("Could not drop the DB schema: %s", err.Error())
+		}
+		fmt.Println("Dropped all tables")
+	case "status":
+		status, err := db.MigrationsStatus(ctx)
+		if err != nil {
+			return fail("Could not read the DB schema: %s", err.Error())
+		}
+		exitCode := ExitOK
+		for _, table := range status {
+			state := "applied"
+			if !table.Applied {
+				state = "pending"
+				exitCode = ExitPending
+			}
+			fmt.Printf("%-20s %s\n", table.Table, state)
+		}
+		return exitCode
+	}
+	return ExitOK
+}
+
+func seedCommand(name string, args []string) int {
+	flags := newFlagSet(name)
+	file := flags.String("file", repositories.DataScript, "SQL file with the statements that insert the data")
+	cfg, ok := loadConfig(flags, args)
+	if !ok {
+		return ExitUsage
+	}
+	db, err := connectDB(cfg)
+	if err != nil {
+		return fail("%s", err.Error())
+	}
+	defer db.Close()
+	if err = db.Seed(context.Background(), *file); err != nil {
+		return fail("Could not seed the DB: %s", err.Error())
+	}
+	fmt.Println("Seeded the DB from " + *file)
+	return ExitOK
+}
+
+func importCommand(name string, args []string) int {
+	flags := newFlagSet(name)
+	cfg, ok := loadConfig(flags, args)
+	if !ok {
+		return ExitUsage
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: prods-api import [flags] products.csv")
+		return ExitUsage
+	}
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return fail("Could not open %s: %s", flags.Arg(0), err.Error())
+	}
+	defer file.Close()
+	db, err := connectDB(cfg)
+	if err != nil {
+		return fail("%s", err.Error())
+	}
+	defer db.Close()
+	sv, closeService, err := newCommandService(cfg, db)
+	if err != nil {
+		return fail("%s", err.Error())
+	}
+	defer closeService()
+
+	result, err := ImportProducts(context.Background(), sv, file)
+	if err != nil {
+		return fail("Could not import %s: %s", flags.Arg(0), err.Error())
+	}
+	for _, rowErr := range result.Errors {
+		fmt.Fprintln(os.Stderr, rowErr.Error())
+	}
+	fmt.Printf("Imported %d products, %d failed\n", result.Imported, len(result.Errors))
+	if len(result.Errors) > 0 {
+		return ExitFailure
+	}
+	return ExitOK
+}
+
+func exportCommand(name string, args []string) int {
+	flags := newFlagSet(name)
+	format := flags.String("format", FormatNDJSON, "Output format, ndjson or csv")
+	output := flags.String("output", "", "File to write to instead of stdout")
+	cfg, ok := loadConfig(flags, args)
+	if !ok {
+		return ExitUsage
+	}
+	entity := "products"
+	if flags.NArg() > 0 {
+		entity = flags.Arg(0)
+	}
+	if flags.NArg() > 1 || (entity != "products" && entity != "categories") || (*format != FormatNDJSON && *format != FormatCSV) {
+		fmt.Fprintln(os.Stderr, "Usage: prods-api export [--format ndjson|csv] [--output file] [flags] products|categories")
+		return ExitUsage
+	}
+	db, err := connectDB(cfg)
+	if err != nil {
+		return fail("%s", err.Error())
+	}
+	defer db.Close()
+	sv, closeService, err := newCommandService(cfg, db)
+	if err != nil {
+		return fail("%s", err.Error())
+	}
+	defer closeService()
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fail("Could not create %s: %s", *output, err.Error())
+		}
+		defer file.Close()
+		w = file
+	}
+	var count int
+	if entity == "categories" {
+		count, err = ExportCategories(context.Background(), sv, *format, w)
+	} else {
+		count, err = ExportProducts(context.Background(), sv, *format, w)
+	}
+	if err != nil {
+		return fail("Could not export the %s: %s", entity, err.Error())
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d %s\n", count, entity)
+	return ExitOK
+}
+
+func checkConfigCommand(name string, args []string) int {
+	cfg, ok := loadConfig(newFlagSet(name), args)
+	if !ok {
+		return ExitUsage
+	}
+	if cfg.TLS.Enabled() {
+		if _, err := tlsconfig.ServerConfig(tlsOptions(cfg.TLS)); err != nil {
+			return fail("Invalid TLS configuration: %s", err.Error())
+		}
+	}
+	fmt.Println("Configuration is valid")
+	return ExitOK
+}
+
+func configCommand(name string, args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "Usage: prods-api config print [flags]")
+		return ExitUsage
+	}
+	cfg, ok := loadConfig(newFlagSet(name+" print"), args[1:])
+	if !ok {
+		return ExitUsage
+	}
+	if err := cfg.Print(os.Stdout); err != nil {
+		return fail("%s", err.Error())
+	}
+	return ExitOK
+}
+
+// connectDB connects to the configured MySQL DB, retrying while it starts up
+func connectDB(cfg *config.Config) (*repositories.DB, error) {
+	db, err := repositories.ConnectDB("mysql", cfg.MySQL.URL(), cfg.DB.ConnectRetries, cfg.DB.ConnectBackoff)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to DB: %s", err.Error())
+	}
+	return db, nil
+}
+
+// newCommandService creates the services used by the commands that change or read the DB's data.
+// When the API's response cache is shared through Redis the changes invalidate it.
+func newCommandService(cfg *config.Config, db *repositories.DB) (*services.Service, func(), error) {
+	sv, err := newService(cfg, db)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the commands page through all rows, regardless of the page size of the API
+	sv.Pages.MaxLimit = 0
+	closeService := func() {}
+	if cfg.Cache.Backend == "redis" {
+		if cacheClient := newCacheClient(cfg.Cache); cacheClient != nil {
+			sv.Cache = cacheClient
+			closeService = func() { cacheClient.Close() }
+		}
+	}
+	return sv, closeService, nil
+}
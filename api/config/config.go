@@ -0,0 +1,513 @@
+// Package config provides the typed configuration of the API. Each setting has a default value which is
+// overridden by the config file, then by the env vars and finally by the command line flags.
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/idempotency"
+	"github.com/mzampetakis/prods-api/api/ratelimit"
+	"github.com/mzampetakis/prods-api/api/tlsconfig"
+	"github.com/sirupsen/logrus"
+)
+
+// localeTag matches the language tags of the locales, e.g. en or de-AT
+var localeTag = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// Config is the configuration of the API. Each setting is read from the config file with its key path
+// (e.g. server.port), from its env var (e.g. SERVER_PORT) and from its flag (e.g. --server-port).
+type Config struct {
+	Server      ServerConfig      `key:"server"`
+	TLS         TLSConfig         `key:"tls"`
+	MySQL       MySQLConfig       `key:"mysql"`
+	DB          DBConfig          `key:"db"`
+	Pagination  PaginationConfig  `key:"pagination"`
+	Media       MediaConfig       `key:"media"`
+	S3          S3Config          `key:"s3"`
+	ImageURLs   ImageURLsConfig   `key:"image_urls"`
+	Cache       CacheConfig       `key:"cache"`
+	HTTPCache   HTTPCacheConfig   `key:"http_cache"`
+	Health      HealthConfig      `key:"health"`
+	Log         LogConfig         `key:"log"`
+	Tracing     TracingConfig     `key:"tracing"`
+	RateLimit   RateLimitConfig   `key:"rate_limit"`
+	Idempotency IdempotencyConfig `key:"idempotency"`
+	CORS        CORSConfig        `key:"cors"`
+	Security    SecurityConfig    `key:"security_headers"`
+	Compression CompressionConfig `key:"compression"`
+	API         APIConfig         `key:"api"`
+	Locales     LocalesConfig     `key:"locales"`
+}
+
+type ServerConfig struct {
+	Port              int           `key:"port" env:"SERVER_PORT"`
+	APIPrefix         string        `key:"api_prefix" env:"API_PREFIX"`
+	ReadTimeout       time.Duration `key:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	ReadHeaderTimeout time.Duration `key:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	WriteTimeout      time.Duration `key:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `key:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	MaxHeaderBytes    int           `key:"max_header_bytes" env:"SERVER_MAX_HEADER_BYTES"`
+	ShutdownTimeout   time.Duration `key:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+	MaxBodyBytes      int64         `key:"max_body_bytes" env:"SERVER_MAX_BODY_BYTES"`
+	// MaxUploadBodyBytes is the maximum size of the multipart bodies of the image uploads
+	MaxUploadBodyBytes int64 `key:"max_upload_body_bytes" env:"SERVER_MAX_UPLOAD_BODY_BYTES"`
+}
+
+type TLSConfig struct {
+	// CertFile and KeyFile of the server certificate. HTTPS is served when they are set.
+	CertFile string `key:"cert_file" env:"TLS_CERT_FILE"`
+	KeyFile  string `key:"key_file" env:"TLS_KEY_FILE"`
+	// ReloadInterval between the checks of the certificate files for changes
+	ReloadInterval time.Duration `key:"reload_interval" env:"TLS_RELOAD_INTERVAL"`
+	HTTP2          bool          `key:"http2" env:"TLS_HTTP2"`
+	// ClientAuth is the client certificate authentication: none, optional or require
+	ClientAuth   string `key:"client_auth" env:"TLS_CLIENT_AUTH"`
+	ClientCAFile string `key:"client_ca_file" env:"TLS_CLIENT_CA_FILE"`
+	// ClientPrincipals maps the subjects (e.g. CN=orders,O=Shop) or common names of the client certificates to principals
+	ClientPrincipals map[string]string `key:"client_principals" env:"TLS_CLIENT_PRINCIPALS"`
+}
+
+// Enabled reports whether the server serves HTTPS
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != ""
+}
+
+type MySQLConfig struct {
+	Host     string `key:"host" env:"MYSQL_HOST"`
+	Port     int    `key:"port" env:"MYSQL_PORT"`
+	Database string `key:"database" env:"MYSQL_DATABASE"`
+	User     string `key:"user" env:"MYSQL_USER"`
+	Password string `key:"password" env:"MYSQL_PASSWORD" secret:"true"`
+}
+
+// URL returns the connection URL of the MySQL DB. The sessions use the UTC time zone, so that the timestamps
+// are read and written in UTC regardless of the time zone of the DB server and the application.
+func (c MySQLConfig) URL() string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8&parseTime=True&loc=UTC&time_zone=%%27%%2B00%%3A00%%27",
+		c.User, c.Password, c.Host, c.Port, c.Database)
+}
+
+type DBConfig struct {
+	ConnectRetries int           `key:"connect_retries" env:"DB_CONNECT_RETRIES"`
+	ConnectBackoff time.Duration `key:"connect_backoff" env:"DB_CONNECT_BACKOFF"`
+	// CacheTTL of the entity reads' cache. A non positive TTL disables the cache, which is only
+	// consistent for a single instance as its invalidations are not shared.
+	CacheTTL        time.Duration `key:"cache_ttl" env:"DB_CACHE_TTL"`
+	CacheMaxEntries int           `key:"cache_max_entries" env:"DB_CACHE_MAX_ENTRIES"`
+	// StatementTimeout bounds each DB statement of the requests. Zero means no bound.
+	StatementTimeout time.Duration `key:"statement_timeout" env:"DB_STATEMENT_TIMEOUT"`
+	// RouteStatementTimeouts overrides StatementTimeout for the paths that start with each key
+	RouteStatementTimeouts map[string]time.Duration `key:"route_statement_timeouts" env:"DB_ROUTE_STATEMENT_TIMEOUTS"`
+}
+
+type PaginationConfig struct {
+	DefaultLimit int `key:"default_limit" env:"PAGINATION_DEFAULT_LIMIT"`
+	// MaxLimit of the listings. Zero means no maximum.
+	MaxLimit int `key:"max_limit" env:"PAGINATION_MAX_LIMIT"`
+	// OverMax is what happens to the limits over MaxLimit: clamp to MaxLimit or reject the request
+	OverMax string `key:"over_max" env:"PAGINATION_OVER_MAX"`
+}
+
+type MediaConfig struct {
+	Storage       string `key:"storage" env:"MEDIA_STORAGE"`
+	LocalPath     string `key:"local_path" env:"MEDIA_LOCAL_PATH"`
+	MaxUploadSize int64  `key:"max_upload_size" env:"MEDIA_MAX_UPLOAD_SIZE"`
+	// MaxImagePixels is the maximum width × height of the uploaded images
+	MaxImagePixels int64          `key:"max_image_pixels" env:"MEDIA_MAX_IMAGE_PIXELS"`
+	ThumbnailSizes map[string]int `key:"thumbnail_sizes" env:"MEDIA_THUMBNAIL_SIZES"`
+}
+
+type S3Config struct {
+	Endpoint        string `key:"endpoint" env:"S3_ENDPOINT"`
+	Region          string `key:"region" env:"S3_REGION"`
+	Bucket          string `key:"bucket" env:"S3_BUCKET"`
+	AccessKeyID     string `key:"access_key_id" env:"S3_ACCESS_KEY_ID"`
+	SecretAccessKey string `key:"secret_access_key" env:"S3_SECRET_ACCESS_KEY" secret:"true"`
+}
+
+type ImageURLsConfig struct {
+	AllowedHosts []string `key:"allowed_hosts" env:"IMAGE_URL_ALLOWED_HOSTS"`
+	// LinkCheckInterval between the checks of the image URLs. A non positive interval disables the check.
+	LinkCheckInterval time.Duration `key:"link_check_interval" env:"LINK_CHECK_INTERVAL"`
+}
+
+type CacheConfig struct {
+	Backend string `key:"backend" env:"CACHE_BACKEND"`
+	// TTL of the cached responses. A non positive TTL disables the cache.
+	TTL           time.Duration `key:"ttl" env:"CACHE_TTL"`
+	MaxEntries    int           `key:"max_entries" env:"CACHE_MAX_ENTRIES"`
+	RedisAddr     string        `key:"redis_addr" env:"CACHE_REDIS_ADDR"`
+	RedisPassword string        `key:"redis_password" env:"CACHE_REDIS_PASSWORD" secret:"true"`
+	RedisDB       int           `key:"redis_db" env:"CACHE_REDIS_DB"`
+	RefreshKey    string        `key:"refresh_key" env:"CACHE_REFRESH_KEY" secret:"true"`
+}
+
+type HTTPCacheConfig struct {
+	MaxAge       time.Duration            `key:"max_age" env:"HTTP_CACHE_MAX_AGE"`
+	RouteMaxAges map[string]time.Duration `key:"route_max_ages" env:"HTTP_CACHE_ROUTE_MAX_AGES"`
+}
+
+type HealthConfig struct {
+	CheckTimeout time.Duration `key:"check_timeout" env:"HEALTH_CHECK_TIMEOUT"`
+}
+
+type LogConfig struct {
+	Level         string   `key:"level" env:"LOG_LEVEL"`
+	Format        string   `key:"format" env:"LOG_FORMAT"`
+	Headers       bool     `key:"headers" env:"LOG_HEADERS"`
+	RedactHeaders []string `key:"redact_headers" env:"LOG_REDACT_HEADERS"`
+}
+
+type TracingConfig struct {
+	Exporter     string `key:"exporter" env:"TRACING_EXPORTER"`
+	File         string `key:"file" env:"TRACING_FILE"`
+	OTLPEndpoint string `key:"otlp_endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	ServiceName  string `key:"service_name" env:"OTEL_SERVICE_NAME"`
+}
+
+type RateLimitConfig struct {
+	// Store of the token buckets (memory, redis or none). The redis store uses the Redis server of the cache.
+	Store string `key:"store" env:"RATE_LIMIT_STORE"`
+	// Quotas holds the quota (e.g. 100/1m) of each role and optionally of each role.group (e.g. anonymous.write)
+	Quotas       map[string]string `key:"quotas" env:"RATE_LIMIT_QUOTAS"`
+	APIKeys      map[string]string `key:"api_keys" env:"RATE_LIMIT_API_KEYS" secret:"true"`
+	APIKeyHeader string            `key:"api_key_header" env:"RATE_LIMIT_API_KEY_HEADER"`
+	// TrustedProxies are the CIDR networks or addresses of the proxies whose X-Forwarded-For entries are trusted
+	TrustedProxies []string `key:"trusted_proxies" env:"RATE_LIMIT_TRUSTED_PROXIES"`
+}
+
+type IdempotencyConfig struct {
+	// Store of the responses (memory, redis or none). The redis store uses the Redis server of the cache.
+	Store  string `key:"store" env:"IDEMPOTENCY_STORE"`
+	Header string `key:"header" env:"IDEMPOTENCY_HEADER"`
+	// TTL of the stored responses
+	TTL time.Duration `key:"ttl" env:"IDEMPOTENCY_TTL"`
+	// LockTTL bounds how long a key stays in progress if its request never completes
+	LockTTL time.Duration `key:"lock_ttl" env:"IDEMPOTENCY_LOCK_TTL"`
+	// Wait is how long the duplicates of a request in progress wait for its response before getting a 409
+	Wait time.Duration `key:"wait" env:"IDEMPOTENCY_WAIT"`
+}
+
+type CORSConfig struct {
+	// AllowedOrigins of the browser requests, e.g. https://*.example.com. CORS is disabled when empty.
+	AllowedOrigins   []string      `key:"allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+	AllowedMethods   []string      `key:"allowed_methods" env:"CORS_ALLOWED_METHODS"`
+	AllowedHeaders   []string      `key:"allowed_headers" env:"CORS_ALLOWED_HEADERS"`
+	ExposedHeaders   []string      `key:"exposed_headers" env:"CORS_EXPOSED_HEADERS"`
+	AllowCredentials bool          `key:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS"`
+	MaxAge           time.Duration `key:"max_age" env:"CORS_MAX_AGE"`
+}
+
+type SecurityConfig struct {
+	// HSTSMaxAge of the Strict-Transport-Security header. Zero omits the header.
+	HSTSMaxAge            time.Duration `key:"hsts_max_age" env:"SECURITY_HSTS_MAX_AGE"`
+	HSTSIncludeSubdomains bool          `key:"hsts_include_subdomains" env:"SECURITY_HSTS_INCLUDE_SUBDOMAINS"`
+	ContentTypeNosniff    bool          `key:"content_type_nosniff" env:"SECURITY_CONTENT_TYPE_NOSNIFF"`
+	FrameOptions          string        `key:"frame_options" env:"SECURITY_FRAME_OPTIONS"`
+	ReferrerPolicy        string        `key:"referrer_policy" env:"SECURITY_REFERRER_POLICY"`
+	ContentSecurityPolicy string        `key:"content_security_policy" env:"SECURITY_CONTENT_SECURITY_POLICY"`
+	// SwaggerContentSecurityPolicy of the Swagger UI pages
+	SwaggerContentSecurityPolicy string `key:"swagger_content_security_policy" env:"SECURITY_SWAGGER_CONTENT_SECURITY_POLICY"`
+}
+
+type CompressionConfig struct {
+	// Enabled compresses the GET responses with the encodings that the clients accept
+	Enabled bool `key:"enabled" env:"COMPRESSION_ENABLED"`
+	// Encodings of the responses in order of preference (br, gzip)
+	Encodings []string `key:"encodings" env:"COMPRESSION_ENCODINGS"`
+	// MinSize in bytes of the compressed responses
+	MinSize     int `key:"min_size" env:"COMPRESSION_MIN_SIZE"`
+	GzipLevel   int `key:"gzip_level" env:"COMPRESSION_GZIP_LEVEL"`
+	BrotliLevel int `key:"brotli_level" env:"COMPRESSION_BROTLI_LEVEL"`
+}
+
+type APIConfig struct {
+	// DefaultVersion of the requests to the unversioned routes without a vendor media type
+	DefaultVersion int  `key:"default_version" env:"API_DEFAULT_VERSION"`
+	V1Deprecated   bool `key:"v1_deprecated" env:"API_V1_DEPRECATED"`
+	// V1DeprecationDate and V1Sunset are RFC 3339 dates, e.g. 2027-06-30, or date-times
+	V1DeprecationDate string `key:"v1_deprecation_date" env:"API_V1_DEPRECATION_DATE"`
+	V1Sunset          string `key:"v1_sunset" env:"API_V1_SUNSET"`
+	// V1DeprecationLink is the URL of the migration guide of the v1 clients
+	V1DeprecationLink string `key:"v1_deprecation_link" env:"API_V1_DEPRECATION_LINK"`
+	// Currency of the prices, as the ISO 4217 code that the v2 contract exposes
+	Currency string `key:"currency" env:"API_CURRENCY"`
+}
+
+type LocalesConfig struct {
+	// Default locale of the titles and descriptions stored on the products and categories themselves
+	Default string `key:"default" env:"LOCALES_DEFAULT"`
+	// Supported locales of the titles and descriptions, including the default one
+	Supported []string `key:"supported" env:"LOCALES_SUPPORTED"`
+}
+
+// Locales returns the configured locales
+func (c LocalesConfig) Locales() app.Locales {
+	return app.Locales{Default: c.Default, Supported: c.Supported}
+}
+
+// Default returns the default configuration
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Port:               8080,
+			APIPrefix:          "/api",
+			ReadTimeout:        30 * time.Second,
+			ReadHeaderTimeout:  5 * time.Second,
+			WriteTimeout:       30 * time.Second,
+			IdleTimeout:        2 * time.Minute,
+			MaxHeaderBytes:     1 << 20,
+			ShutdownTimeout:    30 * time.Second,
+			MaxBodyBytes:       1 << 20,
+			MaxUploadBodyBytes: 50 << 20,
+		},
+		TLS: TLSConfig{
+			ReloadInterval:   10 * time.Second,
+			HTTP2:            true,
+			ClientAuth:       tlsconfig.ClientAuthNone,
+			ClientPrincipals: map[string]string{},
+		},
+		MySQL: MySQLConfig{Host: "127.0.0.1", Port: 3306},
+		DB: DBConfig{
+			ConnectRetries:         5,
+			ConnectBackoff:         time.Second,
+			CacheTTL:               0,
+			CacheMaxEntries:        1000,
+			StatementTimeout:       5 * time.Second,
+			RouteStatementTimeouts: map[string]time.Duration{},
+		},
+		Pagination: PaginationConfig{DefaultLimit: 3, MaxLimit: 100, OverMax: "clamp"},
+		Media: MediaConfig{
+			Storage:        "local",
+			LocalPath:      "media",
+			MaxUploadSize:  5 << 20,
+			MaxImagePixels: 40000000,
+			ThumbnailSizes: map[string]int{"small": 150, "medium": 300, "large": 600},
+		},
+		ImageURLs: ImageURLsConfig{AllowedHosts: []string{}, LinkCheckInterval: time.Hour},
+		Cache: CacheConfig{
+			Backend:    "memory",
+			TTL:        time.Minute,
+			MaxEntries: 1000,
+			RedisAddr:  ":6379",
+		},
+		HTTPCache: HTTPCacheConfig{RouteMaxAges: map[string]time.Duration{}},
+		Health:    HealthConfig{CheckTimeout: 2 * time.Second},
+		Log:       LogConfig{Level: "info", Format: "text", RedactHeaders: []string{}},
+		Tracing: TracingConfig{
+			Exporter:     "none",
+			File:         "traces.json",
+			OTLPEndpoint: "http://localhost:4318",
+			ServiceName:  "prods-api",
+		},
+		RateLimit: RateLimitConfig{
+			Store:        "memory",
+			Quotas:       map[string]string{ratelimit.AnonymousRole: "300/1m", ratelimit.AnonymousRole + "." + ratelimit.WriteGroup: "60/1m"},
+			APIKeys:      map[string]string{},
+			APIKeyHeader: "X-Api-Key",
+		},
+		Idempotency: IdempotencyConfig{
+			Store:   "memory",
+			Header:  idempotency.DefaultHeader,
+			TTL:     24 * time.Hour,
+			LockTTL: time.Minute,
+			Wait:    5 * time.Second,
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{},
+			AllowedMethods: []string{"GET", "HEAD", "POST", "PUT", "DELETE"},
+			AllowedHeaders: []string{"Accept", "Content-Type", "Content-Encoding", "If-None-Match", "If-Modified-Since", "X-Api-Key", idempotency.DefaultHeader},
+			ExposedHeaders: []string{"ETag", "Last-Modified", "Retry-After", "X-Cache", "Idempotent-Replayed",
+				"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Deprecation", "Sunset", "Link"},
+			MaxAge: 10 * time.Minute,
+		},
+		Security: SecurityConfig{
+			HSTSMaxAge:            0,
+			ContentTypeNosniff:    true,
+			FrameOptions:          "DENY",
+			ReferrerPolicy:        "no-referrer",
+			ContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'",
+			SwaggerContentSecurityPolicy: "default-src 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'; " +
+				"img-src 'self' data:; frame-ancestors 'none'",
+		},
+		Compression: CompressionConfig{
+			Enabled:     true,
+			Encodings:   []string{"br", "gzip"},
+			MinSize:     1024,
+			GzipLevel:   6,
+			BrotliLevel: 4,
+		},
+		API:     APIConfig{DefaultVersion: 1, V1Deprecated: true, Currency: "EUR"},
+		Locales: LocalesConfig{Default: "en", Supported: []string{"en", "el", "de"}},
+	}
+}
+
+// Validate checks the configuration and returns an app.Error with a detail for each invalid setting
+func (c *Config) Validate() error {
+	v := &validator{}
+	v.check(c.Server.Port > 0 && c.Server.Port <= 65535, "server.port", "range", "must be between 1 and 65535")
+	v.check(c.Server.APIPrefix == "" || strings.HasPrefix(c.Server.APIPrefix, "/"), "server.api_prefix", "format", "must start with /")
+	v.check(c.Server.ReadTimeout >= 0, "server.read_timeout", "min", "must not be negative")
+	v.check(c.Server.ReadHeaderTimeout >= 0, "server.read_header_timeout", "min", "must not be negative")
+	v.check(c.Server.WriteTimeout >= 0, "server.write_timeout", "min", "must not be negative")
+	v.check(c.Server.IdleTimeout >= 0, "server.idle_timeout", "min", "must not be negative")
+	v.check(c.Server.MaxHeaderBytes > 0, "server.max_header_bytes", "min", "must be positive")
+	v.check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout", "min", "must be positive")
+	v.check(c.Server.MaxBodyBytes > 0, "server.max_body_bytes", "min", "must be positive")
+	v.check(c.Server.MaxUploadBodyBytes > 0, "server.max_upload_body_bytes", "min", "must be positive")
+
+	v.check(c.TLS.KeyFile != "" || !c.TLS.Enabled(), "tls.key_file", "required", "is required for the certificate")
+	v.check(c.TLS.ReloadInterval >= 0, "tls.reload_interval", "min", "must not be negative")
+	v.oneOf(c.TLS.ClientAuth, "tls.client_auth", tlsconfig.ClientAuthNone, tlsconfig.ClientAuthOptional, tlsconfig.ClientAuthRequire)
+	if c.TLS.ClientAuth != tlsconfig.ClientAuthNone {
+		v.check(c.TLS.Enabled(), "tls.cert_file", "required", "is required for the client authentication")
+		v.check(c.TLS.ClientCAFile != "", "tls.client_ca_file", "required", "is required for the client authentication")
+	}
+
+	v.check(c.MySQL.Host != "", "mysql.host", "required", "is required")
+	v.check(c.MySQL.Port > 0 && c.MySQL.Port <= 65535, "mysql.port", "range", "must be between 1 and 65535")
+	v.check(c.MySQL.Database != "", "mysql.database", "required", "is required")
+	v.check(c.MySQL.User != "", "mysql.user", "required", "is required")
+	v.check(c.DB.ConnectRetries >= 0, "db.connect_retries", "min", "must not be negative")
+	v.check(c.DB.ConnectBackoff > 0, "db.connect_backoff", "min", "must be positive")
+	v.check(c.DB.CacheMaxEntries >= 0, "db.cache_max_entries", "min", "must not be negative")
+	v.check(c.DB.StatementTimeout >= 0, "db.statement_timeout", "min", "must not be negative")
+	for route, timeout := range c.DB.RouteStatementTimeouts {
+		v.check(timeout >= 0, "db.route_statement_timeouts", "min", "of "+route+" must not be negative")
+	}
+	v.check(c.Pagination.DefaultLimit > 0, "pagination.default_limit", "min", "must be positive")
+	v.check(c.Pagination.MaxLimit >= 0, "pagination.max_limit", "min", "must not be negative")
+	v.check(c.Pagination.MaxLimit == 0 || c.Pagination.DefaultLimit <= c.Pagination.MaxLimit, "pagination.default_limit", "max", "must not exceed pagination.max_limit")
+	v.oneOf(c.Pagination.OverMax, "pagination.over_max", "clamp", "reject")
+
+	v.oneOf(c.Media.Storage, "media.storage", "local", "s3")
+	v.check(c.Media.Storage != "local" || c.Media.LocalPath != "", "media.local_path", "required", "is required for the local storage")
+	v.check(c.Media.MaxUploadSize > 0, "media.max_upload_size", "min", "must be positive")
+	v.check(c.Media.MaxImagePixels > 0, "media.max_image_pixels", "min", "must be positive")
+	for name, width := range c.Media.ThumbnailSizes {
+		v.check(width > 0, "media.thumbnail_sizes", "min", "width of "+name+" must be positive")
+	}
+	v.check(c.Media.Storage != "s3" || c.S3.Bucket != "", "s3.bucket", "required", "is required for the s3 storage")
+
+	v.oneOf(c.Cache.Backend, "cache.backend", "memory", "redis", "none")
+	v.check(c.Cache.Backend != "redis" || c.Cache.RedisAddr != "", "cache.redis_addr", "required", "is required for the redis backend")
+	v.check(c.Cache.MaxEntries >= 0, "cache.max_entries", "min", "must not be negative")
+	v.check(c.Cache.RedisDB >= 0, "cache.redis_db", "min", "must not be negative")
+	v.check(c.Health.CheckTimeout > 0, "health.check_timeout", "min", "must be positive")
+
+	if _, err := logrus.ParseLevel(c.Log.Level); err != nil {
+		v.check(false, "log.level", "oneof", "must be one of trace, debug, info, warn, error")
+	}
+	v.oneOf(c.Log.Format, "log.format", "text", "json")
+	v.oneOf(c.Tracing.Exporter, "tracing.exporter", "none", "stdout", "file", "otlp")
+	v.check(c.Tracing.Exporter != "file" || c.Tracing.File != "", "tracing.file", "required", "is required for the file exporter")
+	v.check(c.Tracing.Exporter != "otlp" || c.Tracing.OTLPEndpoint != "", "tracing.otlp_endpoint", "required", "is required for the otlp exporter")
+	v.check(c.Tracing.ServiceName != "", "tracing.service_name", "required", "is required")
+
+	v.oneOf(c.RateLimit.Store, "rate_limit.store", "memory", "redis", "none")
+	v.check(c.RateLimit.Store != "redis" || c.Cache.RedisAddr != "", "cache.redis_addr", "required", "is required for the redis rate limit store")
+	if _, err := c.RateLimit.ParseQuotas(); err != nil {
+		v.check(false, "rate_limit.quotas", "format", err.Error())
+	}
+	if _, err := ratelimit.ParseNetworks(c.RateLimit.TrustedProxies); err != nil {
+		v.check(false, "rate_limit.trusted_proxies", "format", err.Error())
+	}
+	v.check(len(c.RateLimit.APIKeys) == 0 || c.RateLimit.APIKeyHeader != "", "rate_limit.api_key_header", "required", "is required for the API keys")
+
+	v.oneOf(c.Idempotency.Store, "idempotency.store", "memory", "redis", "none")
+	v.check(c.Idempotency.Store != "redis" || c.Cache.RedisAddr != "", "cache.redis_addr", "required", "is required for the redis idempotency store")
+	v.check(c.Idempotency.Header != "", "idempotency.header", "required", "is required")
+	v.check(c.Idempotency.TTL > 0, "idempotency.ttl", "min", "must be positive")
+	v.check(c.Idempotency.LockTTL > 0, "idempotency.lock_ttl", "min", "must be positive")
+	v.check(c.Idempotency.Wait >= 0, "idempotency.wait", "min", "must not be negative")
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		v.check(origin == "*" || strings.HasPrefix(origin, "http://") || strings.HasPrefix(origin, "https://"),
+			"cors.allowed_origins", "format", "must be * or start with http:// or https://, got "+origin)
+	}
+	v.check(len(c.CORS.AllowedOrigins) == 0 || len(c.CORS.AllowedMethods) > 0, "cors.allowed_methods", "required", "is required for the allowed origins")
+	v.check(c.CORS.MaxAge >= 0, "cors.max_age", "min", "must not be negative")
+	v.check(c.Security.HSTSMaxAge >= 0, "security_headers.hsts_max_age", "min", "must not be negative")
+	v.oneOf(c.Security.FrameOptions, "security_headers.frame_options", "", "DENY", "SAMEORIGIN")
+
+	v.check(!c.Compression.Enabled || len(c.Compression.Encodings) > 0, "compression.encodings", "required", "is required when compression is enabled")
+	for _, encoding := range c.Compression.Encodings {
+		v.oneOf(encoding, "compression.encodings", "br", "gzip")
+	}
+	v.check(c.Compression.MinSize >= 0, "compression.min_size", "min", "must not be negative")
+	v.check(c.Compression.GzipLevel >= 1 && c.Compression.GzipLevel <= 9, "compression.gzip_level", "range", "must be between 1 and 9")
+	v.check(c.Compression.BrotliLevel >= 0 && c.Compression.BrotliLevel <= 11, "compression.brotli_level", "range", "must be between 0 and 11")
+
+	v.check(c.API.DefaultVersion == 1 || c.API.DefaultVersion == 2, "api.default_version", "oneof", "must be one of 1, 2")
+	if _, err := ParseDate(c.API.V1DeprecationDate); err != nil {
+		v.check(false, "api.v1_deprecation_date", "format", "must be an RFC 3339 date, e.g. 2027-06-30")
+	}
+	if _, err := ParseDate(c.API.V1Sunset); err != nil {
+		v.check(false, "api.v1_sunset", "format", "must be an RFC 3339 date, e.g. 2027-06-30")
+	}
+	v.check(len(c.API.Currency) == 3 && strings.ToUpper(c.API.Currency) == c.API.Currency, "api.currency", "format", "must be an ISO 4217 code, e.g. EUR")
+
+	for _, locale := range c.Locales.Supported {
+		v.check(localeTag.MatchString(locale), "locales.supported", "format", "must be language tags, e.g. en or de-AT, got "+locale)
+	}
+	_, supported := c.Locales.Locales().Supports(c.Locales.Default)
+	v.check(supported, "locales.default", "oneof", "must be one of locales.supported")
+
+	if len(v.details) > 0 {
+		return &app.Error{Op: "config.Validate", Code: app.EINVALID, Message: "Invalid configuration", Details: v.details}
+	}
+	return nil
+}
+
+// ParseQuotas parses the quota of each role
+func (c RateLimitConfig) ParseQuotas() (map[string]ratelimit.Quota, error) {
+	roles := make([]string, 0, len(c.Quotas))
+	for role := range c.Quotas {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	quotas := make(map[string]ratelimit.Quota, len(c.Quotas))
+	for _, role := range roles {
+		quota, err := ratelimit.ParseQuota(c.Quotas[role])
+		if err != nil {
+			return nil, fmt.Errorf("of %s %s", role, err.Error())
+		}
+		quotas[role] = quota
+	}
+	return quotas, nil
+}
+
+// ParseDate parses an RFC 3339 date (e.g. 2027-06-30) or date-time. An empty value is the zero time.
+func ParseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+type validator struct {
+	details []app.FieldError
+}
+
+func (v *validator) check(valid bool, field string, rule string, message string) {
+	if !valid {
+		v.details = append(v.details, app.FieldError{Field: field, Rule: rule, Message: field + " " + message})
+	}
+}
+
+func (v *validator) oneOf(value string, field string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.check(false, field, "oneof", "must be one of "+strings.Join(allowed, ", "))
+}
@@ -0,0 +1,214 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mzampetakis/prods-api/api/app"
+)
+
+func setEnv(t *testing.T, env map[string]string) {
+	for name, value := range env {
+		os.Setenv(name, value)
+	}
+	t.Cleanup(func() {
+		for name := range env {
+			os.Unsetenv(name)
+		}
+	})
+}
+
+func writeFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	//Prepare
+	path := writeFile(t, "config.yaml", `
+server:
+  port: 9000
+  read_timeout: 10s
+mysql:
+  database: prods_db
+  user: file_user
+media:
+  thumbnail_sizes:
+    small: 100
+log:
+  level: warn
+`)
+	setEnv(t, map[string]string{"MYSQL_USER": "env_user", "SERVER_READ_TIMEOUT": "20s", "LOG_LEVEL": "error"})
+
+	//Act
+	cfg, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"--config", path, "--log-level", "debug"})
+
+	//Assert
+	if err != nil {
+		t.Fatalf("Unexpected error %v", app.ErrorDetails(err))
+	}
+	if cfg.Server.Port != 9000 || cfg.MySQL.Database != "prods_db" {
+		t.Errorf("Expected the file to override the defaults")
+	}
+	if cfg.MySQL.User != "env_user" || cfg.Server.ReadTimeout != 20*time.Second {
+		t.Errorf("Expected the env vars to override the file")
+	}
+	if cfg.Log.Level != "debug" {
+		t.Errorf("Expected the flags to override the env vars but got level %s", cfg.Log.Level)
+	}
+	if cfg.Server.WriteTimeout != 30*time.Second || cfg.MySQL.Port != 3306 {
+		t.Errorf("Expected the unset settings to keep their defaults")
+	}
+	if len(cfg.Media.ThumbnailSizes) != 1 || cfg.Media.ThumbnailSizes["small"] != 100 {
+		t.Errorf("Unexpected thumbnail sizes %v", cfg.Media.ThumbnailSizes)
+	}
+}
+
+func TestLoad_TOML(t *testing.T) {
+	//Prepare
+	path := writeFile(t, "config.toml", `
+[mysql]
+database = "prods_db"
+user = "prods_user"
+
+[http_cache]
+route_max_ages = { "/categories" = "5m" }
+
+[image_urls]
+allowed_hosts = ["example.com", "*.example.org"]
+`)
+
+	//Act
+	cfg, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"--config", path})
+
+	//Assert
+	if err != nil {
+		t.Fatalf("Unexpected error %v", app.ErrorDetails(err))
+	}
+	if cfg.HTTPCache.RouteMaxAges["/categories"] != 5*time.Minute {
+		t.Errorf("Unexpected route max-ages %v", cfg.HTTPCache.RouteMaxAges)
+	}
+	if strings.Join(cfg.ImageURLs.AllowedHosts, ",") != "example.com,*.example.org" {
+		t.Errorf("Unexpected allowed hosts %v", cfg.ImageURLs.AllowedHosts)
+	}
+}
+
+func TestLoad_FileMapKeysWithCommas(t *testing.T) {
+	//Prepare
+	path := writeFile(t, "config.yaml", `
+mysql:
+  database: prods_db
+  user: prods_user
+tls:
+  client_principals:
+    "CN=orders,O=Shop": shop-orders
+    reports: reports-service
+`)
+
+	//Act
+	cfg, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"--config", path})
+
+	//Assert
+	if err != nil {
+		t.Fatalf("Unexpected error %v", app.ErrorDetails(err))
+	}
+	expected := map[string]string{"CN=orders,O=Shop": "shop-orders", "reports": "reports-service"}
+	if !reflect.DeepEqual(cfg.TLS.ClientPrincipals, expected) {
+		t.Errorf("Expected client principals %v but got %v", expected, cfg.TLS.ClientPrincipals)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := map[string]struct {
+		args           []string
+		expectedFields []string
+	}{
+		"Missing required settings": {
+			args:           []string{},
+			expectedFields: []string{"mysql.database", "mysql.user"},
+		},
+		"Invalid values": {
+			args:           []string{"--mysql-database", "db", "--mysql-user", "user", "--server-port", "0", "--cache-backend", "memcached", "--tracing-exporter", "jaeger"},
+			expectedFields: []string{"server.port", "cache.backend", "tracing.exporter"},
+		},
+		"Unparsable values": {
+			args:           []string{"--mysql-database", "db", "--mysql-user", "user", "--server-read-timeout", "10", "--log-headers", "yes please"},
+			expectedFields: []string{"server.read_timeout", "log.headers"},
+		},
+		"Dependent settings": {
+			args:           []string{"--mysql-database", "db", "--mysql-user", "user", "--media-storage", "s3"},
+			expectedFields: []string{"s3.bucket"},
+		},
+		"Client authentication without TLS": {
+			args:           []string{"--mysql-database", "db", "--mysql-user", "user", "--tls-client-auth", "require"},
+			expectedFields: []string{"tls.cert_file", "tls.client_ca_file"},
+		},
+		"Unknown compression encoding": {
+			args:           []string{"--mysql-database", "db", "--mysql-user", "user", "--compression-encodings", "zstd,gzip"},
+			expectedFields: []string{"compression.encodings"},
+		},
+		"Invalid API version settings": {
+			args:           []string{"--mysql-database", "db", "--mysql-user", "user", "--api-default-version", "3", "--api-v1-sunset", "soon", "--api-currency", "eur"},
+			expectedFields: []string{"api.default_version", "api.v1_sunset", "api.currency"},
+		},
+		"Invalid trusted proxies": {
+			args:           []string{"--mysql-database", "db", "--mysql-user", "user", "--rate-limit-trusted-proxies", "10.0.0.0/8,proxy"},
+			expectedFields: []string{"rate_limit.trusted_proxies"},
+		},
+		"Invalid locales": {
+			args:           []string{"--mysql-database", "db", "--mysql-user", "user", "--locales-default", "fr", "--locales-supported", "en,el_GR"},
+			expectedFields: []string{"locales.supported", "locales.default"},
+		},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Act
+			_, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), tc.args)
+
+			//Assert
+			if app.ErrorCode(err) != app.EINVALID {
+				t.Fatalf("Expected error code %s but got %s", app.EINVALID, app.ErrorCode(err))
+			}
+			details := app.ErrorDetails(err)
+			if len(details) != len(tc.expectedFields) {
+				t.Fatalf("Expected %d invalid settings but got %v", len(tc.expectedFields), details)
+			}
+			for i, field := range tc.expectedFields {
+				if details[i].Field != field {
+					t.Errorf("Expected invalid setting %s but got %s", field, details[i].Field)
+				}
+			}
+		})
+	}
+}
+
+func TestConfig_Print(t *testing.T) {
+	//Prepare
+	cfg := Default()
+	cfg.MySQL.Password = "s3cr3t"
+	out := &bytes.Buffer{}
+
+	//Act
+	err := cfg.Print(out)
+
+	//Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "s3cr3t") || !strings.Contains(out.String(), "password: '[REDACTED]'") {
+		t.Errorf("Expected the password to be redacted")
+	}
+	if !strings.Contains(out.String(), "  port: 8080\n") || !strings.Contains(out.String(), "  read_timeout: 30s\n") {
+		t.Errorf("Expected the settings in the format of the config file but got\n%s", out.String())
+	}
+}
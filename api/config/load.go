@@ -0,0 +1,340 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"github.com/mzampetakis/prods-api/api/app"
+	"gopkg.in/yaml.v2"
+)
+
+// FileEnv is the env var with the path of the config file, which can also be set with the --config flag
+const FileEnv = "CONFIG_FILE"
+
+// setting is a single setting of the configuration
+type setting struct {
+	// key is the dot separated path of the setting in the config file
+	key    string
+	env    string
+	secret bool
+	value  reflect.Value
+}
+
+// flagName returns the command line flag of the setting, e.g. server-port for server.port
+func (s setting) flagName() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(s.key)
+}
+
+// Load builds the configuration from the defaults, the config file, the .env file and the env vars
+// and the provided command line flags in increasing precedence, and validates it
+func Load(flags *flag.FlagSet, args []string) (*Config, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	// flags are parsed first to find the config file but are applied last
+	configFile := flags.String("config", "", "Path of the YAML or TOML config file (env "+FileEnv+")")
+	flagValues := make(map[string]interface{})
+	for _, s := range settings {
+		flags.Var(&flagValue{key: s.key, values: flagValues}, s.flagName(), "Overrides "+s.key+" (env "+s.env+")")
+	}
+	// the flag set reports its errors itself
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := godotenv.Load(".env"); err != nil && !os.IsNotExist(err) {
+		return nil, &app.Error{Op: "config.Load", Message: "Could not read .env file: " + err.Error(), Err: err}
+	}
+	if *configFile == "" {
+		*configFile = os.Getenv(FileEnv)
+	}
+	if *configFile != "" {
+		fileValues, err := readFile(*configFile)
+		if err != nil {
+			return nil, &app.Error{Op: "config.Load", Message: "Could not read config file " + *configFile + ": " + err.Error(), Err: err}
+		}
+		if err = apply(settings, fileValues); err != nil {
+			return nil, err
+		}
+	}
+	envValues := make(map[string]interface{})
+	for _, s := range settings {
+		if value := os.Getenv(s.env); value != "" {
+			envValues[s.key] = value
+		}
+	}
+	if err := apply(settings, envValues); err != nil {
+		return nil, err
+	}
+	if err := apply(settings, flagValues); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// flagValue records the raw value of a setting's flag
+type flagValue struct {
+	key    string
+	values map[string]interface{}
+}
+
+func (f *flagValue) String() string {
+	if value, ok := f.values[f.key]; ok {
+		return fmt.Sprintf("%v", value)
+	}
+	return ""
+}
+
+func (f *flagValue) Set(value string) error {
+	f.values[f.key] = value
+	return nil
+}
+
+// Print writes the configuration as YAML, in the format of the config file, with the values of the secret settings redacted
+func (c *Config) Print(w io.Writer) error {
+	root := yaml.MapSlice{}
+	for _, s := range c.settings() {
+		var value interface{} = printValue(s.value)
+		if s.secret && !isEmpty(s.value) {
+			value = "[REDACTED]"
+		}
+		parts := strings.SplitN(s.key, ".", 2)
+		if len(root) == 0 || root[len(root)-1].Key != parts[0] {
+			root = append(root, yaml.MapItem{Key: parts[0], Value: yaml.MapSlice{}})
+		}
+		section := root[len(root)-1].Value.(yaml.MapSlice)
+		root[len(root)-1].Value = append(section, yaml.MapItem{Key: parts[1], Value: value})
+	}
+	out, err := yaml.Marshal(root)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// printValue converts a setting to a value that is marshalled in the syntax of the config file
+func printValue(field reflect.Value) interface{} {
+	switch {
+	case field.Type() == durationType:
+		return time.Duration(field.Int()).String()
+	case field.Kind() == reflect.Map:
+		keys := make([]string, 0, field.Len())
+		for _, key := range field.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys)
+		m := yaml.MapSlice{}
+		for _, key := range keys {
+			m = append(m, yaml.MapItem{Key: key, Value: printValue(field.MapIndex(reflect.ValueOf(key)))})
+		}
+		return m
+	default:
+		return field.Interface()
+	}
+}
+
+func isEmpty(field reflect.Value) bool {
+	switch field.Kind() {
+	case reflect.String, reflect.Map, reflect.Slice:
+		return field.Len() == 0
+	}
+	return field.IsZero()
+}
+
+// settings lists the settings of the configuration in the order of their declaration
+func (c *Config) settings() []setting {
+	settings := make([]setting, 0)
+	sections := reflect.ValueOf(c).Elem()
+	for i := 0; i < sections.NumField(); i++ {
+		section := sections.Field(i)
+		sectionKey := sections.Type().Field(i).Tag.Get("key")
+		for j := 0; j < section.NumField(); j++ {
+			field := section.Type().Field(j)
+			settings = append(settings, setting{
+				key:    sectionKey + "." + field.Tag.Get("key"),
+				env:    field.Tag.Get("env"),
+				secret: field.Tag.Get("secret") == "true",
+				value:  section.Field(j),
+			})
+		}
+	}
+	return settings
+}
+
+// apply sets the provided raw values, by setting key, to the settings. The values are either textual or,
+// for the config file, decoded.
+func apply(settings []setting, values map[string]interface{}) error {
+	details := make([]app.FieldError, 0)
+	for _, s := range settings {
+		value, ok := values[s.key]
+		if !ok {
+			continue
+		}
+		if err := set(s.value, value); err != nil {
+			details = append(details, app.FieldError{Field: s.key, Rule: "format", Message: s.key + " " + err.Error()})
+		}
+		delete(values, s.key)
+	}
+	unknown := make([]string, 0, len(values))
+	for key := range values {
+		unknown = append(unknown, key)
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		details = append(details, app.FieldError{Field: key, Rule: "unknown", Message: key + " is not a known setting"})
+	}
+	if len(details) > 0 {
+		return &app.Error{Op: "config.apply", Code: app.EINVALID, Message: "Invalid configuration", Details: details}
+	}
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// set sets a setting from its raw value. The lists and maps of the config file are set item by item,
+// so that their items may contain commas, while all other values are parsed from their text.
+func set(field reflect.Value, value interface{}) error {
+	if list, ok := value.([]interface{}); ok && field.Kind() == reflect.Slice {
+		items := reflect.MakeSlice(field.Type(), 0, len(list))
+		for _, item := range list {
+			element := reflect.New(field.Type().Elem()).Elem()
+			if err := parse(element, fmt.Sprintf("%v", item)); err != nil {
+				return fmt.Errorf("has an invalid item %v: %s", item, err.Error())
+			}
+			items = reflect.Append(items, element)
+		}
+		field.Set(items)
+		return nil
+	}
+	if m, ok := toStringMap(value); ok && field.Kind() == reflect.Map {
+		items := reflect.MakeMapWithSize(field.Type(), len(m))
+		for key, item := range m {
+			element := reflect.New(field.Type().Elem()).Elem()
+			if err := parse(element, fmt.Sprintf("%v", item)); err != nil {
+				return fmt.Errorf("has an invalid value for %s: %s", key, err.Error())
+			}
+			items.SetMapIndex(reflect.ValueOf(strings.TrimSpace(key)), element)
+		}
+		field.Set(items)
+		return nil
+	}
+	return parse(field, fmt.Sprintf("%v", value))
+}
+
+// parse sets a setting from its textual value. Lists are comma separated and maps are comma separated key:value pairs.
+func parse(field reflect.Value, value string) error {
+	value = strings.TrimSpace(value)
+	switch {
+	case field.Type() == durationType:
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return errors.New("must be a duration like 30s or 5m")
+		}
+		field.SetInt(int64(duration))
+	case field.Kind() == reflect.String:
+		field.SetString(value)
+	case field.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("must be true or false")
+		}
+		field.SetBool(b)
+	case field.Kind() == reflect.Int || field.Kind() == reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		field.SetInt(i)
+	case field.Kind() == reflect.Slice:
+		items := make([]string, 0)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	case field.Kind() == reflect.Map:
+		m := reflect.MakeMap(field.Type())
+		for _, pair := range strings.Split(value, ",") {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+			// keys may contain colons, e.g. paths, so the value follows the last one
+			separator := strings.LastIndex(pair, ":")
+			if separator <= 0 {
+				return fmt.Errorf("must be comma separated key:value pairs but got %s", pair)
+			}
+			item := reflect.New(field.Type().Elem()).Elem()
+			if err := parse(item, pair[separator+1:]); err != nil {
+				return fmt.Errorf("has an invalid value for %s: %s", pair[:separator], err.Error())
+			}
+			m.SetMapIndex(reflect.ValueOf(strings.TrimSpace(pair[:separator])), item)
+		}
+		field.Set(m)
+	default:
+		return fmt.Errorf("has an unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+// readFile reads a YAML or TOML config file, depending on its extension, to decoded values by setting key
+func readFile(path string) (map[string]interface{}, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sections map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &sections)
+	case ".toml":
+		err = toml.Unmarshal(content, &sections)
+	default:
+		err = errors.New("unsupported config file format, use .yaml, .yml or .toml")
+	}
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]interface{})
+	for sectionKey, section := range sections {
+		settings, ok := toStringMap(section)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a section of settings", sectionKey)
+		}
+		for key, value := range settings {
+			values[sectionKey+"."+key] = value
+		}
+	}
+	return values, nil
+}
+
+// toStringMap converts the maps decoded by the YAML and TOML decoders to a map by string keys
+func toStringMap(value interface{}) (map[string]interface{}, bool) {
+	switch m := value.(type) {
+	case map[string]interface{}:
+		return m, true
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(m))
+		for key, item := range m {
+			converted[fmt.Sprintf("%v", key)] = item
+		}
+		return converted, true
+	}
+	return nil, false
+}
@@ -1,7 +1,6 @@
 package controllers
 
 import (
-	"encoding/json"
 	"net/http"
 	"strconv"
 
@@ -9,7 +8,7 @@ import (
 	"github.com/gorilla/schema"
 	"github.com/mzampetakis/prods-api/api/app"
 	"github.com/mzampetakis/prods-api/api/controllers/dtos"
-	"github.com/sirupsen/logrus"
+	"github.com/mzampetakis/prods-api/api/logging"
 )
 
 // GetAllCategories godoc
@@ -17,26 +16,43 @@ import (
 // @Summary Retrives Categories - uses filtering
 // @Description Retrieve a list of Categories
 // @Tags Categories
-// @Produce json
+// @Produce json,application/xml,application/msgpack,text/csv
 // @Param offset query integer false "Offset of the results"
 // @Param limit query integer false "Limit the results"
 // @Param sortby query string false "Sort by of the results"
 // @Param sortdirection query string false "Sort direction of the results (ASC|DESC)"
+// @Param created_from query string false "Earliest creation date-time or date (RFC 3339)"
+// @Param created_to query string false "Latest creation date-time or date (RFC 3339)"
+// @Param updated_from query string false "Earliest update date-time or date (RFC 3339)"
+// @Param updated_to query string false "Latest update date-time or date (RFC 3339)"
+// @Param fields query string false "Comma separated fields to return (id is always returned)"
+// @Param expand query string false "Comma separated relationships to embed (products)"
+// @Param expand_limit query integer false "Limit the embedded products of each category"
+// @Param locale query string false "Locale of the titles and descriptions, preferred over the Accept-Language header"
+// @Param Accept-Language header string false "Preferred locales of the titles and descriptions"
 // @Success 200 {object} dtos.CategoriesResponseDto
 // @Failure 400 {object} dtos.ServeError
 // @Failure 500 {object} dtos.ServeError
 // @Router /categories [get]
 func (h *Handler) GetAllCategories(w http.ResponseWriter, r *http.Request) {
 	filter := new(app.Filter)
+	selection := new(app.Selection)
 	r.ParseForm()
 	schema.NewDecoder().Decode(filter, r.Form)
-	categories, err := h.AppServices.GetCategories(r.Context(), *filter)
+	schema.NewDecoder().Decode(selection, r.Form)
+	if err := h.localize(w, r, selection); err != nil {
+		logging.FromContext(r.Context()).Warn(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.GetAllCategories", Err: err})
+		return
+	}
+	categories, err := h.AppServices.GetCategories(r.Context(), *filter, *selection)
 	if err != nil {
-		logrus.Warn(err.Error())
+		logging.FromContext(r.Context()).Warn(err.Error())
 		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.GetAllCategories", Err: err})
 		return
 	}
-	dtos.JSON(w, http.StatusOK, dtos.ConvertCategoriesResponseModelToDto(categories))
+	dtos.ContentLanguage(w, h.Locales.Default, dtos.CategoriesContentLocales(categories...)...)
+	dtos.JSON(w, http.StatusOK, h.categoriesResponse(r.Context(), categories, *filter, *selection))
 }
 
 // GetCategory godoc
@@ -44,8 +60,13 @@ func (h *Handler) GetAllCategories(w http.ResponseWriter, r *http.Request) {
 // @Summary Retrives single Category
 // @Description Retrieves a Category
 // @Tags Categories
-// @Produce json
+// @Produce json,application/xml,application/msgpack
 // @Param category_id path integer true "Category ID to retrieve"
+// @Param fields query string false "Comma separated fields to return (id is always returned)"
+// @Param expand query string false "Comma separated relationships to embed (products)"
+// @Param expand_limit query integer false "Limit the embedded products"
+// @Param locale query string false "Locale of the titles and descriptions, preferred over the Accept-Language header"
+// @Param Accept-Language header string false "Preferred locales of the titles and descriptions"
 // @Success 200 {object} dtos.CategoryResponseDto
 // @Failure 400 {object} dtos.ServeError
 // @Failure 404 {object} dtos.ServeError
@@ -55,17 +76,27 @@ func (h *Handler) GetCategory(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	categoryID, err := strconv.ParseInt(params["categoryID"], 10, 64)
 	if err != nil {
-		logrus.Warn(err.Error())
-		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.GetCategory", Err: err, Code: app.EINVALID})
+		logging.FromContext(r.Context()).Warn(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.GetCategory", Code: app.EINVALIDID, Err: err})
 		return
 	}
-	category, err := h.AppServices.GetCategory(r.Context(), categoryID)
+	selection := new(app.Selection)
+	r.ParseForm()
+	schema.NewDecoder().Decode(selection, r.Form)
+	if err = h.localize(w, r, selection); err != nil {
+		logging.FromContext(r.Context()).Warn(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.GetCategory", Err: err})
+		return
+	}
+	category, err := h.AppServices.GetCategory(r.Context(), categoryID, *selection)
 	if err != nil {
-		logrus.Warn(err.Error())
+		logging.FromContext(r.Context()).Warn(err.Error())
 		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.GetCategory", Err: err})
 		return
 	}
-	dtos.JSON(w, http.StatusOK, dtos.ConvertCategoryResponseModelToDto(*category))
+	dtos.LastModified(w, dtos.CategoryTimestamps(category)...)
+	dtos.ContentLanguage(w, h.Locales.Default, dtos.CategoriesContentLocales(category)...)
+	dtos.JSON(w, http.StatusOK, h.categoryResponse(r.Context(), *category, *selection))
 
 }
 
@@ -74,23 +105,27 @@ func (h *Handler) GetCategory(w http.ResponseWriter, r *http.Request) {
 // @Summary Creates a Category
 // @Description Create a new Category
 // @Tags Categories
-// @Produce json
+// @Produce json,application/xml,application/msgpack
 // @Param category body dtos.CategoryRequestDto true "Category's data to create"
+// @Param Idempotency-Key header string false "Unique key of the request, whose retries get its stored response"
 // @Success 201 {object} dtos.CreateCategoryResponseDto
 // @Failure 400 {object} dtos.ServeError
+// @Failure 409 {object} dtos.ServeError
+// @Failure 413 {object} dtos.ServeError
+// @Failure 415 {object} dtos.ServeError
+// @Failure 422 {object} dtos.ServeError
 // @Failure 500 {object} dtos.ServeError
 // @Router /categories [post]
 func (h *Handler) CreateCategory(w http.ResponseWriter, r *http.Request) {
-	var newCategory dtos.CategoryRequestDto
-	err := json.NewDecoder(r.Body).Decode(&newCategory)
+	newCategory, err := h.decodeCategoryRequest(r)
 	if err != nil {
-		logrus.Errorf(err.Error())
-		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.CreateCategory", Code: app.EINVALID, Err: err, Message: "Data validation error."})
+		logging.FromContext(r.Context()).Error(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.CreateCategory", Err: err})
 		return
 	}
-	insertedID, err := h.AppServices.CreateCategory(r.Context(), dtos.ConvertCategoryRequestDtoToModel(newCategory))
+	insertedID, err := h.AppServices.CreateCategory(r.Context(), newCategory)
 	if err != nil {
-		logrus.Errorf(err.Error())
+		logging.FromContext(r.Context()).Error(err.Error())
 		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.CreateCategory", Err: err})
 		return
 	}
@@ -102,32 +137,33 @@ func (h *Handler) CreateCategory(w http.ResponseWriter, r *http.Request) {
 // @Summary Updates a Category
 // @Description Updates a Category
 // @Tags Categories
-// @Produce json
+// @Produce json,application/xml,application/msgpack
 // @Param category_id path integer true "Category ID to update"
 // @Param category body dtos.CategoryRequestDto true "Category's data to update"
 // @Success 204
 // @Failure 400 {object} dtos.ServeError
 // @Failure 404 {object} dtos.ServeError
+// @Failure 413 {object} dtos.ServeError
+// @Failure 415 {object} dtos.ServeError
 // @Failure 500 {object} dtos.ServeError
 // @Router /categories/{category_id} [put]
 func (h *Handler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	categoryID, err := strconv.ParseInt(params["categoryID"], 10, 64)
 	if err != nil {
-		logrus.Warn(err.Error())
-		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.UpdateCategory", Code: app.EINVALID, Err: err})
+		logging.FromContext(r.Context()).Warn(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.UpdateCategory", Code: app.EINVALIDID, Err: err})
 		return
 	}
-	var updateCategory dtos.CategoryRequestDto
-	err = json.NewDecoder(r.Body).Decode(&updateCategory)
+	updateCategory, err := h.decodeCategoryRequest(r)
 	if err != nil {
-		logrus.Errorf(err.Error())
-		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.UpdateCategory", Code: app.EINVALID, Err: err})
+		logging.FromContext(r.Context()).Error(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.UpdateCategory", Err: err})
 		return
 	}
-	err = h.AppServices.UpdateCategory(r.Context(), categoryID, dtos.ConvertCategoryRequestDtoToModel(updateCategory))
+	err = h.AppServices.UpdateCategory(r.Context(), categoryID, updateCategory)
 	if err != nil {
-		logrus.Errorf(err.Error())
+		logging.FromContext(r.Context()).Error(err.Error())
 		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.UpdateCategory", Err: err})
 		return
 	}
@@ -139,7 +175,7 @@ func (h *Handler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
 // @Summary Deletes a Category
 // @Description Deletes a Category
 // @Tags Categories
-// @Produce json
+// @Produce json,application/xml,application/msgpack
 // @Param category_id path integer true "Category ID to delete"
 // @Success 204
 // @Failure 500 {object} dtos.ServeError
@@ -148,14 +184,14 @@ func (h *Handler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	categoryID, err := strconv.ParseInt(params["categoryID"], 10, 64)
 	if err != nil {
-		logrus.Warn(err.Error())
-		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.DeleteCategory", Code: app.EINVALID, Err: err})
+		logging.FromContext(r.Context()).Warn(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.DeleteCategory", Code: app.EINVALIDID, Err: err})
 		return
 	}
 
 	err = h.AppServices.DeleteCategory(r.Context(), categoryID)
 	if err != nil {
-		logrus.Errorf(err.Error())
+		logging.FromContext(r.Context()).Error(err.Error())
 		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.DeleteCategory", Err: err})
 		return
 	}
@@ -13,11 +13,13 @@ type CategoryResponseDto struct {
 	Sort      *int64  `json:"sort"`
 	CreatedAt string  `json:"created_at"`
 	UpdatedAt string  `json:"updated_at"`
+
+	Products *ProductsResponseDto `json:"products,omitempty"`
 }
 
 type CategoryRequestDto struct {
-	Title    *string `json:"title"`
-	ImageURL *string `json:"image_url"`
+	Title    *string `json:"title" validate:"required,maxlen=155"`
+	ImageURL *string `json:"image_url" validate:"url,maxlen=1000"`
 	Sort     *int64  `json:"sort"`
 }
 
@@ -28,14 +30,19 @@ type CreateCategoryResponseDto struct {
 type CategoriesResponseDto []CategoryResponseDto
 
 func ConvertCategoryResponseModelToDto(category repositories.CategoryFetchModel) CategoryResponseDto {
-	return CategoryResponseDto{
+	categoryResponseDto := CategoryResponseDto{
 		ID:        category.ID,
 		Title:     category.Title,
 		ImageURL:  category.ImageURL,
 		Sort:      category.Sort,
-		CreatedAt: category.CreatedAt,
-		UpdatedAt: category.UpdatedAt,
+		CreatedAt: Timestamp(category.CreatedAt),
+		UpdatedAt: Timestamp(category.UpdatedAt),
+	}
+	if category.Products != nil {
+		products := ConvertProductsResponseModelToDto(category.Products)
+		categoryResponseDto.Products = &products
 	}
+	return categoryResponseDto
 }
 
 func ConvertCategoryRequestDtoToModel(category CategoryRequestDto) repositories.CategoryCreateModel {
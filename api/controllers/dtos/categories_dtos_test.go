@@ -3,6 +3,7 @@ package dtos
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/mzampetakis/prods-api/api/repositories"
 )
@@ -13,8 +14,8 @@ func TestConvertCategoryResponseModelToDto(t *testing.T) {
 	categoryTitle := "some title"
 	categoryImageURL := "https://some.image"
 	categorySort := int64(3)
-	categoryCreatedAt := "2020-05-25T20:06:40+03:00"
-	categoryUpdatedAt := "2020-05-25T20:06:40+03:00"
+	categoryCreatedAt := time.Date(2020, 5, 25, 20, 6, 40, 0, time.FixedZone("EEST", 3*60*60))
+	categoryUpdatedAt := time.Date(2020, 5, 26, 9, 0, 0, 0, time.FixedZone("EEST", 3*60*60))
 	categoryFetchModel := repositories.CategoryFetchModel{
 		ID:        categoryID,
 		Title:     &categoryTitle,
@@ -28,8 +29,8 @@ func TestConvertCategoryResponseModelToDto(t *testing.T) {
 		Title:     &categoryTitle,
 		ImageURL:  &categoryImageURL,
 		Sort:      &categorySort,
-		CreatedAt: categoryCreatedAt,
-		UpdatedAt: categoryUpdatedAt,
+		CreatedAt: "2020-05-25T17:06:40Z",
+		UpdatedAt: "2020-05-26T06:00:00Z",
 	}
 
 	//Act
@@ -0,0 +1,396 @@
+package dtos
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v4"
+)
+
+// Media types of the responses
+const (
+	MediaTypeJSON    = "application/json"
+	MediaTypeXML     = "application/xml"
+	MediaTypeMsgpack = "application/msgpack"
+	MediaTypeCSV     = "text/csv"
+)
+
+// Encoder writes the responses in a media type
+type Encoder struct {
+	MediaType string
+	// ListsOnly encoders can only write lists. The rest of the responses are written as JSON.
+	ListsOnly bool
+	Encode    func(w io.Writer, data interface{}) error
+}
+
+// lister is implemented by the envelopes of lists
+type lister interface {
+	Items() interface{}
+}
+
+// errNotList is returned by the ListsOnly encoders for data that is not a list
+var errNotList = errors.New("data is not a list")
+
+var (
+	encoders   = map[string]Encoder{}
+	mediaTypes []string
+)
+
+func init() {
+	RegisterEncoder(Encoder{MediaType: MediaTypeJSON, Encode: encodeJSON})
+	RegisterEncoder(Encoder{MediaType: MediaTypeXML, Encode: encodeXML})
+	RegisterEncoder(Encoder{MediaType: "text/xml", Encode: encodeXML})
+	RegisterEncoder(Encoder{MediaType: MediaTypeMsgpack, Encode: encodeMsgpack})
+	RegisterEncoder(Encoder{MediaType: "application/x-msgpack", Encode: encodeMsgpack})
+	RegisterEncoder(Encoder{MediaType: MediaTypeCSV, ListsOnly: true, Encode: encodeCSV})
+}
+
+// RegisterEncoder adds the encoder of a media type to the media types that the responses can be negotiated to.
+// The media types registered first are preferred when a request accepts several with the same quality.
+func RegisterEncoder(encoder Encoder) {
+	if _, ok := encoders[encoder.MediaType]; !ok {
+		mediaTypes = append(mediaTypes, encoder.MediaType)
+	}
+	encoders[encoder.MediaType] = encoder
+}
+
+// MediaTypes returns the media types of the registered encoders in order of preference
+func MediaTypes() []string {
+	return append([]string(nil), mediaTypes...)
+}
+
+// EncoderOf returns the encoder of the Content-Type. Unknown content types, like application/problem+json,
+// are written as JSON.
+func EncoderOf(contentType string) Encoder {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		if encoder, ok := encoders[mediaType]; ok {
+			return encoder
+		}
+	}
+	return encoders[MediaTypeJSON]
+}
+
+// Negotiate returns the offered media type that the Accept header prefers, honouring the q-values and the
+// wildcards of its media ranges. An empty Accept header accepts the first offer.
+func Negotiate(accept string, offers []string) (string, bool) {
+	if strings.TrimSpace(accept) == "" {
+		if len(offers) == 0 {
+			return "", false
+		}
+		return offers[0], true
+	}
+	ranges := parseAccept(accept)
+	best, bestQuality := "", 0.0
+	for _, offer := range offers {
+		// the most specific range that matches the offer sets its quality
+		quality, specificity := 0.0, -1
+		for _, mediaRange := range ranges {
+			if s := mediaRange.matches(offer); s > specificity {
+				quality, specificity = mediaRange.quality, s
+			}
+		}
+		if quality > bestQuality {
+			best, bestQuality = offer, quality
+		}
+	}
+	return best, bestQuality > 0
+}
+
+type mediaRange struct {
+	mediaType string
+	quality   float64
+}
+
+func parseAccept(accept string) []mediaRange {
+	ranges := make([]mediaRange, 0)
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil || quality < 0 || quality > 1 {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, quality: quality})
+	}
+	return ranges
+}
+
+// matches returns how specific the media range is for the media type: 2 for an exact match,
+// 1 for type/* and 0 for */*. It returns -1 when the range does not match.
+func (m mediaRange) matches(mediaType string) int {
+	switch {
+	case m.mediaType == mediaType:
+		return 2
+	case m.mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(m.mediaType, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(m.mediaType, "*")):
+		return 1
+	}
+	return -1
+}
+
+func encodeJSON(w io.Writer, data interface{}) error {
+	return json.NewEncoder(w).Encode(data)
+}
+
+// jsonObject is a JSON object that keeps the order of its fields
+type jsonObject []jsonField
+
+type jsonField struct {
+	key   string
+	value interface{}
+}
+
+// toJSONValue returns the JSON representation of the data as nil, bool, json.Number, string, []interface{}
+// and jsonObject values. The encoders other than JSON write it, so that they honour the json tags of the DTOs
+// and the field selection.
+func toJSONValue(data interface{}) (interface{}, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	return decodeJSONValue(decoder)
+}
+
+func decodeJSONValue(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('['):
+		list := make([]interface{}, 0)
+		for decoder.More() {
+			value, err := decodeJSONValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err = decoder.Token()
+		return list, err
+	case json.Delim('{'):
+		object := make(jsonObject, 0)
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeJSONValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object = append(object, jsonField{key: key.(string), value: value})
+		}
+		_, err = decoder.Token()
+		return object, err
+	}
+	return token, nil
+}
+
+// encodeXML writes the data in a response element. The fields of objects are elements named after them
+// and the entries of lists are item elements.
+func encodeXML(w io.Writer, data interface{}) error {
+	value, err := toJSONValue(data)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	if _, err = io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	if err = writeXMLElement(encoder, "response", value); err != nil {
+		return err
+	}
+	return encoder.Flush()
+}
+
+func writeXMLElement(encoder *xml.Encoder, name string, value interface{}) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if !isXMLName(name) {
+		// e.g. the keys of maps that start with a digit
+		start = xml.StartElement{Name: xml.Name{Local: "entry"}, Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: name}}}
+	}
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case jsonObject:
+		for _, field := range v {
+			if err := writeXMLElement(encoder, field.key, field.value); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if err := writeXMLElement(encoder, "item", item); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		if err := encoder.EncodeToken(xml.CharData(scalarText(v))); err != nil {
+			return err
+		}
+	}
+	return encoder.EncodeToken(start.End())
+}
+
+func isXMLName(name string) bool {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return false
+	}
+	for i, r := range name {
+		letter := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		if !letter && (i == 0 || !(r == '-' || r == '.' || (r >= '0' && r <= '9'))) {
+			return false
+		}
+	}
+	return true
+}
+
+// scalarText returns the text of a bool, json.Number or string value
+func scalarText(value interface{}) string {
+	switch v := value.(type) {
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	case string:
+		return v
+	}
+	return ""
+}
+
+// encodeMsgpack writes the data as MessagePack, with integer numbers as integers and the rest as floats
+func encodeMsgpack(w io.Writer, data interface{}) error {
+	value, err := toJSONValue(data)
+	if err != nil {
+		return err
+	}
+	return writeMsgpack(msgpack.NewEncoder(w), value)
+}
+
+func writeMsgpack(encoder *msgpack.Encoder, value interface{}) error {
+	switch v := value.(type) {
+	case jsonObject:
+		if err := encoder.EncodeMapLen(len(v)); err != nil {
+			return err
+		}
+		for _, field := range v {
+			if err := encoder.EncodeString(field.key); err != nil {
+				return err
+			}
+			if err := writeMsgpack(encoder, field.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []interface{}:
+		if err := encoder.EncodeArrayLen(len(v)); err != nil {
+			return err
+		}
+		for _, item := range v {
+			if err := writeMsgpack(encoder, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return encoder.EncodeInt(i)
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return err
+		}
+		return encoder.EncodeFloat64(f)
+	}
+	return encoder.Encode(value)
+}
+
+// encodeCSV writes a list of objects as CSV with a header row of their fields. Nested objects and lists
+// are written as JSON.
+func encodeCSV(w io.Writer, data interface{}) error {
+	value, err := toJSONValue(data)
+	if err != nil {
+		return err
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		return errNotList
+	}
+	columns := make([]string, 0)
+	seen := map[string]bool{}
+	for _, item := range list {
+		object, ok := item.(jsonObject)
+		if !ok {
+			return errNotList
+		}
+		for _, field := range object {
+			if !seen[field.key] {
+				seen[field.key] = true
+				columns = append(columns, field.key)
+			}
+		}
+	}
+	writer := csv.NewWriter(w)
+	if len(columns) > 0 {
+		writer.Write(columns)
+	}
+	index := make(map[string]int, len(columns))
+	for i, column := range columns {
+		index[column] = i
+	}
+	for _, item := range list {
+		row := make([]string, len(columns))
+		for _, field := range item.(jsonObject) {
+			row[index[field.key]] = csvCell(field.value)
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvCell(value interface{}) string {
+	switch value.(type) {
+	case jsonObject, []interface{}:
+		raw, _ := json.Marshal(toInterface(value))
+		return string(raw)
+	}
+	return scalarText(value)
+}
+
+// toInterface converts the jsonObjects of the value to maps so that they can be marshalled
+func toInterface(value interface{}) interface{} {
+	switch v := value.(type) {
+	case jsonObject:
+		object := make(map[string]interface{}, len(v))
+		for _, field := range v {
+			object[field.key] = toInterface(field.value)
+		}
+		return object
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = toInterface(item)
+		}
+		return list
+	}
+	return value
+}
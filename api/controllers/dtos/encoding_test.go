@@ -0,0 +1,111 @@
+package dtos
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v4"
+)
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{MediaTypeJSON, MediaTypeXML, MediaTypeMsgpack, MediaTypeCSV}
+	tests := map[string]struct {
+		accept     string
+		expected   string
+		expectedOK bool
+	}{
+		"No Accept header":     {accept: "", expected: MediaTypeJSON, expectedOK: true},
+		"Any media type":       {accept: "*/*", expected: MediaTypeJSON, expectedOK: true},
+		"Exact media type":     {accept: "application/xml", expected: MediaTypeXML, expectedOK: true},
+		"Preferred by quality": {accept: "application/json;q=0.5, application/msgpack", expected: MediaTypeMsgpack, expectedOK: true},
+		"Type wildcard":        {accept: "text/*", expected: MediaTypeCSV, expectedOK: true},
+		"Specific range wins":  {accept: "*/*;q=0.9, application/json;q=0.1", expected: MediaTypeXML, expectedOK: true},
+		"Excluded media type":  {accept: "application/json;q=0, */*;q=0.1", expected: MediaTypeXML, expectedOK: true},
+		"Unsupported":          {accept: "image/png", expectedOK: false},
+		"Invalid quality":      {accept: "application/xml;q=2", expectedOK: false},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Act
+			mediaType, ok := Negotiate(tc.accept, offers)
+
+			//Assert
+			if ok != tc.expectedOK || mediaType != tc.expected {
+				t.Errorf("Expected %q (%v) but got %q (%v)", tc.expected, tc.expectedOK, mediaType, ok)
+			}
+		})
+	}
+}
+
+func TestJSON_Encoders(t *testing.T) {
+	type product struct {
+		ID     int64             `json:"id"`
+		Title  string            `json:"title"`
+		Price  float64           `json:"price"`
+		Tags   []string          `json:"tags"`
+		Sizes  map[string]int    `json:"sizes,omitempty"`
+		Extras map[string]string `json:"-"`
+	}
+	list := []product{{ID: 1, Title: "Mouse, wireless", Price: 10.5, Tags: []string{"pc"}}, {ID: 2, Title: "Pad", Tags: []string{}}}
+	item := product{ID: 1, Title: "Mouse & pad", Tags: []string{"pc", "office"}, Sizes: map[string]int{"1x": 1}}
+	tests := map[string]struct {
+		contentType         string
+		data                interface{}
+		expectedContentType string
+		expectedBody        string
+	}{
+		"XML item": {contentType: MediaTypeXML, data: item, expectedContentType: MediaTypeXML,
+			expectedBody: `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<response><id>1</id><title>Mouse &amp; pad</title><price>0</price>` +
+				`<tags><item>pc</item><item>office</item></tags><sizes><entry key="1x">1</entry></sizes></response>`},
+		"CSV list": {contentType: MediaTypeCSV, data: list, expectedContentType: MediaTypeCSV,
+			expectedBody: "id,title,price,tags\n1,\"Mouse, wireless\",10.5,\"[\"\"pc\"\"]\"\n2,Pad,0,[]\n"},
+		"CSV of an item is JSON": {contentType: MediaTypeCSV, data: struct {
+			ID int `json:"id"`
+		}{ID: 1}, expectedContentType: MediaTypeJSON, expectedBody: "{\"id\":1}\n"},
+		"CSV of a list envelope": {contentType: MediaTypeCSV, data: ConvertListToDtoV2(list[1:], 1, 1), expectedContentType: MediaTypeCSV,
+			expectedBody: "id,title,price,tags\n2,Pad,0,[]\n"},
+		"Vendor media type": {contentType: MediaTypeV2, data: ConvertListToDtoV2([]int{1}, 0, 1), expectedContentType: MediaTypeV2,
+			expectedBody: "{\"data\":[1],\"meta\":{\"offset\":0,\"count\":1}}\n"},
+		"Legacy JSON": {data: []int{1}, expectedBody: "[1]\n"},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Prepare
+			w := httptest.NewRecorder()
+			if tc.contentType != "" {
+				w.Header().Set("Content-Type", tc.contentType)
+			}
+
+			//Act
+			JSON(w, http.StatusOK, tc.data)
+
+			//Assert
+			if w.Header().Get("Content-Type") != tc.expectedContentType {
+				t.Errorf("Expected Content-Type %q but got %q", tc.expectedContentType, w.Header().Get("Content-Type"))
+			}
+			if w.Body.String() != tc.expectedBody {
+				t.Errorf("Expected body\n%s\nbut got\n%s", tc.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestJSON_Msgpack(t *testing.T) {
+	//Prepare
+	w := httptest.NewRecorder()
+	w.Header().Set("Content-Type", MediaTypeMsgpack)
+	data := []map[string]interface{}{{"id": 1, "price": 10.5, "title": "Mouse", "image": nil}}
+
+	//Act
+	JSON(w, http.StatusOK, data)
+
+	//Assert
+	var decoded []map[string]interface{}
+	if err := msgpack.Unmarshal(w.Body.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 1 || decoded[0]["id"] != int8(1) || decoded[0]["price"] != 10.5 || decoded[0]["title"] != "Mouse" || decoded[0]["image"] != nil {
+		t.Errorf("Unexpected decoded value %#v", decoded)
+	}
+}
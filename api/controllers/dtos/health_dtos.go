@@ -0,0 +1,72 @@
+package dtos
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/mzampetakis/prods-api/api/health"
+)
+
+const (
+	HealthStatusOK          = "ok"
+	HealthStatusUnavailable = "unavailable"
+)
+
+type HealthCheckDto struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type HealthResponseDto struct {
+	Status string           `json:"status"`
+	Checks []HealthCheckDto `json:"checks,omitempty"`
+}
+
+type StatusResponseDto struct {
+	Status        string           `json:"status"`
+	Version       string           `json:"version"`
+	Commit        string           `json:"commit"`
+	GoVersion     string           `json:"go_version"`
+	StartedAt     string           `json:"started_at"`
+	UptimeSeconds int64            `json:"uptime_seconds"`
+	Checks        []HealthCheckDto `json:"checks"`
+}
+
+func ConvertHealthResultsToDto(results []health.Result) HealthResponseDto {
+	healthResponseDto := HealthResponseDto{Status: HealthStatusOK, Checks: ConvertHealthChecksToDto(results)}
+	if !health.Healthy(results) {
+		healthResponseDto.Status = HealthStatusUnavailable
+	}
+	return healthResponseDto
+}
+
+func ConvertStatusToDto(results []health.Result) StatusResponseDto {
+	return StatusResponseDto{
+		Status:        ConvertHealthResultsToDto(results).Status,
+		Version:       health.Version,
+		Commit:        health.Commit,
+		GoVersion:     runtime.Version(),
+		StartedAt:     health.StartedAt().UTC().Format(time.RFC3339),
+		UptimeSeconds: int64(time.Since(health.StartedAt()).Seconds()),
+		Checks:        ConvertHealthChecksToDto(results),
+	}
+}
+
+func ConvertHealthChecksToDto(results []health.Result) []HealthCheckDto {
+	healthChecksDto := make([]HealthCheckDto, 0, len(results))
+	for _, result := range results {
+		healthCheckDto := HealthCheckDto{
+			Name:      result.Name,
+			Status:    HealthStatusOK,
+			LatencyMs: float64(result.Latency.Microseconds()) / 1000,
+		}
+		if !result.Healthy() {
+			healthCheckDto.Status = HealthStatusUnavailable
+			healthCheckDto.Error = result.Err.Error()
+		}
+		healthChecksDto = append(healthChecksDto, healthCheckDto)
+	}
+	return healthChecksDto
+}
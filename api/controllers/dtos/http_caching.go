@@ -0,0 +1,41 @@
+package dtos
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/mzampetakis/prods-api/api/repositories"
+)
+
+// LastModified sets the Last-Modified header to the latest of the provided timestamps.
+// Zero timestamps (e.g. not selected) are ignored. Only the responses of single entities carry it: removals
+// from a list do not advance any timestamp, so lists are revalidated with their ETag only.
+func LastModified(w http.ResponseWriter, timestamps ...time.Time) {
+	var lastModified time.Time
+	for _, timestamp := range timestamps {
+		if timestamp.After(lastModified) {
+			lastModified = timestamp
+		}
+	}
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// ProductTimestamps returns the update timestamps of the product and its embedded category
+func ProductTimestamps(product *repositories.ProductFetchModel) []time.Time {
+	timestamps := []time.Time{product.UpdatedAt}
+	if product.Category != nil {
+		timestamps = append(timestamps, product.Category.UpdatedAt)
+	}
+	return timestamps
+}
+
+// CategoryTimestamps returns the update timestamp of the category. It returns none when its products are embedded,
+// as they are a list.
+func CategoryTimestamps(category *repositories.CategoryFetchModel) []time.Time {
+	if category.Products != nil {
+		return nil
+	}
+	return []time.Time{category.UpdatedAt}
+}
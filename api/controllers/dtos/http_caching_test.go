@@ -0,0 +1,50 @@
+package dtos
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mzampetakis/prods-api/api/repositories"
+)
+
+func TestLastModified(t *testing.T) {
+	may25 := time.Date(2020, 5, 25, 17, 6, 40, 0, time.UTC)
+	may26 := may25.AddDate(0, 0, 1)
+	tests := map[string]struct {
+		timestamps           []time.Time
+		expectedLastModified string
+	}{
+		"Product with its category": {
+			timestamps:           ProductTimestamps(&repositories.ProductFetchModel{UpdatedAt: may25, Category: &repositories.CategoryFetchModel{UpdatedAt: may26}}),
+			expectedLastModified: "Tue, 26 May 2020 17:06:40 GMT",
+		},
+		"Category": {
+			timestamps:           CategoryTimestamps(&repositories.CategoryFetchModel{UpdatedAt: may25}),
+			expectedLastModified: "Mon, 25 May 2020 17:06:40 GMT",
+		},
+		"Category with its products": {
+			timestamps: CategoryTimestamps(&repositories.CategoryFetchModel{UpdatedAt: may25,
+				Products: []*repositories.ProductFetchModel{{UpdatedAt: may26}}}),
+			expectedLastModified: "",
+		},
+		"Timestamps not selected": {
+			timestamps:           ProductTimestamps(&repositories.ProductFetchModel{}),
+			expectedLastModified: "",
+		},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Prepare
+			w := httptest.NewRecorder()
+
+			//Act
+			LastModified(w, tc.timestamps...)
+
+			//Assert
+			if lastModified := w.Header().Get("Last-Modified"); lastModified != tc.expectedLastModified {
+				t.Errorf("Expected Last-Modified %q but got %q", tc.expectedLastModified, lastModified)
+			}
+		})
+	}
+}
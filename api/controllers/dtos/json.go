@@ -3,43 +3,135 @@
 package dtos
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/tracing"
 )
 
-// JSON responds to a request a json with the provided data alongside with the provided statusCode
+// JSON responds to a request the provided data alongside with the provided statusCode, encoded in the media type
+// of the response's Content-Type as negotiated by the middlewares. Responses without a Content-Type are json.
+// The encoders of lists, e.g. CSV, write the items of list envelopes.
 func JSON(w http.ResponseWriter, statusCode int, data interface{}) {
+	var body bytes.Buffer
+	encoder := EncoderOf(w.Header().Get("Content-Type"))
+	if envelope, ok := data.(lister); ok && encoder.ListsOnly {
+		data = envelope.Items()
+	}
+	err := encoder.Encode(&body, data)
+	if err == errNotList {
+		w.Header().Set("Content-Type", MediaTypeJSON)
+		body.Reset()
+		err = encodeJSON(&body, data)
+	}
 	w.WriteHeader(statusCode)
-	err := json.NewEncoder(w).Encode(data)
 	if err != nil {
 		fmt.Fprintf(w, "%s", err.Error())
+		return
 	}
+	w.Write(body.Bytes())
 	return
 }
 
 type ServeError struct {
-	TraceID        string `json:"trace_id"`
-	Timestamp      string `json:"timestamp"`
-	Message        string `json:"message"`
-	Code           string `json:"code"`
-	HTTPStatusCode int    `json:"http_status_code"`
-	HTTPStatus     string `json:"http_status"`
+	TraceID        string          `json:"trace_id"`
+	Timestamp      string          `json:"timestamp"`
+	Message        string          `json:"message"`
+	Code           string          `json:"code"`
+	HTTPStatusCode int             `json:"http_status_code"`
+	HTTPStatus     string          `json:"http_status"`
+	Details        []FieldErrorDto `json:"details,omitempty"`
+}
+
+// FieldErrorDto describes a rule that a request field violates
+type FieldErrorDto struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ProblemDetails is the RFC 7807 error response extended with the trace id, the fine grained error code
+// and the field level errors
+type ProblemDetails struct {
+	Type      string          `json:"type"`
+	Title     string          `json:"title"`
+	Status    int             `json:"status"`
+	Detail    string          `json:"detail"`
+	Instance  string          `json:"instance"`
+	TraceID   string          `json:"trace_id"`
+	Code      string          `json:"code"`
+	Timestamp string          `json:"timestamp"`
+	Details   []FieldErrorDto `json:"details,omitempty"`
 }
 
-// ERROR responds to a request an error with the provided data
+const (
+	// ProblemJSON is the media type of the RFC 7807 error responses
+	ProblemJSON = "application/problem+json"
+	// ErrorTypeBaseURL is the base URL of the error codes' documentation
+	ErrorTypeBaseURL = "https://github.com/mzampetakis/prods-api/blob/master/docs/errors.md#"
+)
+
+// ERROR responds to a request an error with the provided data.
+// Requests that accept application/problem+json get an RFC 7807 response with the fine grained error code,
+// all other requests get the legacy ServeError with the generic error code.
 func ERROR(w http.ResponseWriter, ctx context.Context, err error) {
+	if ctx.Value("error_format") == ProblemJSON {
+		PROBLEM(w, ctx, err)
+		return
+	}
 	JSON(w, app.StatusCode(err), ServeError{
-		TraceID:        fmt.Sprintf("%v", ctx.Value("request_id")),
+		TraceID:        traceID(ctx),
 		Timestamp:      time.Now().Format(time.RFC3339),
 		Message:        app.ErrorMessage(err),
-		Code:           app.ErrorCode(err),
+		Code:           app.ErrorClass(err),
 		HTTPStatusCode: app.StatusCode(err),
 		HTTPStatus:     http.StatusText(app.StatusCode(err)),
+		Details:        ConvertFieldErrorsToDto(app.ErrorDetails(err)),
+	})
+	return
+}
+
+// PROBLEM responds to a request an RFC 7807 problem+json error
+func PROBLEM(w http.ResponseWriter, ctx context.Context, err error) {
+	definition := app.ErrorDefinitionOf(err)
+	w.Header().Set("Content-Type", ProblemJSON)
+	JSON(w, definition.Status, ProblemDetails{
+		Type:      ErrorTypeBaseURL + definition.Code,
+		Title:     definition.Title,
+		Status:    definition.Status,
+		Detail:    app.ErrorMessage(err),
+		Instance:  fmt.Sprintf("%v", ctx.Value("request_uri")),
+		TraceID:   traceID(ctx),
+		Code:      definition.Code,
+		Timestamp: time.Now().Format(time.RFC3339),
+		Details:   ConvertFieldErrorsToDto(app.ErrorDetails(err)),
 	})
 	return
 }
+
+// traceID returns the trace id of the request, or its request id when the request is not traced
+func traceID(ctx context.Context) string {
+	if traceID := tracing.TraceIDFromContext(ctx); traceID != "" {
+		return traceID
+	}
+	return fmt.Sprintf("%v", ctx.Value("request_id"))
+}
+
+func ConvertFieldErrorsToDto(fieldErrors []app.FieldError) []FieldErrorDto {
+	if len(fieldErrors) == 0 {
+		return nil
+	}
+	fieldErrorsDto := make([]FieldErrorDto, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		fieldErrorsDto = append(fieldErrorsDto, FieldErrorDto{
+			Field:   fieldError.Field,
+			Rule:    fieldError.Rule,
+			Message: fieldError.Message,
+		})
+	}
+	return fieldErrorsDto
+}
@@ -0,0 +1,123 @@
+package dtos
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/repositories"
+)
+
+// blockingDriver is a database/sql driver whose queries only return when their context is done
+type blockingDriver struct{}
+
+func (blockingDriver) Open(name string) (driver.Conn, error) {
+	return blockingConn{}, nil
+}
+
+type blockingConn struct{}
+
+func (blockingConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+
+func (blockingConn) Close() error {
+	return nil
+}
+
+func (blockingConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+func (blockingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func init() {
+	sql.Register("blocking", blockingDriver{})
+}
+
+func TestERROR(t *testing.T) {
+	tests := map[string]struct {
+		errorFormat         string
+		expectedContentType string
+		expectedStatus      int
+		expectedCode        string
+	}{
+		"Legacy error":  {errorFormat: "", expectedStatus: http.StatusNotFound, expectedCode: app.ENOTFOUND},
+		"Problem error": {errorFormat: ProblemJSON, expectedContentType: ProblemJSON, expectedStatus: http.StatusNotFound, expectedCode: app.ECATEGORYNOTFOUND},
+	}
+	err := &app.Error{Op: "services.GetCategory", Err: &app.Error{Op: "repositories.GetCategory", Code: app.ECATEGORYNOTFOUND, Message: "Category not found."}}
+
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Prepare
+			ctx := context.WithValue(context.Background(), "request_id", "some-request-id")
+			ctx = context.WithValue(ctx, "request_uri", "/api/categories/404")
+			ctx = context.WithValue(ctx, "error_format", tc.errorFormat)
+			w := httptest.NewRecorder()
+
+			//Act
+			ERROR(w, ctx, err)
+
+			//Assert
+			if w.Code != tc.expectedStatus {
+				t.Errorf("Expected status %d but got %d", tc.expectedStatus, w.Code)
+			}
+			if w.Header().Get("Content-Type") != tc.expectedContentType {
+				t.Errorf("Expected Content-Type %s but got %s", tc.expectedContentType, w.Header().Get("Content-Type"))
+			}
+			var body map[string]interface{}
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatalf("Expected json body but got %v", err)
+			}
+			if body["code"] != tc.expectedCode {
+				t.Errorf("Expected code %s but got %v", tc.expectedCode, body["code"])
+			}
+			if tc.errorFormat == ProblemJSON {
+				if body["type"] != ErrorTypeBaseURL+app.ECATEGORYNOTFOUND {
+					t.Errorf("Unexpected type %v", body["type"])
+				}
+				if body["instance"] != "/api/categories/404" || body["detail"] != "Category not found." {
+					t.Errorf("Unexpected instance %v or detail %v", body["instance"], body["detail"])
+				}
+			}
+		})
+	}
+}
+
+func TestERROR_StatementTimeout(t *testing.T) {
+	//Prepare
+	sqlDB, err := sql.Open("blocking", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	db := &repositories.DB{DB: sqlDB, StatementTimeout: 10 * time.Millisecond}
+	ctx := context.WithValue(context.Background(), "request_id", "some-request-id")
+	w := httptest.NewRecorder()
+
+	//Act
+	_, err = db.GetProduct(ctx, 1, app.Selection{})
+	ERROR(w, ctx, err)
+
+	//Assert
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d but got %d (%v)", http.StatusServiceUnavailable, w.Code, err)
+	}
+	var body map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("Expected json body but got %v", err)
+	}
+	if body["code"] != app.ETIMEOUT {
+		t.Errorf("Expected code %s but got %v", app.ETIMEOUT, body["code"])
+	}
+}
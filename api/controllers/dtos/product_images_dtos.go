@@ -0,0 +1,56 @@
+// Package dtos stores the API DTOs and functionalities to convert DTOs to Models and vice versa
+// as well as functionality to serve json and error
+package dtos
+
+import (
+	"github.com/mzampetakis/prods-api/api/repositories"
+)
+
+// MediaPath is the path that stored media are served from
+const MediaPath = "/media/"
+
+type ProductImageResponseDto struct {
+	ID          int64             `json:"id"`
+	ProductID   int64             `json:"product_id"`
+	Position    int64             `json:"position"`
+	ContentType string            `json:"content_type"`
+	Size        int64             `json:"size"`
+	URL         string            `json:"url"`
+	Thumbnails  map[string]string `json:"thumbnails"`
+	CreatedAt   string            `json:"created_at"`
+}
+
+type ProductImagesResponseDto []ProductImageResponseDto
+
+type ProductImagesOrderUpdateRequestDto struct {
+	ImageIDs []int64 `json:"image_ids" validate:"required"`
+}
+
+func ConvertProductImageResponseModelToDto(image repositories.ProductImageFetchModel) ProductImageResponseDto {
+	thumbnails := make(map[string]string)
+	for name, key := range image.Thumbnails {
+		thumbnails[name] = MediaPath + key
+	}
+	return ProductImageResponseDto{
+		ID:          image.ID,
+		ProductID:   image.ProductID,
+		Position:    image.Position,
+		ContentType: image.ContentType,
+		Size:        image.Size,
+		URL:         MediaPath + image.StorageKey,
+		Thumbnails:  thumbnails,
+		CreatedAt:   Timestamp(image.CreatedAt),
+	}
+}
+
+func ConvertProductImagesResponseModelToDto(images []*repositories.ProductImageFetchModel) ProductImagesResponseDto {
+	imagesResponseDto := make(ProductImagesResponseDto, 0)
+	for _, image := range images {
+		imagesResponseDto = append(imagesResponseDto, ConvertProductImageResponseModelToDto(*image))
+	}
+	return imagesResponseDto
+}
+
+func ConvertProductImagesOrderUpdateRequestDtoToModel(order ProductImagesOrderUpdateRequestDto) repositories.ProductImagesOrderUpdateModel {
+	return repositories.ProductImagesOrderUpdateModel(order.ImageIDs)
+}
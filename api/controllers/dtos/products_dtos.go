@@ -15,14 +15,16 @@ type ProductResponseDto struct {
 	Description *string `json:"description"`
 	CreatedAt   string  `json:"created_at"`
 	UpdatedAt   string  `json:"updated_at"`
+
+	Category *CategoryResponseDto `json:"category,omitempty"`
 }
 
 type ProductRequestDto struct {
-	CategoryID  *int64  `json:"category_id"`
-	Title       *string `json:"title"`
-	ImageURL    *string `json:"image_url"`
-	Price       *int64  `json:"price"`
-	Description *string `json:"description"`
+	CategoryID  *int64  `json:"category_id" validate:"min=1"`
+	Title       *string `json:"title" validate:"required,maxlen=255"`
+	ImageURL    *string `json:"image_url" validate:"url,maxlen=1000"`
+	Price       *int64  `json:"price" validate:"required,min=0"`
+	Description *string `json:"description" validate:"maxlen=65535"`
 }
 type CreateProductResponseDto struct {
 	ID int64 `json:"id"`
@@ -31,20 +33,25 @@ type CreateProductResponseDto struct {
 type ProductsResponseDto []ProductResponseDto
 
 type ProductsCategoryUpdateRequestDto struct {
-	ProductIDs []int64 `json:"product_ids"`
+	ProductIDs []int64 `json:"product_ids" validate:"required"`
 }
 
 func ConvertProductResponseModelToDto(product repositories.ProductFetchModel) ProductResponseDto {
-	return ProductResponseDto{
+	productResponseDto := ProductResponseDto{
 		ID:          product.ID,
 		CategoryID:  product.CategoryID,
 		Title:       product.Title,
 		ImageURL:    product.ImageURL,
 		Price:       product.Price,
 		Description: product.Description,
-		CreatedAt:   product.CreatedAt,
-		UpdatedAt:   product.UpdatedAt,
+		CreatedAt:   Timestamp(product.CreatedAt),
+		UpdatedAt:   Timestamp(product.UpdatedAt),
+	}
+	if product.Category != nil {
+		category := ConvertCategoryResponseModelToDto(*product.Category)
+		productResponseDto.Category = &category
 	}
+	return productResponseDto
 }
 
 func ConvertProductRequestDtoToModel(product ProductRequestDto) repositories.ProductCreateModel {
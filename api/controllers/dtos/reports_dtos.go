@@ -0,0 +1,33 @@
+// Package dtos stores the API DTOs and functionalities to convert DTOs to Models and vice versa
+// as well as functionality to serve json and error
+package dtos
+
+import (
+	"github.com/mzampetakis/prods-api/api/repositories"
+)
+
+type BrokenImageResponseDto struct {
+	EntityType string  `json:"entity_type"`
+	EntityID   int64   `json:"entity_id"`
+	URL        string  `json:"url"`
+	StatusCode *int64  `json:"status_code"`
+	Error      *string `json:"error"`
+	CheckedAt  string  `json:"checked_at"`
+}
+
+type BrokenImagesResponseDto []BrokenImageResponseDto
+
+func ConvertBrokenImagesResponseModelToDto(brokenImages []*repositories.BrokenImageFetchModel) BrokenImagesResponseDto {
+	brokenImagesResponseDto := make(BrokenImagesResponseDto, 0)
+	for _, brokenImage := range brokenImages {
+		brokenImagesResponseDto = append(brokenImagesResponseDto, BrokenImageResponseDto{
+			EntityType: brokenImage.EntityType,
+			EntityID:   brokenImage.EntityID,
+			URL:        brokenImage.URL,
+			StatusCode: brokenImage.StatusCode,
+			Error:      brokenImage.Error,
+			CheckedAt:  Timestamp(brokenImage.CheckedAt),
+		})
+	}
+	return brokenImagesResponseDto
+}
@@ -0,0 +1,96 @@
+// Package dtos stores the API DTOs and functionalities to convert DTOs to Models and vice versa
+// as well as functionality to serve json and error
+package dtos
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/mzampetakis/prods-api/api/app"
+)
+
+// SelectFields keeps only the requested fields of a DTO or a list of DTOs alongside with the id and
+// any expanded relationships, in the order of the DTO's fields. When no fields are requested the data
+// are returned unchanged.
+func SelectFields(data interface{}, selection app.Selection) interface{} {
+	fields := selection.FieldsList()
+	if len(fields) == 0 {
+		return data
+	}
+	keep := map[string]bool{"id": true}
+	for _, field := range append(fields, selection.ExpandList()...) {
+		keep[field] = true
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return data
+	}
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err == nil {
+		items := make([]selectedFields, 0, len(list))
+		for _, rawItem := range list {
+			item, err := pickFields(rawItem, keep)
+			if err != nil {
+				return data
+			}
+			items = append(items, item)
+		}
+		return items
+	}
+	item, err := pickFields(raw, keep)
+	if err != nil {
+		return data
+	}
+	return item
+}
+
+// selectedFields is a JSON object of the selected fields of a DTO, which keeps their order
+type selectedFields []selectedField
+
+type selectedField struct {
+	key   string
+	value json.RawMessage
+}
+
+// MarshalJSON writes the fields in their order
+func (s selectedFields) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range s {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(field.key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(field.value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// pickFields returns the fields of the JSON object that are kept, in the order of the object
+func pickFields(raw json.RawMessage, keep map[string]bool) (selectedFields, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	fields := make(selectedFields, 0)
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		if keep[key.(string)] {
+			fields = append(fields, selectedField{key: key.(string), value: value})
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,68 @@
+package dtos
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mzampetakis/prods-api/api/app"
+)
+
+func TestSelectFields(t *testing.T) {
+	title := "Mouse"
+	price := int64(1000)
+	categoryTitle := "Peripherals"
+	product := ProductResponseDto{ID: 1, Title: &title, Price: &price, CreatedAt: "2020-05-25T17:06:40Z",
+		Category: &CategoryResponseDto{ID: 2, Title: &categoryTitle}}
+	tests := map[string]struct {
+		data         interface{}
+		selection    app.Selection
+		expectedJSON string
+	}{
+		"No fields selected": {data: ProductResponseDto{ID: 1, Title: &title}, selection: app.Selection{},
+			expectedJSON: `{"id":1,"category_id":null,"title":"Mouse","image_url":null,"price":null,"description":null,"created_at":"","updated_at":""}`},
+		"Single entity in the order of its fields": {data: product, selection: app.Selection{Fields: "price,created_at,title"},
+			expectedJSON: `{"id":1,"title":"Mouse","price":1000,"created_at":"2020-05-25T17:06:40Z"}`},
+		"Single entity with its expanded category": {data: product, selection: app.Selection{Fields: "title", Expand: "category"},
+			expectedJSON: `{"id":1,"title":"Mouse","category":{"id":2,"title":"Peripherals","image_url":null,"sort":null,"created_at":"","updated_at":""}}`},
+		"List": {data: ProductsResponseDto{product, {ID: 3, Price: &price}}, selection: app.Selection{Fields: "price,title"},
+			expectedJSON: `[{"id":1,"title":"Mouse","price":1000},{"id":3,"title":null,"price":1000}]`},
+		"Empty list": {data: ProductsResponseDto{}, selection: app.Selection{Fields: "title"},
+			expectedJSON: `[]`},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Act
+			selected := SelectFields(tc.data, tc.selection)
+
+			//Assert
+			raw, err := json.Marshal(selected)
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if string(raw) != tc.expectedJSON {
+				t.Errorf("Expected\n%s\nbut got\n%s", tc.expectedJSON, raw)
+			}
+		})
+	}
+}
+
+func TestSelectFields_XML(t *testing.T) {
+	//Prepare
+	title := "Mouse"
+	price := int64(1000)
+	w := httptest.NewRecorder()
+	w.Header().Set("Content-Type", MediaTypeXML)
+	selected := SelectFields(ProductsResponseDto{{ID: 1, Title: &title, Price: &price}}, app.Selection{Fields: "price,title"})
+
+	//Act
+	JSON(w, http.StatusOK, selected)
+
+	//Assert
+	expectedBody := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<response><item><id>1</id><title>Mouse</title><price>1000</price></item></response>`
+	if w.Body.String() != expectedBody {
+		t.Errorf("Expected body\n%s\nbut got\n%s", expectedBody, w.Body.String())
+	}
+}
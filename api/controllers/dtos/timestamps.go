@@ -0,0 +1,23 @@
+package dtos
+
+import (
+	"time"
+)
+
+// Timestamp formats a timestamp of a model as an RFC 3339 date-time in UTC.
+// It is empty for zero timestamps, e.g. of columns that were not selected.
+func Timestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// timestampV2 returns the UTC time of a timestamp of a model, or nil when it is zero
+func timestampV2(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
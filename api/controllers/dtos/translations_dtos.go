@@ -0,0 +1,135 @@
+// Package dtos stores the API DTOs and functionalities to convert DTOs to Models and vice versa
+// as well as functionality to serve json and error
+package dtos
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mzampetakis/prods-api/api/repositories"
+)
+
+type ProductTranslationRequestDto struct {
+	Title       *string `json:"title" validate:"required,maxlen=255"`
+	Description *string `json:"description" validate:"maxlen=65535"`
+}
+
+type CategoryTranslationRequestDto struct {
+	Title *string `json:"title" validate:"required,maxlen=155"`
+}
+
+type MissingTranslationResponseDto struct {
+	EntityType string   `json:"entity_type"`
+	EntityID   int64    `json:"entity_id"`
+	Locale     string   `json:"locale"`
+	Fields     []string `json:"fields"`
+}
+
+type MissingTranslationsResponseDto []MissingTranslationResponseDto
+
+func ConvertProductTranslationRequestDtoToModel(translation ProductTranslationRequestDto) repositories.ProductTranslationModel {
+	return repositories.ProductTranslationModel{
+		Title:       translation.Title,
+		Description: translation.Description,
+	}
+}
+
+func ConvertCategoryTranslationRequestDtoToModel(translation CategoryTranslationRequestDto) repositories.CategoryTranslationModel {
+	return repositories.CategoryTranslationModel{
+		Title: translation.Title,
+	}
+}
+
+func ConvertMissingTranslationsResponseModelToDto(missing []*repositories.MissingTranslationModel) MissingTranslationsResponseDto {
+	missingTranslationsResponseDto := make(MissingTranslationsResponseDto, 0)
+	for _, translation := range missing {
+		missingTranslationsResponseDto = append(missingTranslationsResponseDto, MissingTranslationResponseDto{
+			EntityType: translation.EntityType,
+			EntityID:   translation.EntityID,
+			Locale:     translation.Locale,
+			Fields:     translation.Fields,
+		})
+	}
+	return missingTranslationsResponseDto
+}
+
+// AcceptedLanguages returns the language tags of an Accept-Language header in order of preference.
+// Tags with zero or invalid quality and the * wildcard are skipped.
+func AcceptedLanguages(header string) []string {
+	type language struct {
+		tag     string
+		quality float64
+	}
+	languages := make([]language, 0)
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		tag := strings.TrimSpace(params[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+				if err != nil || q < 0 || q > 1 {
+					q = 0
+				}
+				quality = q
+			}
+		}
+		if quality > 0 {
+			languages = append(languages, language{tag: tag, quality: quality})
+		}
+	}
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+	tags := make([]string, 0, len(languages))
+	for _, language := range languages {
+		tags = append(tags, language.tag)
+	}
+	return tags
+}
+
+// ContentLanguage sets the Content-Language header to the locale of the served content, where the empty locale
+// stands for the default one. It is omitted when the content falls back to different locales or none is localized.
+func ContentLanguage(w http.ResponseWriter, defaultLocale string, locales ...string) {
+	contentLocale := ""
+	for i, locale := range locales {
+		if locale == "" {
+			locale = defaultLocale
+		}
+		if i > 0 && !strings.EqualFold(locale, contentLocale) {
+			return
+		}
+		contentLocale = locale
+	}
+	if contentLocale != "" {
+		w.Header().Set("Content-Language", contentLocale)
+	}
+}
+
+// ProductsContentLocales returns the content locales of the products and their embedded categories
+func ProductsContentLocales(products ...*repositories.ProductFetchModel) []string {
+	locales := make([]string, 0)
+	for _, product := range products {
+		locales = append(locales, product.ContentLocales...)
+		if product.Category != nil {
+			locales = append(locales, product.Category.ContentLocales...)
+		}
+	}
+	return locales
+}
+
+// CategoriesContentLocales returns the content locales of the categories and their embedded products
+func CategoriesContentLocales(categories ...*repositories.CategoryFetchModel) []string {
+	locales := make([]string, 0)
+	for _, category := range categories {
+		locales = append(locales, category.ContentLocales...)
+		locales = append(locales, ProductsContentLocales(category.Products...)...)
+	}
+	return locales
+}
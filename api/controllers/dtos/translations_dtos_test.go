@@ -0,0 +1,90 @@
+package dtos
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/mzampetakis/prods-api/api/repositories"
+)
+
+func TestAcceptedLanguages(t *testing.T) {
+	tests := map[string]struct {
+		header   string
+		expected []string
+	}{
+		"No header":             {header: "", expected: []string{}},
+		"Single language":       {header: "el", expected: []string{"el"}},
+		"Preferred by quality":  {header: "en;q=0.5, de-AT, el;q=0.8", expected: []string{"de-AT", "el", "en"}},
+		"Same quality in order": {header: "de, el", expected: []string{"de", "el"}},
+		"Excluded language":     {header: "el;q=0, de", expected: []string{"de"}},
+		"Wildcard":              {header: "*, el;q=0.5", expected: []string{"el"}},
+		"Invalid quality":       {header: "el;q=2, de;q=0.1", expected: []string{"de"}},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Act
+			languages := AcceptedLanguages(tc.header)
+
+			//Assert
+			if !reflect.DeepEqual(languages, tc.expected) {
+				t.Errorf("Expected %v but got %v", tc.expected, languages)
+			}
+		})
+	}
+}
+
+func TestContentLanguage(t *testing.T) {
+	tests := map[string]struct {
+		locales                 []string
+		expectedContentLanguage string
+	}{
+		"Translated product": {
+			locales:                 ProductsContentLocales(&repositories.ProductFetchModel{ContentLocales: []string{"el"}}),
+			expectedContentLanguage: "el",
+		},
+		"Default content": {
+			locales:                 ProductsContentLocales(&repositories.ProductFetchModel{ContentLocales: []string{""}}),
+			expectedContentLanguage: "en",
+		},
+		"Description falls back to the default locale": {
+			locales:                 ProductsContentLocales(&repositories.ProductFetchModel{ContentLocales: []string{"el", ""}}),
+			expectedContentLanguage: "",
+		},
+		"Embedded category falls back to the default locale": {
+			locales: ProductsContentLocales(&repositories.ProductFetchModel{ContentLocales: []string{"el"},
+				Category: &repositories.CategoryFetchModel{ContentLocales: []string{""}}}),
+			expectedContentLanguage: "",
+		},
+		"Categories with their products translated": {
+			locales: CategoriesContentLocales(
+				&repositories.CategoryFetchModel{ContentLocales: []string{"el"},
+					Products: []*repositories.ProductFetchModel{{ContentLocales: []string{"el"}}}},
+				&repositories.CategoryFetchModel{ContentLocales: []string{"el"}}),
+			expectedContentLanguage: "el",
+		},
+		"Categories in different locales": {
+			locales: CategoriesContentLocales(&repositories.CategoryFetchModel{ContentLocales: []string{"el"}},
+				&repositories.CategoryFetchModel{ContentLocales: []string{"de"}}),
+			expectedContentLanguage: "",
+		},
+		"Localized fields not selected": {
+			locales:                 ProductsContentLocales(&repositories.ProductFetchModel{}),
+			expectedContentLanguage: "",
+		},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Prepare
+			w := httptest.NewRecorder()
+
+			//Act
+			ContentLanguage(w, "en", tc.locales...)
+
+			//Assert
+			if contentLanguage := w.Header().Get("Content-Language"); contentLanguage != tc.expectedContentLanguage {
+				t.Errorf("Expected Content-Language %q but got %q", tc.expectedContentLanguage, contentLanguage)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/repositories"
+)
+
+// MoneyDto is an amount in the minor unit (e.g. cents) of an ISO 4217 currency
+type MoneyDto struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// ListResponseDtoV2 is the envelope of the v2 lists
+type ListResponseDtoV2 struct {
+	Data interface{}   `json:"data"`
+	Meta ListMetaDtoV2 `json:"meta"`
+}
+
+type ListMetaDtoV2 struct {
+	Offset int `json:"offset"`
+	Count  int `json:"count"`
+}
+
+// Items returns the entries of the list, which are the data written by the encoders of lists
+func (l ListResponseDtoV2) Items() interface{} {
+	return l.Data
+}
+
+type ProductResponseDtoV2 struct {
+	ID          int64      `json:"id"`
+	CategoryID  *int64     `json:"category_id"`
+	Title       *string    `json:"title"`
+	ImageURL    *string    `json:"image_url"`
+	Price       *MoneyDto  `json:"price"`
+	Description *string    `json:"description"`
+	CreatedAt   *time.Time `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at"`
+
+	Category *CategoryResponseDtoV2 `json:"category,omitempty"`
+}
+
+// ProductRequestDtoV2 declares the rules of its fields, while the rules of its price are checked by its conversion
+type ProductRequestDtoV2 struct {
+	CategoryID  *int64    `json:"category_id" validate:"min=1"`
+	Title       *string   `json:"title" validate:"required,maxlen=255"`
+	ImageURL    *string   `json:"image_url" validate:"url,maxlen=1000"`
+	Price       *MoneyDto `json:"price" validate:"required"`
+	Description *string   `json:"description" validate:"maxlen=65535"`
+}
+
+type CategoryResponseDtoV2 struct {
+	ID        int64      `json:"id"`
+	Title     *string    `json:"title"`
+	ImageURL  *string    `json:"image_url"`
+	Sort      *int64     `json:"sort"`
+	CreatedAt *time.Time `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+
+	Products *[]ProductResponseDtoV2 `json:"products,omitempty"`
+}
+
+// ConvertListToDtoV2 wraps the entries of a list page that starts at offset in its envelope
+func ConvertListToDtoV2(items interface{}, offset int, count int) ListResponseDtoV2 {
+	return ListResponseDtoV2{Data: items, Meta: ListMetaDtoV2{Offset: offset, Count: count}}
+}
+
+func ConvertProductResponseModelToDtoV2(product repositories.ProductFetchModel, currency string) ProductResponseDtoV2 {
+	productResponseDto := ProductResponseDtoV2{
+		ID:          product.ID,
+		CategoryID:  product.CategoryID,
+		Title:       product.Title,
+		ImageURL:    product.ImageURL,
+		Description: product.Description,
+		CreatedAt:   timestampV2(product.CreatedAt),
+		UpdatedAt:   timestampV2(product.UpdatedAt),
+	}
+	if product.Price != nil {
+		productResponseDto.Price = &MoneyDto{Amount: *product.Price, Currency: currency}
+	}
+	if product.Category != nil {
+		category := ConvertCategoryResponseModelToDtoV2(*product.Category, currency)
+		productResponseDto.Category = &category
+	}
+	return productResponseDto
+}
+
+func ConvertProductsResponseModelToDtoV2(products []*repositories.ProductFetchModel, currency string) []ProductResponseDtoV2 {
+	productsResponseDto := make([]ProductResponseDtoV2, 0)
+	for _, product := range products {
+		productsResponseDto = append(productsResponseDto, ConvertProductResponseModelToDtoV2(*product, currency))
+	}
+	return productsResponseDto
+}
+
+// ConvertProductRequestDtoV2ToModel converts the request to a model. Its price must not be negative and must be
+// in the provided currency, otherwise the model is returned without a price along with the violations.
+func ConvertProductRequestDtoV2ToModel(product ProductRequestDtoV2, currency string) (repositories.ProductCreateModel, error) {
+	model := repositories.ProductCreateModel{
+		CategoryID:  product.CategoryID,
+		Title:       product.Title,
+		ImageURL:    product.ImageURL,
+		Description: product.Description,
+	}
+	if product.Price == nil {
+		return model, nil
+	}
+	violations := make([]app.FieldError, 0)
+	if product.Price.Amount < 0 {
+		violations = append(violations, app.FieldError{Field: "price.amount", Rule: "min", Message: "price.amount must be greater than or equal to 0"})
+	}
+	if product.Price.Currency != currency {
+		violations = append(violations, app.FieldError{Field: "price.currency", Rule: "oneof", Message: "price.currency must be " + currency})
+	}
+	if len(violations) > 0 {
+		return model, &app.Error{Op: "dtos.ConvertProductRequestDtoV2ToModel", Code: app.EINVALID, Message: "Data validation error.", Details: violations}
+	}
+	model.Price = &product.Price.Amount
+	return model, nil
+}
+
+func ConvertCategoryResponseModelToDtoV2(category repositories.CategoryFetchModel, currency string) CategoryResponseDtoV2 {
+	categoryResponseDto := CategoryResponseDtoV2{
+		ID:        category.ID,
+		Title:     category.Title,
+		ImageURL:  category.ImageURL,
+		Sort:      category.Sort,
+		CreatedAt: timestampV2(category.CreatedAt),
+		UpdatedAt: timestampV2(category.UpdatedAt),
+	}
+	if category.Products != nil {
+		products := ConvertProductsResponseModelToDtoV2(category.Products, currency)
+		categoryResponseDto.Products = &products
+	}
+	return categoryResponseDto
+}
+
+func ConvertCategoriesResponseModelToDtoV2(categories []*repositories.CategoryFetchModel, currency string) []CategoryResponseDtoV2 {
+	categoriesResponseDto := make([]CategoryResponseDtoV2, 0)
+	for _, category := range categories {
+		categoriesResponseDto = append(categoriesResponseDto, ConvertCategoryResponseModelToDtoV2(*category, currency))
+	}
+	return categoriesResponseDto
+}
@@ -0,0 +1,87 @@
+package dtos
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/repositories"
+)
+
+func TestConvertProductResponseModelToDtoV2(t *testing.T) {
+	//Prepare
+	categoryID := int64(2)
+	title := "some title"
+	price := int64(1999)
+	categoryTitle := "some category"
+	eest := time.FixedZone("EEST", 3*60*60)
+	product := repositories.ProductFetchModel{
+		ID:         1,
+		CategoryID: &categoryID,
+		Title:      &title,
+		Price:      &price,
+		CreatedAt:  time.Date(2020, 5, 25, 20, 6, 40, 0, eest),
+		UpdatedAt:  time.Date(2020, 5, 26, 9, 0, 0, 5e8, eest),
+		Category:   &repositories.CategoryFetchModel{ID: categoryID, Title: &categoryTitle, CreatedAt: time.Date(2020, 5, 20, 10, 0, 0, 0, time.UTC)},
+	}
+	expectedJSON := `{"id":1,"category_id":2,"title":"some title","image_url":null,"price":{"amount":1999,"currency":"EUR"},` +
+		`"description":null,"created_at":"2020-05-25T17:06:40Z","updated_at":"2020-05-26T06:00:00.5Z",` +
+		`"category":{"id":2,"title":"some category","image_url":null,"sort":null,"created_at":"2020-05-20T10:00:00Z","updated_at":null}}`
+
+	//Act
+	productResponseDto := ConvertProductResponseModelToDtoV2(product, "EUR")
+
+	//Assert
+	raw, err := json.Marshal(productResponseDto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != expectedJSON {
+		t.Errorf("Expected ProductResponseDtoV2\n%s\nbut got\n%s", expectedJSON, raw)
+	}
+}
+
+func TestConvertProductRequestDtoV2ToModel(t *testing.T) {
+	title := "some title"
+	tests := map[string]struct {
+		price          MoneyDto
+		expectedModel  repositories.ProductCreateModel
+		expectedFields []string
+	}{
+		"Valid price": {price: MoneyDto{Amount: 1999, Currency: "EUR"},
+			expectedModel: repositories.ProductCreateModel{Title: &title, Price: func() *int64 { p := int64(1999); return &p }()}},
+		"Negative amount":  {price: MoneyDto{Amount: -1, Currency: "EUR"}, expectedFields: []string{"price.amount"}},
+		"Other currency":   {price: MoneyDto{Amount: 1999, Currency: "GBP"}, expectedFields: []string{"price.currency"}},
+		"Missing currency": {price: MoneyDto{Amount: -1}, expectedFields: []string{"price.amount", "price.currency"}},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Prepare
+			price := tc.price
+			request := ProductRequestDtoV2{Title: &title, Price: &price}
+
+			//Act
+			model, err := ConvertProductRequestDtoV2ToModel(request, "EUR")
+
+			//Assert
+			fields := make([]string, 0)
+			for _, detail := range app.ErrorDetails(err) {
+				fields = append(fields, detail.Field)
+			}
+			if len(tc.expectedFields) > 0 {
+				if app.ErrorCode(err) != app.EINVALID || !reflect.DeepEqual(fields, tc.expectedFields) {
+					t.Errorf("Expected invalid fields %v but got %v (%v)", tc.expectedFields, fields, err)
+				}
+				if model.Title != &title || model.Price != nil {
+					t.Errorf("Expected the model without a price but got %+v", model)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(model, tc.expectedModel) {
+				t.Errorf("Expected model %+v but got %+v (%v)", tc.expectedModel, model, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package dtos
+
+import (
+	"context"
+	"mime"
+)
+
+// The versions of the API contract
+const (
+	// V1 is the original contract, served by the unversioned routes unless another default version is configured
+	V1 = 1
+	// V2 has typed timestamps, money prices and list envelopes
+	V2 = 2
+)
+
+// The vendor media types that select the version of the unversioned routes
+const (
+	MediaTypeV1 = "application/vnd.prods.v1+json"
+	MediaTypeV2 = "application/vnd.prods.v2+json"
+)
+
+func init() {
+	RegisterEncoder(Encoder{MediaType: MediaTypeV1, Encode: encodeJSON})
+	RegisterEncoder(Encoder{MediaType: MediaTypeV2, Encode: encodeJSON})
+}
+
+type versionKey struct{}
+
+// WithVersion returns a copy of the context with the API version of the request
+func WithVersion(ctx context.Context, version int) context.Context {
+	return context.WithValue(ctx, versionKey{}, version)
+}
+
+// VersionFromContext returns the API version of the request, V1 when the request has no version
+func VersionFromContext(ctx context.Context) int {
+	if version, ok := ctx.Value(versionKey{}).(int); ok {
+		return version
+	}
+	return V1
+}
+
+// VersionOfMediaType returns the API version of a vendor media type. The bool reports whether the media type
+// selects a version.
+func VersionOfMediaType(contentType string) (int, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return 0, false
+	}
+	switch mediaType {
+	case MediaTypeV1:
+		return V1, true
+	case MediaTypeV2:
+		return V2, true
+	}
+	return 0, false
+}
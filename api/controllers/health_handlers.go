@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/mzampetakis/prods-api/api/controllers/dtos"
+	"github.com/mzampetakis/prods-api/api/health"
+)
+
+// Liveness reports that the API process is up without checking any dependency.
+// The health endpoints are served outside of the API prefix and are not part of the API reference.
+func (h *Handler) Liveness(w http.ResponseWriter, r *http.Request) {
+	healthJSON(w, http.StatusOK, dtos.HealthResponseDto{Status: dtos.HealthStatusOK})
+}
+
+// Readiness checks that the DB, the cache backend and the DB schema are ready to serve requests.
+// It responds with 503 Service Unavailable if any check fails.
+func (h *Handler) Readiness(w http.ResponseWriter, r *http.Request) {
+	results := health.Run(r.Context(), h.HealthChecks)
+	statusCode := http.StatusOK
+	if !health.Healthy(results) {
+		statusCode = http.StatusServiceUnavailable
+	}
+	healthJSON(w, statusCode, dtos.ConvertHealthResultsToDto(results))
+}
+
+// Status responds with the build version, the uptime and the latency of the dependencies of the API
+func (h *Handler) Status(w http.ResponseWriter, r *http.Request) {
+	healthJSON(w, http.StatusOK, dtos.ConvertStatusToDto(health.Run(r.Context(), h.HealthChecks)))
+}
+
+// healthJSON responds to a health request with a response that is never cached
+func healthJSON(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	dtos.JSON(w, statusCode, data)
+}
@@ -0,0 +1,182 @@
+package middlewares
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/controllers/dtos"
+)
+
+// The content codings of the compressed responses
+const (
+	EncodingGzip   = "gzip"
+	EncodingBrotli = "br"
+)
+
+// CompressionOptions configures the compression of the responses
+type CompressionOptions struct {
+	// Encodings are the content codings of the responses in order of preference. Compression is disabled when empty.
+	Encodings []string
+	// MinSize is the minimum size in bytes of the compressed response bodies. Smaller bodies are sent as they are.
+	MinSize int
+	// GzipLevel is the gzip compression level, from 1 (fastest) to 9 (smallest). Zero uses the default level.
+	GzipLevel int
+	// BrotliLevel is the brotli compression level, from 0 (fastest) to 11 (smallest)
+	BrotliLevel int
+}
+
+// Compression compresses the successful GET responses with the content coding of Encodings that the
+// Accept-Encoding header of the request prefers. The responses of other methods are not compressed, so that
+// the idempotent replays of the writes do not depend on the Accept-Encoding of the retried request.
+func Compression(options CompressionOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(options.Encodings) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"), options.Encodings)
+			if encoding == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			buffered := &bufferedResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(buffered, r)
+			body := buffered.body.Bytes()
+			if buffered.statusCode == http.StatusOK && len(body) >= options.MinSize && compressible(w.Header()) {
+				var compressed bytes.Buffer
+				if err := compress(&compressed, encoding, body, options); err == nil {
+					w.Header().Set("Content-Encoding", encoding)
+					w.Header().Del("Content-Length")
+					body = compressed.Bytes()
+				}
+			}
+			w.WriteHeader(buffered.statusCode)
+			w.Write(body)
+		})
+	}
+}
+
+// negotiateEncoding returns the encoding that the Accept-Encoding header prefers, honouring its q-values and
+// the * wildcard. The order of the encodings breaks the ties. It returns an empty string when no encoding is accepted.
+func negotiateEncoding(acceptEncoding string, encodings []string) string {
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(fields[0]))
+		if coding == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			if q := strings.TrimSpace(param); strings.HasPrefix(q, "q=") {
+				value, err := strconv.ParseFloat(strings.TrimPrefix(q, "q="), 64)
+				if err != nil {
+					value = 0
+				}
+				quality = value
+			}
+		}
+		qualities[coding] = quality
+	}
+	best, bestQuality := "", 0.0
+	for _, encoding := range encodings {
+		quality, ok := qualities[encoding]
+		if !ok {
+			quality = qualities["*"]
+		}
+		if quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+	return best
+}
+
+// compressible reports whether the response is not already encoded and has a content type that compresses well
+func compressible(header http.Header) bool {
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	for _, prefix := range []string{"image/", "audio/", "video/"} {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+func compress(w io.Writer, encoding string, body []byte, options CompressionOptions) error {
+	var encoder io.WriteCloser
+	switch encoding {
+	case EncodingBrotli:
+		encoder = brotli.NewWriterLevel(w, options.BrotliLevel)
+	default:
+		level := options.GzipLevel
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		gzipWriter, err := gzip.NewWriterLevel(w, level)
+		if err != nil {
+			return err
+		}
+		encoder = gzipWriter
+	}
+	if _, err := encoder.Write(body); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// Decompress decodes the gzip request bodies, e.g. of bulk updates, and rejects the bodies of other content
+// codings with 415 Unsupported Media Type. It runs before BodyLimit so that the limit applies to the decoded body.
+func Decompress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); encoding {
+		case "", "identity":
+		case EncodingGzip, "x-gzip":
+			r.Body = &gzipBody{body: r.Body}
+			// the decoded size is unknown, the body limit is enforced while reading it
+			r.ContentLength = -1
+			r.Header.Del("Content-Length")
+			r.Header.Del("Content-Encoding")
+		default:
+			msg := "Unsupported Content-Encoding " + encoding + ". Request bodies can be sent as they are or gzip encoded."
+			dtos.ERROR(w, r.Context(), &app.Error{Op: "Decompress", Code: app.EUNSUPPORTEDENCODING, Message: msg})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// gzipBody decodes a gzip body on its first read, so that the errors of a corrupt body are returned by the reads
+// of the handler, like any other error of reading a body
+type gzipBody struct {
+	body   io.ReadCloser
+	reader *gzip.Reader
+	err    error
+}
+
+func (g *gzipBody) Read(p []byte) (int, error) {
+	if g.reader == nil && g.err == nil {
+		g.reader, g.err = gzip.NewReader(g.body)
+	}
+	if g.err != nil {
+		return 0, g.err
+	}
+	return g.reader.Read(p)
+}
+
+func (g *gzipBody) Close() error {
+	return g.body.Close()
+}
@@ -0,0 +1,171 @@
+package middlewares
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/mzampetakis/prods-api/api/app"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	encodings := []string{EncodingBrotli, EncodingGzip}
+	tests := map[string]struct {
+		acceptEncoding string
+		expected       string
+	}{
+		"No Accept-Encoding":      {acceptEncoding: "", expected: ""},
+		"Gzip only":               {acceptEncoding: "gzip, deflate", expected: "gzip"},
+		"Server preference":       {acceptEncoding: "gzip, deflate, br", expected: "br"},
+		"Client preference":       {acceptEncoding: "br;q=0.5, gzip", expected: "gzip"},
+		"Refused encoding":        {acceptEncoding: "br;q=0, gzip;q=0.1", expected: "gzip"},
+		"Wildcard":                {acceptEncoding: "*", expected: "br"},
+		"Wildcard with exclusion": {acceptEncoding: "*, br;q=0", expected: "gzip"},
+		"Identity only":           {acceptEncoding: "identity", expected: ""},
+		"Case insensitive":        {acceptEncoding: "GZIP", expected: "gzip"},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Act
+			encoding := negotiateEncoding(tc.acceptEncoding, encodings)
+
+			//Assert
+			if encoding != tc.expected {
+				t.Errorf("Expected encoding %q but got %q", tc.expected, encoding)
+			}
+		})
+	}
+}
+
+func TestCompression(t *testing.T) {
+	large := strings.Repeat(`{"title":"Product","description":"A long description"},`, 50)
+	options := CompressionOptions{Encodings: []string{EncodingBrotli, EncodingGzip}, MinSize: 100}
+	tests := map[string]struct {
+		options          CompressionOptions
+		method           string
+		acceptEncoding   string
+		status           int
+		contentType      string
+		body             string
+		expectedEncoding string
+		expectedVary     bool
+	}{
+		"Gzip":             {options: options, method: http.MethodGet, acceptEncoding: "gzip", status: http.StatusOK, body: large, expectedEncoding: "gzip", expectedVary: true},
+		"Brotli":           {options: options, method: http.MethodGet, acceptEncoding: "gzip, br", status: http.StatusOK, body: large, expectedEncoding: "br", expectedVary: true},
+		"Not accepted":     {options: options, method: http.MethodGet, status: http.StatusOK, body: large, expectedVary: true},
+		"Below min size":   {options: options, method: http.MethodGet, acceptEncoding: "gzip", status: http.StatusOK, body: `{"id":1}`, expectedVary: true},
+		"Error response":   {options: options, method: http.MethodGet, acceptEncoding: "gzip", status: http.StatusNotFound, body: large, expectedVary: true},
+		"Image":            {options: options, method: http.MethodGet, acceptEncoding: "gzip", status: http.StatusOK, contentType: "image/png", body: large, expectedVary: true},
+		"Write request":    {options: options, method: http.MethodPost, acceptEncoding: "gzip", status: http.StatusCreated, body: large},
+		"Disabled":         {options: CompressionOptions{}, method: http.MethodGet, acceptEncoding: "gzip", status: http.StatusOK, body: large},
+		"Gzip with levels": {options: CompressionOptions{Encodings: []string{EncodingGzip}, GzipLevel: 9}, method: http.MethodGet, acceptEncoding: "gzip", status: http.StatusOK, body: large, expectedEncoding: "gzip", expectedVary: true},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Prepare
+			handler := Compression(tc.options)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.contentType != "" {
+					w.Header().Set("Content-Type", tc.contentType)
+				}
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			}))
+			r := httptest.NewRequest(tc.method, "/api/products", nil)
+			if tc.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+
+			//Act
+			handler.ServeHTTP(w, r)
+
+			//Assert
+			if w.Code != tc.status {
+				t.Errorf("Expected status %d but got %d", tc.status, w.Code)
+			}
+			if encoding := w.Header().Get("Content-Encoding"); encoding != tc.expectedEncoding {
+				t.Fatalf("Expected Content-Encoding %q but got %q", tc.expectedEncoding, encoding)
+			}
+			if vary := w.Header().Get("Vary") == "Accept-Encoding"; vary != tc.expectedVary {
+				t.Errorf("Expected Vary: Accept-Encoding %v but got %q", tc.expectedVary, w.Header().Get("Vary"))
+			}
+			body := w.Body.Bytes()
+			switch tc.expectedEncoding {
+			case EncodingGzip:
+				reader, err := gzip.NewReader(bytes.NewReader(body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				body, _ = ioutil.ReadAll(reader)
+			case EncodingBrotli:
+				body, _ = ioutil.ReadAll(brotli.NewReader(bytes.NewReader(body)))
+			}
+			if string(body) != tc.body {
+				t.Errorf("Expected the decoded body to be the handler's body but got %q", body)
+			}
+		})
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte(`{"title":"Product"}`))
+	writer.Close()
+	tests := map[string]struct {
+		contentEncoding string
+		body            []byte
+		expectedStatus  int
+		expectedBody    string
+		expectedError   bool
+	}{
+		"Gzip body":            {contentEncoding: "gzip", body: compressed.Bytes(), expectedStatus: http.StatusOK, expectedBody: `{"title":"Product"}`},
+		"Plain body":           {body: []byte(`{"title":"Product"}`), expectedStatus: http.StatusOK, expectedBody: `{"title":"Product"}`},
+		"Identity body":        {contentEncoding: "identity", body: []byte(`{}`), expectedStatus: http.StatusOK, expectedBody: `{}`},
+		"Corrupt gzip body":    {contentEncoding: "gzip", body: []byte(`{"title":"Product"}`), expectedStatus: http.StatusOK, expectedError: true},
+		"Unsupported encoding": {contentEncoding: "br", body: []byte(`{}`), expectedStatus: http.StatusUnsupportedMediaType},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Prepare
+			var body []byte
+			var readErr error
+			handler := Decompress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, readErr = ioutil.ReadAll(r.Body)
+				if tc.contentEncoding == "gzip" && (r.Header.Get("Content-Encoding") != "" || r.ContentLength != -1) {
+					t.Errorf("Expected the decoded request to have no Content-Encoding and length")
+				}
+			}))
+			r := httptest.NewRequest(http.MethodPost, "/api/products", bytes.NewReader(tc.body))
+			if tc.contentEncoding != "" {
+				r.Header.Set("Content-Encoding", tc.contentEncoding)
+			}
+			w := httptest.NewRecorder()
+
+			//Act
+			handler.ServeHTTP(w, r)
+
+			//Assert
+			if w.Code != tc.expectedStatus {
+				t.Fatalf("Expected status %d but got %d", tc.expectedStatus, w.Code)
+			}
+			if tc.expectedStatus != http.StatusOK {
+				if !strings.Contains(w.Body.String(), app.EINVALID) {
+					t.Errorf("Expected an %s error but got %s", app.EINVALID, w.Body.String())
+				}
+				return
+			}
+			if (readErr != nil) != tc.expectedError {
+				t.Errorf("Expected read error %v but got %v", tc.expectedError, readErr)
+			}
+			if !tc.expectedError && string(body) != tc.expectedBody {
+				t.Errorf("Expected body %s but got %s", tc.expectedBody, body)
+			}
+		})
+	}
+}
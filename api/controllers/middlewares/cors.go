@@ -0,0 +1,182 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOptions configures the cross-origin requests that browsers are allowed to make
+type CORSOptions struct {
+	// AllowedOrigins are the allowed origins, e.g. https://admin.example.com. Entries like https://*.example.com
+	// match any subdomain and * matches any origin. CORS is disabled when empty.
+	AllowedOrigins []string
+	AllowedMethods []string
+	// AllowedHeaders are the request headers that the clients may send. * allows any header.
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers that the clients may read
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache the result of a preflight request
+	MaxAge time.Duration
+}
+
+// CORS adds the CORS headers to the responses of the requests from the allowed origins and answers their
+// preflight requests with 204 No Content. Preflight requests of other origins get no CORS headers, so browsers block them.
+func CORS(options CORSOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(options.AllowedOrigins) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			w.Header().Add("Vary", "Origin")
+			if preflight {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+			}
+			if origin == "" || !options.allowsOrigin(origin) {
+				if preflight {
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if options.allowsAnyOrigin() && !options.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			if options.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+			if !preflight {
+				if len(options.ExposedHeaders) > 0 {
+					w.Header().Set("Access-Control-Expose-Headers", strings.Join(options.ExposedHeaders, ", "))
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			method := r.Header.Get("Access-Control-Request-Method")
+			requestedHeaders := splitHeaderList(r.Header.Get("Access-Control-Request-Headers"))
+			if !containsFold(options.AllowedMethods, method) || !options.allowsHeaders(requestedHeaders) {
+				w.Header().Del("Access-Control-Allow-Origin")
+				w.Header().Del("Access-Control-Allow-Credentials")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(options.AllowedMethods, ", "))
+			if len(requestedHeaders) > 0 {
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join(requestedHeaders, ", "))
+			}
+			if options.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(options.MaxAge/time.Second)))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+func (o CORSOptions) allowsAnyOrigin() bool {
+	return containsFold(o.AllowedOrigins, "*")
+}
+
+func (o CORSOptions) allowsOrigin(origin string) bool {
+	for _, allowed := range o.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		// https://*.example.com matches the subdomains of example.com over https
+		if i := strings.Index(allowed, "://*."); i >= 0 {
+			scheme, domain := allowed[:i+3], allowed[i+4:]
+			if strings.HasPrefix(strings.ToLower(origin), strings.ToLower(scheme)) &&
+				strings.HasSuffix(strings.ToLower(origin), strings.ToLower(domain)) && len(origin) > len(scheme)+len(domain) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (o CORSOptions) allowsHeaders(headers []string) bool {
+	if containsFold(o.AllowedHeaders, "*") {
+		return true
+	}
+	for _, header := range headers {
+		if !containsFold(o.AllowedHeaders, header) {
+			return false
+		}
+	}
+	return true
+}
+
+func splitHeaderList(list string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// SecurityHeadersOptions configures the security headers of the responses. Empty values omit their header.
+type SecurityHeadersOptions struct {
+	// HSTSMaxAge is the max-age of Strict-Transport-Security. Zero omits the header.
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	ContentTypeNosniff    bool
+	FrameOptions          string
+	ReferrerPolicy        string
+	// ContentSecurityPolicy of the API responses
+	ContentSecurityPolicy string
+	// SwaggerContentSecurityPolicy of the Swagger UI, which needs its scripts and styles
+	SwaggerContentSecurityPolicy string
+}
+
+// SecurityHeaders adds the configured security headers to all responses
+func SecurityHeaders(options SecurityHeadersOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			if options.HSTSMaxAge > 0 {
+				hsts := "max-age=" + strconv.Itoa(int(options.HSTSMaxAge/time.Second))
+				if options.HSTSIncludeSubdomains {
+					hsts += "; includeSubDomains"
+				}
+				header.Set("Strict-Transport-Security", hsts)
+			}
+			if options.ContentTypeNosniff {
+				header.Set("X-Content-Type-Options", "nosniff")
+			}
+			if options.FrameOptions != "" {
+				header.Set("X-Frame-Options", options.FrameOptions)
+			}
+			if options.ReferrerPolicy != "" {
+				header.Set("Referrer-Policy", options.ReferrerPolicy)
+			}
+			csp := options.ContentSecurityPolicy
+			if strings.HasPrefix(r.URL.Path, "/swagger") {
+				csp = options.SwaggerContentSecurityPolicy
+			}
+			if csp != "" {
+				header.Set("Content-Security-Policy", csp)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	options := CORSOptions{
+		AllowedOrigins: []string{"https://admin.example.com", "https://*.shop.com"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders: []string{"Content-Type"},
+		ExposedHeaders: []string{"ETag"},
+		MaxAge:         10 * time.Minute,
+	}
+	tests := map[string]struct {
+		options               CORSOptions
+		method                string
+		origin                string
+		requestMethod         string
+		requestHeaders        string
+		expectedStatus        int
+		expectedAllowOrigin   string
+		expectedAllowMethods  string
+		expectedExposeHeaders string
+		expectedMaxAge        string
+	}{
+		"Allowed origin": {options: options, method: http.MethodGet, origin: "https://admin.example.com",
+			expectedStatus: http.StatusOK, expectedAllowOrigin: "https://admin.example.com", expectedExposeHeaders: "ETag"},
+		"Allowed subdomain": {options: options, method: http.MethodGet, origin: "https://eu.shop.com",
+			expectedStatus: http.StatusOK, expectedAllowOrigin: "https://eu.shop.com", expectedExposeHeaders: "ETag"},
+		"Domain of the wildcard": {options: options, method: http.MethodGet, origin: "https://shop.com", expectedStatus: http.StatusOK},
+		"Other origin":           {options: options, method: http.MethodGet, origin: "https://evil.com", expectedStatus: http.StatusOK},
+		"Same origin request":    {options: options, method: http.MethodGet, expectedStatus: http.StatusOK},
+		"Any origin":             {options: CORSOptions{AllowedOrigins: []string{"*"}}, method: http.MethodGet, origin: "https://evil.com", expectedStatus: http.StatusOK, expectedAllowOrigin: "*"},
+		"Any origin with credentials": {options: CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true}, method: http.MethodGet,
+			origin: "https://evil.com", expectedStatus: http.StatusOK, expectedAllowOrigin: "https://evil.com"},
+		"Disabled": {options: CORSOptions{}, method: http.MethodGet, origin: "https://admin.example.com", expectedStatus: http.StatusOK},
+		"Preflight": {options: options, method: http.MethodOptions, origin: "https://admin.example.com", requestMethod: http.MethodPost,
+			requestHeaders: "content-type", expectedStatus: http.StatusNoContent, expectedAllowOrigin: "https://admin.example.com",
+			expectedAllowMethods: "GET, POST", expectedMaxAge: "600"},
+		"Preflight of method not allowed": {options: options, method: http.MethodOptions, origin: "https://admin.example.com",
+			requestMethod: http.MethodDelete, expectedStatus: http.StatusNoContent},
+		"Preflight of header not allowed": {options: options, method: http.MethodOptions, origin: "https://admin.example.com",
+			requestMethod: http.MethodPost, requestHeaders: "X-Custom", expectedStatus: http.StatusNoContent},
+		"Preflight of other origin": {options: options, method: http.MethodOptions, origin: "https://evil.com",
+			requestMethod: http.MethodPost, expectedStatus: http.StatusNoContent},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, "/api/products", nil)
+			if tc.origin != "" {
+				r.Header.Set("Origin", tc.origin)
+			}
+			if tc.requestMethod != "" {
+				r.Header.Set("Access-Control-Request-Method", tc.requestMethod)
+			}
+			if tc.requestHeaders != "" {
+				r.Header.Set("Access-Control-Request-Headers", tc.requestHeaders)
+			}
+			w := httptest.NewRecorder()
+
+			//Act
+			CORS(tc.options)(next).ServeHTTP(w, r)
+
+			//Assert
+			if w.Code != tc.expectedStatus {
+				t.Errorf("Expected status %d but got %d", tc.expectedStatus, w.Code)
+			}
+			if allowOrigin := w.Header().Get("Access-Control-Allow-Origin"); allowOrigin != tc.expectedAllowOrigin {
+				t.Errorf("Expected Access-Control-Allow-Origin %q but got %q", tc.expectedAllowOrigin, allowOrigin)
+			}
+			if allowMethods := w.Header().Get("Access-Control-Allow-Methods"); allowMethods != tc.expectedAllowMethods {
+				t.Errorf("Expected Access-Control-Allow-Methods %q but got %q", tc.expectedAllowMethods, allowMethods)
+			}
+			if exposeHeaders := w.Header().Get("Access-Control-Expose-Headers"); exposeHeaders != tc.expectedExposeHeaders {
+				t.Errorf("Expected Access-Control-Expose-Headers %q but got %q", tc.expectedExposeHeaders, exposeHeaders)
+			}
+			if maxAge := w.Header().Get("Access-Control-Max-Age"); maxAge != tc.expectedMaxAge {
+				t.Errorf("Expected Access-Control-Max-Age %q but got %q", tc.expectedMaxAge, maxAge)
+			}
+			if len(tc.options.AllowedOrigins) > 0 && w.Header().Get("Vary") != "Origin" {
+				t.Errorf("Expected the response to vary by Origin but got %q", w.Header().Get("Vary"))
+			}
+		})
+	}
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	//Prepare
+	handler := SecurityHeaders(SecurityHeadersOptions{
+		HSTSMaxAge:                   365 * 24 * time.Hour,
+		HSTSIncludeSubdomains:        true,
+		ContentTypeNosniff:           true,
+		FrameOptions:                 "DENY",
+		ContentSecurityPolicy:        "default-src 'none'",
+		SwaggerContentSecurityPolicy: "default-src 'self'",
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	tests := map[string]struct {
+		path        string
+		expectedCSP string
+	}{
+		"API":        {path: "/api/products", expectedCSP: "default-src 'none'"},
+		"Swagger UI": {path: "/swagger/index.html", expectedCSP: "default-src 'self'"},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			//Act
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			//Assert
+			expected := map[string]string{
+				"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+				"X-Content-Type-Options":    "nosniff",
+				"X-Frame-Options":           "DENY",
+				"Referrer-Policy":           "",
+				"Content-Security-Policy":   tc.expectedCSP,
+			}
+			for header, value := range expected {
+				if w.Header().Get(header) != value {
+					t.Errorf("Expected %s %q but got %q", header, value, w.Header().Get(header))
+				}
+			}
+		})
+	}
+}
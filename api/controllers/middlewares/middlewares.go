@@ -2,45 +2,153 @@
 package middlewares
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha1"
 	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/auth"
 	"github.com/mzampetakis/prods-api/api/controllers/dtos"
+	"github.com/mzampetakis/prods-api/api/idempotency"
+	"github.com/mzampetakis/prods-api/api/logging"
+	"github.com/mzampetakis/prods-api/api/ratelimit"
+	"github.com/mzampetakis/prods-api/api/repositories"
+	"github.com/mzampetakis/prods-api/api/tlsconfig"
+	"github.com/mzampetakis/prods-api/api/tracing"
 	"github.com/sirupsen/logrus"
 )
 
-// LogRequest logs each request with details
-func LogRequest(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		requestID := uuid.New()
-		ctx := context.WithValue(r.Context(), "request_id", requestID)
-		logrus.Printf("%s : %s: Method: %s | URL: %s%s | Proto: %s",
-			time.Now().Format(time.RFC3339), requestID, r.Method, r.Host, r.URL, r.Proto)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+// AccessLogOptions configures the access logs
+type AccessLogOptions struct {
+	// Headers adds the request headers to the access logs
+	Headers bool
+	// SensitiveHeaders are logged with a redacted value
+	SensitiveHeaders []string
+}
+
+// AccessLog carries a logger enriched with the request details in the request's context
+// and logs a line for each request after its completion with the status, size and latency of its response.
+// Traced requests are identified by their trace id.
+func AccessLog(options AccessLogOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			requestID := tracing.TraceIDFromContext(r.Context())
+			if requestID == "" {
+				requestID = uuid.New().String()
+			}
+			route := r.URL.Path
+			if currentRoute := mux.CurrentRoute(r); currentRoute != nil {
+				if template, err := currentRoute.GetPathTemplate(); err == nil {
+					route = template
+				}
+			}
+			fields := logrus.Fields{
+				"request_id": requestID,
+				"method":     r.Method,
+				"route":      route,
+			}
+			if user, _, ok := r.BasicAuth(); ok {
+				fields["user"] = user
+			}
+			if principal, ok := auth.FromContext(r.Context()); ok {
+				fields["principal"] = principal.Name
+			}
+			logger := logrus.WithFields(fields)
+			ctx := context.WithValue(r.Context(), "request_id", requestID)
+			ctx = logging.NewContext(ctx, logger)
+
+			recorder := &accessLogRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+
+			accessLogger := logger.WithFields(logrus.Fields{
+				"uri":        r.URL.RequestURI(),
+				"proto":      r.Proto,
+				"remote":     r.RemoteAddr,
+				"status":     recorder.status,
+				"bytes":      recorder.bytes,
+				"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+				"user_agent": r.UserAgent(),
+			})
+			if options.Headers {
+				accessLogger = accessLogger.WithField("headers", logging.RedactHeaders(r.Header, options.SensitiveHeaders))
+			}
+			switch {
+			case recorder.status >= http.StatusInternalServerError:
+				accessLogger.Error("Request completed")
+			case recorder.status >= http.StatusBadRequest:
+				accessLogger.Warn("Request completed")
+			default:
+				accessLogger.Info("Request completed")
+			}
+		})
+	}
+}
+
+// accessLogRecorder keeps the status code and the size of the response
+type accessLogRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *accessLogRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *accessLogRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
 }
 
-// AcceptJSON stricts usage to accept only 'application/json' or '*/*' request header
-func AcceptJSON(next http.Handler) http.Handler {
+// ErrorFormat negotiates the format of the error responses. Requests whose Accept header prefers
+// 'application/problem+json' to 'application/json', by the q-values of its media ranges, get RFC 7807 errors
+// while the rest keep getting the legacy error format.
+func ErrorFormat(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !strings.Contains(r.Header.Get("Accept"), "application/json") && !strings.Contains(r.Header.Get("Accept"), "*/*") {
-			msg := "Header accept: '" + r.Header.Get("Accept") + "' is not accepted. application/json or */* should be accepted."
-			dtos.ERROR(w, r.Context(), &app.Error{Op: "AcceptJSON", Err: errors.New(msg), Code: app.ENOTACCEPTED, Message: msg})
-			return
+		ctx := context.WithValue(r.Context(), "request_uri", r.URL.RequestURI())
+		if errorFormat, _ := dtos.Negotiate(r.Header.Get("Accept"), []string{dtos.MediaTypeJSON, dtos.ProblemJSON}); errorFormat == dtos.ProblemJSON {
+			ctx = context.WithValue(ctx, "error_format", dtos.ProblemJSON)
 		}
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-// ContentTypeJSON adds 'Content-Type'='application/json' header to each response
-func ContentTypeJSON(next http.Handler) http.Handler {
+// Negotiate picks the media type of the response among the media types of the dtos encoders from the Accept
+// header and sets it as the response's Content-Type. Requests that accept none of them get a 406 response,
+// except for those that accept application/problem+json, which get JSON.
+func Negotiate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("Vary", "Accept")
+		accept := r.Header.Get("Accept")
+		mediaType, ok := dtos.Negotiate(accept, dtos.MediaTypes())
+		if !ok {
+			// the requests that only accept application/problem+json get JSON
+			if _, ok = dtos.Negotiate(accept, []string{dtos.ProblemJSON}); ok {
+				mediaType = dtos.MediaTypeJSON
+			}
+		}
+		if !ok {
+			w.Header().Set("Content-Type", dtos.MediaTypeJSON)
+			msg := "Header accept: '" + accept + "' is not accepted. One of " + strings.Join(dtos.MediaTypes(), ", ") +
+				", application/problem+json or */* should be accepted."
+			dtos.ERROR(w, r.Context(), &app.Error{Op: "Negotiate", Err: errors.New(msg), Code: app.ENOTACCEPTED, Message: msg})
+			return
+		}
+		w.Header().Set("Content-Type", mediaType)
 		next.ServeHTTP(w, r)
 	})
 }
@@ -60,7 +168,7 @@ func Recovery(next http.Handler) http.Handler {
 				default:
 					err = errors.New("Unknown error")
 				}
-				logrus.Error(err.Error())
+				logging.FromContext(r.Context()).WithField("panic", true).Error(err.Error())
 				dtos.ERROR(w, r.Context(), &app.Error{Code: app.EINTERNAL, Err: err})
 			}
 
@@ -68,3 +176,394 @@ func Recovery(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// ClientPrincipal carries the principal of the request's client certificate in the request's context.
+// The principal of each certificate subject or common name is looked up in principals.
+func ClientPrincipal(principals map[string]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if principal, ok := tlsconfig.ClientPrincipal(r.TLS, principals); ok {
+				r = r.WithContext(auth.WithPrincipal(r.Context(), principal))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RateLimit limits the requests of each client to the quota of its role and route group.
+// Limited responses carry the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy headers
+// and requests over the quota get a 429 response with a Retry-After header.
+// If the store fails, requests are served without a limit.
+func RateLimit(limiter *ratelimit.Limiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result, quota, err := limiter.Take(r)
+			if err != nil {
+				logging.FromContext(r.Context()).Warnf("Rate limit store error: %s", err.Error())
+				next.ServeHTTP(w, r)
+				return
+			}
+			if quota.Unlimited() {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+			w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", quota.Requests, ceilSeconds(quota.Period)))
+			if !result.Allowed {
+				retryAfter := ceilSeconds(result.RetryAfter)
+				if retryAfter < 1 {
+					retryAfter = 1
+				}
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				msg := fmt.Sprintf("Rate limit of %s requests exceeded. Retry after %d seconds.", quota, retryAfter)
+				dtos.ERROR(w, r.Context(), &app.Error{Op: "RateLimit", Code: app.ERATELIMITED, Message: msg})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Idempotency replays the stored response of the POST and PATCH requests whose idempotency key was already used
+// by the same request. Requests that reuse a key with a different method, URI or body get a 422 response and
+// duplicates of a request in progress get a 409 response if it does not complete while they wait.
+// If the store fails, requests are served without deduplication.
+func Idempotency(keys *idempotency.Keys) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := keys.Key(r)
+			if !ok || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(r.Header.Get(keys.Header)) > idempotency.MaxKeyLength {
+				msg := fmt.Sprintf("Header %s must be at most %d characters long.", keys.Header, idempotency.MaxKeyLength)
+				dtos.ERROR(w, r.Context(), &app.Error{Op: "Idempotency", Code: app.EINVALIDIDEMPOTENCYKEY, Message: msg})
+				return
+			}
+			// the body is hashed as it is read and kept for the handler, in a temporary file once it is large
+			body := &spooledBody{maxMemory: idempotencyMemoryBytes}
+			defer body.Close()
+			fingerprint, readErr := idempotency.Fingerprint(r, io.TeeReader(r.Body, body))
+			replay, err := body.Reader()
+			if err != nil {
+				logging.FromContext(r.Context()).Errorf("Could not keep the request body: %s", err.Error())
+				dtos.ERROR(w, r.Context(), &app.Error{Op: "Idempotency", Code: app.EINTERNAL, Err: err})
+				return
+			}
+			r.Body = ioutil.NopCloser(io.MultiReader(replay, errorReader{readErr}))
+			if readErr != nil {
+				// e.g. a body over the limit, which the handler reports
+				next.ServeHTTP(w, r)
+				return
+			}
+			record, outcome, err := keys.Begin(r.Context(), key, fingerprint)
+			if err != nil {
+				logging.FromContext(r.Context()).Warnf("Idempotency store error: %s", err.Error())
+				next.ServeHTTP(w, r)
+				return
+			}
+			switch outcome {
+			case idempotency.Mismatch:
+				msg := "Idempotency key was already used by a different request."
+				dtos.ERROR(w, r.Context(), &app.Error{Op: "Idempotency", Code: app.EIDEMPOTENCYMISMATCH, Message: msg})
+				return
+			case idempotency.InProgress:
+				w.Header().Set("Retry-After", "1")
+				msg := "A request with the idempotency key is still in progress."
+				dtos.ERROR(w, r.Context(), &app.Error{Op: "Idempotency", Code: app.EIDEMPOTENCYINFLIGHT, Message: msg})
+				return
+			case idempotency.Replay:
+				for name, values := range record.Response.Header {
+					w.Header()[name] = values
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(record.Response.StatusCode)
+				w.Write(record.Response.Body)
+				return
+			}
+
+			// the headers set before the handler vary per request and are not stored
+			outerHeader := w.Header().Clone()
+			recorder := &idempotencyRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+			completed := false
+			defer func() {
+				if !completed {
+					// the handler panicked, release the key so that the request can be retried
+					keys.Store.Release(context.Background(), key)
+				}
+			}()
+			next.ServeHTTP(recorder, r)
+			completed = true
+			response := idempotency.Response{StatusCode: recorder.statusCode, Header: changedHeader(w.Header(), outerHeader), Body: recorder.body.Bytes()}
+			if err = keys.Complete(context.Background(), key, fingerprint, response); err != nil {
+				logging.FromContext(r.Context()).Warnf("Could not store the idempotent response: %s", err.Error())
+			}
+		})
+	}
+}
+
+// idempotencyRecorder keeps the status code and the body of the response
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (r *idempotencyRecorder) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *idempotencyRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// idempotencyMemoryBytes is how much of a request body the idempotency keys keep in memory
+const idempotencyMemoryBytes = 1 << 20
+
+// spooledBody keeps the bytes written to it in memory up to maxMemory and all of them in a temporary file after that
+type spooledBody struct {
+	maxMemory int64
+	memory    bytes.Buffer
+	file      *os.File
+	err       error
+}
+
+func (s *spooledBody) Write(p []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	if s.file == nil && int64(s.memory.Len()+len(p)) <= s.maxMemory {
+		return s.memory.Write(p)
+	}
+	if s.file == nil {
+		if s.file, s.err = ioutil.TempFile("", "request-body-*"); s.err != nil {
+			return 0, s.err
+		}
+		if _, s.err = s.memory.WriteTo(s.file); s.err != nil {
+			return 0, s.err
+		}
+	}
+	n, err := s.file.Write(p)
+	s.err = err
+	return n, err
+}
+
+// Reader returns a reader of the written bytes, or the error that writing them failed with
+func (s *spooledBody) Reader() (io.Reader, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.file == nil {
+		return &s.memory, nil
+	}
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return s.file, nil
+}
+
+// Close removes the temporary file
+func (s *spooledBody) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	s.file.Close()
+	return os.Remove(s.file.Name())
+}
+
+// errorReader returns its error, or io.EOF when it has none
+type errorReader struct {
+	err error
+}
+
+func (r errorReader) Read([]byte) (int, error) {
+	if r.err == nil {
+		return 0, io.EOF
+	}
+	return 0, r.err
+}
+
+// changedHeader returns the headers that were set or changed after the outer ones
+func changedHeader(header http.Header, outer http.Header) http.Header {
+	changed := make(http.Header)
+	for name, values := range header {
+		if strings.Join(outer[name], ",") != strings.Join(values, ",") {
+			changed[name] = values
+		}
+	}
+	return changed
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// matchRoute returns the value of the longest route that the path starts with
+func matchRoute(routes map[string]time.Duration, path string) (time.Duration, bool) {
+	var value time.Duration
+	matched, found := "", false
+	for route, routeValue := range routes {
+		if strings.HasPrefix(path, route) && (!found || len(route) > len(matched)) {
+			matched, value, found = route, routeValue, true
+		}
+	}
+	return value, found
+}
+
+// BodyLimits configures the maximum size of the request bodies
+type BodyLimits struct {
+	// MaxBytes is the maximum size of the request bodies
+	MaxBytes int64
+	// MaxMultipartBytes is the maximum size of the multipart/form-data bodies, i.e. the image uploads
+	MaxMultipartBytes int64
+}
+
+// BodyLimit rejects the requests whose declared Content-Length exceeds the limit with 413 Request Entity Too Large
+// and limits the reading of all other bodies, so that the handlers fail once they read past the limit
+func BodyLimit(limits BodyLimits) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limit := limits.MaxBytes
+			if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+				limit = limits.MaxMultipartBytes
+			}
+			if limit <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if r.ContentLength > limit {
+				msg := fmt.Sprintf("Request body too large. The maximum size is %d bytes.", limit)
+				dtos.ERROR(w, r.Context(), &app.Error{Op: "BodyLimit", Code: app.EBODYTOOLARGE, Message: msg})
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// StatementTimeoutPolicy configures the timeout of the DB statements of the requests
+type StatementTimeoutPolicy struct {
+	// RouteTimeouts overrides the DB's statement timeout for the paths (without the API prefix) that start with each key.
+	// The longest matching key wins.
+	RouteTimeouts map[string]time.Duration
+	// Prefix is the API prefix that is stripped from the paths before matching them
+	Prefix string
+}
+
+// StatementTimeout bounds the DB statements of the requests of the routes with a statement timeout
+func StatementTimeout(policy StatementTimeoutPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if timeout, ok := matchRoute(policy.RouteTimeouts, strings.TrimPrefix(r.URL.Path, policy.Prefix)); ok {
+				r = r.WithContext(repositories.WithStatementTimeout(r.Context(), timeout))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CachePolicy configures the Cache-Control header of the GET responses
+type CachePolicy struct {
+	// MaxAge is the default max-age of the responses. Zero requires clients to revalidate each response
+	// and a negative value forbids them to store it.
+	MaxAge time.Duration
+	// RouteMaxAges overrides MaxAge for the paths (without the API prefix) that start with each key.
+	// The longest matching key wins.
+	RouteMaxAges map[string]time.Duration
+	// Prefix is the API prefix that is stripped from the paths before matching them
+	Prefix string
+}
+
+// CacheControl returns the Cache-Control header value for the provided request path
+func (p CachePolicy) CacheControl(path string) string {
+	maxAge := p.MaxAge
+	if routeMaxAge, ok := matchRoute(p.RouteMaxAges, strings.TrimPrefix(path, p.Prefix)); ok {
+		maxAge = routeMaxAge
+	}
+	switch {
+	case maxAge < 0:
+		return "no-store"
+	case maxAge == 0:
+		return "no-cache"
+	}
+	return "public, max-age=" + strconv.Itoa(int(maxAge/time.Second))
+}
+
+// HTTPCaching adds Cache-Control and ETag headers to the successful GET responses and answers
+// conditional requests (If-None-Match, If-Modified-Since) with 304 Not Modified when the response is unchanged
+func HTTPCaching(policy CachePolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				next.ServeHTTP(w, r)
+				return
+			}
+			buffered := &bufferedResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(buffered, r)
+			if buffered.statusCode != http.StatusOK {
+				w.WriteHeader(buffered.statusCode)
+				w.Write(buffered.body.Bytes())
+				return
+			}
+			if w.Header().Get("Cache-Control") == "" {
+				w.Header().Set("Cache-Control", policy.CacheControl(r.URL.Path))
+			}
+			if w.Header().Get("ETag") == "" {
+				w.Header().Set("ETag", fmt.Sprintf(`"%x"`, sha1.Sum(buffered.body.Bytes())))
+			}
+			if notModified(r, w.Header()) {
+				w.Header().Del("Content-Type")
+				w.Header().Del("Content-Length")
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write(buffered.body.Bytes())
+		})
+	}
+}
+
+// notModified evaluates the conditional headers of the request against the response headers.
+// If-Modified-Since is only evaluated when the request has no If-None-Match.
+func notModified(r *http.Request, header http.Header) bool {
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		etag := strings.TrimPrefix(header.Get("ETag"), "W/")
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+	ifModifiedSince, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(header.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.After(ifModifiedSince)
+}
+
+// bufferedResponseWriter holds back the status code and the body of a response so that they can be inspected
+type bufferedResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (b *bufferedResponseWriter) WriteHeader(statusCode int) {
+	b.statusCode = statusCode
+}
+
+func (b *bufferedResponseWriter) Write(data []byte) (int, error) {
+	return b.body.Write(data)
+}
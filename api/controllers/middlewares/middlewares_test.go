@@ -0,0 +1,411 @@
+package middlewares
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/controllers/dtos"
+	"github.com/mzampetakis/prods-api/api/idempotency"
+	"github.com/mzampetakis/prods-api/api/logging"
+	"github.com/mzampetakis/prods-api/api/ratelimit"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestCachePolicy_CacheControl(t *testing.T) {
+	policy := CachePolicy{
+		MaxAge: time.Minute,
+		RouteMaxAges: map[string]time.Duration{
+			"/reports":            -1,
+			"/categories":         0,
+			"/products":           30 * time.Second,
+			"/products/1/images":  5 * time.Minute,
+			"/products/category/": time.Hour,
+		},
+		Prefix: "/api",
+	}
+	tests := map[string]struct {
+		path     string
+		expected string
+	}{
+		"Default max-age":      {path: "/api/", expected: "public, max-age=60"},
+		"Route max-age":        {path: "/api/products/5", expected: "public, max-age=30"},
+		"Longest route wins":   {path: "/api/products/1/images", expected: "public, max-age=300"},
+		"Zero max-age":         {path: "/api/categories", expected: "no-cache"},
+		"Negative max-age":     {path: "/api/reports/broken-images", expected: "no-store"},
+		"Path without prefix":  {path: "/products", expected: "public, max-age=30"},
+		"Unmatched route path": {path: "/api/unknown", expected: "public, max-age=60"},
+	}
+
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			if cacheControl := policy.CacheControl(tc.path); cacheControl != tc.expected {
+				t.Errorf("Expected Cache-Control %s but got %s", tc.expected, cacheControl)
+			}
+		})
+	}
+}
+
+func TestHTTPCaching(t *testing.T) {
+	handler := HTTPCaching(CachePolicy{MaxAge: time.Minute})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", "Mon, 25 May 2020 17:06:40 GMT")
+		w.Write([]byte(`{"id":1}`))
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products/1", nil))
+	etag := w.Header().Get("ETag")
+
+	tests := map[string]struct {
+		header         map[string]string
+		expectedStatus int
+	}{
+		"Unconditional request":             {expectedStatus: http.StatusOK},
+		"Matching If-None-Match":            {header: map[string]string{"If-None-Match": etag}, expectedStatus: http.StatusNotModified},
+		"Matching weak If-None-Match":       {header: map[string]string{"If-None-Match": `"other", W/` + etag}, expectedStatus: http.StatusNotModified},
+		"Not matching If-None-Match":        {header: map[string]string{"If-None-Match": `"other"`}, expectedStatus: http.StatusOK},
+		"Not modified since":                {header: map[string]string{"If-Modified-Since": "Mon, 25 May 2020 17:06:40 GMT"}, expectedStatus: http.StatusNotModified},
+		"Modified since":                    {header: map[string]string{"If-Modified-Since": "Mon, 25 May 2020 17:06:39 GMT"}, expectedStatus: http.StatusOK},
+		"If-None-Match over modified since": {header: map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": "Mon, 25 May 2020 17:06:40 GMT"}, expectedStatus: http.StatusOK},
+	}
+
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Prepare
+			r := httptest.NewRequest(http.MethodGet, "/products/1", nil)
+			for name, value := range tc.header {
+				r.Header.Set(name, value)
+			}
+			w := httptest.NewRecorder()
+
+			//Act
+			handler.ServeHTTP(w, r)
+
+			//Assert
+			if w.Code != tc.expectedStatus {
+				t.Errorf("Expected status %d but got %d", tc.expectedStatus, w.Code)
+			}
+			if w.Header().Get("ETag") != etag || w.Header().Get("Cache-Control") != "public, max-age=60" {
+				t.Errorf("Unexpected ETag %s or Cache-Control %s", w.Header().Get("ETag"), w.Header().Get("Cache-Control"))
+			}
+			if tc.expectedStatus == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("Expected empty body but got %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHTTPCaching_WhenResponseFails_SkipsCachingHeaders(t *testing.T) {
+	handler := HTTPCaching(CachePolicy{MaxAge: time.Minute})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"code":"not_found"}`))
+	}))
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products/404", nil))
+
+	if w.Code != http.StatusNotFound || w.Body.String() != `{"code":"not_found"}` {
+		t.Errorf("Expected the failed response to pass through but got %d %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("ETag") != "" || w.Header().Get("Cache-Control") != "" {
+		t.Errorf("Expected no caching headers but got ETag %s and Cache-Control %s", w.Header().Get("ETag"), w.Header().Get("Cache-Control"))
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	//Prepare
+	logger, hook := test.NewNullLogger()
+	previousOut, previousHooks := logrus.StandardLogger().Out, logrus.StandardLogger().Hooks
+	logrus.StandardLogger().Out = logger.Out
+	logrus.StandardLogger().Hooks = logger.Hooks
+	defer func() {
+		logrus.StandardLogger().Out = previousOut
+		logrus.StandardLogger().Hooks = previousHooks
+	}()
+	handler := AccessLog(AccessLogOptions{Headers: true, SensitiveHeaders: []string{"Authorization"}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logging.FromContext(r.Context()).Info("Handling request")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"code":"ENOTFOUND"}`))
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/products/1", nil)
+	req.SetBasicAuth("admin", "secret")
+
+	//Act
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	//Assert
+	entries := hook.AllEntries()
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 log entries but got %d", len(entries))
+	}
+	if entries[0].Data["request_id"] == nil || entries[0].Data["request_id"] != entries[1].Data["request_id"] {
+		t.Errorf("Expected both entries to carry the same request_id")
+	}
+	accessLog := entries[1]
+	if accessLog.Level != logrus.WarnLevel {
+		t.Errorf("Expected a client error to be logged as warning but got %s", accessLog.Level)
+	}
+	if accessLog.Data["status"] != http.StatusNotFound || accessLog.Data["bytes"] != 20 || accessLog.Data["user"] != "admin" {
+		t.Errorf("Unexpected access log fields %v", accessLog.Data)
+	}
+	if headers := accessLog.Data["headers"].(map[string]string); headers["Authorization"] != "[REDACTED]" {
+		t.Errorf("Expected the Authorization header to be redacted but got %s", headers["Authorization"])
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	//Prepare
+	limiter := &ratelimit.Limiter{
+		Store:  ratelimit.NewMemoryStore(),
+		Quotas: map[string]ratelimit.Quota{ratelimit.AnonymousRole: {Requests: 2, Period: time.Minute}},
+	}
+	handler := RateLimit(limiter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	tests := []struct {
+		expectedStatus     int
+		expectedRemaining  string
+		expectedRetryAfter string
+	}{
+		{expectedStatus: http.StatusOK, expectedRemaining: "1"},
+		{expectedStatus: http.StatusOK, expectedRemaining: "0"},
+		{expectedStatus: http.StatusTooManyRequests, expectedRemaining: "0", expectedRetryAfter: "30"},
+	}
+	for i, tc := range tests {
+		w := httptest.NewRecorder()
+
+		//Act
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products", nil))
+
+		//Assert
+		if w.Code != tc.expectedStatus {
+			t.Errorf("Expected request %d to get status %d but got %d", i+1, tc.expectedStatus, w.Code)
+		}
+		if w.Header().Get("RateLimit-Limit") != "2" || w.Header().Get("RateLimit-Policy") != "2;w=60" {
+			t.Errorf("Unexpected rate limit headers %v", w.Header())
+		}
+		if w.Header().Get("RateLimit-Remaining") != tc.expectedRemaining || w.Header().Get("Retry-After") != tc.expectedRetryAfter {
+			t.Errorf("Expected remaining %s and Retry-After %q but got %v", tc.expectedRemaining, tc.expectedRetryAfter, w.Header())
+		}
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	handler := BodyLimit(BodyLimits{MaxBytes: 10, MaxMultipartBytes: 100})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := ioutil.ReadAll(r.Body); err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	tests := map[string]struct {
+		body           string
+		contentType    string
+		chunked        bool
+		expectedStatus int
+	}{
+		"Body within limit":           {body: `{"id":1}`, contentType: "application/json", expectedStatus: http.StatusOK},
+		"Declared body over limit":    {body: `{"title":"Mouse"}`, contentType: "application/json", expectedStatus: http.StatusRequestEntityTooLarge},
+		"Chunked body over limit":     {body: `{"title":"Mouse"}`, contentType: "application/json", chunked: true, expectedStatus: http.StatusRequestEntityTooLarge},
+		"Multipart body within limit": {body: strings.Repeat("a", 50), contentType: "multipart/form-data; boundary=x", expectedStatus: http.StatusOK},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(tc.body))
+			r.Header.Set("Content-Type", tc.contentType)
+			if tc.chunked {
+				r.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+
+			//Act
+			handler.ServeHTTP(w, r)
+
+			//Assert
+			if w.Code != tc.expectedStatus {
+				t.Errorf("Expected status %d but got %d", tc.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestIdempotency(t *testing.T) {
+	//Prepare
+	keys := &idempotency.Keys{Store: idempotency.NewMemoryStore(), Header: idempotency.DefaultHeader, TTL: time.Hour, LockTTL: time.Minute}
+	created := 0
+	handler := Idempotency(keys)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		created++
+		w.Header().Set("ETag", `"`+strconv.Itoa(created)+`"`)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":` + strconv.Itoa(created) + `,"request":` + string(body) + `}`))
+	}))
+	tests := []struct {
+		name             string
+		method           string
+		key              string
+		body             string
+		expectedStatus   int
+		expectedBody     string
+		expectedReplayed bool
+	}{
+		{name: "First request", method: http.MethodPost, key: "k1", body: `{"title":"Mouse"}`, expectedStatus: http.StatusCreated,
+			expectedBody: `{"id":1,"request":{"title":"Mouse"}}`},
+		{name: "Retry", method: http.MethodPost, key: "k1", body: `{"title":"Mouse"}`, expectedStatus: http.StatusCreated,
+			expectedBody: `{"id":1,"request":{"title":"Mouse"}}`, expectedReplayed: true},
+		{name: "Reused key", method: http.MethodPost, key: "k1", body: `{"title":"Keyboard"}`, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "New key", method: http.MethodPost, key: "k2", body: `{"title":"Mouse"}`, expectedStatus: http.StatusCreated,
+			expectedBody: `{"id":2,"request":{"title":"Mouse"}}`},
+		{name: "Without key", method: http.MethodPost, body: `{"title":"Mouse"}`, expectedStatus: http.StatusCreated,
+			expectedBody: `{"id":3,"request":{"title":"Mouse"}}`},
+		{name: "Method without idempotency keys", method: http.MethodPut, key: "k2", body: `{"title":"Mouse"}`, expectedStatus: http.StatusCreated,
+			expectedBody: `{"id":4,"request":{"title":"Mouse"}}`},
+		{name: "Key too long", method: http.MethodPost, key: strings.Repeat("k", 256), body: `{}`, expectedStatus: http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		r := httptest.NewRequest(tc.method, "/products", strings.NewReader(tc.body))
+		if tc.key != "" {
+			r.Header.Set(idempotency.DefaultHeader, tc.key)
+		}
+		w := httptest.NewRecorder()
+
+		//Act
+		handler.ServeHTTP(w, r)
+
+		//Assert
+		if w.Code != tc.expectedStatus {
+			t.Errorf("%s: expected status %d but got %d", tc.name, tc.expectedStatus, w.Code)
+		}
+		if tc.expectedBody != "" && w.Body.String() != tc.expectedBody {
+			t.Errorf("%s: expected body %s but got %s", tc.name, tc.expectedBody, w.Body.String())
+		}
+		if replayed := w.Header().Get("Idempotent-Replayed") == "true"; replayed != tc.expectedReplayed {
+			t.Errorf("%s: expected replayed %v but got %v", tc.name, tc.expectedReplayed, replayed)
+		}
+		if tc.expectedReplayed && w.Header().Get("ETag") != `"1"` {
+			t.Errorf("%s: expected the headers of the first response but got %v", tc.name, w.Header())
+		}
+	}
+}
+
+func TestSpooledBody(t *testing.T) {
+	tests := map[string]struct {
+		chunks       []string
+		expectedFile bool
+	}{
+		"Small body": {chunks: []string{"ab", "cd"}, expectedFile: false},
+		"Large body": {chunks: []string{"ab", "cd", "ef"}, expectedFile: true},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Prepare
+			body := &spooledBody{maxMemory: 4}
+
+			//Act
+			for _, chunk := range tc.chunks {
+				if _, err := body.Write([]byte(chunk)); err != nil {
+					t.Fatalf("Expected no error but got %s", err.Error())
+				}
+			}
+			reader, err := body.Reader()
+
+			//Assert
+			if err != nil {
+				t.Fatalf("Expected no error but got %s", err.Error())
+			}
+			if content, _ := ioutil.ReadAll(reader); string(content) != strings.Join(tc.chunks, "") {
+				t.Errorf("Expected the body %q but got %q", strings.Join(tc.chunks, ""), content)
+			}
+			if (body.file != nil) != tc.expectedFile {
+				t.Fatalf("Expected a temporary file %v but got %v", tc.expectedFile, body.file != nil)
+			}
+			body.Close()
+			if body.file != nil {
+				if _, err := os.Stat(body.file.Name()); !os.IsNotExist(err) {
+					t.Errorf("Expected the temporary file to be removed")
+				}
+			}
+		})
+	}
+}
+
+func TestErrorFormat(t *testing.T) {
+	tests := map[string]struct {
+		accept          string
+		expectedProblem bool
+	}{
+		"Problem JSON":                 {accept: dtos.ProblemJSON, expectedProblem: true},
+		"Problem JSON preferred":       {accept: "application/json;q=0.5, application/problem+json", expectedProblem: true},
+		"Problem JSON excluded":        {accept: "application/problem+json;q=0, */*"},
+		"JSON preferred":               {accept: "application/problem+json;q=0.5, application/json"},
+		"Any media type":               {accept: "*/*"},
+		"Without Accept":               {accept: ""},
+		"Problem JSON among the types": {accept: "application/xml, application/problem+json", expectedProblem: true},
+	}
+	handler := ErrorFormat(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dtos.ERROR(w, r.Context(), &app.Error{Code: app.EPRODUCTNOTFOUND, Message: "Product not found."})
+	}))
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Prepare
+			r := httptest.NewRequest(http.MethodGet, "/products/1", nil)
+			r.Header.Set("Accept", tc.accept)
+			w := httptest.NewRecorder()
+
+			//Act
+			handler.ServeHTTP(w, r)
+
+			//Assert
+			if problem := w.Header().Get("Content-Type") == dtos.ProblemJSON; problem != tc.expectedProblem {
+				t.Errorf("Expected a problem error %v but got %v", tc.expectedProblem, problem)
+			}
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	handler := Negotiate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dtos.JSON(w, http.StatusOK, []map[string]int{{"id": 1}})
+	}))
+	tests := map[string]struct {
+		accept              string
+		expectedStatus      int
+		expectedContentType string
+	}{
+		"JSON":          {accept: "application/json", expectedStatus: http.StatusOK, expectedContentType: dtos.MediaTypeJSON},
+		"XML":           {accept: "application/xml", expectedStatus: http.StatusOK, expectedContentType: dtos.MediaTypeXML},
+		"CSV over JSON": {accept: "application/json;q=0.8, text/csv", expectedStatus: http.StatusOK, expectedContentType: dtos.MediaTypeCSV},
+		"Problem JSON":  {accept: dtos.ProblemJSON, expectedStatus: http.StatusOK, expectedContentType: dtos.MediaTypeJSON},
+		"Unsupported":   {accept: "image/png", expectedStatus: http.StatusNotAcceptable, expectedContentType: dtos.MediaTypeJSON},
+		"Problem JSON excluded": {accept: "image/png, application/problem+json;q=0", expectedStatus: http.StatusNotAcceptable,
+			expectedContentType: dtos.MediaTypeJSON},
+		"Without Accept": {accept: "", expectedStatus: http.StatusOK, expectedContentType: dtos.MediaTypeJSON},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/products", nil)
+			r.Header.Set("Accept", tc.accept)
+			w := httptest.NewRecorder()
+
+			//Act
+			handler.ServeHTTP(w, r)
+
+			//Assert
+			if w.Code != tc.expectedStatus {
+				t.Errorf("Expected status %d but got %d", tc.expectedStatus, w.Code)
+			}
+			if w.Header().Get("Content-Type") != tc.expectedContentType {
+				t.Errorf("Expected Content-Type %s but got %s", tc.expectedContentType, w.Header().Get("Content-Type"))
+			}
+			if w.Header().Get("Vary") != "Accept" {
+				t.Errorf("Expected the response to vary by Accept but got %q", w.Header().Get("Vary"))
+			}
+		})
+	}
+}
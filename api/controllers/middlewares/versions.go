@@ -0,0 +1,71 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/controllers/dtos"
+)
+
+// Deprecation describes the deprecation of an API version
+type Deprecation struct {
+	// Date since the version is deprecated. The version is deprecated without a date when zero.
+	Date time.Time
+	// Sunset is the date after which the version may no longer be served. The Sunset header is omitted when zero.
+	Sunset time.Time
+	// Link is the URL of the documentation of the deprecation, e.g. a migration guide
+	Link string
+}
+
+// VersionOptions configures the versions of the API
+type VersionOptions struct {
+	// Default is the version of the unversioned routes for the requests without a vendor media type
+	Default int
+	// Deprecations holds the deprecation of each deprecated version
+	Deprecations map[int]Deprecation
+}
+
+// APIVersion sets the API version of the requests of the routes of a version. The unversioned routes (version 0)
+// serve the version of the negotiated vendor media type, e.g. application/vnd.prods.v2+json, or the default
+// version. The responses of deprecated versions carry the Deprecation, Sunset and Link headers.
+func APIVersion(routeVersion int, options VersionOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			version := routeVersion
+			mediaTypeVersion, ok := dtos.VersionOfMediaType(w.Header().Get("Content-Type"))
+			switch {
+			case ok && version != 0 && mediaTypeVersion != version:
+				w.Header().Set("Content-Type", dtos.MediaTypeJSON)
+				msg := fmt.Sprintf("Not acceptable: the route serves version %d of the API but version %d was requested.", version, mediaTypeVersion)
+				dtos.ERROR(w, r.Context(), &app.Error{Op: "APIVersion", Code: app.ENOTACCEPTED, Message: msg})
+				return
+			case ok:
+				version = mediaTypeVersion
+			case version == 0:
+				version = options.Default
+			}
+			if deprecation, ok := options.Deprecations[version]; ok {
+				setDeprecationHeaders(w.Header(), deprecation)
+			}
+			next.ServeHTTP(w, r.WithContext(dtos.WithVersion(r.Context(), version)))
+		})
+	}
+}
+
+func setDeprecationHeaders(header http.Header, deprecation Deprecation) {
+	if deprecation.Date.IsZero() {
+		header.Set("Deprecation", "true")
+	} else {
+		// RFC 9745 structured date
+		header.Set("Deprecation", "@"+strconv.FormatInt(deprecation.Date.Unix(), 10))
+	}
+	if !deprecation.Sunset.IsZero() {
+		header.Set("Sunset", deprecation.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if deprecation.Link != "" {
+		header.Add("Link", "<"+deprecation.Link+`>; rel="deprecation"; type="text/html"`)
+	}
+}
@@ -0,0 +1,88 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mzampetakis/prods-api/api/controllers/dtos"
+)
+
+func TestAPIVersion(t *testing.T) {
+	deprecated := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC)
+	deprecation := "@" + strconv.FormatInt(deprecated.Unix(), 10)
+	options := VersionOptions{
+		Default:      dtos.V1,
+		Deprecations: map[int]Deprecation{dtos.V1: {Date: deprecated, Sunset: sunset, Link: "https://example.com/migrate"}},
+	}
+	tests := map[string]struct {
+		routeVersion        int
+		accept              string
+		expectedStatus      int
+		expectedVersion     int
+		expectedDeprecation string
+	}{
+		"Unversioned route":      {routeVersion: 0, expectedStatus: http.StatusOK, expectedVersion: dtos.V1, expectedDeprecation: deprecation},
+		"Unversioned v2 request": {routeVersion: 0, accept: dtos.MediaTypeV2, expectedStatus: http.StatusOK, expectedVersion: dtos.V2},
+		"Unversioned v1 request": {routeVersion: 0, accept: dtos.MediaTypeV1, expectedStatus: http.StatusOK, expectedVersion: dtos.V1, expectedDeprecation: deprecation},
+		"v2 route":               {routeVersion: dtos.V2, accept: "application/json", expectedStatus: http.StatusOK, expectedVersion: dtos.V2},
+		"v2 route as XML":        {routeVersion: dtos.V2, accept: "application/xml", expectedStatus: http.StatusOK, expectedVersion: dtos.V2},
+		"v1 route":               {routeVersion: dtos.V1, expectedStatus: http.StatusOK, expectedVersion: dtos.V1, expectedDeprecation: deprecation},
+		"Other version of route": {routeVersion: dtos.V1, accept: dtos.MediaTypeV2, expectedStatus: http.StatusNotAcceptable},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Prepare
+			version := 0
+			handler := Negotiate(APIVersion(tc.routeVersion, options)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				version = dtos.VersionFromContext(r.Context())
+			})))
+			r := httptest.NewRequest(http.MethodGet, "/api/products", nil)
+			r.Header.Set("Accept", tc.accept)
+			w := httptest.NewRecorder()
+
+			//Act
+			handler.ServeHTTP(w, r)
+
+			//Assert
+			if w.Code != tc.expectedStatus {
+				t.Fatalf("Expected status %d but got %d", tc.expectedStatus, w.Code)
+			}
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+			if version != tc.expectedVersion {
+				t.Errorf("Expected version %d but got %d", tc.expectedVersion, version)
+			}
+			if deprecation := w.Header().Get("Deprecation"); deprecation != tc.expectedDeprecation {
+				t.Errorf("Expected Deprecation %q but got %q", tc.expectedDeprecation, deprecation)
+			}
+			if tc.expectedDeprecation == "" {
+				return
+			}
+			if w.Header().Get("Sunset") != "Wed, 30 Jun 2027 00:00:00 GMT" {
+				t.Errorf("Expected the Sunset header but got %q", w.Header().Get("Sunset"))
+			}
+			if link := w.Header().Get("Link"); link != `<https://example.com/migrate>; rel="deprecation"; type="text/html"` {
+				t.Errorf("Expected the deprecation Link but got %q", link)
+			}
+		})
+	}
+}
+
+func TestAPIVersion_DeprecatedWithoutDate(t *testing.T) {
+	//Prepare
+	handler := APIVersion(0, VersionOptions{Default: dtos.V1, Deprecations: map[int]Deprecation{dtos.V1: {}}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	w := httptest.NewRecorder()
+
+	//Act
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/products", nil))
+
+	//Assert
+	if w.Header().Get("Deprecation") != "true" || w.Header().Get("Sunset") != "" || w.Header().Get("Link") != "" {
+		t.Errorf("Expected only Deprecation: true but got %v", w.Header())
+	}
+}
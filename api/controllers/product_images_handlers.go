@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/controllers/dtos"
+	"github.com/mzampetakis/prods-api/api/logging"
+	"github.com/mzampetakis/prods-api/api/repositories"
+)
+
+// maxMultipartMemory is the part of a multipart upload kept in memory. The rest is stored in temporary files.
+const maxMultipartMemory = 32 << 20
+
+// GetProductImages godoc
+// Id GetProductImages
+// @Summary Retrieves Product's Images
+// @Description Retrieve the ordered images of a Product alongside with their thumbnails
+// @Tags Product Images
+// @Produce json,application/xml,application/msgpack
+// @Param product_id path integer true "Product ID to retrieve images for"
+// @Success 200 {object} dtos.ProductImagesResponseDto
+// @Failure 400 {object} dtos.ServeError
+// @Failure 404 {object} dtos.ServeError
+// @Failure 500 {object} dtos.ServeError
+// @Router /products/{product_id}/images [get]
+func (h *Handler) GetProductImages(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	productID, err := strconv.ParseInt(params["productID"], 10, 64)
+	if err != nil {
+		logging.FromContext(r.Context()).Warn(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.GetProductImages", Code: app.EINVALIDID, Err: err})
+		return
+	}
+	images, err := h.AppServices.GetProductImages(r.Context(), productID)
+	if err != nil {
+		logging.FromContext(r.Context()).Warn(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.GetProductImages", Err: err})
+		return
+	}
+	dtos.JSON(w, http.StatusOK, dtos.ConvertProductImagesResponseModelToDto(images))
+}
+
+// UploadProductImages godoc
+// Id UploadProductImages
+// @Summary Uploads Product's Images
+// @Description Upload one or more JPEG, PNG or GIF images of a Product. Thumbnails are generated for each image.
+// @Tags Product Images
+// @Accept mpfd
+// @Produce json,application/xml,application/msgpack
+// @Param product_id path integer true "Product ID to upload images for"
+// @Param images formData file true "Image files to upload"
+// @Param Idempotency-Key header string false "Unique key of the request, whose retries get its stored response"
+// @Success 201 {object} dtos.ProductImagesResponseDto
+// @Failure 400 {object} dtos.ServeError
+// @Failure 404 {object} dtos.ServeError
+// @Failure 409 {object} dtos.ServeError
+// @Failure 413 {object} dtos.ServeError
+// @Failure 415 {object} dtos.ServeError
+// @Failure 422 {object} dtos.ServeError
+// @Failure 500 {object} dtos.ServeError
+// @Router /products/{product_id}/images [post]
+func (h *Handler) UploadProductImages(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	productID, err := strconv.ParseInt(params["productID"], 10, 64)
+	if err != nil {
+		logging.FromContext(r.Context()).Warn(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.UploadProductImages", Code: app.EINVALIDID, Err: err})
+		return
+	}
+	err = r.ParseMultipartForm(maxMultipartMemory)
+	if err != nil && isBodyTooLarge(err) {
+		logging.FromContext(r.Context()).Warn(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.UploadProductImages", Code: app.EBODYTOOLARGE, Err: err, Message: "Request body too large."})
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Warn(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.UploadProductImages", Code: app.EINVALID, Err: err, Message: "Invalid multipart form data."})
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+	uploads := make([]repositories.ProductImageUploadModel, 0)
+	for _, fileHeader := range r.MultipartForm.File["images"] {
+		file, err := fileHeader.Open()
+		if err != nil {
+			logging.FromContext(r.Context()).Error(err.Error())
+			dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.UploadProductImages", Code: app.EINVALID, Err: err, Message: "Could not read image " + fileHeader.Filename})
+			return
+		}
+		defer file.Close()
+		uploads = append(uploads, repositories.ProductImageUploadModel{Filename: fileHeader.Filename, Data: file})
+	}
+	images, err := h.AppServices.UploadProductImages(r.Context(), productID, uploads)
+	if err != nil {
+		logging.FromContext(r.Context()).Error(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.UploadProductImages", Err: err})
+		return
+	}
+	dtos.JSON(w, http.StatusCreated, dtos.ConvertProductImagesResponseModelToDto(images))
+}
+
+// UpdateProductImagesOrder godoc
+// Id UpdateProductImagesOrder
+// @Summary Orders Product's Images
+// @Description Set the order of a Product's images. All the images of the Product should be provided.
+// @Tags Product Images
+// @Produce json,application/xml,application/msgpack
+// @Param product_id path integer true "Product ID to order images for"
+// @Param images_order body dtos.ProductImagesOrderUpdateRequestDto true "Images' IDs in the requested order"
+// @Success 204
+// @Failure 400 {object} dtos.ServeError
+// @Failure 404 {object} dtos.ServeError
+// @Failure 413 {object} dtos.ServeError
+// @Failure 415 {object} dtos.ServeError
+// @Failure 500 {object} dtos.ServeError
+// @Router /products/{product_id}/images/order [put]
+func (h *Handler) UpdateProductImagesOrder(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	productID, err := strconv.ParseInt(params["productID"], 10, 64)
+	if err != nil {
+		logging.FromContext(r.Context()).Warn(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.UpdateProductImagesOrder", Code: app.EINVALIDID, Err: err})
+		return
+	}
+	var imagesOrder dtos.ProductImagesOrderUpdateRequestDto
+	err = decodeRequest(r, &imagesOrder)
+	if err != nil {
+		logging.FromContext(r.Context()).Error(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.UpdateProductImagesOrder", Err: err})
+		return
+	}
+	err = h.AppServices.UpdateProductImagesOrder(r.Context(), productID, dtos.ConvertProductImagesOrderUpdateRequestDtoToModel(imagesOrder))
+	if err != nil {
+		logging.FromContext(r.Context()).Error(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.UpdateProductImagesOrder", Err: err})
+		return
+	}
+	dtos.JSON(w, http.StatusNoContent, nil)
+}
+
+// DeleteProductImage godoc
+// Id DeleteProductImage
+// @Summary Deletes a Product's Image
+// @Description Delete a Product's image alongside with its thumbnails
+// @Tags Product Images
+// @Produce json,application/xml,application/msgpack
+// @Param product_id path integer true "Product ID of the image"
+// @Param image_id path integer true "Image ID to delete"
+// @Success 204
+// @Failure 404 {object} dtos.ServeError
+// @Failure 500 {object} dtos.ServeError
+// @Router /products/{product_id}/images/{image_id} [delete]
+func (h *Handler) DeleteProductImage(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	productID, err := strconv.ParseInt(params["productID"], 10, 64)
+	if err != nil {
+		logging.FromContext(r.Context()).Warn(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.DeleteProductImage", Code: app.EINVALIDID, Err: err})
+		return
+	}
+	imageID, err := strconv.ParseInt(params["imageID"], 10, 64)
+	if err != nil {
+		logging.FromContext(r.Context()).Warn(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.DeleteProductImage", Code: app.EINVALIDID, Err: err})
+		return
+	}
+	err = h.AppServices.DeleteProductImage(r.Context(), productID, imageID)
+	if err != nil {
+		logging.FromContext(r.Context()).Error(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.DeleteProductImage", Err: err})
+		return
+	}
+	dtos.JSON(w, http.StatusNoContent, nil)
+}
+
+// ServeMedia serves the stored media. Media keys are unique so they are cached as immutable.
+func (h *Handler) ServeMedia(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, dtos.MediaPath)
+	blob, err := h.AppServices.GetMedia(r.Context(), key)
+	if err != nil {
+		logging.FromContext(r.Context()).Warn(err.Error())
+		w.Header().Set("Content-Type", "application/json")
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.ServeMedia", Err: err})
+		return
+	}
+	defer blob.Close()
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	if blob.ContentType != "" {
+		w.Header().Set("Content-Type", blob.ContentType)
+	}
+	if content, ok := blob.ReadCloser.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, key, blob.ModTime, content)
+		return
+	}
+	if !blob.ModTime.IsZero() {
+		w.Header().Set("Last-Modified", blob.ModTime.UTC().Format(http.TimeFormat))
+	}
+	if blob.Size > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(blob.Size, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		io.Copy(w, blob)
+	}
+}
@@ -1,7 +1,6 @@
 package controllers
 
 import (
-	"encoding/json"
 	"net/http"
 	"strconv"
 
@@ -9,7 +8,7 @@ import (
 	"github.com/gorilla/schema"
 	"github.com/mzampetakis/prods-api/api/app"
 	"github.com/mzampetakis/prods-api/api/controllers/dtos"
-	"github.com/sirupsen/logrus"
+	"github.com/mzampetakis/prods-api/api/logging"
 )
 
 // GetAllProducts godoc
@@ -17,26 +16,42 @@ import (
 // @Summary Retrive Products - uses filtering
 // @Description Retrieve a list of products
 // @Tags Products
-// @Produce json
+// @Produce json,application/xml,application/msgpack,text/csv
 // @Param offset query integer false "Offset of the results"
 // @Param limit query integer false "Limit the results"
 // @Param sortby query string false "Sort by of the results"
 // @Param sortdirection query string false "Sort direction of the results (ASC|DESC)"
+// @Param created_from query string false "Earliest creation date-time or date (RFC 3339)"
+// @Param created_to query string false "Latest creation date-time or date (RFC 3339)"
+// @Param updated_from query string false "Earliest update date-time or date (RFC 3339)"
+// @Param updated_to query string false "Latest update date-time or date (RFC 3339)"
+// @Param fields query string false "Comma separated fields to return (id is always returned)"
+// @Param expand query string false "Comma separated relationships to embed (category)"
+// @Param locale query string false "Locale of the titles and descriptions, preferred over the Accept-Language header"
+// @Param Accept-Language header string false "Preferred locales of the titles and descriptions"
 // @Success 200 {object} dtos.ProductsResponseDto
 // @Failure 400 {object} dtos.ServeError
 // @Failure 500 {object} dtos.ServeError
 // @Router /products [get]
 func (h *Handler) GetAllProducts(w http.ResponseWriter, r *http.Request) {
 	filter := new(app.Filter)
+	selection := new(app.Selection)
 	r.ParseForm()
 	schema.NewDecoder().Decode(filter, r.Form)
-	products, err := h.AppServices.GetProducts(r.Context(), *filter)
+	schema.NewDecoder().Decode(selection, r.Form)
+	if err := h.localize(w, r, selection); err != nil {
+		logging.FromContext(r.Context()).Warn(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.GetAllProducts", Err: err})
+		return
+	}
+	products, err := h.AppServices.GetProducts(r.Context(), *filter, *selection)
 	if err != nil {
-		logrus.Warn(err.Error())
+		logging.FromContext(r.Context()).Warn(err.Error())
 		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.GetAllProducts", Err: err})
 		return
 	}
-	dtos.JSON(w, http.StatusOK, dtos.ConvertProductsResponseModelToDto(products))
+	dtos.ContentLanguage(w, h.Locales.Default, dtos.ProductsContentLocales(products...)...)
+	dtos.JSON(w, http.StatusOK, h.productsResponse(r.Context(), products, *filter, *selection))
 }
 
 // GetProduct godoc
@@ -44,8 +59,12 @@ func (h *Handler) GetAllProducts(w http.ResponseWriter, r *http.Request) {
 // @Summary Retrives single Product
 // @Description Retrieve a Product
 // @Tags Products
-// @Produce json
+// @Produce json,application/xml,application/msgpack
 // @Param product_id path integer true "Product ID to retrieve"
+// @Param fields query string false "Comma separated fields to return (id is always returned)"
+// @Param expand query string false "Comma separated relationships to embed (category)"
+// @Param locale query string false "Locale of the titles and descriptions, preferred over the Accept-Language header"
+// @Param Accept-Language header string false "Preferred locales of the titles and descriptions"
 // @Success 200 {object} dtos.ProductResponseDto
 // @Failure 400 {object} dtos.ServeError
 // @Failure 404 {object} dtos.ServeError
@@ -55,17 +74,27 @@ func (h *Handler) GetProduct(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	productID, err := strconv.ParseInt(params["productID"], 10, 64)
 	if err != nil {
-		logrus.Warn(err.Error())
-		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.GetProduct", Err: err, Code: app.EINVALID})
+		logging.FromContext(r.Context()).Warn(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.GetProduct", Code: app.EINVALIDID, Err: err})
 		return
 	}
-	product, err := h.AppServices.GetProduct(r.Context(), productID)
+	selection := new(app.Selection)
+	r.ParseForm()
+	schema.NewDecoder().Decode(selection, r.Form)
+	if err = h.localize(w, r, selection); err != nil {
+		logging.FromContext(r.Context()).Warn(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.GetProduct", Err: err})
+		return
+	}
+	product, err := h.AppServices.GetProduct(r.Context(), productID, *selection)
 	if err != nil {
-		logrus.Warn(err.Error())
+		logging.FromContext(r.Context()).Warn(err.Error())
 		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.GetProduct", Err: err})
 		return
 	}
-	dtos.JSON(w, http.StatusOK, dtos.ConvertProductResponseModelToDto(*product))
+	dtos.LastModified(w, dtos.ProductTimestamps(product)...)
+	dtos.ContentLanguage(w, h.Locales.Default, dtos.ProductsContentLocales(product)...)
+	dtos.JSON(w, http.StatusOK, h.productResponse(r.Context(), *product, *selection))
 
 }
 
@@ -74,23 +103,27 @@ func (h *Handler) GetProduct(w http.ResponseWriter, r *http.Request) {
 // @Summary Creates a Product
 // @Description Create a new Product
 // @Tags Products
-// @Produce json
+// @Produce json,application/xml,application/msgpack
 // @Param product body dtos.ProductRequestDto true "Product's data to create"
+// @Param Idempotency-Key header string false "Unique key of the request, whose retries get its stored response"
 // @Success 201 {object} dtos.CreateProductResponseDto
 // @Failure 400 {object} dtos.ServeError
+// @Failure 409 {object} dtos.ServeError
+// @Failure 413 {object} dtos.ServeError
+// @Failure 415 {object} dtos.ServeError
+// @Failure 422 {object} dtos.ServeError
 // @Failure 500 {object} dtos.ServeError
 // @Router /products [post]
 func (h *Handler) CreateProduct(w http.ResponseWriter, r *http.Request) {
-	var newProduct dtos.ProductRequestDto
-	err := json.NewDecoder(r.Body).Decode(&newProduct)
+	newProduct, err := h.decodeProductRequest(r)
 	if err != nil {
-		logrus.Errorf(err.Error())
-		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.CreateProduct", Code: app.EINVALID, Err: err, Message: "Data validation error."})
+		logging.FromContext(r.Context()).Error(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.CreateProduct", Err: err})
 		return
 	}
-	insertedID, err := h.AppServices.CreateProduct(r.Context(), dtos.ConvertProductRequestDtoToModel(newProduct))
+	insertedID, err := h.AppServices.CreateProduct(r.Context(), newProduct)
 	if err != nil {
-		logrus.Errorf(err.Error())
+		logging.FromContext(r.Context()).Error(err.Error())
 		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.CreateBook", Err: err})
 		return
 	}
@@ -102,31 +135,32 @@ func (h *Handler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 // @Summary Updates a Product
 // @Description Update a Product
 // @Tags Products
-// @Produce json
+// @Produce json,application/xml,application/msgpack
 // @Param product_id path integer true "Product ID to update"
 // @Param product body dtos.ProductRequestDto true "Product's data to update"
 // @Success 204
 // @Failure 400 {object} dtos.ServeError
+// @Failure 413 {object} dtos.ServeError
+// @Failure 415 {object} dtos.ServeError
 // @Failure 500 {object} dtos.ServeError
 // @Router /products/{product_id} [put]
 func (h *Handler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	productID, err := strconv.ParseInt(params["productID"], 10, 64)
 	if err != nil {
-		logrus.Warn(err.Error())
-		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.UpdateProduct", Code: app.EINVALID, Err: err})
+		logging.FromContext(r.Context()).Warn(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.UpdateProduct", Code: app.EINVALIDID, Err: err})
 		return
 	}
-	var updateProduct dtos.ProductRequestDto
-	err = json.NewDecoder(r.Body).Decode(&updateProduct)
+	updateProduct, err := h.decodeProductRequest(r)
 	if err != nil {
-		logrus.Errorf(err.Error())
-		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.UpdateProduct", Code: app.EINVALID, Err: err})
+		logging.FromContext(r.Context()).Error(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.UpdateProduct", Err: err})
 		return
 	}
-	err = h.AppServices.UpdateProduct(r.Context(), productID, dtos.ConvertProductRequestDtoToModel(updateProduct))
+	err = h.AppServices.UpdateProduct(r.Context(), productID, updateProduct)
 	if err != nil {
-		logrus.Errorf(err.Error())
+		logging.FromContext(r.Context()).Error(err.Error())
 		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.UpdateProduct", Err: err})
 		return
 	}
@@ -138,7 +172,7 @@ func (h *Handler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 // @Summary Deletes a Product
 // @Description Deletes a Product
 // @Tags Products
-// @Produce json
+// @Produce json,application/xml,application/msgpack
 // @Param product_id path integer true "Product ID to delete"
 // @Success 204
 // @Failure 500 {object} dtos.ServeError
@@ -147,14 +181,14 @@ func (h *Handler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	productID, err := strconv.ParseInt(params["productID"], 10, 64)
 	if err != nil {
-		logrus.Warn(err.Error())
-		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.DeleteProduct", Code: app.EINVALID, Err: err})
+		logging.FromContext(r.Context()).Warn(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.DeleteProduct", Code: app.EINVALIDID, Err: err})
 		return
 	}
 
 	err = h.AppServices.DeleteProduct(r.Context(), productID)
 	if err != nil {
-		logrus.Errorf(err.Error())
+		logging.FromContext(r.Context()).Error(err.Error())
 		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.DeleteProduct", Err: err})
 		return
 	}
@@ -166,32 +200,34 @@ func (h *Handler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 // @Summary Assing Products to a category
 // @Description Assing Products to a category
 // @Tags Products
-// @Produce json
+// @Produce json,application/xml,application/msgpack
 // @Param category_id path integer true "Category ID to assign products to"
 // @Param products_category body dtos.ProductsCategoryUpdateRequestDto true "Products' ID to assign to the category"
 // @Success 204
 // @Failure 400 {object} dtos.ServeError
 // @Failure 404 {object} dtos.ServeError
+// @Failure 413 {object} dtos.ServeError
+// @Failure 415 {object} dtos.ServeError
 // @Failure 500 {object} dtos.ServeError
 // @Router /products/category/{category_id} [put]
 func (h *Handler) AssignProductsToCategory(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	categoryID, err := strconv.ParseInt(params["categoryID"], 10, 64)
 	if err != nil {
-		logrus.Warn(err.Error())
-		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.AssignProductsToCategory", Code: app.EINVALID, Err: err})
+		logging.FromContext(r.Context()).Warn(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.AssignProductsToCategory", Code: app.EINVALIDID, Err: err})
 		return
 	}
 	var productsCategoryUpdate dtos.ProductsCategoryUpdateRequestDto
-	err = json.NewDecoder(r.Body).Decode(&productsCategoryUpdate)
+	err = decodeRequest(r, &productsCategoryUpdate)
 	if err != nil {
-		logrus.Errorf(err.Error())
-		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.AssignProductsToCategory", Code: app.EINVALID, Err: err})
+		logging.FromContext(r.Context()).Error(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.AssignProductsToCategory", Err: err})
 		return
 	}
 	err = h.AppServices.AssignProductsToCategory(r.Context(), categoryID, dtos.ConvertProductsCategoryUpdateRequestDtoToModel(productsCategoryUpdate))
 	if err != nil {
-		logrus.Errorf(err.Error())
+		logging.FromContext(r.Context()).Error(err.Error())
 		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.AssignProductsToCategory", Err: err})
 		return
 	}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/controllers/dtos"
+	"github.com/mzampetakis/prods-api/api/logging"
+)
+
+// GetBrokenImagesReport godoc
+// Id GetBrokenImagesReport
+// @Summary Retrieves broken image URLs
+// @Description Retrieve the image URLs of Products and Categories that were found broken by the latest link check
+// @Tags Reports
+// @Produce json,application/xml,application/msgpack
+// @Success 200 {object} dtos.BrokenImagesResponseDto
+// @Failure 500 {object} dtos.ServeError
+// @Router /reports/broken-images [get]
+func (h *Handler) GetBrokenImagesReport(w http.ResponseWriter, r *http.Request) {
+	brokenImages, err := h.AppServices.GetBrokenImages(r.Context())
+	if err != nil {
+		logging.FromContext(r.Context()).Warn(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.GetBrokenImagesReport", Err: err})
+		return
+	}
+	dtos.JSON(w, http.StatusOK, dtos.ConvertBrokenImagesResponseModelToDto(brokenImages))
+}
+
+// GetMissingTranslationsReport godoc
+// Id GetMissingTranslationsReport
+// @Summary Retrieves missing translations
+// @Description Retrieve the fields of Products and Categories that are not translated to each supported locale other than the default one
+// @Tags Reports
+// @Produce json,application/xml,application/msgpack
+// @Success 200 {object} dtos.MissingTranslationsResponseDto
+// @Failure 500 {object} dtos.ServeError
+// @Router /reports/missing-translations [get]
+func (h *Handler) GetMissingTranslationsReport(w http.ResponseWriter, r *http.Request) {
+	missing, err := h.AppServices.GetMissingTranslations(r.Context())
+	if err != nil {
+		logging.FromContext(r.Context()).Warn(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.GetMissingTranslationsReport", Err: err})
+		return
+	}
+	dtos.JSON(w, http.StatusOK, dtos.ConvertMissingTranslationsResponseModelToDto(missing))
+}
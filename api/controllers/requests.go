@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/controllers/dtos"
+	"github.com/mzampetakis/prods-api/api/repositories"
+	"github.com/mzampetakis/prods-api/api/validation"
+)
+
+// decodeRequest decodes the JSON body of the request into the provided DTO and validates it against its rules.
+// Bodies with fields that the DTO does not have or with data after the JSON value are rejected.
+func decodeRequest(r *http.Request, dto interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dto); err != nil {
+		return decodeError(err)
+	}
+	if err := decoder.Decode(&json.RawMessage{}); err != io.EOF {
+		if err != nil && isBodyTooLarge(err) {
+			return decodeError(err)
+		}
+		return &app.Error{Op: "handlers.decodeRequest", Code: app.EINVALIDJSON, Message: "Invalid JSON body: unexpected data after the JSON value."}
+	}
+	violations, err := validation.Validate(dto)
+	if err != nil {
+		return &app.Error{Op: "handlers.decodeRequest", Err: err}
+	}
+	if len(violations) > 0 {
+		return validation.Error("handlers.decodeRequest", violations)
+	}
+	return nil
+}
+
+// decodeCategoryRequest decodes the category of the request body. When the request violates the rules of its DTO,
+// the violations of the category's business rules are reported along with them.
+func (h *Handler) decodeCategoryRequest(r *http.Request) (repositories.CategoryCreateModel, error) {
+	var category dtos.CategoryRequestDto
+	if err := decodeRequest(r, &category); err != nil {
+		if !isValidationError(err) {
+			return repositories.CategoryCreateModel{}, err
+		}
+		model := dtos.ConvertCategoryRequestDtoToModel(category)
+		return repositories.CategoryCreateModel{}, validation.Merge("handlers.decodeCategoryRequest", err, h.AppServices.ValidateCategory(r.Context(), model))
+	}
+	return dtos.ConvertCategoryRequestDtoToModel(category), nil
+}
+
+// isValidationError reports whether the error is a violation of the data rules, whose details list the violations
+func isValidationError(err error) bool {
+	return app.ErrorCode(err) == app.EINVALID
+}
+
+func decodeError(err error) error {
+	if isBodyTooLarge(err) {
+		return &app.Error{Op: "handlers.decodeRequest", Code: app.EBODYTOOLARGE, Err: err, Message: "Request body too large."}
+	}
+	// the decoder reports unknown fields as: json: unknown field "name"
+	if field := strings.TrimPrefix(err.Error(), `json: unknown field "`); field != err.Error() {
+		field = strings.TrimSuffix(field, `"`)
+		return &app.Error{Op: "handlers.decodeRequest", Code: app.EINVALIDJSON, Err: err, Message: "Invalid JSON body.",
+			Details: []app.FieldError{{Field: field, Rule: "unknown", Message: field + " is not a known field"}}}
+	}
+	return &app.Error{Op: "handlers.decodeRequest", Code: app.EINVALIDJSON, Err: err, Message: "Invalid JSON body."}
+}
+
+// isBodyTooLarge reports whether the error is caused by a request body over the limit of http.MaxBytesReader
+func isBodyTooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
+}
@@ -5,14 +5,32 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/mzampetakis/prods-api/api/controllers/middlewares"
-	cache "github.com/victorspringer/http-cache"
 )
 
-func (h *Handler) initializeRoutes(router *mux.Router, cacheClient *cache.Client) {
-	router.Use(middlewares.AcceptJSON)
-	router.Use(middlewares.ContentTypeJSON)
+// initializeRoutes registers the routes of an API version under its prefix. Version 0 registers the
+// unversioned routes, whose version is negotiated.
+func (h *Handler) initializeRoutes(router *mux.Router, prefix string, version int) {
+	cachePolicy, statementTimeouts := h.CachePolicy, h.StatementTimeouts
+	cachePolicy.Prefix, statementTimeouts.Prefix = prefix, prefix
+
+	router.Use(middlewares.ErrorFormat)
+	router.Use(middlewares.Negotiate)
+	router.Use(middlewares.APIVersion(version, h.Versions))
 	router.Use(middlewares.Recovery)
-	router.Use(cacheClient.Middleware)
+	if h.RateLimiter != nil {
+		router.Use(middlewares.RateLimit(h.RateLimiter))
+	}
+	router.Use(middlewares.Decompress)
+	router.Use(middlewares.BodyLimit(h.BodyLimits))
+	if h.Idempotency != nil {
+		router.Use(middlewares.Idempotency(h.Idempotency))
+	}
+	router.Use(middlewares.StatementTimeout(statementTimeouts))
+	router.Use(middlewares.HTTPCaching(cachePolicy))
+	if h.Cache != nil {
+		router.Use(h.Cache.Middleware)
+	}
+	router.Use(middlewares.Compression(h.Compression))
 
 	// Home Route
 	router.HandleFunc("/", h.Home).Methods("GET")
@@ -24,6 +42,14 @@ func (h *Handler) initializeRoutes(router *mux.Router, cacheClient *cache.Client
 	router.HandleFunc("/products/{productID:[0-9]+}", h.UpdateProduct).Methods(http.MethodPut)
 	router.HandleFunc("/products/{productID:[0-9]+}", h.DeleteProduct).Methods(http.MethodDelete)
 	router.HandleFunc("/products/category/{categoryID:[0-9]+}", h.AssignProductsToCategory).Methods(http.MethodPut)
+	router.HandleFunc("/products/{productID:[0-9]+}/translations/{locale}", h.SaveProductTranslation).Methods(http.MethodPut)
+	router.HandleFunc("/products/{productID:[0-9]+}/translations/{locale}", h.DeleteProductTranslation).Methods(http.MethodDelete)
+
+	// Product Images Routes
+	router.HandleFunc("/products/{productID:[0-9]+}/images", h.GetProductImages).Methods(http.MethodGet)
+	router.HandleFunc("/products/{productID:[0-9]+}/images", h.UploadProductImages).Methods(http.MethodPost)
+	router.HandleFunc("/products/{productID:[0-9]+}/images/order", h.UpdateProductImagesOrder).Methods(http.MethodPut)
+	router.HandleFunc("/products/{productID:[0-9]+}/images/{imageID:[0-9]+}", h.DeleteProductImage).Methods(http.MethodDelete)
 
 	// Categories Routes
 	router.HandleFunc("/categories", h.GetAllCategories).Methods(http.MethodGet)
@@ -31,5 +57,11 @@ func (h *Handler) initializeRoutes(router *mux.Router, cacheClient *cache.Client
 	router.HandleFunc("/categories", h.CreateCategory).Methods(http.MethodPost)
 	router.HandleFunc("/categories/{categoryID:[0-9]+}", h.UpdateCategory).Methods(http.MethodPut)
 	router.HandleFunc("/categories/{categoryID:[0-9]+}", h.DeleteCategory).Methods(http.MethodDelete)
+	router.HandleFunc("/categories/{categoryID:[0-9]+}/translations/{locale}", h.SaveCategoryTranslation).Methods(http.MethodPut)
+	router.HandleFunc("/categories/{categoryID:[0-9]+}/translations/{locale}", h.DeleteCategoryTranslation).Methods(http.MethodDelete)
+
+	// Reports Routes
+	router.HandleFunc("/reports/broken-images", h.GetBrokenImagesReport).Methods(http.MethodGet)
+	router.HandleFunc("/reports/missing-translations", h.GetMissingTranslationsReport).Methods(http.MethodGet)
 
 }
@@ -1,44 +1,166 @@
 package controllers
 
 import (
-	"fmt"
+	"context"
+	"crypto/tls"
 	"net/http"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/cache"
+	"github.com/mzampetakis/prods-api/api/controllers/dtos"
 	"github.com/mzampetakis/prods-api/api/controllers/middlewares"
+	"github.com/mzampetakis/prods-api/api/health"
+	"github.com/mzampetakis/prods-api/api/idempotency"
+	"github.com/mzampetakis/prods-api/api/metrics"
+	"github.com/mzampetakis/prods-api/api/ratelimit"
 	"github.com/mzampetakis/prods-api/api/services"
+	"github.com/mzampetakis/prods-api/api/tracing"
 	"github.com/sirupsen/logrus"
 	httpSwagger "github.com/swaggo/http-swagger"
-	cache "github.com/victorspringer/http-cache"
-	"github.com/victorspringer/http-cache/adapter/redis"
 )
 
 type Handler struct {
 	AppServices services.FunctionalitiesIface
+	// Cache serves the GET responses from the server side cache. Caching is disabled when nil.
+	Cache *cache.Client
+	// CachePolicy configures the Cache-Control header of the GET responses
+	CachePolicy middlewares.CachePolicy
+	// Metrics instruments the API requests and serves them at /metrics. Metrics are disabled when nil.
+	Metrics *metrics.Metrics
+	// HealthChecks are the dependency checks of the readiness and status endpoints
+	HealthChecks []health.Check
+	// AccessLog configures the access logs of the API requests
+	AccessLog middlewares.AccessLogOptions
+	// RateLimiter limits the requests of each client. Rate limiting is disabled when nil.
+	RateLimiter *ratelimit.Limiter
+	// Idempotency replays the responses of the retried POST and PATCH requests. Idempotency keys are ignored when nil.
+	Idempotency *idempotency.Keys
+	// Compression configures the compression of the GET responses. The cache stores the compressed responses.
+	Compression middlewares.CompressionOptions
+	// BodyLimits configures the maximum size of the request bodies
+	BodyLimits middlewares.BodyLimits
+	// StatementTimeouts configures the DB statement timeouts of the routes
+	StatementTimeouts middlewares.StatementTimeoutPolicy
+	// CORS configures the cross-origin requests of the browsers. CORS is disabled when no origins are allowed.
+	CORS middlewares.CORSOptions
+	// SecurityHeaders configures the security headers of all responses
+	SecurityHeaders middlewares.SecurityHeadersOptions
+	// Versions configures the default and the deprecated versions of the API
+	Versions middlewares.VersionOptions
+	// Currency is the ISO 4217 currency of the prices, which the v2 contract exposes
+	Currency string
+	// Locales are the locales that the products and categories are served in
+	Locales app.Locales
+	// ClientPrincipals maps the subjects or common names of the verified client certificates to principals
+	ClientPrincipals map[string]string
 }
 
-func (h *Handler) ServerRun(addr string, prefix string) {
+// ServerConfig configures the HTTP server of the API
+type ServerConfig struct {
+	Addr   string
+	Prefix string
+	// ReadTimeout bounds the reading of a whole request, including its body
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	// IdleTimeout bounds the wait for the next request of a keep-alive connection
+	IdleTimeout    time.Duration
+	MaxHeaderBytes int
+	// ShutdownTimeout bounds the draining of the in-flight requests on shutdown
+	ShutdownTimeout time.Duration
+	// TLS serves HTTPS with the configuration. Plain HTTP is served when nil.
+	TLS *tls.Config
+}
+
+// ServerRun serves the API until the provided context is cancelled. The server then stops accepting connections
+// and waits up to ShutdownTimeout for the in-flight requests to complete before closing the remaining ones.
+func (h *Handler) ServerRun(ctx context.Context, config ServerConfig) error {
+	server := &http.Server{
+		Addr:              config.Addr,
+		Handler:           h.handler(config.Prefix),
+		ReadTimeout:       config.ReadTimeout,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
+		MaxHeaderBytes:    config.MaxHeaderBytes,
+		TLSConfig:         config.TLS,
+	}
+	if config.TLS != nil && !containsString(config.TLS.NextProtos, "h2") {
+		// an empty TLSNextProto stops the server from enabling HTTP/2
+		server.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		if config.TLS != nil {
+			logrus.Infof("Listening at %s with TLS", config.Addr)
+			// the certificate is provided by the TLS configuration
+			serverErr <- server.ListenAndServeTLS("", "")
+			return
+		}
+		logrus.Infof("Listening at %s", config.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+	select {
+	case err := <-serverErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	logrus.Infof("Shutting down, waiting up to %s for the in-flight requests", config.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		server.Close()
+		return err
+	}
+	logrus.Info("Server stopped")
+	return nil
+}
+
+// handler wraps the router with the middlewares that apply to all responses. CORS wraps the router instead of
+// being a router middleware so that it answers the preflight requests of routes without an OPTIONS method.
+func (h *Handler) handler(prefix string) http.Handler {
+	router := middlewares.ClientPrincipal(h.ClientPrincipals)(h.router(prefix))
+	return middlewares.SecurityHeaders(h.SecurityHeaders)(middlewares.CORS(h.CORS)(router))
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) router(prefix string) *mux.Router {
 	router := mux.NewRouter()
 	router.PathPrefix("/swagger").Handler(httpSwagger.WrapHandler)
 	router.HandleFunc("/api-docs.json", swagger).Methods("GET")
-	apiRouter := router.PathPrefix(prefix).Subrouter().StrictSlash(true)
-
-	ringOpt := &redis.RingOptions{
-		Addrs: map[string]string{
-			"server": ":6379",
-		},
+	router.HandleFunc("/healthz", h.Liveness).Methods(http.MethodGet)
+	router.HandleFunc("/readyz", h.Readiness).Methods(http.MethodGet)
+	router.HandleFunc("/status", h.Status).Methods(http.MethodGet)
+	router.PathPrefix(dtos.MediaPath).HandlerFunc(h.ServeMedia).Methods(http.MethodGet, http.MethodHead)
+	if h.Metrics != nil {
+		router.Handle("/metrics", h.Metrics.Handler()).Methods(http.MethodGet)
+	}
+	// the versioned prefixes are registered before the unversioned one, which also matches their paths
+	versions := []struct {
+		prefix  string
+		version int
+	}{{prefix + "/v1", dtos.V1}, {prefix + "/v2", dtos.V2}, {prefix, 0}}
+	for _, v := range versions {
+		apiRouter := router.PathPrefix(v.prefix).Subrouter().StrictSlash(true)
+		apiRouter.Use(tracing.Middleware)
+		if h.Metrics != nil {
+			apiRouter.Use(h.Metrics.Middleware)
+		}
+		apiRouter.Use(middlewares.AccessLog(h.AccessLog))
+		h.initializeRoutes(apiRouter, v.prefix, v.version)
 	}
-	cacheClient, _ := cache.NewClient(
-		cache.ClientWithAdapter(redis.NewAdapter(ringOpt)),
-		cache.ClientWithTTL(time.Minute),
-		cache.ClientWithRefreshKey("opn"),
-	)
-
-	apiRouter.Use(middlewares.LogRequest)
-	h.initializeRoutes(apiRouter, cacheClient)
-	fmt.Println("Listening at " + addr)
-	logrus.Fatal(http.ListenAndServe(addr, router))
+	return router
 }
 
 func swagger(w http.ResponseWriter, r *http.Request) {
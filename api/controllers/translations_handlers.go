@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/controllers/dtos"
+	"github.com/mzampetakis/prods-api/api/logging"
+)
+
+// SaveProductTranslation godoc
+// Id SaveProductTranslation
+// @Summary Creates or updates a Product translation
+// @Description Create or update the title and description of a Product in a supported locale other than the default one
+// @Tags Translations
+// @Produce json,application/xml,application/msgpack
+// @Param product_id path integer true "Product ID to translate"
+// @Param locale path string true "Locale of the translation"
+// @Param translation body dtos.ProductTranslationRequestDto true "Product's translated data"
+// @Success 204
+// @Failure 400 {object} dtos.ServeError
+// @Failure 404 {object} dtos.ServeError
+// @Failure 413 {object} dtos.ServeError
+// @Failure 415 {object} dtos.ServeError
+// @Failure 422 {object} dtos.ServeError
+// @Failure 500 {object} dtos.ServeError
+// @Router /products/{product_id}/translations/{locale} [put]
+func (h *Handler) SaveProductTranslation(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	productID, err := strconv.ParseInt(params["productID"], 10, 64)
+	if err != nil {
+		logging.FromContext(r.Context()).Warn(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.SaveProductTranslation", Code: app.EINVALIDID, Err: err})
+		return
+	}
+	var translation dtos.ProductTranslationRequestDto
+	err = decodeRequest(r, &translation)
+	if err != nil {
+		logging.FromContext(r.Context()).Error(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.SaveProductTranslation", Err: err})
+		return
+	}
+	err = h.AppServices.SaveProductTranslation(r.Context(), productID, params["locale"], dtos.ConvertProductTranslationRequestDtoToModel(translation))
+	if err != nil {
+		logging.FromContext(r.Context()).Error(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.SaveProductTranslation", Err: err})
+		return
+	}
+	dtos.JSON(w, http.StatusNoContent, nil)
+}
+
+// DeleteProductTranslation godoc
+// Id DeleteProductTranslation
+// @Summary Deletes a Product translation
+// @Description Delete the translation of a Product to a locale, whose content then falls back to the default locale
+// @Tags Translations
+// @Produce json,application/xml,application/msgpack
+// @Param product_id path integer true "Product ID of the translation"
+// @Param locale path string true "Locale of the translation"
+// @Success 204
+// @Failure 400 {object} dtos.ServeError
+// @Failure 404 {object} dtos.ServeError
+// @Failure 500 {object} dtos.ServeError
+// @Router /products/{product_id}/translations/{locale} [delete]
+func (h *Handler) DeleteProductTranslation(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	productID, err := strconv.ParseInt(params["productID"], 10, 64)
+	if err != nil {
+		logging.FromContext(r.Context()).Warn(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.DeleteProductTranslation", Code: app.EINVALIDID, Err: err})
+		return
+	}
+	err = h.AppServices.DeleteProductTranslation(r.Context(), productID, params["locale"])
+	if err != nil {
+		logging.FromContext(r.Context()).Error(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.DeleteProductTranslation", Err: err})
+		return
+	}
+	dtos.JSON(w, http.StatusNoContent, nil)
+}
+
+// SaveCategoryTranslation godoc
+// Id SaveCategoryTranslation
+// @Summary Creates or updates a Category translation
+// @Description Create or update the title of a Category in a supported locale other than the default one
+// @Tags Translations
+// @Produce json,application/xml,application/msgpack
+// @Param category_id path integer true "Category ID to translate"
+// @Param locale path string true "Locale of the translation"
+// @Param translation body dtos.CategoryTranslationRequestDto true "Category's translated data"
+// @Success 204
+// @Failure 400 {object} dtos.ServeError
+// @Failure 404 {object} dtos.ServeError
+// @Failure 413 {object} dtos.ServeError
+// @Failure 415 {object} dtos.ServeError
+// @Failure 422 {object} dtos.ServeError
+// @Failure 500 {object} dtos.ServeError
+// @Router /categories/{category_id}/translations/{locale} [put]
+func (h *Handler) SaveCategoryTranslation(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	categoryID, err := strconv.ParseInt(params["categoryID"], 10, 64)
+	if err != nil {
+		logging.FromContext(r.Context()).Warn(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.SaveCategoryTranslation", Code: app.EINVALIDID, Err: err})
+		return
+	}
+	var translation dtos.CategoryTranslationRequestDto
+	err = decodeRequest(r, &translation)
+	if err != nil {
+		logging.FromContext(r.Context()).Error(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.SaveCategoryTranslation", Err: err})
+		return
+	}
+	err = h.AppServices.SaveCategoryTranslation(r.Context(), categoryID, params["locale"], dtos.ConvertCategoryTranslationRequestDtoToModel(translation))
+	if err != nil {
+		logging.FromContext(r.Context()).Error(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.SaveCategoryTranslation", Err: err})
+		return
+	}
+	dtos.JSON(w, http.StatusNoContent, nil)
+}
+
+// DeleteCategoryTranslation godoc
+// Id DeleteCategoryTranslation
+// @Summary Deletes a Category translation
+// @Description Delete the translation of a Category to a locale, whose content then falls back to the default locale
+// @Tags Translations
+// @Produce json,application/xml,application/msgpack
+// @Param category_id path integer true "Category ID of the translation"
+// @Param locale path string true "Locale of the translation"
+// @Success 204
+// @Failure 400 {object} dtos.ServeError
+// @Failure 404 {object} dtos.ServeError
+// @Failure 500 {object} dtos.ServeError
+// @Router /categories/{category_id}/translations/{locale} [delete]
+func (h *Handler) DeleteCategoryTranslation(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	categoryID, err := strconv.ParseInt(params["categoryID"], 10, 64)
+	if err != nil {
+		logging.FromContext(r.Context()).Warn(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.DeleteCategoryTranslation", Code: app.EINVALIDID, Err: err})
+		return
+	}
+	err = h.AppServices.DeleteCategoryTranslation(r.Context(), categoryID, params["locale"])
+	if err != nil {
+		logging.FromContext(r.Context()).Error(err.Error())
+		dtos.ERROR(w, r.Context(), &app.Error{Op: "handlers.DeleteCategoryTranslation", Err: err})
+		return
+	}
+	dtos.JSON(w, http.StatusNoContent, nil)
+}
+
+// localize resolves the locale of the response from the locale query parameter, which must be supported,
+// followed by the languages of the Accept-Language header, and selects the translations of the resulting
+// fallback chain. The Content-Language header is set after the fetch, from the locales the content resolved to.
+func (h *Handler) localize(w http.ResponseWriter, r *http.Request, selection *app.Selection) error {
+	w.Header().Add("Vary", "Accept-Language")
+	requested := dtos.AcceptedLanguages(r.Header.Get("Accept-Language"))
+	if locale := r.Form.Get("locale"); locale != "" {
+		if _, ok := h.Locales.Supports(locale); !ok {
+			return &app.Error{Op: "handlers.localize", Code: app.EINVALIDLOCALE, Message: "Invalid locale: " + locale}
+		}
+		requested = append([]string{locale}, requested...)
+	}
+	chain := h.Locales.Chain(requested...)
+	selection.Locales = h.Locales.Translations(chain)
+	return nil
+}
@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/controllers/dtos"
+	"github.com/mzampetakis/prods-api/api/repositories"
+	"github.com/mzampetakis/prods-api/api/validation"
+)
+
+// productResponse converts the product to the selected fields of the DTO of the request's API version
+func (h *Handler) productResponse(ctx context.Context, product repositories.ProductFetchModel, selection app.Selection) interface{} {
+	if dtos.VersionFromContext(ctx) == dtos.V2 {
+		return dtos.SelectFields(dtos.ConvertProductResponseModelToDtoV2(product, h.Currency), selection)
+	}
+	return dtos.SelectFields(dtos.ConvertProductResponseModelToDto(product), selection)
+}
+
+// productsResponse converts the products to the selected fields of the DTOs of the request's API version.
+// The v2 lists are wrapped in their envelope.
+func (h *Handler) productsResponse(ctx context.Context, products []*repositories.ProductFetchModel, filter app.Filter, selection app.Selection) interface{} {
+	if dtos.VersionFromContext(ctx) == dtos.V2 {
+		items := dtos.SelectFields(dtos.ConvertProductsResponseModelToDtoV2(products, h.Currency), selection)
+		return dtos.ConvertListToDtoV2(items, filter.Offset, len(products))
+	}
+	return dtos.SelectFields(dtos.ConvertProductsResponseModelToDto(products), selection)
+}
+
+// categoryResponse converts the category to the selected fields of the DTO of the request's API version
+func (h *Handler) categoryResponse(ctx context.Context, category repositories.CategoryFetchModel, selection app.Selection) interface{} {
+	if dtos.VersionFromContext(ctx) == dtos.V2 {
+		return dtos.SelectFields(dtos.ConvertCategoryResponseModelToDtoV2(category, h.Currency), selection)
+	}
+	return dtos.SelectFields(dtos.ConvertCategoryResponseModelToDto(category), selection)
+}
+
+// categoriesResponse converts the categories to the selected fields of the DTOs of the request's API version.
+// The v2 lists are wrapped in their envelope.
+func (h *Handler) categoriesResponse(ctx context.Context, categories []*repositories.CategoryFetchModel, filter app.Filter, selection app.Selection) interface{} {
+	if dtos.VersionFromContext(ctx) == dtos.V2 {
+		items := dtos.SelectFields(dtos.ConvertCategoriesResponseModelToDtoV2(categories, h.Currency), selection)
+		return dtos.ConvertListToDtoV2(items, filter.Offset, len(categories))
+	}
+	return dtos.SelectFields(dtos.ConvertCategoriesResponseModelToDto(categories), selection)
+}
+
+// decodeProductRequest decodes the product of the request body in the contract of the request's API version.
+// When the request violates the rules of its DTO, the violations of the product's business rules are reported along with them.
+func (h *Handler) decodeProductRequest(r *http.Request) (repositories.ProductCreateModel, error) {
+	var model repositories.ProductCreateModel
+	var err error
+	if dtos.VersionFromContext(r.Context()) == dtos.V2 {
+		var product dtos.ProductRequestDtoV2
+		if err = decodeRequest(r, &product); err != nil && !isValidationError(err) {
+			return repositories.ProductCreateModel{}, err
+		}
+		var convertErr error
+		model, convertErr = dtos.ConvertProductRequestDtoV2ToModel(product, h.Currency)
+		err = validation.Merge("handlers.decodeProductRequest", err, convertErr)
+	} else {
+		var product dtos.ProductRequestDto
+		if err = decodeRequest(r, &product); err != nil && !isValidationError(err) {
+			return repositories.ProductCreateModel{}, err
+		}
+		model = dtos.ConvertProductRequestDtoToModel(product)
+	}
+	if err != nil {
+		return repositories.ProductCreateModel{}, validation.Merge("handlers.decodeProductRequest", err, h.AppServices.ValidateProduct(r.Context(), model))
+	}
+	return model, nil
+}
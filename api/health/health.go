@@ -0,0 +1,87 @@
+// Package health checks the dependencies of the API and describes its build
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Version and Commit describe the build of the API. They are set at build time with
+// -ldflags "-X github.com/mzampetakis/prods-api/api/health.Version=1.0.0 -X github.com/mzampetakis/prods-api/api/health.Commit=$(git rev-parse HEAD)"
+var (
+	Version = "dev"
+	Commit  = "unknown"
+)
+
+var startedAt = time.Now()
+
+// StartedAt returns the time the API started at
+func StartedAt() time.Time {
+	return startedAt
+}
+
+// Check is a named check of a dependency of the API
+type Check struct {
+	Name string
+	// Timeout bounds the duration of the check. Checks without a timeout are bounded by the caller's context.
+	Timeout time.Duration
+	Check   func(context.Context) error
+}
+
+// Result is the outcome of a Check
+type Result struct {
+	Name    string
+	Latency time.Duration
+	Err     error
+}
+
+// Healthy reports whether the check succeeded
+func (r Result) Healthy() bool {
+	return r.Err == nil
+}
+
+// Run runs the checks concurrently and returns their results in the order of the checks
+func Run(ctx context.Context, checks []Check) []Result {
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+	return results
+}
+
+// Healthy reports whether all the results are healthy
+func Healthy(results []Result) bool {
+	for _, result := range results {
+		if !result.Healthy() {
+			return false
+		}
+	}
+	return true
+}
+
+func run(ctx context.Context, check Check) Result {
+	if check.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, check.Timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- check.Check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	return Result{Name: check.Name, Latency: time.Since(start), Err: err}
+}
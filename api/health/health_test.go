@@ -0,0 +1,46 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	//Prepare
+	checks := []Check{
+		{Name: "healthy", Check: func(ctx context.Context) error { return nil }},
+		{Name: "failing", Check: func(ctx context.Context) error { return errors.New("connection refused") }},
+		{Name: "slow", Timeout: 10 * time.Millisecond, Check: func(ctx context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		}},
+	}
+
+	//Act
+	start := time.Now()
+	results := Run(context.Background(), checks)
+
+	//Assert
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the slow check to time out but the checks took %s", elapsed)
+	}
+	if len(results) != len(checks) {
+		t.Fatalf("Expected %d results but got %d", len(checks), len(results))
+	}
+	for i, expectedHealthy := range []bool{true, false, false} {
+		if results[i].Name != checks[i].Name {
+			t.Errorf("Expected result %d to be of check %s but got %s", i, checks[i].Name, results[i].Name)
+		}
+		if results[i].Healthy() != expectedHealthy {
+			t.Errorf("Expected check %s healthy %v but got error %v", results[i].Name, expectedHealthy, results[i].Err)
+		}
+	}
+	if results[2].Err != context.DeadlineExceeded {
+		t.Errorf("Expected the slow check to exceed its deadline but got %v", results[2].Err)
+	}
+	if Healthy(results) {
+		t.Errorf("Expected the results to be unhealthy")
+	}
+}
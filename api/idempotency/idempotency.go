@@ -0,0 +1,155 @@
+// Package idempotency stores the responses of the requests with an idempotency key, so that the retries of a
+// request with the same key get its response instead of repeating its effects
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/mzampetakis/prods-api/api/auth"
+)
+
+// DefaultHeader is the request header of the idempotency keys
+const DefaultHeader = "Idempotency-Key"
+
+// MaxKeyLength is the maximum length of an idempotency key
+const MaxKeyLength = 255
+
+// Response is a stored response
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Record is the state of an idempotency key. Its Response is nil while the first request is in flight.
+type Record struct {
+	// Fingerprint identifies the request that used the key first
+	Fingerprint string
+	Response    *Response
+}
+
+// Store keeps the records of the idempotency keys
+type Store interface {
+	// Reserve stores an in-flight record of the fingerprint for the key if the key has no record.
+	// Otherwise it returns the existing record and false.
+	Reserve(ctx context.Context, key string, fingerprint string, ttl time.Duration) (Record, bool, error)
+	// Get returns the record of the key. The bool reports whether the key has a record.
+	Get(ctx context.Context, key string) (Record, bool, error)
+	// Complete stores the record of the key for ttl
+	Complete(ctx context.Context, key string, record Record, ttl time.Duration) error
+	// Release removes the record of the key so that the request can be retried
+	Release(ctx context.Context, key string) error
+}
+
+// Outcome is the outcome of beginning a request with an idempotency key
+type Outcome int
+
+const (
+	// Reserved means that the request is the first with the key and must be processed
+	Reserved Outcome = iota
+	// Replay means that the key has the response of the same request
+	Replay
+	// Mismatch means that the key was used by a different request
+	Mismatch
+	// InProgress means that the request with the key is still in flight
+	InProgress
+)
+
+// Keys applies the idempotency keys of the requests
+type Keys struct {
+	Store Store
+	// Header is the request header of the keys
+	Header string
+	// TTL is how long the responses are stored
+	TTL time.Duration
+	// LockTTL bounds how long a key stays in flight, in case its request never completes
+	LockTTL time.Duration
+	// Wait is how long the duplicates of an in-flight request wait for its response
+	Wait time.Duration
+	// Client identifies the clients of the requests without a principal, e.g. by their API key or address.
+	// Without it, they are identified by their remote address.
+	Client       func(r *http.Request) string
+	pollInterval time.Duration
+}
+
+// Key returns the store key of the request's idempotency key, which is scoped to the request's principal
+// or, without one, to its client. The bool reports whether the request has an idempotency key.
+func (k *Keys) Key(r *http.Request) (string, bool) {
+	key := r.Header.Get(k.Header)
+	if key == "" {
+		return "", false
+	}
+	var client string
+	if p, ok := auth.FromContext(r.Context()); ok {
+		client = "principal:" + p.Name
+	} else if k.Client != nil {
+		client = k.Client(r)
+	} else {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		client = "ip:" + host
+	}
+	hash := sha256.Sum256([]byte(client + "\x00" + key))
+	return hex.EncodeToString(hash[:]), true
+}
+
+// Fingerprint identifies the request by its method, its URI and its body, which it reads to the end
+func Fingerprint(r *http.Request, body io.Reader) (string, error) {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	if _, err := io.Copy(hash, body); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Begin reserves the key for the request of the fingerprint. If the key is in flight, it waits up to Wait for
+// its response and reserves it again if its request is released in the meantime.
+func (k *Keys) Begin(ctx context.Context, key string, fingerprint string) (Record, Outcome, error) {
+	pollInterval := k.pollInterval
+	if pollInterval <= 0 {
+		pollInterval = 50 * time.Millisecond
+	}
+	deadline := time.Now().Add(k.Wait)
+	record, reserved, err := k.Store.Reserve(ctx, key, fingerprint, k.LockTTL)
+	for {
+		switch {
+		case err != nil:
+			return Record{}, Reserved, err
+		case reserved:
+			return record, Reserved, nil
+		case record.Fingerprint != fingerprint:
+			return record, Mismatch, nil
+		case record.Response != nil:
+			return record, Replay, nil
+		case !time.Now().Before(deadline):
+			return record, InProgress, nil
+		}
+		select {
+		case <-ctx.Done():
+			return record, InProgress, nil
+		case <-time.After(pollInterval):
+		}
+		var found bool
+		if record, found, err = k.Store.Get(ctx, key); err == nil && !found {
+			record, reserved, err = k.Store.Reserve(ctx, key, fingerprint, k.LockTTL)
+		}
+	}
+}
+
+// Complete stores the response of the key's request, or releases the key when the response is a server error
+// so that the request can be retried
+func (k *Keys) Complete(ctx context.Context, key string, fingerprint string, response Response) error {
+	if response.StatusCode >= http.StatusInternalServerError {
+		return k.Store.Release(ctx, key)
+	}
+	return k.Store.Complete(ctx, key, Record{Fingerprint: fingerprint, Response: &response}, k.TTL)
+}
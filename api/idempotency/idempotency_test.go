@@ -0,0 +1,146 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mzampetakis/prods-api/api/auth"
+)
+
+func TestKeys_Begin(t *testing.T) {
+	completed := Record{Fingerprint: "a", Response: &Response{StatusCode: http.StatusCreated, Body: []byte(`{"id":1}`)}}
+	tests := map[string]struct {
+		existing        *Record
+		fingerprint     string
+		expectedOutcome Outcome
+	}{
+		"First request":         {fingerprint: "a", expectedOutcome: Reserved},
+		"Completed request":     {existing: &completed, fingerprint: "a", expectedOutcome: Replay},
+		"Different request":     {existing: &completed, fingerprint: "b", expectedOutcome: Mismatch},
+		"Request in progress":   {existing: &Record{Fingerprint: "a"}, fingerprint: "a", expectedOutcome: InProgress},
+		"Different in progress": {existing: &Record{Fingerprint: "a"}, fingerprint: "b", expectedOutcome: Mismatch},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Prepare
+			store := NewMemoryStore()
+			if tc.existing != nil {
+				store.Complete(context.Background(), "key", *tc.existing, time.Minute)
+			}
+			keys := &Keys{Store: store, TTL: time.Hour, LockTTL: time.Minute, Wait: 20 * time.Millisecond, pollInterval: time.Millisecond}
+
+			//Act
+			_, outcome, err := keys.Begin(context.Background(), "key", tc.fingerprint)
+
+			//Assert
+			if err != nil {
+				t.Fatal(err)
+			}
+			if outcome != tc.expectedOutcome {
+				t.Errorf("Expected outcome %d but got %d", tc.expectedOutcome, outcome)
+			}
+		})
+	}
+}
+
+func TestKeys_Begin_WaitsForRequestInProgress(t *testing.T) {
+	//Prepare
+	store := NewMemoryStore()
+	keys := &Keys{Store: store, TTL: time.Hour, LockTTL: time.Minute, Wait: time.Second, pollInterval: time.Millisecond}
+	if _, outcome, _ := keys.Begin(context.Background(), "key", "a"); outcome != Reserved {
+		t.Fatalf("Expected the first request to reserve the key")
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		keys.Complete(context.Background(), "key", "a", Response{StatusCode: http.StatusCreated})
+	}()
+
+	//Act
+	record, outcome, err := keys.Begin(context.Background(), "key", "a")
+
+	//Assert
+	if err != nil || outcome != Replay || record.Response.StatusCode != http.StatusCreated {
+		t.Errorf("Expected the duplicate to replay the response but got %d %+v %v", outcome, record, err)
+	}
+}
+
+func TestKeys_Complete_ReleasesServerErrors(t *testing.T) {
+	//Prepare
+	store := NewMemoryStore()
+	keys := &Keys{Store: store, TTL: time.Hour, LockTTL: time.Minute}
+	keys.Begin(context.Background(), "key", "a")
+
+	//Act
+	keys.Complete(context.Background(), "key", "a", Response{StatusCode: http.StatusInternalServerError})
+
+	//Assert
+	if _, outcome, _ := keys.Begin(context.Background(), "key", "a"); outcome != Reserved {
+		t.Errorf("Expected the retry of a failed request to reserve the key again but got %d", outcome)
+	}
+}
+
+func TestKeys_Key(t *testing.T) {
+	//Prepare
+	keys := &Keys{Header: DefaultHeader}
+	anonymous := httptest.NewRequest(http.MethodPost, "/products", nil)
+	anonymous.Header.Set(DefaultHeader, "abc")
+	otherAnonymous := anonymous.Clone(anonymous.Context())
+	otherAnonymous.RemoteAddr = "203.0.113.7:1234"
+	authenticated := anonymous.WithContext(auth.WithPrincipal(anonymous.Context(), auth.Principal{Name: "orders"}))
+
+	//Act
+	anonymousKey, anonymousOK := keys.Key(anonymous)
+	otherAnonymousKey, _ := keys.Key(otherAnonymous)
+	authenticatedKey, authenticatedOK := keys.Key(authenticated)
+	_, missingOK := keys.Key(httptest.NewRequest(http.MethodPost, "/products", nil))
+
+	//Assert
+	if !anonymousOK || !authenticatedOK || missingOK {
+		t.Fatalf("Expected only the requests with the header to have a key")
+	}
+	if anonymousKey == authenticatedKey {
+		t.Errorf("Expected the keys to be scoped to the principal")
+	}
+	if anonymousKey == otherAnonymousKey {
+		t.Errorf("Expected the keys of the requests without a principal to be scoped to their address")
+	}
+}
+
+func TestKeys_Key_Client(t *testing.T) {
+	//Prepare
+	keys := &Keys{Header: DefaultHeader, Client: func(r *http.Request) string { return "key:" + r.Header.Get("X-Api-Key") }}
+	partner := httptest.NewRequest(http.MethodPost, "/products", nil)
+	partner.Header.Set(DefaultHeader, "abc")
+	partner.Header.Set("X-Api-Key", "partner")
+	other := partner.Clone(partner.Context())
+	other.Header.Set("X-Api-Key", "other")
+
+	//Act
+	partnerKey, _ := keys.Key(partner)
+	otherKey, _ := keys.Key(other)
+
+	//Assert
+	if partnerKey == otherKey {
+		t.Errorf("Expected the keys to be scoped to the client")
+	}
+}
+
+func TestMemoryStore_Expiry(t *testing.T) {
+	//Prepare
+	now := time.Date(2020, 5, 25, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	store.Reserve(context.Background(), "key", "a", time.Minute)
+
+	//Act
+	now = now.Add(time.Minute)
+	_, reserved, _ := store.Reserve(context.Background(), "key", "b", time.Minute)
+
+	//Assert
+	if !reserved {
+		t.Errorf("Expected an expired record to be reserved again")
+	}
+}
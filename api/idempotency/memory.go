@@ -0,0 +1,83 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps the records in process, deduplicating the requests of each instance of the API separately
+type MemoryStore struct {
+	// SweepInterval is how often the expired records are removed
+	SweepInterval time.Duration
+	now           func() time.Time
+
+	mu        sync.Mutex
+	records   map[string]memoryRecord
+	lastSweep time.Time
+}
+
+type memoryRecord struct {
+	record  Record
+	expires time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{SweepInterval: time.Minute, now: time.Now, records: make(map[string]memoryRecord)}
+}
+
+func (m *MemoryStore) Reserve(ctx context.Context, key string, fingerprint string, ttl time.Duration) (Record, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	m.sweep(now)
+	if existing, ok := m.records[key]; ok && now.Before(existing.expires) {
+		return existing.record, false, nil
+	}
+	record := Record{Fingerprint: fingerprint}
+	m.records[key] = memoryRecord{record: record, expires: now.Add(ttl)}
+	return record, true, nil
+}
+
+func (m *MemoryStore) Get(ctx context.Context, key string) (Record, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	existing, ok := m.records[key]
+	if !ok || !m.now().Before(existing.expires) {
+		return Record{}, false, nil
+	}
+	return existing.record, true, nil
+}
+
+func (m *MemoryStore) Complete(ctx context.Context, key string, record Record, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[key] = memoryRecord{record: record, expires: m.now().Add(ttl)}
+	return nil
+}
+
+func (m *MemoryStore) Release(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.records, key)
+	return nil
+}
+
+// Len returns the number of records in the store
+func (m *MemoryStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.records)
+}
+
+func (m *MemoryStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < m.SweepInterval {
+		return
+	}
+	m.lastSweep = now
+	for key, existing := range m.records {
+		if !now.Before(existing.expires) {
+			delete(m.records, key)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// RedisStore keeps the records in Redis, deduplicating the requests across all instances of the API
+type RedisStore struct {
+	client    *redis.Client
+	keyPrefix string
+}
+
+// RedisConfig holds the connection details of the Redis server
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+	// KeyPrefix namespaces the keys of the records
+	KeyPrefix string
+}
+
+func NewRedisStore(config RedisConfig) *RedisStore {
+	if config.KeyPrefix == "" {
+		config.KeyPrefix = "prods-api:idempotency:"
+	}
+	return &RedisStore{
+		client: redis.NewClient(&redis.Options{
+			Addr:        config.Addr,
+			Password:    config.Password,
+			DB:          config.DB,
+			DialTimeout: time.Second,
+			ReadTimeout: time.Second,
+			MaxRetries:  0,
+		}),
+		keyPrefix: config.KeyPrefix,
+	}
+}
+
+func (r *RedisStore) Reserve(ctx context.Context, key string, fingerprint string, ttl time.Duration) (Record, bool, error) {
+	record := Record{Fingerprint: fingerprint}
+	value, err := json.Marshal(record)
+	if err != nil {
+		return Record{}, false, err
+	}
+	reserved, err := r.client.WithContext(ctx).SetNX(r.keyPrefix+key, value, ttl).Result()
+	if err != nil {
+		return Record{}, false, err
+	}
+	if reserved {
+		return record, true, nil
+	}
+	existing, found, err := r.Get(ctx, key)
+	if err == nil && !found {
+		// the record expired after SETNX, try again
+		return r.Reserve(ctx, key, fingerprint, ttl)
+	}
+	return existing, false, err
+}
+
+func (r *RedisStore) Get(ctx context.Context, key string) (Record, bool, error) {
+	value, err := r.client.WithContext(ctx).Get(r.keyPrefix + key).Bytes()
+	if err == redis.Nil {
+		return Record{}, false, nil
+	}
+	if err != nil {
+		return Record{}, false, err
+	}
+	var record Record
+	if err = json.Unmarshal(value, &record); err != nil {
+		return Record{}, false, err
+	}
+	return record, true, nil
+}
+
+func (r *RedisStore) Complete(ctx context.Context, key string, record Record, ttl time.Duration) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return r.client.WithContext(ctx).Set(r.keyPrefix+key, value, ttl).Err()
+}
+
+func (r *RedisStore) Release(ctx context.Context, key string) error {
+	return r.client.WithContext(ctx).Del(r.keyPrefix + key).Err()
+}
+
+func (r *RedisStore) Close() error {
+	return r.client.Close()
+}
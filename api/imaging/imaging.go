@@ -0,0 +1,83 @@
+// Package imaging provides functionality to decode uploaded images and generate their thumbnails
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"github.com/mzampetakis/prods-api/api/app"
+	"golang.org/x/image/draw"
+)
+
+// Extensions holds the supported image content types alongside with their file extension
+var Extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// DetectContentType sniffs the content type of the provided image data.
+// An EINVALIDIMAGE error is returned if the content type is not supported.
+func DetectContentType(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	if _, ok := Extensions[contentType]; !ok {
+		return "", &app.Error{Op: "imaging.DetectContentType", Code: app.EINVALIDIMAGE, Message: "Unsupported image type: " + contentType}
+	}
+	return contentType, nil
+}
+
+// DecodeConfig decodes the dimensions of the provided image data without decoding its pixels
+func DecodeConfig(data []byte) (image.Config, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return image.Config{}, &app.Error{Op: "imaging.DecodeConfig", Code: app.EINVALIDIMAGE, Err: err, Message: "Invalid image data."}
+	}
+	return config, nil
+}
+
+// Decode decodes the provided image data
+func Decode(data []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, &app.Error{Op: "imaging.Decode", Code: app.EINVALIDIMAGE, Err: err, Message: "Invalid image data."}
+	}
+	return img, nil
+}
+
+// Thumbnail resizes the image to the provided width keeping its aspect ratio.
+// Images narrower than the width are not upscaled.
+func Thumbnail(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	if bounds.Dx() <= width {
+		return img
+	}
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+	thumbnail := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(thumbnail, thumbnail.Bounds(), img, bounds, draw.Over, nil)
+	return thumbnail
+}
+
+// Encode encodes the image in the format of the provided content type
+func Encode(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch contentType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+	case "image/gif":
+		err = gif.Encode(&buf, img, nil)
+	default:
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return nil, &app.Error{Op: "imaging.Encode", Code: app.EINTERNAL, Err: err}
+	}
+	return buf.Bytes(), nil
+}
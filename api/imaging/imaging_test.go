@@ -0,0 +1,52 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/mzampetakis/prods-api/api/app"
+)
+
+func TestThumbnail(t *testing.T) {
+	tests := map[string]struct {
+		width          int
+		height         int
+		thumbnailWidth int
+		expectedWidth  int
+		expectedHeight int
+	}{
+		"Landscape image is resized": {width: 800, height: 400, thumbnailWidth: 200, expectedWidth: 200, expectedHeight: 100},
+		"Portrait image is resized":  {width: 300, height: 600, thumbnailWidth: 150, expectedWidth: 150, expectedHeight: 300},
+		"Small image is kept":        {width: 100, height: 50, thumbnailWidth: 200, expectedWidth: 100, expectedHeight: 50},
+	}
+
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			img := image.NewRGBA(image.Rect(0, 0, tc.width, tc.height))
+
+			thumbnail := Thumbnail(img, tc.thumbnailWidth)
+
+			if thumbnail.Bounds().Dx() != tc.expectedWidth || thumbnail.Bounds().Dy() != tc.expectedHeight {
+				t.Errorf("Expected thumbnail %dx%d but got %dx%d", tc.expectedWidth, tc.expectedHeight,
+					thumbnail.Bounds().Dx(), thumbnail.Bounds().Dy())
+			}
+		})
+	}
+}
+
+func TestDetectContentType(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1, 1)))
+
+	contentType, err := DetectContentType(buf.Bytes())
+	if err != nil || contentType != "image/png" {
+		t.Errorf("Expected image/png but got %s (%v)", contentType, err)
+	}
+
+	_, err = DetectContentType([]byte("<html></html>"))
+	if app.ErrorCode(err) != app.EINVALIDIMAGE {
+		t.Errorf("Expected error code %s but got %s", app.EINVALIDIMAGE, app.ErrorCode(err))
+	}
+}
@@ -0,0 +1,72 @@
+// Package logging provides the structured, request scoped logger of the API
+package logging
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultSensitiveHeaders are the headers whose values are never logged
+var DefaultSensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+const redacted = "[REDACTED]"
+
+// Configure sets the level (trace, debug, info, warn, error) and the format (text or json) of the logs
+func Configure(level string, format string) error {
+	logger := logrus.StandardLogger()
+	logger.SetOutput(os.Stdout)
+	if level != "" {
+		parsedLevel, err := logrus.ParseLevel(level)
+		if err != nil {
+			return err
+		}
+		logger.SetLevel(parsedLevel)
+	}
+	switch strings.ToLower(format) {
+	case "json":
+		logger.SetFormatter(&logrus.JSONFormatter{})
+	case "", "text":
+		logger.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	default:
+		return fmt.Errorf("invalid log format: %s", format)
+	}
+	return nil
+}
+
+type contextKey string
+
+const loggerContextKey contextKey = "logger"
+
+// NewContext returns a context that carries the provided logger
+func NewContext(ctx context.Context, logger *logrus.Entry) context.Context {
+	return context.WithValue(ctx, loggerContextKey, logger)
+}
+
+// FromContext returns the logger of the context, enriched with the details of the request it serves,
+// or the standard logger if there is none
+func FromContext(ctx context.Context) *logrus.Entry {
+	if logger, ok := ctx.Value(loggerContextKey).(*logrus.Entry); ok {
+		return logger
+	}
+	return logrus.NewEntry(logrus.StandardLogger())
+}
+
+// RedactHeaders flattens the headers for logging, replacing the values of the sensitive ones
+func RedactHeaders(header http.Header, sensitive []string) map[string]string {
+	headers := make(map[string]string, len(header))
+	for name, values := range header {
+		headers[name] = strings.Join(values, ", ")
+	}
+	for _, name := range sensitive {
+		name = http.CanonicalHeaderKey(name)
+		if _, ok := headers[name]; ok {
+			headers[name] = redacted
+		}
+	}
+	return headers
+}
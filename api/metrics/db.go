@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	dbMaxOpenConnections = dbDesc("max_open_connections", "Maximum number of open connections to the DB.")
+	dbOpenConnections    = dbDesc("open_connections", "Number of established connections both in use and idle.")
+	dbInUse              = dbDesc("in_use_connections", "Number of connections currently in use.")
+	dbIdle               = dbDesc("idle_connections", "Number of idle connections.")
+	dbWaitCount          = dbDesc("wait_count_total", "Number of connections waited for.")
+	dbWaitDuration       = dbDesc("wait_duration_seconds_total", "Time blocked waiting for a new connection.")
+	dbMaxIdleClosed      = dbDesc("max_idle_closed_total", "Number of connections closed due to the maximum idle connections.")
+	dbMaxLifetimeClosed  = dbDesc("max_lifetime_closed_total", "Number of connections closed due to the maximum connection lifetime.")
+)
+
+func dbDesc(name string, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db", name), help, nil, nil)
+}
+
+// dbStatsCollector collects the connection pool statistics of a DB on each scrape
+type dbStatsCollector struct {
+	db *sql.DB
+}
+
+func (c *dbStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- dbMaxOpenConnections
+	ch <- dbOpenConnections
+	ch <- dbInUse
+	ch <- dbIdle
+	ch <- dbWaitCount
+	ch <- dbWaitDuration
+	ch <- dbMaxIdleClosed
+	ch <- dbMaxLifetimeClosed
+}
+
+func (c *dbStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.db.Stats()
+	ch <- prometheus.MustNewConstMetric(dbMaxOpenConnections, prometheus.GaugeValue, float64(stats.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(dbOpenConnections, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(dbInUse, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(dbIdle, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(dbWaitCount, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(dbWaitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(dbMaxIdleClosed, prometheus.CounterValue, float64(stats.MaxIdleClosed))
+	ch <- prometheus.MustNewConstMetric(dbMaxLifetimeClosed, prometheus.CounterValue, float64(stats.MaxLifetimeClosed))
+}
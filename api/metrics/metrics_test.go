@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics_Middleware(t *testing.T) {
+	//Prepare
+	m := New()
+	router := mux.NewRouter()
+	router.Use(m.Middleware)
+	router.HandleFunc("/products/{productID:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["productID"] == "404" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	//Act
+	for _, url := range []string{"/products/1", "/products/2", "/products/404"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, url, nil))
+	}
+
+	//Assert
+	route := "/products/{productID:[0-9]+}"
+	if count := testutil.ToFloat64(m.requests.WithLabelValues(http.MethodGet, route, "2xx")); count != 2 {
+		t.Errorf("Expected 2 successful requests but got %v", count)
+	}
+	if count := testutil.ToFloat64(m.requests.WithLabelValues(http.MethodGet, route, "4xx")); count != 1 {
+		t.Errorf("Expected 1 client error request but got %v", count)
+	}
+}
+
+func TestMetrics_Handler(t *testing.T) {
+	//Prepare
+	m := New()
+	m.RegisterCache("datastore", func() (uint64, uint64) { return 3, 1 })
+	w := httptest.NewRecorder()
+
+	//Act
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	//Assert
+	body, _ := ioutil.ReadAll(w.Body)
+	for _, expected := range []string{
+		`prods_api_cache_hits_total{cache="datastore"} 3`,
+		`prods_api_cache_misses_total{cache="datastore"} 1`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(string(body), expected) {
+			t.Errorf("Expected metrics to contain %s", expected)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps the buckets in process, limiting the requests of each instance of the API separately
+type MemoryStore struct {
+	// SweepInterval is how often the buckets that have been refilled are removed
+	SweepInterval time.Duration
+	now           func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	tokens  float64
+	updated time.Time
+	// full is when the bucket is full again, after which it is the same as a new bucket
+	full time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		SweepInterval: time.Minute,
+		now:           time.Now,
+		buckets:       make(map[string]*memoryBucket),
+	}
+}
+
+func (m *MemoryStore) Take(ctx context.Context, key string, quota Quota) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	m.sweep(now)
+	bucket, ok := m.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{tokens: float64(quota.Requests), updated: now}
+		m.buckets[key] = bucket
+	}
+	tokens, allowed := refill(quota, bucket.tokens, now.Sub(bucket.updated))
+	result := newResult(quota, tokens, allowed)
+	bucket.tokens = tokens
+	bucket.updated = now
+	bucket.full = now.Add(result.Reset)
+	return result, nil
+}
+
+// Len returns the number of buckets in the store
+func (m *MemoryStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.buckets)
+}
+
+func (m *MemoryStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < m.SweepInterval {
+		return
+	}
+	m.lastSweep = now
+	for key, bucket := range m.buckets {
+		if !now.Before(bucket.full) {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+// Package ratelimit limits the rate of the requests of each client with token buckets
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mzampetakis/prods-api/api/auth"
+)
+
+// AnonymousRole is the role of the clients without a known API key
+const AnonymousRole = "anonymous"
+
+// Route groups of the requests, whose quotas are tracked separately
+const (
+	ReadGroup  = "read"
+	WriteGroup = "write"
+)
+
+// Quota is the number of requests that a client may make within a period. The client's token bucket holds
+// up to Requests tokens and is refilled at Requests per Period, so bursts of up to Requests are allowed.
+type Quota struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Unlimited reports whether the quota does not limit the requests
+func (q Quota) Unlimited() bool {
+	return q.Requests <= 0 || q.Period <= 0
+}
+
+// String formats the quota in the syntax of ParseQuota
+func (q Quota) String() string {
+	if q.Unlimited() {
+		return "unlimited"
+	}
+	return strconv.Itoa(q.Requests) + "/" + q.Period.String()
+}
+
+// ratePerSecond is the rate that the bucket of the quota is refilled at
+func (q Quota) ratePerSecond() float64 {
+	return float64(q.Requests) / q.Period.Seconds()
+}
+
+// ParseQuota parses a quota like 100/1m. 0 and unlimited disable the limit.
+func ParseQuota(value string) (Quota, error) {
+	value = strings.TrimSpace(value)
+	if value == "0" || value == "unlimited" {
+		return Quota{}, nil
+	}
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		return Quota{}, errors.New("must be requests/period like 100/1m, 0 or unlimited")
+	}
+	requests, err := strconv.Atoi(parts[0])
+	if err != nil || requests < 0 {
+		return Quota{}, errors.New("must have a non negative number of requests")
+	}
+	period, err := time.ParseDuration(parts[1])
+	if err != nil || period <= 0 {
+		return Quota{}, errors.New("must have a positive period like 1s or 1m")
+	}
+	return Quota{Requests: requests, Period: period}, nil
+}
+
+// Result is the outcome of taking a token from a client's bucket
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next token when the request is not allowed
+	RetryAfter time.Duration
+}
+
+// newResult describes the bucket of the quota that holds the provided tokens after a take
+func newResult(quota Quota, tokens float64, allowed bool) Result {
+	rate := quota.ratePerSecond()
+	result := Result{
+		Allowed:   allowed,
+		Limit:     quota.Requests,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(quota.Requests) - tokens) / rate * float64(time.Second)),
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return result
+}
+
+// refill returns the tokens of a bucket after elapsed and whether one of them can be taken
+func refill(quota Quota, tokens float64, elapsed time.Duration) (float64, bool) {
+	tokens = math.Min(float64(quota.Requests), tokens+elapsed.Seconds()*quota.ratePerSecond())
+	if tokens >= 1 {
+		return tokens - 1, true
+	}
+	return tokens, false
+}
+
+// Store keeps the token buckets of the clients
+type Store interface {
+	// Take takes a token from the bucket of the key, creating a full bucket of the quota if it does not exist
+	Take(ctx context.Context, key string, quota Quota) (Result, error)
+}
+
+// Limiter applies the quota of each client's role to its requests
+type Limiter struct {
+	Store Store
+	// Quotas holds the quota of each role. A quota for role.group (e.g. anonymous.write) takes precedence over
+	// the quota of the role for the requests of the route group. Roles without a quota are unlimited.
+	Quotas map[string]Quota
+	// APIKeys holds the role of each API key. Clients with a known API key are identified by it,
+	// all others get the anonymous role and are identified by their IP.
+	APIKeys      map[string]string
+	APIKeyHeader string
+	// TrustedProxies are the networks of the proxies in front of the server. The requests that they forward
+	// identify their clients by the right-most address of X-Forwarded-For that is not a trusted proxy,
+	// as the proxies append to the header the addresses they receive requests from.
+	TrustedProxies []*net.IPNet
+}
+
+// RouteGroup returns the route group of the request, read for the safe methods and write for the rest
+func RouteGroup(r *http.Request) string {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return ReadGroup
+	}
+	return WriteGroup
+}
+
+// Client returns the id and the role of the client of the request. Authenticated principals are identified by
+// their name, which is also their role.
+func (l *Limiter) Client(r *http.Request) (string, string) {
+	if principal, ok := auth.FromContext(r.Context()); ok {
+		return "principal:" + principal.Name, principal.Name
+	}
+	if key := r.Header.Get(l.APIKeyHeader); key != "" && l.APIKeyHeader != "" {
+		if role, ok := l.APIKeys[key]; ok {
+			// the key is hashed so that it is not stored in the buckets' keys
+			hash := sha256.Sum256([]byte(key))
+			return "key:" + hex.EncodeToString(hash[:8]), role
+		}
+	}
+	return "ip:" + l.clientIP(r), AnonymousRole
+}
+
+// clientIP returns the address of the request's client. The addresses that clients send in X-Forwarded-For
+// are ignored, as only the entries appended by the trusted proxies are considered.
+func (l *Limiter) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !l.trustedProxy(host) {
+		return host
+	}
+	forwardedFor := make([]string, 0)
+	for _, header := range r.Header[http.CanonicalHeaderKey("X-Forwarded-For")] {
+		forwardedFor = append(forwardedFor, strings.Split(header, ",")...)
+	}
+	for i := len(forwardedFor) - 1; i >= 0; i-- {
+		address := strings.TrimSpace(forwardedFor[i])
+		if address == "" {
+			continue
+		}
+		host = address
+		if !l.trustedProxy(address) {
+			break
+		}
+	}
+	return host
+}
+
+func (l *Limiter) trustedProxy(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, network := range l.TrustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseNetworks parses CIDR networks (e.g. 10.0.0.0/8) and IP addresses, which are networks of a single address
+func ParseNetworks(values []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if ip := net.ParseIP(value); ip != nil {
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, errors.New("must be CIDR networks or IP addresses, got " + value)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// Quota returns the quota of the role for the route group
+func (l *Limiter) Quota(role string, group string) Quota {
+	if quota, ok := l.Quotas[role+"."+group]; ok {
+		return quota
+	}
+	return l.Quotas[role]
+}
+
+// Take takes a token from the bucket of the request's client and route group and returns the applied quota.
+// The result is always allowed when the quota is unlimited.
+func (l *Limiter) Take(r *http.Request) (Result, Quota, error) {
+	client, role := l.Client(r)
+	group := RouteGroup(r)
+	quota := l.Quota(role, group)
+	if quota.Unlimited() {
+		return Result{Allowed: true}, quota, nil
+	}
+	result, err := l.Store.Take(r.Context(), client+":"+group, quota)
+	return result, quota, err
+}
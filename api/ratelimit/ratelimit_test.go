@@ -0,0 +1,176 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mzampetakis/prods-api/api/auth"
+)
+
+func TestParseQuota(t *testing.T) {
+	tests := map[string]struct {
+		value         string
+		expected      Quota
+		expectedError bool
+	}{
+		"Requests per period": {value: "100/1m", expected: Quota{Requests: 100, Period: time.Minute}},
+		"Zero is unlimited":   {value: "0", expected: Quota{}},
+		"Unlimited":           {value: "unlimited", expected: Quota{}},
+		"Missing period":      {value: "100", expectedError: true},
+		"Invalid requests":    {value: "many/1m", expectedError: true},
+		"Invalid period":      {value: "100/0s", expectedError: true},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Act
+			quota, err := ParseQuota(tc.value)
+
+			//Assert
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("Expected error %v but got %v", tc.expectedError, err)
+			}
+			if quota != tc.expected {
+				t.Errorf("Expected quota %v but got %v", tc.expected, quota)
+			}
+		})
+	}
+}
+
+func TestMemoryStore_Take(t *testing.T) {
+	//Prepare
+	now := time.Date(2020, 5, 25, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	quota := Quota{Requests: 2, Period: 10 * time.Second}
+
+	//Act & Assert
+	for i, expectedRemaining := range []int{1, 0} {
+		result, _ := store.Take(context.Background(), "ip:1", quota)
+		if !result.Allowed || result.Remaining != expectedRemaining {
+			t.Fatalf("Expected request %d to be allowed with %d remaining but got %+v", i+1, expectedRemaining, result)
+		}
+	}
+	result, _ := store.Take(context.Background(), "ip:1", quota)
+	if result.Allowed || result.RetryAfter != 5*time.Second || result.Reset != 10*time.Second {
+		t.Errorf("Expected the request over the quota to be limited for 5s but got %+v", result)
+	}
+	if result, _ = store.Take(context.Background(), "ip:2", quota); !result.Allowed {
+		t.Errorf("Expected the buckets to be per key")
+	}
+
+	now = now.Add(5 * time.Second)
+	if result, _ = store.Take(context.Background(), "ip:1", quota); !result.Allowed {
+		t.Errorf("Expected the bucket to be refilled after 5s")
+	}
+
+	now = now.Add(time.Hour)
+	store.Take(context.Background(), "ip:3", quota)
+	if store.Len() != 1 {
+		t.Errorf("Expected the full buckets to be swept but got %d buckets", store.Len())
+	}
+}
+
+func TestLimiter_Take(t *testing.T) {
+	//Prepare
+	limiter := &Limiter{
+		Store: NewMemoryStore(),
+		Quotas: map[string]Quota{
+			AnonymousRole:                    {Requests: 5, Period: time.Minute},
+			AnonymousRole + "." + WriteGroup: {Requests: 1, Period: time.Minute},
+			"partner":                        {Requests: 100, Period: time.Minute},
+		},
+		APIKeys:      map[string]string{"s3cr3t": "partner", "admin-key": "admin"},
+		APIKeyHeader: "X-Api-Key",
+	}
+	tests := map[string]struct {
+		method            string
+		apiKey            string
+		expectedLimit     int
+		expectedUnlimited bool
+	}{
+		"Anonymous read":      {method: http.MethodGet, expectedLimit: 5},
+		"Anonymous write":     {method: http.MethodPost, expectedLimit: 1},
+		"Known API key":       {method: http.MethodPost, apiKey: "s3cr3t", expectedLimit: 100},
+		"Unknown API key":     {method: http.MethodGet, apiKey: "guess", expectedLimit: 5},
+		"Role without quotas": {method: http.MethodGet, apiKey: "admin-key", expectedUnlimited: true},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, "/products", nil)
+			if tc.apiKey != "" {
+				r.Header.Set("X-Api-Key", tc.apiKey)
+			}
+
+			//Act
+			result, quota, err := limiter.Take(r)
+
+			//Assert
+			if err != nil {
+				t.Fatal(err)
+			}
+			if quota.Unlimited() != tc.expectedUnlimited || !result.Allowed {
+				t.Fatalf("Expected unlimited %v but got quota %v and result %+v", tc.expectedUnlimited, quota, result)
+			}
+			if !tc.expectedUnlimited && result.Limit != tc.expectedLimit {
+				t.Errorf("Expected limit %d but got %d", tc.expectedLimit, result.Limit)
+			}
+		})
+	}
+}
+
+func TestLimiter_Client(t *testing.T) {
+	//Prepare
+	r := httptest.NewRequest(http.MethodGet, "/products", nil)
+	r.RemoteAddr = "10.0.0.1:4321"
+	r.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.2")
+
+	//Act
+	direct, _ := (&Limiter{}).Client(r)
+	authenticated, role := (&Limiter{}).Client(r.WithContext(auth.WithPrincipal(r.Context(), auth.Principal{Name: "orders"})))
+
+	//Assert
+	if direct != "ip:10.0.0.1" {
+		t.Errorf("Expected the remote address to identify the client but got %s", direct)
+	}
+	if authenticated != "principal:orders" || role != "orders" {
+		t.Errorf("Expected the principal to identify the client and be its role but got %s with role %s", authenticated, role)
+	}
+}
+
+func TestLimiter_Client_TrustedProxies(t *testing.T) {
+	trustedProxies, _ := ParseNetworks([]string{"10.0.0.0/8", "192.0.2.1"})
+	tests := map[string]struct {
+		remoteAddr     string
+		forwardedFor   []string
+		expectedClient string
+	}{
+		"Direct request":               {remoteAddr: "203.0.113.7:4321", forwardedFor: []string{"198.51.100.1"}, expectedClient: "ip:203.0.113.7"},
+		"Through a trusted proxy":      {remoteAddr: "10.0.0.1:4321", forwardedFor: []string{"203.0.113.7"}, expectedClient: "ip:203.0.113.7"},
+		"Spoofed entry is ignored":     {remoteAddr: "10.0.0.1:4321", forwardedFor: []string{"198.51.100.1, 203.0.113.7"}, expectedClient: "ip:203.0.113.7"},
+		"Through trusted proxies":      {remoteAddr: "10.0.0.1:4321", forwardedFor: []string{"198.51.100.1, 203.0.113.7, 192.0.2.1"}, expectedClient: "ip:203.0.113.7"},
+		"Over several headers":         {remoteAddr: "10.0.0.1:4321", forwardedFor: []string{"198.51.100.1", "203.0.113.7, 10.0.0.2"}, expectedClient: "ip:203.0.113.7"},
+		"Only trusted proxies":         {remoteAddr: "10.0.0.1:4321", forwardedFor: []string{"10.0.0.3, 10.0.0.2"}, expectedClient: "ip:10.0.0.3"},
+		"Trusted proxy without header": {remoteAddr: "10.0.0.1:4321", expectedClient: "ip:10.0.0.1"},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Prepare
+			r := httptest.NewRequest(http.MethodGet, "/products", nil)
+			r.RemoteAddr = tc.remoteAddr
+			for _, header := range tc.forwardedFor {
+				r.Header.Add("X-Forwarded-For", header)
+			}
+
+			//Act
+			client, _ := (&Limiter{TrustedProxies: trustedProxies}).Client(r)
+
+			//Assert
+			if client != tc.expectedClient {
+				t.Errorf("Expected client %s but got %s", tc.expectedClient, client)
+			}
+		})
+	}
+}
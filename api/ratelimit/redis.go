@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// takeScript refills and takes a token from a bucket atomically, using the clock of the Redis server
+// so that all instances of the API agree on the time. It returns whether the token was taken
+// and the remaining tokens as a string, as Redis truncates the numbers that scripts return.
+var takeScript = redis.NewScript(`
+redis.replicate_commands()
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(bucket[1]) or capacity
+local updated = tonumber(bucket[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - updated) * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'updated', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil((capacity - tokens) / rate) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore keeps the buckets in Redis, limiting the requests of a client across all instances of the API
+type RedisStore struct {
+	client    *redis.Client
+	keyPrefix string
+}
+
+// RedisConfig holds the connection details of the Redis server
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+	// KeyPrefix namespaces the keys of the buckets
+	KeyPrefix string
+}
+
+func NewRedisStore(config RedisConfig) *RedisStore {
+	if config.KeyPrefix == "" {
+		config.KeyPrefix = "prods-api:ratelimit:"
+	}
+	return &RedisStore{
+		client: redis.NewClient(&redis.Options{
+			Addr:        config.Addr,
+			Password:    config.Password,
+			DB:          config.DB,
+			DialTimeout: time.Second,
+			ReadTimeout: time.Second,
+			MaxRetries:  0,
+		}),
+		keyPrefix: config.KeyPrefix,
+	}
+}
+
+func (r *RedisStore) Take(ctx context.Context, key string, quota Quota) (Result, error) {
+	ratePerMillisecond := quota.ratePerSecond() / 1000
+	reply, err := takeScript.Run(r.client.WithContext(ctx), []string{r.keyPrefix + key},
+		quota.Requests, strconv.FormatFloat(ratePerMillisecond, 'f', -1, 64)).Result()
+	if err != nil {
+		return Result{}, err
+	}
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return Result{}, fmt.Errorf("unexpected reply %v", reply)
+	}
+	allowed, _ := values[0].(int64)
+	remaining, _ := values[1].(string)
+	tokens, err := strconv.ParseFloat(remaining, 64)
+	if err != nil {
+		return Result{}, err
+	}
+	return newResult(quota, tokens, allowed == 1), nil
+}
+
+func (r *RedisStore) Close() error {
+	return r.client.Close()
+}
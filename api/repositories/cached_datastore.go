@@ -0,0 +1,253 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/cache"
+	"golang.org/x/sync/singleflight"
+)
+
+// Tags of the cached entities
+const (
+	// allProductsTag is attached to every cached product
+	allProductsTag = "products:all"
+	// expandsProductsTag is attached to the cached entities that embed products
+	expandsProductsTag = "expands:products"
+	// expandsCategoryTag is attached to the cached entities that embed their category
+	expandsCategoryTag = "expands:category"
+)
+
+// CachedDatastore is a read-through cache of the single entity reads of a datastore.
+// Writes pass through it to the datastore and invalidate the affected cached entities.
+// All other methods are served directly by the decorated datastore.
+// The invalidations reach only the cache of the instance that made the write, so with several instances
+// the other ones serve stale entities, and rebuild shared caches from them, until their TTL expires.
+type CachedDatastore struct {
+	DatastoreIface
+	cache *cache.MemoryBackend
+	ttl   time.Duration
+	group singleflight.Group
+
+	// generation changes on every invalidation so that loads racing with a write are not cached.
+	// mu makes each invalidation and each check of a load's generation along with its caching atomic.
+	mu         sync.Mutex
+	generation uint64
+	hits       uint64
+	misses     uint64
+}
+
+// CachedDatastoreStats holds the hits and misses of the cached reads
+type CachedDatastoreStats struct {
+	Hits    uint64
+	Misses  uint64
+	Entries int
+}
+
+func NewCachedDatastore(datastore DatastoreIface, ttl time.Duration, maxEntries int) *CachedDatastore {
+	return &CachedDatastore{
+		DatastoreIface: datastore,
+		cache:          cache.NewMemoryBackend(maxEntries),
+		ttl:            ttl,
+	}
+}
+
+// Stats returns the hits and misses of the cached reads since the datastore was created
+func (c *CachedDatastore) Stats() CachedDatastoreStats {
+	return CachedDatastoreStats{
+		Hits:    atomic.LoadUint64(&c.hits),
+		Misses:  atomic.LoadUint64(&c.misses),
+		Entries: c.cache.Len(),
+	}
+}
+
+func (c *CachedDatastore) GetProduct(ctx context.Context, productID int64, selection app.Selection) (*ProductFetchModel, error) {
+	tags := []string{cache.ProductTag(productID), allProductsTag}
+	if selection.Expands("category") {
+		tags = append(tags, expandsCategoryTag)
+	}
+	product := new(ProductFetchModel)
+	err := c.read(ctx, "product:"+selectionKey(productID, selection), tags, product, func(ctx context.Context) (interface{}, error) {
+		return c.DatastoreIface.GetProduct(ctx, productID, selection)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+func (c *CachedDatastore) GetCategory(ctx context.Context, categoryID int64, selection app.Selection) (*CategoryFetchModel, error) {
+	tags := []string{cache.CategoryTag(categoryID)}
+	if selection.Expands("products") {
+		tags = append(tags, expandsProductsTag)
+	}
+	category := new(CategoryFetchModel)
+	err := c.read(ctx, "category:"+selectionKey(categoryID, selection), tags, category, func(ctx context.Context) (interface{}, error) {
+		return c.DatastoreIface.GetCategory(ctx, categoryID, selection)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// gob decodes empty slices as nil while an expanded category always has a products list
+	if selection.Expands("products") && category.Products == nil {
+		category.Products = make([]*ProductFetchModel, 0)
+	}
+	return category, nil
+}
+
+func (c *CachedDatastore) CreateProduct(ctx context.Context, product ProductCreateModel) (int64, error) {
+	productID, err := c.DatastoreIface.CreateProduct(ctx, product)
+	if err == nil {
+		c.invalidate(ctx, expandsProductsTag)
+	}
+	return productID, err
+}
+
+func (c *CachedDatastore) UpdateProduct(ctx context.Context, productID int64, product ProductCreateModel) error {
+	err := c.DatastoreIface.UpdateProduct(ctx, productID, product)
+	if err == nil {
+		c.invalidate(ctx, cache.ProductTag(productID), expandsProductsTag)
+	}
+	return err
+}
+
+func (c *CachedDatastore) DeleteProduct(ctx context.Context, productID int64) error {
+	err := c.DatastoreIface.DeleteProduct(ctx, productID)
+	if err == nil {
+		c.invalidate(ctx, cache.ProductTag(productID), expandsProductsTag)
+	}
+	return err
+}
+
+func (c *CachedDatastore) AssignProductsToCategory(ctx context.Context, categoryID int64, productsCategory ProductsCategoryUpdateModel) error {
+	err := c.DatastoreIface.AssignProductsToCategory(ctx, categoryID, productsCategory)
+	if err == nil {
+		tags := []string{expandsProductsTag}
+		for _, productID := range productsCategory {
+			tags = append(tags, cache.ProductTag(productID))
+		}
+		c.invalidate(ctx, tags...)
+	}
+	return err
+}
+
+func (c *CachedDatastore) SaveProductTranslation(ctx context.Context, productID int64, locale string, translation ProductTranslationModel) error {
+	err := c.DatastoreIface.SaveProductTranslation(ctx, productID, locale, translation)
+	if err == nil {
+		c.invalidate(ctx, cache.ProductTag(productID), expandsProductsTag)
+	}
+	return err
+}
+
+func (c *CachedDatastore) DeleteProductTranslation(ctx context.Context, productID int64, locale string) error {
+	err := c.DatastoreIface.DeleteProductTranslation(ctx, productID, locale)
+	if err == nil {
+		c.invalidate(ctx, cache.ProductTag(productID), expandsProductsTag)
+	}
+	return err
+}
+
+func (c *CachedDatastore) UpdateCategory(ctx context.Context, categoryID int64, category CategoryCreateModel) error {
+	err := c.DatastoreIface.UpdateCategory(ctx, categoryID, category)
+	if err == nil {
+		c.invalidate(ctx, cache.CategoryTag(categoryID), expandsCategoryTag)
+	}
+	return err
+}
+
+func (c *CachedDatastore) SaveCategoryTranslation(ctx context.Context, categoryID int64, locale string, translation CategoryTranslationModel) error {
+	err := c.DatastoreIface.SaveCategoryTranslation(ctx, categoryID, locale, translation)
+	if err == nil {
+		c.invalidate(ctx, cache.CategoryTag(categoryID), expandsCategoryTag)
+	}
+	return err
+}
+
+func (c *CachedDatastore) DeleteCategoryTranslation(ctx context.Context, categoryID int64, locale string) error {
+	err := c.DatastoreIface.DeleteCategoryTranslation(ctx, categoryID, locale)
+	if err == nil {
+		c.invalidate(ctx, cache.CategoryTag(categoryID), expandsCategoryTag)
+	}
+	return err
+}
+
+func (c *CachedDatastore) DeleteCategory(ctx context.Context, categoryID int64) error {
+	err := c.DatastoreIface.DeleteCategory(ctx, categoryID)
+	if err == nil {
+		// the category's products are left without a category
+		c.invalidate(ctx, cache.CategoryTag(categoryID), allProductsTag)
+	}
+	return err
+}
+
+// read decodes the cached entity of the key into target. On a miss the entity is loaded once,
+// no matter how many concurrent reads of the same key miss, and gets cached with the provided tags.
+// The load runs detached from the cancellation of the read that started it, so that a cancelled read
+// does not fail the others waiting on it, and reads after a write never join a load started before it.
+func (c *CachedDatastore) read(ctx context.Context, key string, tags []string, target interface{}, load func(context.Context) (interface{}, error)) error {
+	if value, found, _ := c.cache.Get(ctx, key); found {
+		atomic.AddUint64(&c.hits, 1)
+		return decodeEntity(value, target)
+	}
+	atomic.AddUint64(&c.misses, 1)
+	generation := atomic.LoadUint64(&c.generation)
+	loadCtx := context.WithoutCancel(ctx)
+	results := c.group.DoChan(fmt.Sprintf("%d|%s", generation, key), func() (interface{}, error) {
+		entity, err := load(loadCtx)
+		if err != nil {
+			return nil, err
+		}
+		value, err := encodeEntity(entity)
+		if err != nil {
+			return nil, &app.Error{Op: "repositories.CachedDatastore.read", Code: app.EINTERNAL, Err: err}
+		}
+		c.mu.Lock()
+		if generation == atomic.LoadUint64(&c.generation) {
+			c.cache.Set(loadCtx, key, value, c.ttl, tags)
+		}
+		c.mu.Unlock()
+		return value, nil
+	})
+	select {
+	case result := <-results:
+		if result.Err != nil {
+			return result.Err
+		}
+		return decodeEntity(result.Val.([]byte), target)
+	case <-ctx.Done():
+		return &app.Error{Op: "repositories.CachedDatastore.read", Err: ctx.Err()}
+	}
+}
+
+func (c *CachedDatastore) invalidate(ctx context.Context, tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	atomic.AddUint64(&c.generation, 1)
+	c.cache.Invalidate(ctx, tags...)
+}
+
+// selectionKey identifies an entity read by the entity's id and the selection's fields, expansions and locales
+func selectionKey(id int64, selection app.Selection) string {
+	return fmt.Sprintf("%d|%s|%s|%d|%s", id, selection.Fields, selection.Expand, selection.ExpandLimit, strings.Join(selection.Locales, ","))
+}
+
+// encodeEntity serializes the entity so that callers of the cache never share the same instance
+func encodeEntity(entity interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(entity)
+	return buf.Bytes(), err
+}
+
+func decodeEntity(value []byte, target interface{}) error {
+	if err := gob.NewDecoder(bytes.NewReader(value)).Decode(target); err != nil {
+		return &app.Error{Op: "repositories.decodeEntity", Code: app.EINTERNAL, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mzampetakis/prods-api/api/app"
+)
+
+type DatastoreMock struct {
+	DatastoreIface
+	productReads  int32
+	categoryReads int32
+	readDelay     time.Duration
+}
+
+func (db *DatastoreMock) GetProduct(ctx context.Context, productID int64, selection app.Selection) (*ProductFetchModel, error) {
+	atomic.AddInt32(&db.productReads, 1)
+	select {
+	case <-time.After(db.readDelay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if productID == 404 {
+		return nil, &app.Error{Op: "repositories.GetProduct", Code: app.EPRODUCTNOTFOUND}
+	}
+	title := "some title"
+	return &ProductFetchModel{ID: productID, Title: &title}, nil
+}
+
+func (db *DatastoreMock) GetCategory(ctx context.Context, categoryID int64, selection app.Selection) (*CategoryFetchModel, error) {
+	atomic.AddInt32(&db.categoryReads, 1)
+	category := &CategoryFetchModel{ID: categoryID}
+	if selection.Expands("products") {
+		category.Products = make([]*ProductFetchModel, 0)
+	}
+	return category, nil
+}
+
+func (db *DatastoreMock) UpdateProduct(ctx context.Context, productID int64, product ProductCreateModel) error {
+	return nil
+}
+
+func (db *DatastoreMock) UpdateCategory(ctx context.Context, categoryID int64, category CategoryCreateModel) error {
+	return nil
+}
+
+func TestCachedDatastore_GetProduct(t *testing.T) {
+	//Prepare
+	ctx := context.Background()
+	db := &DatastoreMock{}
+	cachedDB := NewCachedDatastore(db, time.Minute, 10)
+
+	//Act
+	first, _ := cachedDB.GetProduct(ctx, 1, app.Selection{})
+	*first.Title = "changed by the caller"
+	second, _ := cachedDB.GetProduct(ctx, 1, app.Selection{})
+	cachedDB.GetProduct(ctx, 1, app.Selection{Fields: "title"})
+	cachedDB.UpdateProduct(ctx, 1, ProductCreateModel{})
+	cachedDB.GetProduct(ctx, 1, app.Selection{})
+	_, err := cachedDB.GetProduct(ctx, 404, app.Selection{})
+
+	//Assert
+	if *second.Title != "some title" {
+		t.Errorf("Expected cached product to be isolated from callers but got title %s", *second.Title)
+	}
+	if app.ErrorCode(err) != app.EPRODUCTNOTFOUND {
+		t.Errorf("Expected error code %s but got %s", app.EPRODUCTNOTFOUND, app.ErrorCode(err))
+	}
+	if db.productReads != 4 {
+		t.Errorf("Expected 4 product reads from the datastore but got %d", db.productReads)
+	}
+	stats := cachedDB.Stats()
+	if stats.Hits != 1 || stats.Misses != 4 {
+		t.Errorf("Expected 1 hit and 4 misses but got %d hits and %d misses", stats.Hits, stats.Misses)
+	}
+}
+
+func TestCachedDatastore_GetProduct_CollapsesConcurrentMisses(t *testing.T) {
+	//Prepare
+	ctx := context.Background()
+	db := &DatastoreMock{readDelay: 50 * time.Millisecond}
+	cachedDB := NewCachedDatastore(db, time.Minute, 10)
+	var wg sync.WaitGroup
+
+	//Act
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cachedDB.GetProduct(ctx, 1, app.Selection{})
+		}()
+	}
+	wg.Wait()
+
+	//Assert
+	if db.productReads != 1 {
+		t.Errorf("Expected 1 product read from the datastore but got %d", db.productReads)
+	}
+}
+
+func TestCachedDatastore_GetCategory_InvalidatedByProductWrites(t *testing.T) {
+	//Prepare
+	ctx := context.Background()
+	db := &DatastoreMock{}
+	cachedDB := NewCachedDatastore(db, time.Minute, 10)
+	expanded := app.Selection{Expand: "products"}
+
+	//Act
+	cachedDB.GetCategory(ctx, 1, app.Selection{})
+	cachedDB.GetCategory(ctx, 1, expanded)
+	cachedDB.UpdateProduct(ctx, 5, ProductCreateModel{})
+	cachedDB.GetCategory(ctx, 1, app.Selection{})
+	category, _ := cachedDB.GetCategory(ctx, 1, expanded)
+
+	//Assert
+	if db.categoryReads != 3 {
+		t.Errorf("Expected 3 category reads from the datastore but got %d", db.categoryReads)
+	}
+	if category.Products == nil {
+		t.Errorf("Expected expanded category to have a products list")
+	}
+}
+
+func TestCachedDatastore_GetProduct_CancelledReadDoesNotFailOthers(t *testing.T) {
+	//Prepare
+	db := &DatastoreMock{readDelay: 50 * time.Millisecond}
+	cachedDB := NewCachedDatastore(db, time.Minute, 10)
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	var cancelledErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	//Act
+	go func() {
+		defer wg.Done()
+		_, cancelledErr = cachedDB.GetProduct(cancelledCtx, 1, app.Selection{})
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	product, err := cachedDB.GetProduct(context.Background(), 1, app.Selection{})
+	wg.Wait()
+
+	//Assert
+	if cancelledErr == nil {
+		t.Errorf("Expected the cancelled read to fail")
+	}
+	if err != nil || product == nil {
+		t.Errorf("Expected the product but got error %v", err)
+	}
+	if db.productReads != 1 {
+		t.Errorf("Expected 1 product read from the datastore but got %d", db.productReads)
+	}
+}
+
+func TestCachedDatastore_GetProduct_ReadAfterWriteDoesNotJoinEarlierLoad(t *testing.T) {
+	//Prepare
+	ctx := context.Background()
+	db := &DatastoreMock{readDelay: 50 * time.Millisecond}
+	cachedDB := NewCachedDatastore(db, time.Minute, 10)
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	//Act
+	go func() {
+		defer wg.Done()
+		cachedDB.GetProduct(ctx, 1, app.Selection{})
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cachedDB.UpdateProduct(ctx, 1, ProductCreateModel{})
+	cachedDB.GetProduct(ctx, 1, app.Selection{})
+	wg.Wait()
+
+	//Assert
+	if db.productReads != 2 {
+		t.Errorf("Expected 2 product reads from the datastore but got %d", db.productReads)
+	}
+}
@@ -5,44 +5,120 @@ import (
 	"database/sql"
 	"fmt"
 	"reflect"
+	"strings"
+	"time"
 
 	"github.com/mzampetakis/prods-api/api/app"
 )
 
 type CategoryFetchModel struct {
-	ID        int64   `json:"id"`
-	Title     *string `json:"title"`
-	ImageURL  *string `json:"image_url"`
-	Sort      *int64  `json:"sort"`
-	CreatedAt string  `json:"created_at"`
-	UpdatedAt string  `json:"updated_at"`
+	ID        int64     `json:"id"`
+	Title     *string   `json:"title"`
+	ImageURL  *string   `json:"image_url"`
+	Sort      *int64    `json:"sort"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Products is only populated when the products relationship is expanded
+	Products []*ProductFetchModel `json:"-"`
+	// ContentLocales are the locales of the selected localized fields, where the default content is an empty locale
+	ContentLocales []string `json:"-"`
 }
 
+// CategoryCreateModel declares the fields that every category must have, which the services check along with
+// its business rules. The rules of the requests are declared on their DTOs.
 type CategoryCreateModel struct {
-	Title    *string `json:"title"`
+	Title    *string `json:"title" validate:"required"`
 	ImageURL *string `json:"image_url"`
 	Sort     *int64  `json:"sort"`
 }
 
+// categoryColumns are all the columns of a category as used when a category is joined
+var categoryColumns = []string{"id", "title", "image_url", "sort", "created_at", "updated_at"}
+
+// joinedCategory holds a category fetched through a LEFT JOIN where all columns may be NULL
+type joinedCategory struct {
+	ID        sql.NullInt64
+	Title     *string
+	ImageURL  *string
+	Sort      *int64
+	CreatedAt sql.NullTime
+	UpdatedAt sql.NullTime
+}
+
+func (categ *joinedCategory) scanTargets() []interface{} {
+	return []interface{}{&categ.ID, &categ.Title, &categ.ImageURL, &categ.Sort, &categ.CreatedAt, &categ.UpdatedAt}
+}
+
+func (categ *joinedCategory) toModel() *CategoryFetchModel {
+	if !categ.ID.Valid {
+		return nil
+	}
+	return &CategoryFetchModel{
+		ID:        categ.ID.Int64,
+		Title:     categ.Title,
+		ImageURL:  categ.ImageURL,
+		Sort:      categ.Sort,
+		CreatedAt: categ.CreatedAt.Time,
+		UpdatedAt: categ.UpdatedAt.Time,
+	}
+}
+
 func isCategoryValidField(field string) bool {
-	var model CategoryFetchModel
-	val := reflect.ValueOf(model)
-	for i := 0; i < val.Type().NumField(); i++ {
-		existing := val.Type().Field(i).Tag.Get("json")
-		if field == existing {
-			return true
+	return isModelField(reflect.TypeOf(CategoryFetchModel{}), field)
+}
+
+// expandCategoriesProducts fetches with a single query up to limit products of each of the provided categories.
+// As MySQL 5.7 has no window functions, the products of each category are limited by a subquery of their own.
+func (db *DB) expandCategoriesProducts(ctx context.Context, categs []*CategoryFetchModel, limit int) error {
+	if len(categs) == 0 {
+		return nil
+	}
+	categsByID := make(map[int64]*CategoryFetchModel)
+	categIDs := make([]interface{}, 0, len(categs))
+	for _, categ := range categs {
+		categ.Products = make([]*ProductFetchModel, 0)
+		categsByID[categ.ID] = categ
+		categIDs = append(categIDs, categ.ID)
+	}
+	columns, _ := selectColumns(ProductFetchModel{}, nil)
+	subquery := fmt.Sprintf("(SELECT %s FROM products p WHERE p.category_id = ? ORDER BY p.id LIMIT %d)", qualifyColumns("p", columns), limit)
+	subqueries := make([]string, len(categIDs))
+	for i := range subqueries {
+		subqueries[i] = subquery
+	}
+	query := strings.Join(subqueries, " UNION ALL ") + " ORDER BY category_id, id"
+	rows, err := db.QueryContext(ctx, query, categIDs...)
+	if err != nil {
+		return &app.Error{Op: "repositories.expandCategoriesProducts", Code: app.EINTERNAL, Err: err, Message: "Could not query Categories' Products from DB"}
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		prod, err := scanProduct(rows, columns, app.Selection{})
+		if err != nil {
+			return &app.Error{Op: "repositories.expandCategoriesProducts", Code: app.EINTERNAL, Err: err, Message: "Could not fetch Categories' Products from DB"}
 		}
+		categ := categsByID[*prod.CategoryID]
+		categ.Products = append(categ.Products, prod)
+	}
+	if err = rows.Err(); err != nil {
+		return &app.Error{Op: "repositories.expandCategoriesProducts", Code: app.EINTERNAL, Err: err, Message: "Could not fetch Categories' Products from DB"}
 	}
-	return false
+	return nil
 }
 
-func (db *DB) GetCategories(ctx context.Context, filter app.Filter) ([]*CategoryFetchModel, error) {
+func (db *DB) GetCategories(ctx context.Context, filter app.Filter, selection app.Selection) ([]*CategoryFetchModel, error) {
 	if !isCategoryValidField(filter.SortBy) {
-		return nil, &app.Error{Op: "repositories.GetCategories", Code: app.EINVALID, Message: "Invalid SortBy field: " + filter.SortBy}
+		return nil, &app.Error{Op: "repositories.GetCategories", Code: app.EINVALIDSORTFIELD, Message: "Invalid SortBy field: " + filter.SortBy}
 	}
-	query := fmt.Sprintf("SELECT id, title, image_url, sort, created_at, updated_at FROM categories ORDER BY %s %s LIMIT %d OFFSET %d",
-		filter.SortBy, filter.SortDirection, filter.Limit, filter.Offset)
-	rows, err := db.QueryContext(ctx, query)
+	columns, err := selectColumns(CategoryFetchModel{}, selection.FieldsList())
+	if err != nil {
+		return nil, &app.Error{Op: "repositories.GetCategories", Err: err}
+	}
+	condition, args := timeRangesCondition("", filter)
+	query := fmt.Sprintf("SELECT %s FROM categories%s ORDER BY %s %s LIMIT %d OFFSET %d",
+		strings.Join(columns, ", "), condition, filter.SortBy, filter.SortDirection, filter.Limit, filter.Offset)
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, &app.Error{Op: "repositories.GetCategories", Code: app.EINTERNAL, Err: err, Message: "Could not query Categories from DB"}
 	}
@@ -51,7 +127,7 @@ func (db *DB) GetCategories(ctx context.Context, filter app.Filter) ([]*Category
 	categs := make([]*CategoryFetchModel, 0)
 	for rows.Next() {
 		categ := new(CategoryFetchModel)
-		err := rows.Scan(&categ.ID, &categ.Title, &categ.ImageURL, &categ.Sort, &categ.CreatedAt, &categ.UpdatedAt)
+		err := rows.Scan(scanTargets(categ, columns)...)
 		if err != nil {
 			return nil, &app.Error{Op: "repositories.GetCategories", Code: app.EINTERNAL, Err: err, Message: "Could not fetch Categories from DB"}
 		}
@@ -60,23 +136,57 @@ func (db *DB) GetCategories(ctx context.Context, filter app.Filter) ([]*Category
 	if err = rows.Err(); err != nil {
 		return nil, &app.Error{Op: "repositories.GetCategories", Code: app.EINTERNAL, Err: err, Message: "Could not fetch Categories from DB"}
 	}
+	if selection.Expands("products") {
+		if err = db.expandCategoriesProducts(ctx, categs, selection.ExpandLimit); err != nil {
+			return nil, &app.Error{Op: "repositories.GetCategories", Err: err}
+		}
+	}
+	if err = db.localizeCategoriesWithProducts(ctx, categs, columns, selection.Locales); err != nil {
+		return nil, &app.Error{Op: "repositories.GetCategories", Err: err}
+	}
 	return categs, nil
 }
 
-func (db *DB) GetCategory(ctx context.Context, categoryID int64) (*CategoryFetchModel, error) {
-	row := db.QueryRowContext(ctx, "SELECT id, title, image_url, sort, created_at, updated_at FROM categories WHERE id= ?",
+func (db *DB) GetCategory(ctx context.Context, categoryID int64, selection app.Selection) (*CategoryFetchModel, error) {
+	columns, err := selectColumns(CategoryFetchModel{}, selection.FieldsList())
+	if err != nil {
+		return nil, &app.Error{Op: "repositories.GetCategory", Err: err}
+	}
+	row := db.QueryRowContext(ctx, "SELECT "+strings.Join(columns, ", ")+" FROM categories WHERE id= ?",
 		categoryID)
 	categ := new(CategoryFetchModel)
-	err := row.Scan(&categ.ID, &categ.Title, &categ.ImageURL, &categ.Sort, &categ.CreatedAt, &categ.UpdatedAt)
+	err = row.Scan(scanTargets(categ, columns)...)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, &app.Error{Op: "repositories.GetCategory", Code: app.ENOTFOUND, Err: err, Message: "Category not found."}
+			return nil, &app.Error{Op: "repositories.GetCategory", Code: app.ECATEGORYNOTFOUND, Err: err, Message: "Category not found."}
 		}
 		return nil, &app.Error{Op: "repositories.GetCategory", Code: app.EINTERNAL, Err: err, Message: "Could not query Category from DB"}
 	}
+	if selection.Expands("products") {
+		if err = db.expandCategoriesProducts(ctx, []*CategoryFetchModel{categ}, selection.ExpandLimit); err != nil {
+			return nil, &app.Error{Op: "repositories.GetCategory", Err: err}
+		}
+	}
+	if err = db.localizeCategoriesWithProducts(ctx, []*CategoryFetchModel{categ}, columns, selection.Locales); err != nil {
+		return nil, &app.Error{Op: "repositories.GetCategory", Err: err}
+	}
 	return categ, nil
 }
 
+// localizeCategoriesWithProducts translates the selected columns of the categories and their expanded products
+// to the first of the locales that each of them is translated to
+func (db *DB) localizeCategoriesWithProducts(ctx context.Context, categs []*CategoryFetchModel, columns []string, locales []string) error {
+	if err := db.localizeCategories(ctx, categs, columns, locales); err != nil {
+		return err
+	}
+	prods := make([]*ProductFetchModel, 0)
+	for _, categ := range categs {
+		prods = append(prods, categ.Products...)
+	}
+	productColumns, _ := selectColumns(ProductFetchModel{}, nil)
+	return db.localizeProducts(ctx, prods, productColumns, locales)
+}
+
 func (db *DB) CreateCategory(ctx context.Context, category CategoryCreateModel) (int64, error) {
 	res, err := db.ExecContext(ctx, "INSERT INTO categories (title, image_url, sort) VALUES (?, ?, ?)",
 		category.Title, category.ImageURL, category.Sort)
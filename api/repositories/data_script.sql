@@ -1,3 +1,6 @@
+TRUNCATE `category_translations`;
+TRUNCATE `product_translations`;
+TRUNCATE `product_images`;
 TRUNCATE `products`;
 TRUNCATE `categories`;
 
@@ -24,4 +27,19 @@ VALUES
 	(11,2,'OLED 24','https://product11.image',34000,'Description of a really good monitor'),
 	(12,2,'OLED 27','https://product12.image',37000,'VFM monitor\n'),
 	(13,2,'OLED 30','https://product13.image',40000,NULL),
-	(14,5,'1GB','https://product10.image',1050,'The biggest flash drive ever!');
\ No newline at end of file
+	(14,5,'1GB','https://product10.image',1050,'The biggest flash drive ever!');
+
+INSERT INTO `category_translations` (`category_id`, `locale`, `title`)
+VALUES
+	(1,'el','Φορητοί υπολογιστές'),
+	(1,'de','Laptops'),
+	(2,'el','Οθόνες'),
+	(2,'de','Monitore'),
+	(3,'el','Πληκτρολόγια'),
+	(3,'de','Tastaturen');
+
+INSERT INTO `product_translations` (`product_id`, `locale`, `title`, `description`)
+VALUES
+	(1,'el','Laptop 15','Περιγραφή του Laptop1'),
+	(1,'de','Laptop 15','Beschreibung von Laptop1'),
+	(7,'de','Ultrasharp 24','Beschreibung eines wirklich guten Monitors');
@@ -3,8 +3,7 @@ package repositories
 import (
 	"context"
 	"database/sql"
-	"io/ioutil"
-	"strings"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/mzampetakis/prods-api/api/app"
@@ -12,22 +11,40 @@ import (
 )
 
 type DatastoreIface interface {
-	GetProducts(context.Context, app.Filter) ([]*ProductFetchModel, error)
-	GetProduct(context.Context, int64) (*ProductFetchModel, error)
+	GetProducts(context.Context, app.Filter, app.Selection) ([]*ProductFetchModel, error)
+	GetProduct(context.Context, int64, app.Selection) (*ProductFetchModel, error)
 	CreateProduct(context.Context, ProductCreateModel) (int64, error)
 	UpdateProduct(context.Context, int64, ProductCreateModel) error
 	DeleteProduct(context.Context, int64) error
 	AssignProductsToCategory(context.Context, int64, ProductsCategoryUpdateModel) error
 
-	GetCategories(context.Context, app.Filter) ([]*CategoryFetchModel, error)
-	GetCategory(context.Context, int64) (*CategoryFetchModel, error)
+	GetProductImages(context.Context, int64) ([]*ProductImageFetchModel, error)
+	GetProductImage(context.Context, int64, int64) (*ProductImageFetchModel, error)
+	CreateProductImages(context.Context, []ProductImageCreateModel) ([]int64, error)
+	UpdateProductImagesOrder(context.Context, int64, ProductImagesOrderUpdateModel) error
+	DeleteProductImage(context.Context, int64, int64) error
+
+	GetCategories(context.Context, app.Filter, app.Selection) ([]*CategoryFetchModel, error)
+	GetCategory(context.Context, int64, app.Selection) (*CategoryFetchModel, error)
 	CreateCategory(context.Context, CategoryCreateModel) (int64, error)
 	UpdateCategory(context.Context, int64, CategoryCreateModel) error
 	DeleteCategory(context.Context, int64) error
+
+	SaveProductTranslation(context.Context, int64, string, ProductTranslationModel) error
+	DeleteProductTranslation(context.Context, int64, string) error
+	SaveCategoryTranslation(context.Context, int64, string, CategoryTranslationModel) error
+	DeleteCategoryTranslation(context.Context, int64, string) error
+	GetMissingTranslations(context.Context, []string) ([]*MissingTranslationModel, error)
+
+	GetImageURLs(context.Context) ([]*ImageURLModel, error)
+	GetBrokenImages(context.Context) ([]*BrokenImageFetchModel, error)
+	ReplaceBrokenImages(context.Context, []BrokenImageCreateModel) error
 }
 
 type DB struct {
 	*sql.DB
+	// StatementTimeout bounds each statement whose context does not carry a statement timeout. Zero means no bound.
+	StatementTimeout time.Duration
 }
 
 func NewDB(DBType string, DBURL string) (*DB, error) {
@@ -38,42 +55,29 @@ func NewDB(DBType string, DBURL string) (*DB, error) {
 		return nil, err
 	}
 	if err = db.Ping(); err != nil {
+		db.Close()
 		return nil, err
 	}
-	myDB := &DB{db}
+	myDB := &DB{DB: db}
 	return myDB, nil
 }
 
-func (db *DB) MigrateDB() error {
-	logrus.Info("Migrating DB schema...")
-	stmt, err := ioutil.ReadFile("api/repositories/schema_script.sql")
-	if err != nil {
-		panic(err)
-	}
-	db.executeCommands(string(stmt))
-	logrus.Info("DB Schema Migrated.")
-	return nil
-}
-
-func (db *DB) SeedData() error {
-	logrus.Info("Seeding sample data into DB...")
-	stmt, err := ioutil.ReadFile("api/repositories/data_script.sql")
-	if err != nil {
-		panic(err)
-	}
-	db.executeCommands(string(stmt))
-	logrus.Info("Sample Data Seeding Completed.")
-	return nil
-}
-
-func (db *DB) executeCommands(statements string) {
-	for _, statement := range strings.Split(statements, ";") {
-		if len(strings.Trim(statement, " ")) == 0 {
-			continue
+// ConnectDB connects to the DB retrying up to retries times on failure. The wait between two attempts
+// starts at backoff and doubles after each failed attempt, up to maxBackoff.
+func ConnectDB(DBType string, DBURL string, retries int, backoff time.Duration) (*DB, error) {
+	const maxBackoff = 30 * time.Second
+	for attempt := 0; ; attempt++ {
+		db, err := NewDB(DBType, DBURL)
+		if err == nil {
+			return db, nil
+		}
+		if attempt >= retries {
+			return nil, err
 		}
-		_, err := db.Exec(statement)
-		if err != nil {
-			logrus.Warn(err.Error())
+		logrus.Warnf("Could not connect to DB (attempt %d of %d), retrying in %s: %s", attempt+1, retries+1, backoff, err.Error())
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
 		}
 	}
 }
@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+// unreachableDriver is a database/sql driver whose connections fail to ping and count how many of them are closed
+type unreachableDriver struct {
+	opened int
+	closed int
+}
+
+func (d *unreachableDriver) Open(name string) (driver.Conn, error) {
+	d.opened++
+	return &unreachableConn{driver: d}, nil
+}
+
+type unreachableConn struct {
+	driver *unreachableDriver
+}
+
+func (c *unreachableConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+
+func (c *unreachableConn) Close() error {
+	c.driver.closed++
+	return nil
+}
+
+func (c *unreachableConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+func (c *unreachableConn) Ping(ctx context.Context) error {
+	return errors.New("database is unreachable")
+}
+
+var pingDriver = &unreachableDriver{}
+
+func init() {
+	sql.Register("unreachable", pingDriver)
+}
+
+func TestNewDB_ClosesPoolWhenPingFails(t *testing.T) {
+	//Prepare
+	pingDriver.opened, pingDriver.closed = 0, 0
+
+	//Act
+	db, err := NewDB("unreachable", "")
+
+	//Assert
+	if err == nil || db != nil {
+		t.Fatalf("Expected a ping error but got %v", err)
+	}
+	if pingDriver.opened == 0 || pingDriver.closed != pingDriver.opened {
+		t.Errorf("Expected all %d opened connections to be closed but %d were", pingDriver.opened, pingDriver.closed)
+	}
+}
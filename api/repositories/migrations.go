@@ -0,0 +1,169 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+const (
+	// SchemaScript creates the tables of the DB schema. Its statements other than CREATE TABLE and DROP TABLE
+	// upgrade the tables created by earlier versions of the schema and are executed by every migration,
+	// so they must have no effect on up to date tables.
+	SchemaScript = "api/repositories/schema_script.sql"
+	// DataScript replaces the DB's data with sample data
+	DataScript = "api/repositories/data_script.sql"
+)
+
+var (
+	createTable = regexp.MustCompile(`(?i)^CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?` + "`?" + `(\w+)`)
+	dropTable   = regexp.MustCompile(`(?i)^DROP\s+TABLE\s+`)
+)
+
+// MigrationStatus describes whether a table of the DB schema exists
+type MigrationStatus struct {
+	Table   string
+	Applied bool
+}
+
+// MigrationsStatus returns the status of each table of the DB schema in the order of their creation
+func (db *DB) MigrationsStatus(ctx context.Context) ([]MigrationStatus, error) {
+	statements, err := readScript(SchemaScript)
+	if err != nil {
+		return nil, err
+	}
+	status := make([]MigrationStatus, 0)
+	for _, statement := range statements {
+		match := createTable.FindStringSubmatch(statement)
+		if match == nil {
+			continue
+		}
+		var count int
+		err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?",
+			match[1]).Scan(&count)
+		if err != nil {
+			return nil, err
+		}
+		status = append(status, MigrationStatus{Table: match[1], Applied: count > 0})
+	}
+	return status, nil
+}
+
+// PendingMigrations returns the tables of the schema that do not exist in the DB
+func (db *DB) PendingMigrations(ctx context.Context) ([]string, error) {
+	status, err := db.MigrationsStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending := make([]string, 0)
+	for _, table := range status {
+		if !table.Applied {
+			pending = append(pending, table.Table)
+		}
+	}
+	return pending, nil
+}
+
+// MigrateUp creates the tables of the schema that do not exist in the DB, upgrades the existing ones
+// and returns the created tables
+func (db *DB) MigrateUp(ctx context.Context) ([]string, error) {
+	pending, err := db.PendingMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statements, err := readScript(SchemaScript)
+	if err != nil {
+		return nil, err
+	}
+	toCreate := make([]string, 0)
+	upgrades := make([]string, 0)
+	for _, statement := range statements {
+		if match := createTable.FindStringSubmatch(statement); match != nil && contains(pending, match[1]) {
+			toCreate = append(toCreate, statement)
+		} else if match == nil && !dropTable.MatchString(statement) {
+			upgrades = append(upgrades, statement)
+		}
+	}
+	if err = db.execStatements(ctx, append(toCreate, upgrades...)); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// MigrateDown drops all the tables of the schema along with their data
+func (db *DB) MigrateDown(ctx context.Context) error {
+	statements, err := readScript(SchemaScript)
+	if err != nil {
+		return err
+	}
+	toDrop := make([]string, 0)
+	for _, statement := range statements {
+		if dropTable.MatchString(statement) {
+			toDrop = append(toDrop, statement)
+		}
+	}
+	return db.execStatements(ctx, toDrop)
+}
+
+// Seed executes the SQL statements of the provided script, e.g. DataScript
+func (db *DB) Seed(ctx context.Context, path string) error {
+	statements, err := readScript(path)
+	if err != nil {
+		return err
+	}
+	return db.execStatements(ctx, statements)
+}
+
+// execStatements executes the statements in order on a single connection with the foreign key checks disabled,
+// so that tables can be truncated and dropped regardless of their references. It stops at the first failure.
+func (db *DB) execStatements(ctx context.Context, statements []string) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err = conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 0"); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), "SET FOREIGN_KEY_CHECKS = 1")
+	for _, statement := range statements {
+		if err = execTraced(ctx, conn, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func execTraced(ctx context.Context, conn *sql.Conn, statement string) error {
+	ctx, span := startQuerySpan(ctx, statement)
+	defer span.End()
+	_, err := conn.ExecContext(ctx, statement)
+	span.SetError(err)
+	return err
+}
+
+// readScript splits an SQL script to its statements
+func readScript(path string) ([]string, error) {
+	script, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	statements := make([]string, 0)
+	for _, statement := range strings.Split(string(script), ";") {
+		if statement = strings.TrimSpace(statement); statement != "" {
+			statements = append(statements, statement)
+		}
+	}
+	return statements, nil
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
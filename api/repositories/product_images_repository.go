@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"time"
+
+	"github.com/mzampetakis/prods-api/api/app"
+)
+
+type ProductImageFetchModel struct {
+	ID          int64     `json:"id"`
+	ProductID   int64     `json:"product_id"`
+	Position    int64     `json:"position"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	StorageKey  string    `json:"storage_key"`
+	CreatedAt   time.Time `json:"created_at"`
+	// Thumbnails holds the storage keys of the image's thumbnails by size name
+	Thumbnails map[string]string `json:"-"`
+}
+
+type ProductImageCreateModel struct {
+	ProductID   int64  `json:"product_id"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	StorageKey  string `json:"storage_key"`
+}
+
+// ProductImageUploadModel is an uploaded image file before it gets stored
+type ProductImageUploadModel struct {
+	Filename string
+	Data     io.Reader
+}
+
+type ProductImagesOrderUpdateModel []int64
+
+func (db *DB) GetProductImages(ctx context.Context, productID int64) ([]*ProductImageFetchModel, error) {
+	rows, err := db.QueryContext(ctx, "SELECT id, product_id, position, content_type, size, storage_key, created_at FROM product_images WHERE product_id = ? ORDER BY position, id",
+		productID)
+	if err != nil {
+		return nil, &app.Error{Op: "repositories.GetProductImages", Code: app.EINTERNAL, Err: err, Message: "Could not query Product Images from DB"}
+	}
+	defer rows.Close()
+
+	images := make([]*ProductImageFetchModel, 0)
+	for rows.Next() {
+		image := new(ProductImageFetchModel)
+		err := rows.Scan(&image.ID, &image.ProductID, &image.Position, &image.ContentType, &image.Size, &image.StorageKey, &image.CreatedAt)
+		if err != nil {
+			return nil, &app.Error{Op: "repositories.GetProductImages", Code: app.EINTERNAL, Err: err, Message: "Could not fetch Product Images from DB"}
+		}
+		images = append(images, image)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, &app.Error{Op: "repositories.GetProductImages", Code: app.EINTERNAL, Err: err, Message: "Could not fetch Product Images from DB"}
+	}
+	return images, nil
+}
+
+func (db *DB) GetProductImage(ctx context.Context, productID int64, imageID int64) (*ProductImageFetchModel, error) {
+	row := db.QueryRowContext(ctx, "SELECT id, product_id, position, content_type, size, storage_key, created_at FROM product_images WHERE product_id = ? AND id = ?",
+		productID, imageID)
+	image := new(ProductImageFetchModel)
+	err := row.Scan(&image.ID, &image.ProductID, &image.Position, &image.ContentType, &image.Size, &image.StorageKey, &image.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &app.Error{Op: "repositories.GetProductImage", Code: app.EIMAGENOTFOUND, Err: err, Message: "Product Image not found."}
+		}
+		return nil, &app.Error{Op: "repositories.GetProductImage", Code: app.EINTERNAL, Err: err, Message: "Could not query Product Image from DB"}
+	}
+	return image, nil
+}
+
+// CreateProductImages inserts the images in a single transaction after the product's images, in their order,
+// and returns their ids. Either all the images are inserted or none of them.
+func (db *DB) CreateProductImages(ctx context.Context, images []ProductImageCreateModel) ([]int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, &app.Error{Op: "repositories.CreateProductImages", Code: app.EINTERNAL, Err: err, Message: "Could not insert Product Images to DB"}
+	}
+	insertedIDs := make([]int64, 0, len(images))
+	for _, image := range images {
+		res, err := tx.ExecContext(ctx, "INSERT INTO product_images (product_id, position, content_type, size, storage_key) "+
+			"SELECT ?, COALESCE(MAX(position), 0) + 1, ?, ?, ? FROM product_images WHERE product_id = ?",
+			image.ProductID, image.ContentType, image.Size, image.StorageKey, image.ProductID)
+		if err != nil {
+			tx.Rollback()
+			return nil, &app.Error{Op: "repositories.CreateProductImages", Code: app.EINTERNAL, Err: err, Message: "Could not execute insert Product Image to DB"}
+		}
+		insertedID, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			return nil, &app.Error{Op: "repositories.CreateProductImages", Code: app.EINTERNAL, Err: err, Message: "Could not insert Product Image to DB"}
+		}
+		insertedIDs = append(insertedIDs, insertedID)
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, &app.Error{Op: "repositories.CreateProductImages", Code: app.EINTERNAL, Err: err, Message: "Could not insert Product Images to DB"}
+	}
+	return insertedIDs, nil
+}
+
+// UpdateProductImagesOrder sets the position of each image according to its index in the provided order
+func (db *DB) UpdateProductImagesOrder(ctx context.Context, productID int64, order ProductImagesOrderUpdateModel) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return &app.Error{Op: "repositories.UpdateProductImagesOrder", Code: app.EINTERNAL, Err: err, Message: "Could not update Product Images' order in DB"}
+	}
+	for position, imageID := range order {
+		_, err = tx.ExecContext(ctx, "UPDATE product_images SET position=? WHERE product_id = ? AND id = ?",
+			position+1, productID, imageID)
+		if err != nil {
+			tx.Rollback()
+			return &app.Error{Op: "repositories.UpdateProductImagesOrder", Code: app.EINTERNAL, Err: err, Message: "Could not update Product Images' order in DB"}
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return &app.Error{Op: "repositories.UpdateProductImagesOrder", Code: app.EINTERNAL, Err: err, Message: "Could not update Product Images' order in DB"}
+	}
+	return nil
+}
+
+func (db *DB) DeleteProductImage(ctx context.Context, productID int64, imageID int64) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM product_images WHERE product_id = ? AND id = ?",
+		productID, imageID)
+	if err != nil {
+		return &app.Error{Op: "repositories.DeleteProductImage", Code: app.EINTERNAL, Err: err, Message: "Could not delete Product Image from DB"}
+	}
+	return nil
+}
@@ -6,49 +6,86 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/mzampetakis/prods-api/api/app"
 )
 
 type ProductFetchModel struct {
-	ID          int64   `json:"id"`
-	CategoryID  *int64  `json:"category_id"`
-	Title       *string `json:"title"`
-	ImageURL    *string `json:"image_url"`
-	Price       *int64  `json:"price"`
-	Description *string `json:"description"`
-	CreatedAt   string  `json:"created_at"`
-	UpdatedAt   string  `json:"updated_at"`
+	ID          int64     `json:"id"`
+	CategoryID  *int64    `json:"category_id"`
+	Title       *string   `json:"title"`
+	ImageURL    *string   `json:"image_url"`
+	Price       *int64    `json:"price"`
+	Description *string   `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	// Category is only populated when the category relationship is expanded
+	Category *CategoryFetchModel `json:"-"`
+	// ContentLocales are the locales of the selected localized fields, where the default content is an empty locale
+	ContentLocales []string `json:"-"`
 }
 
+// ProductCreateModel declares the fields that every product must have, which the services check along with
+// its business rules. The rules of the requests are declared on their DTOs.
 type ProductCreateModel struct {
 	CategoryID  *int64  `json:"category_id"`
-	Title       *string `json:"title"`
+	Title       *string `json:"title" validate:"required"`
 	ImageURL    *string `json:"image_url"`
-	Price       *int64  `json:"price"`
+	Price       *int64  `json:"price" validate:"required"`
 	Description *string `json:"description"`
 }
 
 type ProductsCategoryUpdateModel []int64
 
 func isProductsValidField(field string) bool {
-	var model ProductFetchModel
-	val := reflect.ValueOf(model)
-	for i := 0; i < val.Type().NumField(); i++ {
-		if field == val.Type().Field(i).Tag.Get("json") {
-			return true
-		}
+	return isModelField(reflect.TypeOf(ProductFetchModel{}), field)
+}
+
+// productsQuery builds the select query for the requested product fields, joining the category when expanded
+func productsQuery(selection app.Selection) (string, []string, error) {
+	columns, err := selectColumns(ProductFetchModel{}, selection.FieldsList())
+	if err != nil {
+		return "", nil, err
 	}
-	return false
+	query := "SELECT " + qualifyColumns("p", columns)
+	if selection.Expands("category") {
+		query += ", " + qualifyColumns("c", categoryColumns) + " FROM products p LEFT JOIN categories c ON c.id = p.category_id"
+	} else {
+		query += " FROM products p"
+	}
+	return query, columns, nil
 }
 
-func (db *DB) GetProducts(ctx context.Context, filter app.Filter) ([]*ProductFetchModel, error) {
+func scanProduct(scanner interface{ Scan(...interface{}) error }, columns []string, selection app.Selection) (*ProductFetchModel, error) {
+	prod := new(ProductFetchModel)
+	targets := scanTargets(prod, columns)
+	var category *joinedCategory
+	if selection.Expands("category") {
+		category = new(joinedCategory)
+		targets = append(targets, category.scanTargets()...)
+	}
+	if err := scanner.Scan(targets...); err != nil {
+		return nil, err
+	}
+	if category != nil {
+		prod.Category = category.toModel()
+	}
+	return prod, nil
+}
+
+func (db *DB) GetProducts(ctx context.Context, filter app.Filter, selection app.Selection) ([]*ProductFetchModel, error) {
 	if !isProductsValidField(filter.SortBy) {
-		return nil, &app.Error{Op: "repositories.GetProducts", Code: app.EINVALID, Message: "Invalid SortBy field: " + filter.SortBy}
+		return nil, &app.Error{Op: "repositories.GetProducts", Code: app.EINVALIDSORTFIELD, Message: "Invalid SortBy field: " + filter.SortBy}
+	}
+	selectQuery, columns, err := productsQuery(selection)
+	if err != nil {
+		return nil, &app.Error{Op: "repositories.GetProducts", Err: err}
 	}
-	query := fmt.Sprintf("SELECT id, category_id, title, image_url, price, description, created_at, updated_at FROM products ORDER BY %s %s LIMIT %d OFFSET %d",
-		filter.SortBy, filter.SortDirection, filter.Limit, filter.Offset)
-	rows, err := db.QueryContext(ctx, query)
+	condition, args := timeRangesCondition("p.", filter)
+	query := fmt.Sprintf("%s%s ORDER BY p.%s %s LIMIT %d OFFSET %d",
+		selectQuery, condition, filter.SortBy, filter.SortDirection, filter.Limit, filter.Offset)
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, &app.Error{Op: "repositories.GetProducts", Code: app.EINTERNAL, Err: err, Message: "Could not query Products from DB"}
 	}
@@ -56,8 +93,7 @@ func (db *DB) GetProducts(ctx context.Context, filter app.Filter) ([]*ProductFet
 
 	prods := make([]*ProductFetchModel, 0)
 	for rows.Next() {
-		prod := new(ProductFetchModel)
-		err := rows.Scan(&prod.ID, &prod.CategoryID, &prod.Title, &prod.ImageURL, &prod.Price, &prod.Description, &prod.CreatedAt, &prod.UpdatedAt)
+		prod, err := scanProduct(rows, columns, selection)
 		if err != nil {
 			return nil, &app.Error{Op: "repositories.GetProducts", Code: app.EINTERNAL, Err: err, Message: "Could not fetch Products from DB"}
 		}
@@ -66,24 +102,48 @@ func (db *DB) GetProducts(ctx context.Context, filter app.Filter) ([]*ProductFet
 	if err = rows.Err(); err != nil {
 		return nil, &app.Error{Op: "repositories.GetProducts", Code: app.EINTERNAL, Err: err, Message: "Could not fetch Products from DB"}
 	}
+	if err = db.localizeProductsWithCategories(ctx, prods, columns, selection.Locales); err != nil {
+		return nil, &app.Error{Op: "repositories.GetProducts", Err: err}
+	}
 	return prods, nil
 }
 
-func (db *DB) GetProduct(ctx context.Context, productID int64) (*ProductFetchModel, error) {
-	row := db.QueryRowContext(ctx, "SELECT id, category_id, title, image_url, price, description, created_at, updated_at FROM products WHERE id= ?",
+func (db *DB) GetProduct(ctx context.Context, productID int64, selection app.Selection) (*ProductFetchModel, error) {
+	selectQuery, columns, err := productsQuery(selection)
+	if err != nil {
+		return nil, &app.Error{Op: "repositories.GetProduct", Err: err}
+	}
+	row := db.QueryRowContext(ctx, selectQuery+" WHERE p.id= ?",
 		productID)
 
-	prod := new(ProductFetchModel)
-	err := row.Scan(&prod.ID, &prod.CategoryID, &prod.Title, &prod.ImageURL, &prod.Price, &prod.Description, &prod.CreatedAt, &prod.UpdatedAt)
+	prod, err := scanProduct(row, columns, selection)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, &app.Error{Op: "repositories.GetProduct", Code: app.ENOTFOUND, Err: err, Message: "Product not found."}
+			return nil, &app.Error{Op: "repositories.GetProduct", Code: app.EPRODUCTNOTFOUND, Err: err, Message: "Product not found."}
 		}
 		return nil, &app.Error{Op: "repositories.GetProduct", Code: app.EINTERNAL, Err: err, Message: "Could not query Product from DB"}
 	}
+	if err = db.localizeProductsWithCategories(ctx, []*ProductFetchModel{prod}, columns, selection.Locales); err != nil {
+		return nil, &app.Error{Op: "repositories.GetProduct", Err: err}
+	}
 	return prod, nil
 }
 
+// localizeProductsWithCategories translates the selected columns of the products and their expanded categories
+// to the first of the locales that each of them is translated to
+func (db *DB) localizeProductsWithCategories(ctx context.Context, prods []*ProductFetchModel, columns []string, locales []string) error {
+	if err := db.localizeProducts(ctx, prods, columns, locales); err != nil {
+		return err
+	}
+	categs := make([]*CategoryFetchModel, 0)
+	for _, prod := range prods {
+		if prod.Category != nil {
+			categs = append(categs, prod.Category)
+		}
+	}
+	return db.localizeCategories(ctx, categs, categoryColumns, locales)
+}
+
 func (db *DB) CreateProduct(ctx context.Context, product ProductCreateModel) (int64, error) {
 	res, err := db.ExecContext(ctx, "INSERT INTO products (category_id, title, image_url, price, description) VALUES (?, ?, ?, ?, ?)",
 		product.CategoryID, product.Title, product.ImageURL, product.Price, product.Description)
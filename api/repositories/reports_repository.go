@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/mzampetakis/prods-api/api/app"
+)
+
+// ImageURLModel is an image URL stored on a product or a category
+type ImageURLModel struct {
+	EntityType string `json:"entity_type"`
+	EntityID   int64  `json:"entity_id"`
+	URL        string `json:"url"`
+}
+
+type BrokenImageFetchModel struct {
+	EntityType string    `json:"entity_type"`
+	EntityID   int64     `json:"entity_id"`
+	URL        string    `json:"url"`
+	StatusCode *int64    `json:"status_code"`
+	Error      *string   `json:"error"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+type BrokenImageCreateModel struct {
+	EntityType string  `json:"entity_type"`
+	EntityID   int64   `json:"entity_id"`
+	URL        string  `json:"url"`
+	StatusCode *int64  `json:"status_code"`
+	Error      *string `json:"error"`
+}
+
+// GetImageURLs returns all the image URLs stored on products and categories
+func (db *DB) GetImageURLs(ctx context.Context) ([]*ImageURLModel, error) {
+	rows, err := db.QueryContext(ctx, "SELECT 'product', id, image_url FROM products WHERE image_url IS NOT NULL AND image_url != '' "+
+		"UNION ALL SELECT 'category', id, image_url FROM categories WHERE image_url IS NOT NULL AND image_url != ''")
+	if err != nil {
+		return nil, &app.Error{Op: "repositories.GetImageURLs", Code: app.EINTERNAL, Err: err, Message: "Could not query Image URLs from DB"}
+	}
+	defer rows.Close()
+
+	imageURLs := make([]*ImageURLModel, 0)
+	for rows.Next() {
+		imageURL := new(ImageURLModel)
+		err := rows.Scan(&imageURL.EntityType, &imageURL.EntityID, &imageURL.URL)
+		if err != nil {
+			return nil, &app.Error{Op: "repositories.GetImageURLs", Code: app.EINTERNAL, Err: err, Message: "Could not fetch Image URLs from DB"}
+		}
+		imageURLs = append(imageURLs, imageURL)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, &app.Error{Op: "repositories.GetImageURLs", Code: app.EINTERNAL, Err: err, Message: "Could not fetch Image URLs from DB"}
+	}
+	return imageURLs, nil
+}
+
+func (db *DB) GetBrokenImages(ctx context.Context) ([]*BrokenImageFetchModel, error) {
+	rows, err := db.QueryContext(ctx, "SELECT entity_type, entity_id, url, status_code, error, checked_at FROM broken_images ORDER BY entity_type, entity_id")
+	if err != nil {
+		return nil, &app.Error{Op: "repositories.GetBrokenImages", Code: app.EINTERNAL, Err: err, Message: "Could not query Broken Images from DB"}
+	}
+	defer rows.Close()
+
+	brokenImages := make([]*BrokenImageFetchModel, 0)
+	for rows.Next() {
+		brokenImage := new(BrokenImageFetchModel)
+		err := rows.Scan(&brokenImage.EntityType, &brokenImage.EntityID, &brokenImage.URL, &brokenImage.StatusCode, &brokenImage.Error, &brokenImage.CheckedAt)
+		if err != nil {
+			return nil, &app.Error{Op: "repositories.GetBrokenImages", Code: app.EINTERNAL, Err: err, Message: "Could not fetch Broken Images from DB"}
+		}
+		brokenImages = append(brokenImages, brokenImage)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, &app.Error{Op: "repositories.GetBrokenImages", Code: app.EINTERNAL, Err: err, Message: "Could not fetch Broken Images from DB"}
+	}
+	return brokenImages, nil
+}
+
+// ReplaceBrokenImages replaces the stored broken images with the results of the latest link check
+func (db *DB) ReplaceBrokenImages(ctx context.Context, brokenImages []BrokenImageCreateModel) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return &app.Error{Op: "repositories.ReplaceBrokenImages", Code: app.EINTERNAL, Err: err, Message: "Could not replace Broken Images in DB"}
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM broken_images"); err != nil {
+		tx.Rollback()
+		return &app.Error{Op: "repositories.ReplaceBrokenImages", Code: app.EINTERNAL, Err: err, Message: "Could not replace Broken Images in DB"}
+	}
+	for _, brokenImage := range brokenImages {
+		_, err = tx.ExecContext(ctx, "INSERT INTO broken_images (entity_type, entity_id, url, status_code, error) VALUES (?, ?, ?, ?, ?)",
+			brokenImage.EntityType, brokenImage.EntityID, brokenImage.URL, brokenImage.StatusCode, brokenImage.Error)
+		if err != nil {
+			tx.Rollback()
+			return &app.Error{Op: "repositories.ReplaceBrokenImages", Code: app.EINTERNAL, Err: err, Message: "Could not replace Broken Images in DB"}
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return &app.Error{Op: "repositories.ReplaceBrokenImages", Code: app.EINTERNAL, Err: err, Message: "Could not replace Broken Images in DB"}
+	}
+	return nil
+}
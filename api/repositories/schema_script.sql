@@ -1,3 +1,7 @@
+DROP TABLE IF EXISTS category_translations;
+DROP TABLE IF EXISTS product_translations;
+DROP TABLE IF EXISTS broken_images;
+DROP TABLE IF EXISTS product_images;
 DROP TABLE IF EXISTS products;
 DROP TABLE IF EXISTS categories;
 
@@ -6,8 +10,8 @@ CREATE TABLE categories (
     title varchar(155) NOT NULL DEFAULT '',
     sort bigint(16) DEFAULT NULL,
     image_url varchar(1000) DEFAULT NULL,
-    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     PRIMARY KEY (id)
     ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
 
@@ -18,9 +22,66 @@ CREATE TABLE products (
     image_url varchar(1000) DEFAULT NULL,
     price bigint(16) NOT NULL,
     description text,
-    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     PRIMARY KEY (id),
     KEY category_product_id_fk (category_id),
     CONSTRAINT category_product_id_fk FOREIGN KEY (category_id) REFERENCES categories (id) ON DELETE SET NULL ON UPDATE CASCADE
-) ENGINE=InnoDB DEFAULT CHARSET=utf8;
\ No newline at end of file
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE product_images (
+    id bigint(16) unsigned NOT NULL AUTO_INCREMENT,
+    product_id bigint(16) unsigned NOT NULL,
+    position int(11) NOT NULL DEFAULT 0,
+    content_type varchar(100) NOT NULL,
+    size bigint(16) NOT NULL,
+    storage_key varchar(500) NOT NULL,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    KEY product_images_product_id_position (product_id, position),
+    CONSTRAINT product_images_product_id_fk FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE product_translations (
+    product_id bigint(16) unsigned NOT NULL,
+    locale varchar(20) NOT NULL,
+    title varchar(255) NOT NULL DEFAULT '',
+    description text,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (product_id, locale),
+    CONSTRAINT product_translations_product_id_fk FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE category_translations (
+    category_id bigint(16) unsigned NOT NULL,
+    locale varchar(20) NOT NULL,
+    title varchar(155) NOT NULL DEFAULT '',
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (category_id, locale),
+    CONSTRAINT category_translations_category_id_fk FOREIGN KEY (category_id) REFERENCES categories (id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE broken_images (
+    id bigint(16) unsigned NOT NULL AUTO_INCREMENT,
+    entity_type varchar(20) NOT NULL,
+    entity_id bigint(16) unsigned NOT NULL,
+    url varchar(1000) NOT NULL,
+    status_code int(11) DEFAULT NULL,
+    error varchar(1000) DEFAULT NULL,
+    checked_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+ALTER TABLE categories
+    MODIFY created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    MODIFY updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;
+
+UPDATE categories SET updated_at = created_at WHERE updated_at < created_at;
+
+ALTER TABLE products
+    MODIFY created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    MODIFY updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;
+
+UPDATE products SET updated_at = created_at WHERE updated_at < created_at;
@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/mzampetakis/prods-api/api/app"
+)
+
+// selectColumns returns the columns of the provided model that match the requested fields.
+// The id column is always selected and an empty fields list selects all the model's columns.
+func selectColumns(model interface{}, fields []string) ([]string, error) {
+	modelType := reflect.TypeOf(model)
+	requested := make(map[string]bool)
+	for _, field := range fields {
+		if !isModelField(modelType, field) {
+			return nil, &app.Error{Op: "repositories.selectColumns", Code: app.EINVALIDFIELD, Message: "Invalid field: " + field}
+		}
+		requested[field] = true
+	}
+	columns := make([]string, 0)
+	for i := 0; i < modelType.NumField(); i++ {
+		column := modelType.Field(i).Tag.Get("json")
+		if column == "-" {
+			continue
+		}
+		if len(fields) == 0 || requested[column] || column == "id" {
+			columns = append(columns, column)
+		}
+	}
+	return columns, nil
+}
+
+// scanTargets returns pointers to the fields of the provided model that match the given columns
+// in order to be used with rows.Scan
+func scanTargets(model interface{}, columns []string) []interface{} {
+	val := reflect.ValueOf(model).Elem()
+	targets := make([]interface{}, 0, len(columns))
+	for _, column := range columns {
+		for i := 0; i < val.NumField(); i++ {
+			if val.Type().Field(i).Tag.Get("json") == column {
+				targets = append(targets, val.Field(i).Addr().Interface())
+				break
+			}
+		}
+	}
+	return targets
+}
+
+// qualifyColumns prefixes the given columns with the provided table alias
+func qualifyColumns(alias string, columns []string) string {
+	qualified := make([]string, 0, len(columns))
+	for _, column := range columns {
+		qualified = append(qualified, alias+"."+column)
+	}
+	return strings.Join(qualified, ", ")
+}
+
+// timeRangesCondition returns the WHERE clause, with its arguments, that keeps the rows of the column prefix
+// (e.g. "p." or empty) whose created_at and updated_at are in the filter's ranges. It is empty without ranges.
+func timeRangesCondition(prefix string, filter app.Filter) (string, []interface{}) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	ranges := []struct {
+		column string
+		r      app.TimeRange
+	}{{"created_at", filter.Created}, {"updated_at", filter.Updated}}
+	for _, timeRange := range ranges {
+		if !timeRange.r.From.IsZero() {
+			conditions = append(conditions, prefix+timeRange.column+" >= ?")
+			args = append(args, timeRange.r.From)
+		}
+		if !timeRange.r.Before.IsZero() {
+			conditions = append(conditions, prefix+timeRange.column+" < ?")
+			args = append(args, timeRange.r.Before)
+		}
+	}
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// placeholders returns a comma separated list of n query placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func isModelField(modelType reflect.Type, field string) bool {
+	for i := 0; i < modelType.NumField(); i++ {
+		existing := modelType.Field(i).Tag.Get("json")
+		if existing != "-" && field == existing {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,254 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mzampetakis/prods-api/api/app"
+)
+
+// queryDriver is a database/sql driver that records its queries and returns the same rows for each of them
+type queryDriver struct {
+	queries []string
+	args    [][]interface{}
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (d *queryDriver) Open(name string) (driver.Conn, error) {
+	return &queryConn{driver: d}, nil
+}
+
+type queryConn struct {
+	driver *queryDriver
+}
+
+func (c *queryConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+
+func (c *queryConn) Close() error {
+	return nil
+}
+
+func (c *queryConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+func (c *queryConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	values := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		values = append(values, arg.Value)
+	}
+	c.driver.queries = append(c.driver.queries, query)
+	c.driver.args = append(c.driver.args, values)
+	return &queryRows{columns: c.driver.columns, rows: c.driver.rows}, nil
+}
+
+type queryRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *queryRows) Columns() []string {
+	return r.columns
+}
+
+func (r *queryRows) Close() error {
+	return nil
+}
+
+func (r *queryRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+var recordingDriver = &queryDriver{}
+
+func init() {
+	sql.Register("query", recordingDriver)
+}
+
+func TestSelectColumns(t *testing.T) {
+	tests := map[string]struct {
+		fields          []string
+		expectedColumns []string
+		expectedErr     string
+	}{
+		"All fields":                 {fields: nil, expectedColumns: []string{"id", "category_id", "title", "image_url", "price", "description", "created_at", "updated_at"}},
+		"Sparse fields in order":     {fields: []string{"price", "title"}, expectedColumns: []string{"id", "title", "price"}},
+		"Id is always selected":      {fields: []string{"id"}, expectedColumns: []string{"id"}},
+		"Unknown field":              {fields: []string{"title", "secret"}, expectedErr: app.EINVALIDFIELD},
+		"Relationship is not column": {fields: []string{"category"}, expectedErr: app.EINVALIDFIELD},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Act
+			columns, err := selectColumns(ProductFetchModel{}, tc.fields)
+
+			//Assert
+			if app.ErrorCode(err) != tc.expectedErr {
+				t.Fatalf("Expected error code %q but got %q", tc.expectedErr, app.ErrorCode(err))
+			}
+			if tc.expectedErr == "" && !reflect.DeepEqual(columns, tc.expectedColumns) {
+				t.Errorf("Expected columns %v but got %v", tc.expectedColumns, columns)
+			}
+		})
+	}
+}
+
+func TestScanTargets(t *testing.T) {
+	//Prepare
+	prod := new(ProductFetchModel)
+
+	//Act
+	targets := scanTargets(prod, []string{"id", "title", "price"})
+
+	//Assert
+	expected := []interface{}{&prod.ID, &prod.Title, &prod.Price}
+	if !reflect.DeepEqual(targets, expected) {
+		t.Errorf("Expected the targets of id, title and price but got %v", targets)
+	}
+}
+
+func TestProductsQuery(t *testing.T) {
+	tests := map[string]struct {
+		selection       app.Selection
+		expectedQuery   string
+		expectedColumns []string
+	}{
+		"All fields": {selection: app.Selection{},
+			expectedQuery:   "SELECT p.id, p.category_id, p.title, p.image_url, p.price, p.description, p.created_at, p.updated_at FROM products p",
+			expectedColumns: []string{"id", "category_id", "title", "image_url", "price", "description", "created_at", "updated_at"}},
+		"Sparse fields": {selection: app.Selection{Fields: "title,price"},
+			expectedQuery:   "SELECT p.id, p.title, p.price FROM products p",
+			expectedColumns: []string{"id", "title", "price"}},
+		"Expanded category": {selection: app.Selection{Fields: "title", Expand: "category"},
+			expectedQuery: "SELECT p.id, p.title, c.id, c.title, c.image_url, c.sort, c.created_at, c.updated_at " +
+				"FROM products p LEFT JOIN categories c ON c.id = p.category_id",
+			expectedColumns: []string{"id", "title"}},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Act
+			query, columns, err := productsQuery(tc.selection)
+
+			//Assert
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if query != tc.expectedQuery {
+				t.Errorf("Expected query\n%s\nbut got\n%s", tc.expectedQuery, query)
+			}
+			if !reflect.DeepEqual(columns, tc.expectedColumns) {
+				t.Errorf("Expected columns %v but got %v", tc.expectedColumns, columns)
+			}
+		})
+	}
+}
+
+func TestDB_GetProduct_ExpandedCategoryWithoutCategory(t *testing.T) {
+	//Prepare
+	sqlDB, err := sql.Open("query", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	db := &DB{DB: sqlDB}
+	recordingDriver.queries, recordingDriver.args = nil, nil
+	recordingDriver.columns = []string{"id", "title", "c.id", "c.title", "c.image_url", "c.sort", "c.created_at", "c.updated_at"}
+	recordingDriver.rows = [][]driver.Value{{int64(1), "Mouse", nil, nil, nil, nil, nil, nil}}
+
+	//Act
+	prod, err := db.GetProduct(context.Background(), 1, app.Selection{Fields: "title", Expand: "category"})
+
+	//Assert
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if prod.ID != 1 || prod.Title == nil || *prod.Title != "Mouse" || prod.Category != nil {
+		t.Errorf("Expected product 1 without a category but got %+v", prod)
+	}
+	expectedQuery := "SELECT p.id, p.title, c.id, c.title, c.image_url, c.sort, c.created_at, c.updated_at " +
+		"FROM products p LEFT JOIN categories c ON c.id = p.category_id WHERE p.id= ?"
+	if len(recordingDriver.queries) != 1 || recordingDriver.queries[0] != expectedQuery {
+		t.Errorf("Expected the query\n%s\nbut got\n%v", expectedQuery, recordingDriver.queries)
+	}
+}
+
+func TestDB_expandCategoriesProducts(t *testing.T) {
+	created := time.Date(2020, 5, 25, 17, 6, 40, 0, time.UTC)
+	subquery := "(SELECT p.id, p.category_id, p.title, p.image_url, p.price, p.description, p.created_at, p.updated_at " +
+		"FROM products p WHERE p.category_id = ? ORDER BY p.id LIMIT 2)"
+	tests := map[string]struct {
+		categs           []*CategoryFetchModel
+		rows             [][]driver.Value
+		expectedQueries  []string
+		expectedArgs     [][]interface{}
+		expectedProducts map[int64][]int64
+	}{
+		"No categories": {categs: []*CategoryFetchModel{}, expectedQueries: nil, expectedProducts: map[int64][]int64{}},
+		"Single category": {categs: []*CategoryFetchModel{{ID: 7}},
+			rows:             [][]driver.Value{{int64(1), int64(7), "Mouse", nil, int64(10), nil, created, created}},
+			expectedQueries:  []string{subquery + " ORDER BY category_id, id"},
+			expectedArgs:     [][]interface{}{{int64(7)}},
+			expectedProducts: map[int64][]int64{7: {1}}},
+		"Categories with and without products": {categs: []*CategoryFetchModel{{ID: 7}, {ID: 8}, {ID: 9}},
+			rows: [][]driver.Value{
+				{int64(1), int64(7), "Mouse", nil, int64(10), nil, created, created},
+				{int64(2), int64(7), "Pad", nil, int64(5), nil, created, created},
+				{int64(3), int64(9), "Cable", nil, int64(2), nil, created, created},
+			},
+			expectedQueries:  []string{subquery + " UNION ALL " + subquery + " UNION ALL " + subquery + " ORDER BY category_id, id"},
+			expectedArgs:     [][]interface{}{{int64(7), int64(8), int64(9)}},
+			expectedProducts: map[int64][]int64{7: {1, 2}, 8: {}, 9: {3}}},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Prepare
+			sqlDB, err := sql.Open("query", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer sqlDB.Close()
+			db := &DB{DB: sqlDB}
+			recordingDriver.queries, recordingDriver.args = nil, nil
+			recordingDriver.columns = []string{"id", "category_id", "title", "image_url", "price", "description", "created_at", "updated_at"}
+			recordingDriver.rows = tc.rows
+
+			//Act
+			err = db.expandCategoriesProducts(context.Background(), tc.categs, 2)
+
+			//Assert
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(recordingDriver.queries, tc.expectedQueries) {
+				t.Errorf("Expected the queries\n%v\nbut got\n%v", tc.expectedQueries, recordingDriver.queries)
+			}
+			if !reflect.DeepEqual(recordingDriver.args, tc.expectedArgs) {
+				t.Errorf("Expected the arguments %v but got %v", tc.expectedArgs, recordingDriver.args)
+			}
+			products := make(map[int64][]int64)
+			for _, categ := range tc.categs {
+				products[categ.ID] = make([]int64, 0)
+				for _, prod := range categ.Products {
+					products[categ.ID] = append(products[categ.ID], prod.ID)
+				}
+			}
+			if !reflect.DeepEqual(products, tc.expectedProducts) {
+				t.Errorf("Expected the products %v but got %v", tc.expectedProducts, products)
+			}
+		})
+	}
+}
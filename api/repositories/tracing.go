@@ -0,0 +1,149 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mzampetakis/prods-api/api/tracing"
+)
+
+var (
+	sqlStringLiteral  = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'`)
+	sqlNumericLiteral = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	sqlWhitespace     = regexp.MustCompile(`\s+`)
+)
+
+// sanitizeSQL replaces the literals of a statement with placeholders so that no data leaks into the spans
+func sanitizeSQL(query string) string {
+	query = sqlStringLiteral.ReplaceAllString(query, "?")
+	query = sqlNumericLiteral.ReplaceAllString(query, "?")
+	return strings.TrimSpace(sqlWhitespace.ReplaceAllString(query, " "))
+}
+
+// startQuerySpan starts a client span for a DB statement, named after its operation. The parentheses of
+// a statement starting with a subquery, such as a UNION of subqueries, are not part of its operation.
+func startQuerySpan(ctx context.Context, query string) (context.Context, *tracing.Span) {
+	statement := sanitizeSQL(query)
+	operation := strings.ToUpper(strings.SplitN(strings.TrimLeft(statement, "( "), " ", 2)[0])
+	ctx, span := tracing.StartWithKind(ctx, "db."+strings.ToLower(operation), tracing.SpanKindClient)
+	span.SetAttribute("db.system", "mysql")
+	span.SetAttribute("db.operation", operation)
+	span.SetAttribute("db.statement", statement)
+	return ctx, span
+}
+
+type statementTimeoutKey struct{}
+
+// WithStatementTimeout returns a context whose DB statements are bounded by the provided timeout
+// instead of the DB's StatementTimeout
+func WithStatementTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, statementTimeoutKey{}, timeout)
+}
+
+// statementContext bounds a statement by the timeout of its context or else by the DB's StatementTimeout
+func (db *DB) statementContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := db.StatementTimeout
+	if ctxTimeout, ok := ctx.Value(statementTimeoutKey{}).(time.Duration); ok {
+		timeout = ctxTimeout
+	}
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// Rows are the rows of a query, whose statement context is released and span ended when they are closed
+type Rows struct {
+	*sql.Rows
+	cancel context.CancelFunc
+	span   *tracing.Span
+}
+
+func (r *Rows) Close() error {
+	defer r.cancel()
+	err := r.Rows.Close()
+	if rowsErr := r.Rows.Err(); rowsErr != nil {
+		r.span.SetError(rowsErr)
+	} else {
+		r.span.SetError(err)
+	}
+	r.span.End()
+	return err
+}
+
+// Row is the row of a query, whose statement context is released and span ended when it is scanned
+type Row struct {
+	*sql.Row
+	cancel context.CancelFunc
+	span   *tracing.Span
+}
+
+func (r *Row) Scan(dest ...interface{}) error {
+	defer r.cancel()
+	err := r.Row.Scan(dest...)
+	if err != sql.ErrNoRows {
+		r.span.SetError(err)
+	}
+	r.span.End()
+	return err
+}
+
+// QueryContext runs a query bounded by the statement timeout. As the rows are read after it returns,
+// the statement's context is released and its span ended when they are closed.
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	ctx, cancel := db.statementContext(ctx)
+	ctx, span := startQuerySpan(ctx, query)
+	rows, err := db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		span.SetError(err)
+		span.End()
+		cancel()
+		return nil, err
+	}
+	return &Rows{Rows: rows, cancel: cancel, span: span}, nil
+}
+
+// QueryRowContext runs a query bounded by the statement timeout. As the row is scanned after it returns,
+// the statement's context is released and its span ended when it is scanned.
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *Row {
+	ctx, cancel := db.statementContext(ctx)
+	ctx, span := startQuerySpan(ctx, query)
+	return &Row{Row: db.DB.QueryRowContext(ctx, query, args...), cancel: cancel, span: span}
+}
+
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, cancel := db.statementContext(ctx)
+	defer cancel()
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+	res, err := db.DB.ExecContext(ctx, query, args...)
+	span.SetError(err)
+	return res, err
+}
+
+// Tx is a transaction whose statements are traced and bounded by the statement timeout
+type Tx struct {
+	*sql.Tx
+	db *DB
+}
+
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, db: db}, nil
+}
+
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, cancel := tx.db.statementContext(ctx)
+	defer cancel()
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+	res, err := tx.Tx.ExecContext(ctx, query, args...)
+	span.SetError(err)
+	return res, err
+}
@@ -0,0 +1,240 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/mzampetakis/prods-api/api/tracing"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// statementDriver is a database/sql driver whose queries return a single row and record their context
+type statementDriver struct {
+	queryContexts []context.Context
+}
+
+func (d *statementDriver) Open(name string) (driver.Conn, error) {
+	return &statementConn{driver: d}, nil
+}
+
+type statementConn struct {
+	driver *statementDriver
+}
+
+func (c *statementConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+
+func (c *statementConn) Close() error {
+	return nil
+}
+
+func (c *statementConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+func (c *statementConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.driver.queryContexts = append(c.driver.queryContexts, ctx)
+	return &statementRows{}, nil
+}
+
+type statementRows struct {
+	read bool
+}
+
+func (r *statementRows) Columns() []string {
+	return []string{"id"}
+}
+
+func (r *statementRows) Close() error {
+	return nil
+}
+
+func (r *statementRows) Next(dest []driver.Value) error {
+	if r.read {
+		return io.EOF
+	}
+	r.read = true
+	dest[0] = int64(1)
+	return nil
+}
+
+var testDriver = &statementDriver{}
+
+func init() {
+	sql.Register("statement", testDriver)
+}
+
+func TestSanitizeSQL(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{"Placeholders", "SELECT id FROM products WHERE id= ?", "SELECT id FROM products WHERE id= ?"},
+		{"String literals", "SELECT id FROM products WHERE title = 'it''s a secret' AND image_url != ''", "SELECT id FROM products WHERE title = ? AND image_url != ?"},
+		{"Numeric literals", "SELECT id FROM products LIMIT 10 OFFSET 20", "SELECT id FROM products LIMIT ? OFFSET ?"},
+		{"Identifiers with digits", "SELECT c1.id FROM categories c1", "SELECT c1.id FROM categories c1"},
+		{"Whitespace", "UPDATE products\n\tSET title=?   WHERE id = ?", "UPDATE products SET title=? WHERE id = ?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//Act
+			sanitized := sanitizeSQL(tt.query)
+
+			//Assert
+			if sanitized != tt.expected {
+				t.Errorf("Expected %q but got %q", tt.expected, sanitized)
+			}
+		})
+	}
+}
+
+func TestDB_statementContext(t *testing.T) {
+	tests := []struct {
+		name             string
+		statementTimeout time.Duration
+		ctx              context.Context
+		expectedTimeout  time.Duration
+	}{
+		{"DB timeout", 5 * time.Second, context.Background(), 5 * time.Second},
+		{"Route timeout", 5 * time.Second, WithStatementTimeout(context.Background(), time.Minute), time.Minute},
+		{"No timeout", 0, context.Background(), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &DB{StatementTimeout: tt.statementTimeout}
+
+			//Act
+			ctx, cancel := db.statementContext(tt.ctx)
+			defer cancel()
+
+			//Assert
+			deadline, ok := ctx.Deadline()
+			if ok != (tt.expectedTimeout > 0) {
+				t.Fatalf("Expected a deadline %v but got %v", tt.expectedTimeout > 0, ok)
+			}
+			if remaining := time.Until(deadline); ok && (remaining > tt.expectedTimeout || remaining < tt.expectedTimeout-time.Second) {
+				t.Errorf("Expected a deadline in %s but got %s", tt.expectedTimeout, remaining)
+			}
+		})
+	}
+}
+
+func TestDB_Query_ReleasesStatementContext(t *testing.T) {
+	//Prepare
+	sqlDB, err := sql.Open("statement", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	db := &DB{DB: sqlDB, StatementTimeout: time.Minute}
+	ctx := context.Background()
+	var id int64
+	testDriver.queryContexts = nil
+
+	//Act
+	rows, err := db.QueryContext(ctx, "SELECT id FROM products")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	for rows.Next() {
+	}
+	rowsErrBeforeClose := testDriver.queryContexts[0].Err()
+	rows.Close()
+	err = db.QueryRowContext(ctx, "SELECT id FROM products WHERE id = ?", 1).Scan(&id)
+
+	//Assert
+	if err != nil || id != 1 {
+		t.Fatalf("Expected row 1 but got %d (%v)", id, err)
+	}
+	if rowsErrBeforeClose != nil {
+		t.Errorf("Expected the context of the rows to be active until they are closed but got %v", rowsErrBeforeClose)
+	}
+	for i, queryCtx := range testDriver.queryContexts {
+		if !errors.Is(queryCtx.Err(), context.Canceled) {
+			t.Errorf("Expected the context of statement %d to be released but got %v", i, queryCtx.Err())
+		}
+	}
+}
+
+func TestStartQuerySpan(t *testing.T) {
+	tests := []struct {
+		name              string
+		query             string
+		expectedName      string
+		expectedOperation string
+	}{
+		{"Select", "SELECT id FROM products", "db.select", "SELECT"},
+		{"Lowercase", "update products SET title=? WHERE id = ?", "db.update", "UPDATE"},
+		{"Union of subqueries", "(SELECT id FROM products LIMIT 2) UNION ALL (SELECT id FROM products LIMIT 2)", "db.select", "SELECT"},
+		{"Leading whitespace", "\n\t( SELECT id FROM products)", "db.select", "SELECT"},
+	}
+	tracer := tracing.NewTracer("prods-api-test", tracetest.NewInMemoryExporter())
+	defer tracer.Shutdown(context.Background())
+	tracing.SetTracer(tracer)
+	defer tracing.SetTracer(tracing.NewTracer("prods-api", nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//Act
+			_, span := startQuerySpan(context.Background(), tt.query)
+			span.End()
+
+			//Assert
+			readOnly := span.Span.(sdktrace.ReadOnlySpan)
+			if readOnly.Name() != tt.expectedName {
+				t.Errorf("Expected span name %q but got %q", tt.expectedName, readOnly.Name())
+			}
+			for _, attr := range readOnly.Attributes() {
+				if attr.Key == "db.operation" && attr.Value.AsString() != tt.expectedOperation {
+					t.Errorf("Expected operation %q but got %q", tt.expectedOperation, attr.Value.AsString())
+				}
+			}
+		})
+	}
+}
+
+func TestDB_Query_EndsSpanWhenRead(t *testing.T) {
+	//Prepare
+	tracer := tracing.NewTracer("prods-api-test", tracetest.NewInMemoryExporter())
+	defer tracer.Shutdown(context.Background())
+	tracing.SetTracer(tracer)
+	defer tracing.SetTracer(tracing.NewTracer("prods-api", nil))
+	sqlDB, err := sql.Open("statement", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	db := &DB{DB: sqlDB}
+	var id int64
+
+	//Act
+	rows, err := db.QueryContext(context.Background(), "SELECT id FROM products")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	for rows.Next() {
+	}
+	rowsRecordingBeforeClose := rows.span.IsRecording()
+	rows.Close()
+	row := db.QueryRowContext(context.Background(), "SELECT id FROM products WHERE id = ?", 1)
+	rowRecordingBeforeScan := row.span.IsRecording()
+	err = row.Scan(&id)
+
+	//Assert
+	if err != nil || id != 1 {
+		t.Fatalf("Expected row 1 but got %d (%v)", id, err)
+	}
+	if !rowsRecordingBeforeClose || rows.span.IsRecording() {
+		t.Errorf("Expected the span of the rows to end when they are closed")
+	}
+	if !rowRecordingBeforeScan || row.span.IsRecording() {
+		t.Errorf("Expected the span of the row to end when it is scanned")
+	}
+}
@@ -0,0 +1,259 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mzampetakis/prods-api/api/app"
+)
+
+type ProductTranslationModel struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+}
+
+type CategoryTranslationModel struct {
+	Title *string `json:"title"`
+}
+
+// MissingTranslationModel lists the fields of an entity that are not translated to a locale
+type MissingTranslationModel struct {
+	EntityType string   `json:"entity_type"`
+	EntityID   int64    `json:"entity_id"`
+	Locale     string   `json:"locale"`
+	Fields     []string `json:"fields"`
+}
+
+func (db *DB) SaveProductTranslation(ctx context.Context, productID int64, locale string, translation ProductTranslationModel) error {
+	return db.changeTranslation(ctx, "repositories.SaveProductTranslation", "products", productID, false,
+		"INSERT INTO product_translations (product_id, locale, title, description) VALUES (?, ?, ?, ?) "+
+			"ON DUPLICATE KEY UPDATE title = VALUES(title), description = VALUES(description)",
+		productID, locale, translation.Title, translation.Description)
+}
+
+func (db *DB) DeleteProductTranslation(ctx context.Context, productID int64, locale string) error {
+	return db.changeTranslation(ctx, "repositories.DeleteProductTranslation", "products", productID, true,
+		"DELETE FROM product_translations WHERE product_id = ? AND locale = ?", productID, locale)
+}
+
+func (db *DB) SaveCategoryTranslation(ctx context.Context, categoryID int64, locale string, translation CategoryTranslationModel) error {
+	return db.changeTranslation(ctx, "repositories.SaveCategoryTranslation", "categories", categoryID, false,
+		"INSERT INTO category_translations (category_id, locale, title) VALUES (?, ?, ?) "+
+			"ON DUPLICATE KEY UPDATE title = VALUES(title)",
+		categoryID, locale, translation.Title)
+}
+
+func (db *DB) DeleteCategoryTranslation(ctx context.Context, categoryID int64, locale string) error {
+	return db.changeTranslation(ctx, "repositories.DeleteCategoryTranslation", "categories", categoryID, true,
+		"DELETE FROM category_translations WHERE category_id = ? AND locale = ?", categoryID, locale)
+}
+
+// changeTranslation executes a change of a translation and updates the updated_at of the translated entity,
+// so that the Last-Modified of its localized responses changes too. When mustExist is set, a change that
+// affects no translation fails with ETRANSLATIONNOTFOUND.
+func (db *DB) changeTranslation(ctx context.Context, op string, table string, id int64, mustExist bool, query string, args ...interface{}) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return &app.Error{Op: op, Code: app.EINTERNAL, Err: err, Message: "Could not save Translation in DB"}
+	}
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		tx.Rollback()
+		return &app.Error{Op: op, Code: app.EINTERNAL, Err: err, Message: "Could not save Translation in DB"}
+	}
+	if rowsAffected, err := res.RowsAffected(); mustExist && err == nil && rowsAffected == 0 {
+		tx.Rollback()
+		return &app.Error{Op: op, Code: app.ETRANSLATIONNOTFOUND, Message: "Translation not found."}
+	}
+	if _, err = tx.ExecContext(ctx, "UPDATE "+table+" SET updated_at = CURRENT_TIMESTAMP WHERE id = ?", id); err != nil {
+		tx.Rollback()
+		return &app.Error{Op: op, Code: app.EINTERNAL, Err: err, Message: "Could not save Translation in DB"}
+	}
+	if err = tx.Commit(); err != nil {
+		return &app.Error{Op: op, Code: app.EINTERNAL, Err: err, Message: "Could not save Translation in DB"}
+	}
+	return nil
+}
+
+// GetMissingTranslations returns the products and categories whose title or description (when the product has one)
+// is not translated to each of the provided locales, ordered by entity type, id and locale
+func (db *DB) GetMissingTranslations(ctx context.Context, locales []string) ([]*MissingTranslationModel, error) {
+	missing := make([]*MissingTranslationModel, 0)
+	if len(locales) == 0 {
+		return missing, nil
+	}
+	args := make([]interface{}, 0, 2*len(locales))
+	for _, locale := range locales {
+		args = append(args, locale)
+	}
+	args = append(args, args...)
+	localesTable := strings.TrimSuffix(strings.Repeat("SELECT ? AS locale UNION ALL ", len(locales)), " UNION ALL ")
+	query := fmt.Sprintf("SELECT 'category', c.id, l.locale, t.category_id IS NULL, FALSE FROM categories c CROSS JOIN (%s) l "+
+		"LEFT JOIN category_translations t ON t.category_id = c.id AND t.locale = l.locale WHERE t.category_id IS NULL "+
+		"UNION ALL SELECT 'product', p.id, l.locale, t.product_id IS NULL, p.description IS NOT NULL AND t.description IS NULL "+
+		"FROM products p CROSS JOIN (%s) l LEFT JOIN product_translations t ON t.product_id = p.id AND t.locale = l.locale "+
+		"WHERE t.product_id IS NULL OR (p.description IS NOT NULL AND t.description IS NULL) "+
+		"ORDER BY 1, 2, 3", localesTable, localesTable)
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, &app.Error{Op: "repositories.GetMissingTranslations", Code: app.EINTERNAL, Err: err, Message: "Could not query Missing Translations from DB"}
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		translation := new(MissingTranslationModel)
+		var titleMissing, descriptionMissing bool
+		err := rows.Scan(&translation.EntityType, &translation.EntityID, &translation.Locale, &titleMissing, &descriptionMissing)
+		if err != nil {
+			return nil, &app.Error{Op: "repositories.GetMissingTranslations", Code: app.EINTERNAL, Err: err, Message: "Could not fetch Missing Translations from DB"}
+		}
+		translation.Fields = make([]string, 0, 2)
+		if titleMissing {
+			translation.Fields = append(translation.Fields, "title")
+		}
+		if descriptionMissing {
+			translation.Fields = append(translation.Fields, "description")
+		}
+		missing = append(missing, translation)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, &app.Error{Op: "repositories.GetMissingTranslations", Code: app.EINTERNAL, Err: err, Message: "Could not fetch Missing Translations from DB"}
+	}
+	return missing, nil
+}
+
+// localizeProducts replaces the title and description of the products with their translation to the first
+// of the locales that has one and records the locales of their content. Fields that are not among the selected
+// columns or not translated to any of the locales are kept.
+func (db *DB) localizeProducts(ctx context.Context, prods []*ProductFetchModel, columns []string, locales []string) error {
+	if len(prods) == 0 || (!contains(columns, "title") && !contains(columns, "description")) {
+		return nil
+	}
+	if len(locales) == 0 {
+		for _, prod := range prods {
+			prod.ContentLocales = []string{""}
+		}
+		return nil
+	}
+	ids := make([]interface{}, 0, len(prods))
+	for _, prod := range prods {
+		ids = append(ids, prod.ID)
+	}
+	translations := make(map[int64]map[string]ProductTranslationModel)
+	err := db.queryTranslations(ctx, "product_translations", "product_id", "title, description", ids, locales, func(rows *Rows) error {
+		var id int64
+		var locale string
+		var translation ProductTranslationModel
+		err := rows.Scan(&id, &locale, &translation.Title, &translation.Description)
+		if err == nil {
+			if translations[id] == nil {
+				translations[id] = make(map[string]ProductTranslationModel)
+			}
+			translations[id][locale] = translation
+		}
+		return err
+	})
+	if err != nil {
+		return &app.Error{Op: "repositories.localizeProducts", Err: err}
+	}
+	for _, prod := range prods {
+		titleLocale, descriptionLocale := "", ""
+		for i := len(locales) - 1; i >= 0; i-- {
+			translation, ok := translations[prod.ID][locales[i]]
+			if !ok {
+				continue
+			}
+			if contains(columns, "title") && translation.Title != nil && *translation.Title != "" {
+				prod.Title = translation.Title
+				titleLocale = locales[i]
+			}
+			if contains(columns, "description") && translation.Description != nil {
+				prod.Description = translation.Description
+				descriptionLocale = locales[i]
+			}
+		}
+		prod.ContentLocales = make([]string, 0, 2)
+		if contains(columns, "title") {
+			prod.ContentLocales = append(prod.ContentLocales, titleLocale)
+		}
+		if contains(columns, "description") && descriptionLocale != titleLocale {
+			prod.ContentLocales = append(prod.ContentLocales, descriptionLocale)
+		}
+	}
+	return nil
+}
+
+// localizeCategories replaces the title of the categories with its translation to the first of the locales
+// that has one and records the locale of their content. Titles that are not among the selected columns or not
+// translated to any of the locales are kept.
+func (db *DB) localizeCategories(ctx context.Context, categs []*CategoryFetchModel, columns []string, locales []string) error {
+	if len(categs) == 0 || !contains(columns, "title") {
+		return nil
+	}
+	if len(locales) == 0 {
+		for _, categ := range categs {
+			categ.ContentLocales = []string{""}
+		}
+		return nil
+	}
+	ids := make([]interface{}, 0, len(categs))
+	for _, categ := range categs {
+		ids = append(ids, categ.ID)
+	}
+	translations := make(map[int64]map[string]CategoryTranslationModel)
+	err := db.queryTranslations(ctx, "category_translations", "category_id", "title", ids, locales, func(rows *Rows) error {
+		var id int64
+		var locale string
+		var translation CategoryTranslationModel
+		err := rows.Scan(&id, &locale, &translation.Title)
+		if err == nil {
+			if translations[id] == nil {
+				translations[id] = make(map[string]CategoryTranslationModel)
+			}
+			translations[id][locale] = translation
+		}
+		return err
+	})
+	if err != nil {
+		return &app.Error{Op: "repositories.localizeCategories", Err: err}
+	}
+	for _, categ := range categs {
+		titleLocale := ""
+		for i := len(locales) - 1; i >= 0; i-- {
+			translation, ok := translations[categ.ID][locales[i]]
+			if ok && translation.Title != nil && *translation.Title != "" {
+				categ.Title = translation.Title
+				titleLocale = locales[i]
+			}
+		}
+		categ.ContentLocales = []string{titleLocale}
+	}
+	return nil
+}
+
+// queryTranslations fetches with a single query the translations of the entities with the provided ids
+// to the provided locales and scans each row with scan
+func (db *DB) queryTranslations(ctx context.Context, table string, idColumn string, columns string, ids []interface{}, locales []string,
+	scan func(rows *Rows) error) error {
+	args := append([]interface{}{}, ids...)
+	for _, locale := range locales {
+		args = append(args, locale)
+	}
+	query := fmt.Sprintf("SELECT %s, locale, %s FROM %s WHERE %s IN (%s) AND locale IN (%s)",
+		idColumn, columns, table, idColumn, placeholders(len(ids)), placeholders(len(locales)))
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return &app.Error{Op: "repositories.queryTranslations", Code: app.EINTERNAL, Err: err, Message: "Could not query Translations from DB"}
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err = scan(rows); err != nil {
+			return &app.Error{Op: "repositories.queryTranslations", Code: app.EINTERNAL, Err: err, Message: "Could not fetch Translations from DB"}
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return &app.Error{Op: "repositories.queryTranslations", Code: app.EINTERNAL, Err: err, Message: "Could not fetch Translations from DB"}
+	}
+	return nil
+}
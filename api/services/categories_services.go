@@ -4,31 +4,54 @@ import (
 	"strings"
 
 	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/cache"
 	"github.com/mzampetakis/prods-api/api/repositories"
+	"github.com/mzampetakis/prods-api/api/validation"
 	"golang.org/x/net/context"
 )
 
-func (s *Service) GetCategories(ctx context.Context, filter app.Filter) ([]*repositories.CategoryFetchModel, error) {
-	if filter.Limit <= 0 {
-		filter.Limit = 3
+func (s *Service) GetCategories(ctx context.Context, filter app.Filter, selection app.Selection) ([]*repositories.CategoryFetchModel, error) {
+	limit, err := s.Pages.pageLimit("services.GetCategories", "limit", filter.Limit, s.Pages.defaultLimit())
+	if err != nil {
+		return nil, err
 	}
+	filter.Limit = limit
 	if len(filter.SortBy) == 0 {
 		filter.SortBy = "sort"
 	}
 	filter.SortDirection = strings.ToUpper(filter.SortDirection)
 	if filter.SortDirection != "" && filter.SortDirection != app.ASC && filter.SortDirection != app.DESC {
-		return nil, &app.Error{Op: "services.GetCategories", Code: app.EINVALID, Message: "Invalid SortDirection field: " + filter.SortDirection}
+		return nil, &app.Error{Op: "services.GetCategories", Code: app.EINVALIDSORTDIRECTION, Message: "Invalid SortDirection field: " + filter.SortDirection}
+	}
+	if err := validateExpand(selection, "products"); err != nil {
+		return nil, &app.Error{Op: "services.GetCategories", Err: err}
+	}
+	if err := parseTimeRanges(&filter); err != nil {
+		return nil, &app.Error{Op: "services.GetCategories", Err: err}
 	}
+	expandLimit, err := s.Pages.pageLimit("services.GetCategories", "expand_limit", selection.ExpandLimit, defaultExpandLimit)
+	if err != nil {
+		return nil, err
+	}
+	selection.ExpandLimit = expandLimit
 
-	categs, err := s.DB.GetCategories(ctx, filter)
+	categs, err := s.DB.GetCategories(ctx, filter, selection)
 	if err != nil {
 		return nil, &app.Error{Op: "services.GetCategories", Err: err}
 	}
 	return categs, nil
 }
 
-func (s *Service) GetCategory(ctx context.Context, categoryID int64) (*repositories.CategoryFetchModel, error) {
-	categ, err := s.DB.GetCategory(ctx, categoryID)
+func (s *Service) GetCategory(ctx context.Context, categoryID int64, selection app.Selection) (*repositories.CategoryFetchModel, error) {
+	if err := validateExpand(selection, "products"); err != nil {
+		return nil, &app.Error{Op: "services.GetCategory", Err: err}
+	}
+	expandLimit, err := s.Pages.pageLimit("services.GetCategory", "expand_limit", selection.ExpandLimit, defaultExpandLimit)
+	if err != nil {
+		return nil, err
+	}
+	selection.ExpandLimit = expandLimit
+	categ, err := s.DB.GetCategory(ctx, categoryID, selection)
 	if err != nil {
 		return nil, &app.Error{Op: "services.GetCategory", Err: err}
 	}
@@ -36,24 +59,26 @@ func (s *Service) GetCategory(ctx context.Context, categoryID int64) (*repositor
 }
 
 func (s *Service) CreateCategory(ctx context.Context, category repositories.CategoryCreateModel) (int64, error) {
-	if category.Title == nil || len(*category.Title) == 0 {
-		return -1, &app.Error{Op: "services.CreateCategory", Code: app.EINVALID, Message: "Title cannot be empty."}
+	if err := s.ValidateCategory(ctx, category); err != nil {
+		return -1, &app.Error{Op: "services.CreateCategory", Err: err}
 	}
 	insertedID, err := s.DB.CreateCategory(ctx, category)
 	if err != nil {
 		return -1, &app.Error{Op: "services.CreateCategory", Err: err}
 	}
+	invalidateCache(ctx, s.Cache, cache.CategoriesTag)
 	return insertedID, nil
 }
 
 func (s *Service) UpdateCategory(ctx context.Context, categoryID int64, category repositories.CategoryCreateModel) error {
-	if category.Title == nil || len(*category.Title) == 0 {
-		return &app.Error{Op: "services.UpdateCategory", Code: app.EINVALID, Message: "Title cannot be empty."}
+	if err := s.ValidateCategory(ctx, category); err != nil {
+		return &app.Error{Op: "services.UpdateCategory", Err: err}
 	}
 	err := s.DB.UpdateCategory(ctx, categoryID, category)
 	if err != nil {
 		return &app.Error{Op: "services.UpdateCategory", Err: err}
 	}
+	invalidateCache(ctx, s.Cache, cache.CategoryTag(categoryID), cache.CategoriesTag)
 	return nil
 }
 
@@ -62,5 +87,25 @@ func (s *Service) DeleteCategory(ctx context.Context, categoryID int64) error {
 	if err != nil {
 		return &app.Error{Op: "services.DeleteCategory", Err: err}
 	}
+	// the category's products are left without a category
+	invalidateCache(ctx, s.Cache, cache.CategoryTag(categoryID), cache.CategoriesTag, cache.ProductsTag)
+	return nil
+}
+
+// ValidateCategory checks that the category has the fields of its model and follows the business rules: its image
+// is on an allowed host. All the violations are reported in a single error.
+func (s *Service) ValidateCategory(ctx context.Context, category repositories.CategoryCreateModel) error {
+	violations, err := validation.Validate(category)
+	if err != nil {
+		return &app.Error{Op: "services.ValidateCategory", Err: err}
+	}
+	if category.ImageURL != nil {
+		if err := validateImageHost(*category.ImageURL, s.AllowedImageHosts); err != nil {
+			violations = append(violations, app.ErrorDetails(err)...)
+		}
+	}
+	if len(violations) > 0 {
+		return validation.Error("services.ValidateCategory", violations)
+	}
 	return nil
 }
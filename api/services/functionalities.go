@@ -1,26 +1,168 @@
 package services
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/cache"
+	"github.com/mzampetakis/prods-api/api/logging"
 	"github.com/mzampetakis/prods-api/api/repositories"
+	"github.com/mzampetakis/prods-api/api/storage"
+	"github.com/mzampetakis/prods-api/api/validation"
 	"golang.org/x/net/context"
 )
 
 type FunctionalitiesIface interface {
-	GetProducts(context.Context, app.Filter) ([]*repositories.ProductFetchModel, error)
-	GetProduct(context.Context, int64) (*repositories.ProductFetchModel, error)
+	GetProducts(context.Context, app.Filter, app.Selection) ([]*repositories.ProductFetchModel, error)
+	GetProduct(context.Context, int64, app.Selection) (*repositories.ProductFetchModel, error)
 	CreateProduct(context.Context, repositories.ProductCreateModel) (int64, error)
 	UpdateProduct(context.Context, int64, repositories.ProductCreateModel) error
+	ValidateProduct(context.Context, repositories.ProductCreateModel) error
 	DeleteProduct(context.Context, int64) error
 	AssignProductsToCategory(context.Context, int64, repositories.ProductsCategoryUpdateModel) error
 
-	GetCategories(context.Context, app.Filter) ([]*repositories.CategoryFetchModel, error)
-	GetCategory(context.Context, int64) (*repositories.CategoryFetchModel, error)
+	GetProductImages(context.Context, int64) ([]*repositories.ProductImageFetchModel, error)
+	UploadProductImages(context.Context, int64, []repositories.ProductImageUploadModel) ([]*repositories.ProductImageFetchModel, error)
+	UpdateProductImagesOrder(context.Context, int64, repositories.ProductImagesOrderUpdateModel) error
+	DeleteProductImage(context.Context, int64, int64) error
+	GetMedia(context.Context, string) (*storage.Blob, error)
+
+	GetCategories(context.Context, app.Filter, app.Selection) ([]*repositories.CategoryFetchModel, error)
+	GetCategory(context.Context, int64, app.Selection) (*repositories.CategoryFetchModel, error)
 	CreateCategory(context.Context, repositories.CategoryCreateModel) (int64, error)
 	UpdateCategory(context.Context, int64, repositories.CategoryCreateModel) error
+	ValidateCategory(context.Context, repositories.CategoryCreateModel) error
 	DeleteCategory(context.Context, int64) error
+
+	SaveProductTranslation(context.Context, int64, string, repositories.ProductTranslationModel) error
+	DeleteProductTranslation(context.Context, int64, string) error
+	SaveCategoryTranslation(context.Context, int64, string, repositories.CategoryTranslationModel) error
+	DeleteCategoryTranslation(context.Context, int64, string) error
+
+	GetBrokenImages(context.Context) ([]*repositories.BrokenImageFetchModel, error)
+	GetMissingTranslations(context.Context) ([]*repositories.MissingTranslationModel, error)
 }
 
 type Service struct {
-	DB repositories.DatastoreIface
+	DB      repositories.DatastoreIface
+	Storage storage.BlobStorage
+	Images  ImageOptions
+	Pages   PageOptions
+	// AllowedImageHosts restricts the hosts of the image URLs. Entries like *.example.com match any subdomain.
+	// All hosts are allowed when empty.
+	AllowedImageHosts []string
+	// Cache drops the cached responses affected by each change. Caching is disabled when nil.
+	Cache cache.Invalidator
+	// Locales are the locales that the products and categories are translated to
+	Locales app.Locales
+}
+
+// ImageOptions configures the validation and the thumbnails of the uploaded product images
+type ImageOptions struct {
+	// MaxSize is the maximum size in bytes of each uploaded image
+	MaxSize int64
+	// MaxPixels is the maximum width × height of each uploaded image, which bounds the memory of its decoding
+	MaxPixels int64
+	// ThumbnailSizes holds the width of each generated thumbnail by its name
+	ThumbnailSizes map[string]int
+}
+
+// PageOptions configures the page size of the listings
+type PageOptions struct {
+	// DefaultLimit is the limit of the listings that do not request one
+	DefaultLimit int
+	// MaxLimit is the maximum limit of the listings and of their expanded relationships. Zero means no maximum.
+	MaxLimit int
+	// RejectOverMax rejects the limits over MaxLimit with an error instead of clamping them to MaxLimit
+	RejectOverMax bool
+}
+
+const (
+	// defaultPageLimit is the limit of the listings when no DefaultLimit is configured
+	defaultPageLimit = 3
+	// defaultExpandLimit is the number of the embedded entities of each listed entity when expand_limit is not set
+	defaultExpandLimit = 10
+)
+
+func (p PageOptions) defaultLimit() int {
+	if p.DefaultLimit <= 0 {
+		return defaultPageLimit
+	}
+	return p.DefaultLimit
+}
+
+// pageLimit returns the limit of a listing, or an error when it exceeds the maximum and is to be rejected
+func (p PageOptions) pageLimit(op string, param string, limit int, defaultLimit int) (int, error) {
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	if p.MaxLimit <= 0 || limit <= p.MaxLimit {
+		return limit, nil
+	}
+	if p.RejectOverMax {
+		return -1, &app.Error{Op: op, Code: app.EINVALIDLIMIT, Message: fmt.Sprintf("Invalid %s field: %d exceeds the maximum of %d", param, limit, p.MaxLimit)}
+	}
+	return p.MaxLimit, nil
+}
+
+// invalidateCache drops the cached responses with the provided tags.
+// Failures are only logged as the change itself has already succeeded.
+func invalidateCache(ctx context.Context, invalidator cache.Invalidator, tags ...string) {
+	if invalidator == nil {
+		return
+	}
+	if err := invalidator.Invalidate(ctx, tags...); err != nil {
+		logging.FromContext(ctx).Warnf("Could not invalidate cached responses %v: %s", tags, err.Error())
+	}
+}
+
+// validateExpand checks that only the provided relationships are requested to be expanded
+func validateExpand(selection app.Selection, allowed ...string) error {
+	for _, expand := range selection.ExpandList() {
+		valid := false
+		for _, relationship := range allowed {
+			if expand == relationship {
+				valid = true
+			}
+		}
+		if !valid {
+			return &app.Error{Op: "services.validateExpand", Code: app.EINVALIDEXPAND, Message: "Invalid expand field: " + expand}
+		}
+	}
+	return nil
+}
+
+// parseTimeRanges parses the creation and update time ranges of the listing's filter
+func parseTimeRanges(filter *app.Filter) error {
+	var err error
+	if filter.Created, err = app.ParseTimeRange(filter.CreatedFrom, filter.CreatedTo); err != nil {
+		return &app.Error{Op: "services.parseTimeRanges", Code: app.EINVALIDTIMERANGE, Message: "Invalid created_from or created_to: " + err.Error()}
+	}
+	if filter.Updated, err = app.ParseTimeRange(filter.UpdatedFrom, filter.UpdatedTo); err != nil {
+		return &app.Error{Op: "services.parseTimeRanges", Code: app.EINVALIDTIMERANGE, Message: "Invalid updated_from or updated_to: " + err.Error()}
+	}
+	return nil
+}
+
+// validateImageHost checks that the provided image URL is a URL of an allowed host. The format of the URL is a rule
+// of the requests, so when all hosts are allowed any value passes.
+func validateImageHost(imageURL string, allowedHosts []string) error {
+	if len(allowedHosts) == 0 {
+		return nil
+	}
+	parsedURL, ok := validation.ParseURL(imageURL)
+	if !ok {
+		return validation.Error("services.validateImageHost", []app.FieldError{{Field: "image_url", Rule: "allowed_host",
+			Message: "Invalid image_url: " + imageURL + " is not a URL of an allowed host."}})
+	}
+	host := strings.ToLower(parsedURL.Hostname())
+	for _, allowedHost := range allowedHosts {
+		allowedHost = strings.ToLower(allowedHost)
+		if host == allowedHost || (strings.HasPrefix(allowedHost, "*.") && strings.HasSuffix(host, allowedHost[1:])) {
+			return nil
+		}
+	}
+	return validation.Error("services.validateImageHost", []app.FieldError{{Field: "image_url", Rule: "allowed_host",
+		Message: "Invalid image_url: host " + host + " is not allowed."}})
 }
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/cache"
+	"github.com/mzampetakis/prods-api/api/repositories"
+	"github.com/mzampetakis/prods-api/api/tracing"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// HTTPClient is the client used to reach external URLs
+type HTTPClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// maxBrokenImageErrorLength is the length of the error column of the broken images
+const maxBrokenImageErrorLength = 1000
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which is internal to the provider's network
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// NewLinkCheckClient returns a client for the image URLs that refuses to connect to private, loopback,
+// link-local, shared and unspecified addresses, so that the stored URLs cannot reach internal services.
+// The addresses are checked when dialing, so hosts that resolve to them and redirects to them are refused too.
+func NewLinkCheckClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: refuseInternalAddress}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would be dialed instead of the image hosts
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// refuseInternalAddress fails the connections to the addresses that are not publicly routable
+func refuseInternalAddress(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("connections to the internal address %s are not allowed", host)
+	}
+	return nil
+}
+
+// LinkChecker periodically checks the stored image URLs and records the broken ones
+type LinkChecker struct {
+	DB     repositories.DatastoreIface
+	Client HTTPClient
+	// Interval between two consecutive checks
+	Interval time.Duration
+	// Timeout of each URL check
+	Timeout time.Duration
+	// Concurrency is the number of URLs checked in parallel
+	Concurrency int
+	// Cache drops the cached broken images report after each check
+	Cache cache.Invalidator
+}
+
+// Run checks the image URLs every Interval until the provided context is cancelled
+func (c *LinkChecker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+	for {
+		if err := c.Check(ctx); err != nil {
+			logrus.Warnf("Image link check failed: %s", err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check checks once all the stored image URLs and replaces the broken images report
+func (c *LinkChecker) Check(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "services.LinkChecker.Check")
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	imageURLs, err := c.DB.GetImageURLs(ctx)
+	if err != nil {
+		return &app.Error{Op: "services.LinkChecker.Check", Err: err}
+	}
+	span.SetAttribute("images.count", len(imageURLs))
+	concurrency := c.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	results := make([]*repositories.BrokenImageCreateModel, len(imageURLs))
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, imageURL := range imageURLs {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, imageURL *repositories.ImageURLModel) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			results[i] = c.checkURL(ctx, imageURL)
+		}(i, imageURL)
+	}
+	wg.Wait()
+
+	brokenImages := make([]repositories.BrokenImageCreateModel, 0)
+	for _, result := range results {
+		if result != nil {
+			brokenImages = append(brokenImages, *result)
+		}
+	}
+	if err = c.DB.ReplaceBrokenImages(ctx, brokenImages); err != nil {
+		return &app.Error{Op: "services.LinkChecker.Check", Err: err}
+	}
+	invalidateCache(ctx, c.Cache, cache.ReportsTag)
+	logrus.Infof("Image link check completed: %d of %d image URLs are broken", len(brokenImages), len(imageURLs))
+	return nil
+}
+
+// checkURL requests the image URL with HEAD, falling back to GET for servers that do not support HEAD.
+// A nil result means that the image URL is reachable.
+func (c *LinkChecker) checkURL(ctx context.Context, imageURL *repositories.ImageURLModel) *repositories.BrokenImageCreateModel {
+	statusCode, err := c.request(ctx, http.MethodHead, imageURL.URL)
+	if err == nil && statusCode == http.StatusMethodNotAllowed {
+		statusCode, err = c.request(ctx, http.MethodGet, imageURL.URL)
+	}
+	if err == nil && statusCode < http.StatusBadRequest {
+		return nil
+	}
+	brokenImage := &repositories.BrokenImageCreateModel{
+		EntityType: imageURL.EntityType,
+		EntityID:   imageURL.EntityID,
+		URL:        imageURL.URL,
+	}
+	if err != nil {
+		errMessage := truncate(err.Error(), maxBrokenImageErrorLength)
+		brokenImage.Error = &errMessage
+	} else {
+		code := int64(statusCode)
+		brokenImage.StatusCode = &code
+	}
+	return brokenImage
+}
+
+func (c *LinkChecker) request(ctx context.Context, method string, url string) (int, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	ctx, span := tracing.StartWithKind(ctx, "HTTP "+method, tracing.SpanKindClient)
+	defer span.End()
+	span.SetAttribute("http.method", method)
+	span.SetAttribute("http.url", url)
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		span.SetStatus(tracing.StatusError, err.Error())
+		return 0, err
+	}
+	tracing.Inject(ctx, req.Header)
+	res, err := c.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		span.SetStatus(tracing.StatusError, err.Error())
+		return 0, err
+	}
+	res.Body.Close()
+	span.SetAttribute("http.status_code", res.StatusCode)
+	return res.StatusCode, nil
+}
+
+// truncate shortens the text to up to max characters
+func truncate(text string, max int) string {
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	return string([]rune(text)[:max])
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/cache"
+	"github.com/mzampetakis/prods-api/api/imaging"
+	"github.com/mzampetakis/prods-api/api/logging"
+	"github.com/mzampetakis/prods-api/api/repositories"
+	"github.com/mzampetakis/prods-api/api/storage"
+	"golang.org/x/net/context"
+)
+
+// processedImage is a validated upload alongside with its encoded thumbnails
+type processedImage struct {
+	data        []byte
+	contentType string
+	thumbnails  map[string][]byte
+}
+
+func (s *Service) GetProductImages(ctx context.Context, productID int64) ([]*repositories.ProductImageFetchModel, error) {
+	if _, err := s.DB.GetProduct(ctx, productID, app.Selection{Fields: "id"}); err != nil {
+		return nil, &app.Error{Op: "services.GetProductImages", Err: err}
+	}
+	images, err := s.DB.GetProductImages(ctx, productID)
+	if err != nil {
+		return nil, &app.Error{Op: "services.GetProductImages", Err: err}
+	}
+	for _, image := range images {
+		image.Thumbnails = s.thumbnailKeys(image.StorageKey)
+	}
+	return images, nil
+}
+
+// UploadProductImages validates all the uploaded images before storing them alongside with their thumbnails.
+// The images are appended after the existing images of the product. The images are inserted in the DB
+// at once after all of them are stored, and the stored blobs are removed if any step fails.
+func (s *Service) UploadProductImages(ctx context.Context, productID int64, uploads []repositories.ProductImageUploadModel) ([]*repositories.ProductImageFetchModel, error) {
+	if len(uploads) == 0 {
+		return nil, &app.Error{Op: "services.UploadProductImages", Code: app.EINVALID, Message: "At least one image should be uploaded."}
+	}
+	if _, err := s.DB.GetProduct(ctx, productID, app.Selection{Fields: "id"}); err != nil {
+		return nil, &app.Error{Op: "services.UploadProductImages", Err: err}
+	}
+	processed := make([]*processedImage, 0, len(uploads))
+	for _, upload := range uploads {
+		image, err := s.processImage(upload)
+		if err != nil {
+			return nil, &app.Error{Op: "services.UploadProductImages", Err: err}
+		}
+		processed = append(processed, image)
+	}
+
+	storedKeys := make([]string, 0)
+	newImages := make([]repositories.ProductImageCreateModel, 0, len(processed))
+	for _, image := range processed {
+		key := fmt.Sprintf("products/%d/%s%s", productID, uuid.New(), imaging.Extensions[image.contentType])
+		blobs := map[string][]byte{key: image.data}
+		thumbnailKeys := s.thumbnailKeys(key)
+		for name, thumbnail := range image.thumbnails {
+			blobs[thumbnailKeys[name]] = thumbnail
+		}
+		for blobKey, data := range blobs {
+			if err := s.Storage.Put(ctx, blobKey, bytes.NewReader(data), image.contentType); err != nil {
+				s.deleteBlobs(ctx, storedKeys)
+				return nil, &app.Error{Op: "services.UploadProductImages", Err: err}
+			}
+			storedKeys = append(storedKeys, blobKey)
+		}
+		newImages = append(newImages, repositories.ProductImageCreateModel{
+			ProductID:   productID,
+			ContentType: image.contentType,
+			Size:        int64(len(image.data)),
+			StorageKey:  key,
+		})
+	}
+	imageIDs, err := s.DB.CreateProductImages(ctx, newImages)
+	if err != nil {
+		s.deleteBlobs(ctx, storedKeys)
+		return nil, &app.Error{Op: "services.UploadProductImages", Err: err}
+	}
+	invalidateCache(ctx, s.Cache, cache.ProductTag(productID))
+
+	productImages, err := s.DB.GetProductImages(ctx, productID)
+	if err != nil {
+		return nil, &app.Error{Op: "services.UploadProductImages", Err: err}
+	}
+	created := make(map[int64]*repositories.ProductImageFetchModel)
+	for _, image := range productImages {
+		created[image.ID] = image
+	}
+	images := make([]*repositories.ProductImageFetchModel, 0, len(imageIDs))
+	for _, imageID := range imageIDs {
+		if image, ok := created[imageID]; ok {
+			image.Thumbnails = s.thumbnailKeys(image.StorageKey)
+			images = append(images, image)
+		}
+	}
+	return images, nil
+}
+
+func (s *Service) UpdateProductImagesOrder(ctx context.Context, productID int64, order repositories.ProductImagesOrderUpdateModel) error {
+	if _, err := s.DB.GetProduct(ctx, productID, app.Selection{Fields: "id"}); err != nil {
+		return &app.Error{Op: "services.UpdateProductImagesOrder", Err: err}
+	}
+	images, err := s.DB.GetProductImages(ctx, productID)
+	if err != nil {
+		return &app.Error{Op: "services.UpdateProductImagesOrder", Err: err}
+	}
+	remaining := make(map[int64]bool)
+	for _, image := range images {
+		remaining[image.ID] = true
+	}
+	for _, imageID := range order {
+		if !remaining[imageID] {
+			return &app.Error{Op: "services.UpdateProductImagesOrder", Code: app.EINVALID, Message: "Image order must contain all the product's images exactly once."}
+		}
+		delete(remaining, imageID)
+	}
+	if len(remaining) != 0 {
+		return &app.Error{Op: "services.UpdateProductImagesOrder", Code: app.EINVALID, Message: "Image order must contain all the product's images exactly once."}
+	}
+	if err = s.DB.UpdateProductImagesOrder(ctx, productID, order); err != nil {
+		return &app.Error{Op: "services.UpdateProductImagesOrder", Err: err}
+	}
+	invalidateCache(ctx, s.Cache, cache.ProductTag(productID))
+	return nil
+}
+
+func (s *Service) DeleteProductImage(ctx context.Context, productID int64, imageID int64) error {
+	image, err := s.DB.GetProductImage(ctx, productID, imageID)
+	if err != nil {
+		return &app.Error{Op: "services.DeleteProductImage", Err: err}
+	}
+	if err = s.DB.DeleteProductImage(ctx, productID, imageID); err != nil {
+		return &app.Error{Op: "services.DeleteProductImage", Err: err}
+	}
+	invalidateCache(ctx, s.Cache, cache.ProductTag(productID))
+	s.deleteImageBlobs(ctx, image)
+	return nil
+}
+
+func (s *Service) GetMedia(ctx context.Context, key string) (*storage.Blob, error) {
+	blob, err := s.Storage.Get(ctx, key)
+	if err != nil {
+		return nil, &app.Error{Op: "services.GetMedia", Err: err}
+	}
+	return blob, nil
+}
+
+// processImage checks the size, the type and the dimensions of the upload and generates its thumbnails.
+// The dimensions are checked before the pixels are decoded, as small files can declare huge images.
+func (s *Service) processImage(upload repositories.ProductImageUploadModel) (*processedImage, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(upload.Data, s.Images.MaxSize+1))
+	if err != nil {
+		return nil, &app.Error{Op: "services.processImage", Code: app.EINVALID, Err: err, Message: "Could not read image " + upload.Filename}
+	}
+	if int64(len(data)) > s.Images.MaxSize {
+		return nil, &app.Error{Op: "services.processImage", Code: app.EIMAGETOOLARGE,
+			Message: fmt.Sprintf("Image %s exceeds the maximum size of %d bytes.", upload.Filename, s.Images.MaxSize)}
+	}
+	contentType, err := imaging.DetectContentType(data)
+	if err != nil {
+		return nil, &app.Error{Op: "services.processImage", Err: err}
+	}
+	config, err := imaging.DecodeConfig(data)
+	if err != nil {
+		return nil, &app.Error{Op: "services.processImage", Err: err}
+	}
+	if int64(config.Width)*int64(config.Height) > s.Images.MaxPixels {
+		return nil, &app.Error{Op: "services.processImage", Code: app.EIMAGETOOLARGE,
+			Message: fmt.Sprintf("Image %s of %dx%d pixels exceeds the maximum of %d pixels.", upload.Filename, config.Width, config.Height, s.Images.MaxPixels)}
+	}
+	img, err := imaging.Decode(data)
+	if err != nil {
+		return nil, &app.Error{Op: "services.processImage", Err: err}
+	}
+	thumbnails := make(map[string][]byte)
+	for name, width := range s.Images.ThumbnailSizes {
+		thumbnail, err := imaging.Encode(imaging.Thumbnail(img, width), contentType)
+		if err != nil {
+			return nil, &app.Error{Op: "services.processImage", Err: err}
+		}
+		thumbnails[name] = thumbnail
+	}
+	return &processedImage{data: data, contentType: contentType, thumbnails: thumbnails}, nil
+}
+
+// thumbnailKeys returns the storage keys of the configured thumbnails of an image
+func (s *Service) thumbnailKeys(key string) map[string]string {
+	ext := path.Ext(key)
+	keys := make(map[string]string)
+	for name := range s.Images.ThumbnailSizes {
+		keys[name] = strings.TrimSuffix(key, ext) + "_" + name + ext
+	}
+	return keys
+}
+
+// deleteImageBlobs removes an image and its thumbnails from the storage.
+// Failures are only logged as the image has already been removed from the DB.
+func (s *Service) deleteImageBlobs(ctx context.Context, image *repositories.ProductImageFetchModel) {
+	keys := []string{image.StorageKey}
+	for _, key := range s.thumbnailKeys(image.StorageKey) {
+		keys = append(keys, key)
+	}
+	s.deleteBlobs(ctx, keys)
+}
+
+// deleteBlobs removes the blobs of the keys from the storage, logging the failures
+func (s *Service) deleteBlobs(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := s.Storage.Delete(ctx, key); err != nil {
+			logging.FromContext(ctx).Warnf("Could not delete media %s: %s", key, err.Error())
+		}
+	}
+}
@@ -4,31 +4,44 @@ import (
 	"strings"
 
 	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/cache"
 	"github.com/mzampetakis/prods-api/api/repositories"
+	"github.com/mzampetakis/prods-api/api/validation"
 	"golang.org/x/net/context"
 )
 
-func (s *Service) GetProducts(ctx context.Context, filter app.Filter) ([]*repositories.ProductFetchModel, error) {
-	if filter.Limit <= 0 {
-		filter.Limit = 3
+func (s *Service) GetProducts(ctx context.Context, filter app.Filter, selection app.Selection) ([]*repositories.ProductFetchModel, error) {
+	limit, err := s.Pages.pageLimit("services.GetProducts", "limit", filter.Limit, s.Pages.defaultLimit())
+	if err != nil {
+		return nil, err
 	}
+	filter.Limit = limit
 	if len(filter.SortBy) == 0 {
 		filter.SortBy = "id"
 	}
 	filter.SortDirection = strings.ToUpper(filter.SortDirection)
 	if filter.SortDirection != "" && filter.SortDirection != app.ASC && filter.SortDirection != app.DESC {
-		return nil, &app.Error{Op: "services.GetProducts", Code: app.EINVALID, Message: "Invalid SortDirection field: " + filter.SortDirection}
+		return nil, &app.Error{Op: "services.GetProducts", Code: app.EINVALIDSORTDIRECTION, Message: "Invalid SortDirection field: " + filter.SortDirection}
+	}
+	if err := validateExpand(selection, "category"); err != nil {
+		return nil, &app.Error{Op: "services.GetProducts", Err: err}
+	}
+	if err := parseTimeRanges(&filter); err != nil {
+		return nil, &app.Error{Op: "services.GetProducts", Err: err}
 	}
 
-	prods, err := s.DB.GetProducts(ctx, filter)
+	prods, err := s.DB.GetProducts(ctx, filter, selection)
 	if err != nil {
 		return nil, &app.Error{Op: "services.GetProducts", Err: err}
 	}
 	return prods, nil
 }
 
-func (s *Service) GetProduct(ctx context.Context, productID int64) (*repositories.ProductFetchModel, error) {
-	prod, err := s.DB.GetProduct(ctx, productID)
+func (s *Service) GetProduct(ctx context.Context, productID int64, selection app.Selection) (*repositories.ProductFetchModel, error) {
+	if err := validateExpand(selection, "category"); err != nil {
+		return nil, &app.Error{Op: "services.GetProduct", Err: err}
+	}
+	prod, err := s.DB.GetProduct(ctx, productID, selection)
 	if err != nil {
 		return nil, &app.Error{Op: "services.GetProduct", Err: err}
 	}
@@ -36,55 +49,47 @@ func (s *Service) GetProduct(ctx context.Context, productID int64) (*repositorie
 }
 
 func (s *Service) CreateProduct(ctx context.Context, product repositories.ProductCreateModel) (int64, error) {
-	if product.Title == nil || len(*product.Title) == 0 {
-		return -1, &app.Error{Op: "services.CreateProduct", Code: app.EINVALID, Message: "Title cannot be empty."}
-	}
-	if product.Price == nil {
-		return -1, &app.Error{Op: "services.CreateProduct", Code: app.EINVALID, Message: "Price cannot be empty."}
-	}
-	if product.CategoryID != nil {
-		category, err := s.DB.GetCategory(ctx, *product.CategoryID)
-		if err != nil || category.ID != *product.CategoryID {
-			return -1, &app.Error{Op: "services.CreateProduct", Code: app.EINVALID, Message: "Invalid Category."}
-		}
+	if err := s.ValidateProduct(ctx, product); err != nil {
+		return -1, &app.Error{Op: "services.CreateProduct", Err: err}
 	}
 	insertedID, err := s.DB.CreateProduct(ctx, product)
 	if err != nil {
 		return -1, &app.Error{Op: "services.CreateProduct", Err: err}
 	}
+	invalidateCache(ctx, s.Cache, cache.ProductsTag)
 	return insertedID, nil
 }
 
 func (s *Service) UpdateProduct(ctx context.Context, productID int64, product repositories.ProductCreateModel) error {
-	if product.Title == nil || len(*product.Title) == 0 {
-		return &app.Error{Op: "services.CreateProduct", Code: app.EINVALID, Message: "Title cannot be empty."}
-	}
-	if product.Price == nil {
-		return &app.Error{Op: "services.CreateProduct", Code: app.EINVALID, Message: "Price cannot be empty."}
-	}
-	if product.CategoryID != nil {
-		category, err := s.DB.GetCategory(ctx, *product.CategoryID)
-		if err != nil || category.ID != *product.CategoryID {
-			return &app.Error{Op: "services.CreateProduct", Code: app.EINVALID, Message: "Invalid Category."}
-		}
+	if err := s.ValidateProduct(ctx, product); err != nil {
+		return &app.Error{Op: "services.UpdateProduct", Err: err}
 	}
 	err := s.DB.UpdateProduct(ctx, productID, product)
 	if err != nil {
 		return &app.Error{Op: "services.UpdateProduct", Err: err}
 	}
+	invalidateCache(ctx, s.Cache, cache.ProductTag(productID), cache.ProductsTag)
 	return nil
 }
 
 func (s *Service) DeleteProduct(ctx context.Context, productID int64) error {
-	err := s.DB.DeleteProduct(ctx, productID)
+	images, err := s.DB.GetProductImages(ctx, productID)
 	if err != nil {
 		return &app.Error{Op: "services.DeleteProduct", Err: err}
 	}
+	err = s.DB.DeleteProduct(ctx, productID)
+	if err != nil {
+		return &app.Error{Op: "services.DeleteProduct", Err: err}
+	}
+	invalidateCache(ctx, s.Cache, cache.ProductTag(productID), cache.ProductsTag)
+	for _, image := range images {
+		s.deleteImageBlobs(ctx, image)
+	}
 	return nil
 }
 
 func (s *Service) AssignProductsToCategory(ctx context.Context, CategoryID int64, productsCategory repositories.ProductsCategoryUpdateModel) error {
-	category, err := s.DB.GetCategory(ctx, CategoryID)
+	category, err := s.DB.GetCategory(ctx, CategoryID, app.Selection{})
 	if err != nil || category.ID != CategoryID {
 		return &app.Error{Op: "services.AssignProductsToCategory", Code: app.EINVALID, Message: "Invalid Category."}
 	}
@@ -92,5 +97,34 @@ func (s *Service) AssignProductsToCategory(ctx context.Context, CategoryID int64
 	if err != nil {
 		return &app.Error{Op: "services.AssignProductsToCategory", Err: err}
 	}
+	tags := []string{cache.ProductsTag, cache.CategoryTag(CategoryID)}
+	for _, productID := range productsCategory {
+		tags = append(tags, cache.ProductTag(productID))
+	}
+	invalidateCache(ctx, s.Cache, tags...)
+	return nil
+}
+
+// ValidateProduct checks that the product has the fields of its model and follows the business rules: its image is
+// on an allowed host and its category exists. All the violations are reported in a single error.
+func (s *Service) ValidateProduct(ctx context.Context, product repositories.ProductCreateModel) error {
+	violations, err := validation.Validate(product)
+	if err != nil {
+		return &app.Error{Op: "services.ValidateProduct", Err: err}
+	}
+	if product.ImageURL != nil {
+		if err := validateImageHost(*product.ImageURL, s.AllowedImageHosts); err != nil {
+			violations = append(violations, app.ErrorDetails(err)...)
+		}
+	}
+	if product.CategoryID != nil {
+		category, err := s.DB.GetCategory(ctx, *product.CategoryID, app.Selection{})
+		if err != nil || category.ID != *product.CategoryID {
+			violations = append(violations, app.FieldError{Field: "category_id", Rule: "exists", Message: "Invalid Category."})
+		}
+	}
+	if len(violations) > 0 {
+		return validation.Error("services.ValidateProduct", violations)
+	}
 	return nil
 }
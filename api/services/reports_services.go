@@ -0,0 +1,15 @@
+package services
+
+import (
+	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/repositories"
+	"golang.org/x/net/context"
+)
+
+func (s *Service) GetBrokenImages(ctx context.Context) ([]*repositories.BrokenImageFetchModel, error) {
+	brokenImages, err := s.DB.GetBrokenImages(ctx)
+	if err != nil {
+		return nil, &app.Error{Op: "services.GetBrokenImages", Err: err}
+	}
+	return brokenImages, nil
+}
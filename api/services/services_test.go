@@ -1,19 +1,36 @@
 package services
 
 import (
+	"bytes"
 	"database/sql"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/cache"
 	"github.com/mzampetakis/prods-api/api/repositories"
+	"github.com/mzampetakis/prods-api/api/storage"
 	"golang.org/x/net/context"
 )
 
-type DBMock struct{}
+type DBMock struct {
+	brokenImages     []repositories.BrokenImageCreateModel
+	filter           app.Filter
+	failImageInserts bool
+}
 
-func (db *DBMock) GetCategories(ctx context.Context, filter app.Filter) ([]*repositories.CategoryFetchModel, error) {
+func (db *DBMock) GetCategories(ctx context.Context, filter app.Filter, selection app.Selection) ([]*repositories.CategoryFetchModel, error) {
 	var categories []*repositories.CategoryFetchModel
 	categoryTitle := "Laptops"
 	categoryImageURL := "https://category200.image"
@@ -23,14 +40,14 @@ func (db *DBMock) GetCategories(ctx context.Context, filter app.Filter) ([]*repo
 		Title:     &categoryTitle,
 		ImageURL:  &categoryImageURL,
 		Sort:      &categorySort,
-		CreatedAt: "2020-05-25 21:02:15",
-		UpdatedAt: "2020-05-25 21:05:15",
+		CreatedAt: time.Date(2020, 5, 25, 21, 2, 15, 0, time.UTC),
+		UpdatedAt: time.Date(2020, 5, 25, 21, 5, 15, 0, time.UTC),
 	})
 
 	return categories, nil
 }
 
-func (db *DBMock) GetCategory(ctx context.Context, ID int64) (*repositories.CategoryFetchModel, error) {
+func (db *DBMock) GetCategory(ctx context.Context, ID int64, selection app.Selection) (*repositories.CategoryFetchModel, error) {
 	if ID == 201 {
 		categoryTitle := "Monitors"
 		categoryImageURL := "https://category201.image"
@@ -40,12 +57,12 @@ func (db *DBMock) GetCategory(ctx context.Context, ID int64) (*repositories.Cate
 			Title:     &categoryTitle,
 			ImageURL:  &categoryImageURL,
 			Sort:      &categorySort,
-			CreatedAt: "2020-05-24 21:02:15",
-			UpdatedAt: "2020-05-24 21:05:15",
+			CreatedAt: time.Date(2020, 5, 24, 21, 2, 15, 0, time.UTC),
+			UpdatedAt: time.Date(2020, 5, 24, 21, 5, 15, 0, time.UTC),
 		}, nil
 	}
 
-	return nil, &app.Error{Op: "repositories.getCategory", Code: app.ENOTFOUND, Err: sql.ErrNoRows}
+	return nil, &app.Error{Op: "repositories.getCategory", Code: app.ECATEGORYNOTFOUND, Err: sql.ErrNoRows}
 }
 
 func (db *DBMock) CreateCategory(ctx context.Context, newCategory repositories.CategoryCreateModel) (int64, error) {
@@ -60,7 +77,8 @@ func (db *DBMock) DeleteCategory(ctx context.Context, categoryID int64) error {
 	return nil
 }
 
-func (db *DBMock) GetProducts(ctx context.Context, filter app.Filter) ([]*repositories.ProductFetchModel, error) {
+func (db *DBMock) GetProducts(ctx context.Context, filter app.Filter, selection app.Selection) ([]*repositories.ProductFetchModel, error) {
+	db.filter = filter
 	var products []*repositories.ProductFetchModel
 	productTitle := "Flash Drive 1TB"
 	productImageURL := "https://product200.image"
@@ -72,14 +90,14 @@ func (db *DBMock) GetProducts(ctx context.Context, filter app.Filter) ([]*reposi
 		ImageURL:   &productImageURL,
 		Price:      &productPrice,
 		CategoryID: &productCategory,
-		CreatedAt:  "2020-05-25 21:02:15",
-		UpdatedAt:  "2020-05-25 21:05:15",
+		CreatedAt:  time.Date(2020, 5, 25, 21, 2, 15, 0, time.UTC),
+		UpdatedAt:  time.Date(2020, 5, 25, 21, 5, 15, 0, time.UTC),
 	})
 
 	return products, nil
 }
 
-func (db *DBMock) GetProduct(ctx context.Context, ID int64) (*repositories.ProductFetchModel, error) {
+func (db *DBMock) GetProduct(ctx context.Context, ID int64, selection app.Selection) (*repositories.ProductFetchModel, error) {
 	if ID == 201 {
 		productTitle := "Flash Drive 1TB"
 		productImageURL := "https://product200.image"
@@ -91,12 +109,12 @@ func (db *DBMock) GetProduct(ctx context.Context, ID int64) (*repositories.Produ
 			ImageURL:   &productImageURL,
 			Price:      &productPrice,
 			CategoryID: &productCategory,
-			CreatedAt:  "2020-05-25 21:02:15",
-			UpdatedAt:  "2020-05-25 21:05:15",
+			CreatedAt:  time.Date(2020, 5, 25, 21, 2, 15, 0, time.UTC),
+			UpdatedAt:  time.Date(2020, 5, 25, 21, 5, 15, 0, time.UTC),
 		}, nil
 	}
 
-	return nil, &app.Error{Op: "repositories.Getproduct", Code: app.ENOTFOUND, Err: sql.ErrNoRows}
+	return nil, &app.Error{Op: "repositories.Getproduct", Code: app.EPRODUCTNOTFOUND, Err: sql.ErrNoRows}
 }
 
 func (db *DBMock) CreateProduct(ctx context.Context, newProduct repositories.ProductCreateModel) (int64, error) {
@@ -115,13 +133,97 @@ func (db *DBMock) AssignProductsToCategory(ctx context.Context, categoryID int64
 	return nil
 }
 
+func (db *DBMock) GetProductImages(ctx context.Context, productID int64) ([]*repositories.ProductImageFetchModel, error) {
+	images := make([]*repositories.ProductImageFetchModel, 0)
+	if productID == 201 {
+		images = append(images, &repositories.ProductImageFetchModel{
+			ID:          300,
+			ProductID:   201,
+			Position:    1,
+			ContentType: "image/png",
+			Size:        1024,
+			StorageKey:  "products/201/image.png",
+			CreatedAt:   time.Date(2020, 5, 25, 21, 2, 15, 0, time.UTC),
+		})
+	}
+	return images, nil
+}
+
+func (db *DBMock) GetProductImage(ctx context.Context, productID int64, imageID int64) (*repositories.ProductImageFetchModel, error) {
+	if productID == 201 && imageID == 300 {
+		return &repositories.ProductImageFetchModel{
+			ID:          300,
+			ProductID:   201,
+			Position:    1,
+			ContentType: "image/png",
+			Size:        1024,
+			StorageKey:  "products/201/image.png",
+			CreatedAt:   time.Date(2020, 5, 25, 21, 2, 15, 0, time.UTC),
+		}, nil
+	}
+	return nil, &app.Error{Op: "repositories.GetProductImage", Code: app.EIMAGENOTFOUND, Err: sql.ErrNoRows}
+}
+
+func (db *DBMock) CreateProductImages(ctx context.Context, images []repositories.ProductImageCreateModel) ([]int64, error) {
+	if db.failImageInserts {
+		return nil, &app.Error{Op: "repositories.CreateProductImages", Code: app.EINTERNAL}
+	}
+	return []int64{300}, nil
+}
+
+func (db *DBMock) UpdateProductImagesOrder(ctx context.Context, productID int64, order repositories.ProductImagesOrderUpdateModel) error {
+	return nil
+}
+
+func (db *DBMock) DeleteProductImage(ctx context.Context, productID int64, imageID int64) error {
+	return nil
+}
+
+func (db *DBMock) GetImageURLs(ctx context.Context) ([]*repositories.ImageURLModel, error) {
+	return []*repositories.ImageURLModel{
+		{EntityType: "product", EntityID: 200, URL: "https://images.example.com/ok.png"},
+		{EntityType: "product", EntityID: 201, URL: "https://images.example.com/missing.png"},
+		{EntityType: "category", EntityID: 201, URL: "https://unreachable.example.com/image.png"},
+		{EntityType: "category", EntityID: 202, URL: "https://images.example.com/no-head.png"},
+	}, nil
+}
+
+func (db *DBMock) GetBrokenImages(ctx context.Context) ([]*repositories.BrokenImageFetchModel, error) {
+	return make([]*repositories.BrokenImageFetchModel, 0), nil
+}
+
+func (db *DBMock) ReplaceBrokenImages(ctx context.Context, brokenImages []repositories.BrokenImageCreateModel) error {
+	db.brokenImages = brokenImages
+	return nil
+}
+
+func (db *DBMock) SaveProductTranslation(ctx context.Context, productID int64, locale string, translation repositories.ProductTranslationModel) error {
+	return nil
+}
+
+func (db *DBMock) DeleteProductTranslation(ctx context.Context, productID int64, locale string) error {
+	return nil
+}
+
+func (db *DBMock) SaveCategoryTranslation(ctx context.Context, categoryID int64, locale string, translation repositories.CategoryTranslationModel) error {
+	return nil
+}
+
+func (db *DBMock) DeleteCategoryTranslation(ctx context.Context, categoryID int64, locale string) error {
+	return nil
+}
+
+func (db *DBMock) GetMissingTranslations(ctx context.Context, locales []string) ([]*repositories.MissingTranslationModel, error) {
+	return make([]*repositories.MissingTranslationModel, 0), nil
+}
+
 func TestGetCategories(t *testing.T) {
 	db := DBMock{}
 	mockService := &Service{DB: &db}
 	ctx := context.Background()
 	ctx = context.WithValue(ctx, "request_id", uuid.New())
 	filter := app.Filter{}
-	categs, err := mockService.GetCategories(ctx, filter)
+	categs, err := mockService.GetCategories(ctx, filter, app.Selection{})
 	if err != nil {
 		t.Errorf("Expected success but got error %s", err.Error())
 	}
@@ -145,7 +247,7 @@ func TestGetCategory(t *testing.T) {
 
 	for tName, tc := range tests {
 		t.Run(tName, func(t *testing.T) {
-			categ, err := mockService.GetCategory(ctx, tc.ID)
+			categ, err := mockService.GetCategory(ctx, tc.ID, app.Selection{})
 			if tc.err == nil && err != nil {
 				t.Errorf("Expected success but got error %s", err.Error())
 			}
@@ -255,3 +357,440 @@ func TestCreateProduct_WhenCategoryNotProvided_Succeeds(t *testing.T) {
 		t.Errorf("Expected productID to be %d but got %d", excpectedProductID, productID)
 	}
 }
+
+func TestPageOptions_pageLimit(t *testing.T) {
+	tests := map[string]struct {
+		options       PageOptions
+		limit         int
+		expectedLimit int
+		expectedCode  string
+	}{
+		"Default limit":            {options: PageOptions{}, limit: 0, expectedLimit: 3},
+		"Configured default limit": {options: PageOptions{DefaultLimit: 20, MaxLimit: 100}, limit: 0, expectedLimit: 20},
+		"Limit within maximum":     {options: PageOptions{MaxLimit: 100}, limit: 100, expectedLimit: 100},
+		"No maximum":               {options: PageOptions{}, limit: 1000000, expectedLimit: 1000000},
+		"Clamped limit":            {options: PageOptions{MaxLimit: 100}, limit: 1000000, expectedLimit: 100},
+		"Rejected limit":           {options: PageOptions{MaxLimit: 100, RejectOverMax: true}, limit: 101, expectedLimit: -1, expectedCode: app.EINVALIDLIMIT},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Act
+			limit, err := tc.options.pageLimit("services.GetProducts", "limit", tc.limit, tc.options.defaultLimit())
+
+			//Assert
+			if limit != tc.expectedLimit {
+				t.Errorf("Expected limit %d but got %d", tc.expectedLimit, limit)
+			}
+			if tc.expectedCode != "" && app.ErrorCode(err) != tc.expectedCode {
+				t.Errorf("Expected error code %s but got %v", tc.expectedCode, err)
+			}
+			if tc.expectedCode == "" && err != nil {
+				t.Errorf("Expected success but got error %s", err.Error())
+			}
+		})
+	}
+}
+
+func TestGetProducts_WhenInvalidExpandProvided_Fails(t *testing.T) {
+	db := DBMock{}
+	mockService := &Service{DB: &db}
+	ctx := context.Background()
+	ctx = context.WithValue(ctx, "request_id", uuid.New())
+	selection := app.Selection{Expand: "category,products"}
+
+	products, err := mockService.GetProducts(ctx, app.Filter{}, selection)
+	if err == nil {
+		t.Errorf("Expected error but got no error")
+	}
+	if app.ErrorCode(err) != app.EINVALIDEXPAND {
+		t.Errorf("Expected error code  %s, but got error code %s", app.EINVALIDEXPAND, app.ErrorCode(err))
+	}
+	if products != nil {
+		t.Errorf("Expected no products but got %d", len(products))
+	}
+}
+
+func TestGetProducts_TimeRanges(t *testing.T) {
+	may25 := time.Date(2020, 5, 25, 0, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		filter          app.Filter
+		expectedCreated app.TimeRange
+		expectedUpdated app.TimeRange
+		err             string
+	}{
+		"No ranges":            {filter: app.Filter{}},
+		"Dates":                {filter: app.Filter{CreatedFrom: "2020-05-25", CreatedTo: "2020-05-25"}, expectedCreated: app.TimeRange{From: may25, Before: may25.AddDate(0, 0, 1)}},
+		"Date-time in UTC":     {filter: app.Filter{UpdatedFrom: "2020-05-25T03:00:00+03:00"}, expectedUpdated: app.TimeRange{From: may25}},
+		"Inclusive date-time":  {filter: app.Filter{UpdatedTo: "2020-05-25T00:00:00Z"}, expectedUpdated: app.TimeRange{Before: may25.Add(time.Second)}},
+		"Fractional date-time": {filter: app.Filter{UpdatedTo: "2020-05-25T00:00:00.999Z"}, expectedUpdated: app.TimeRange{Before: may25.Add(time.Second)}},
+		"Same date-time bounds": {filter: app.Filter{CreatedFrom: "2020-05-25T00:00:00Z", CreatedTo: "2020-05-25T00:00:00Z"},
+			expectedCreated: app.TimeRange{From: may25, Before: may25.Add(time.Second)}},
+		"Invalid bound": {filter: app.Filter{CreatedFrom: "25/05/2020"}, err: app.EINVALIDTIMERANGE},
+		"From after to": {filter: app.Filter{UpdatedFrom: "2020-05-26", UpdatedTo: "2020-05-25"}, err: app.EINVALIDTIMERANGE},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Prepare
+			db := DBMock{}
+			mockService := &Service{DB: &db}
+
+			//Act
+			_, err := mockService.GetProducts(context.Background(), tc.filter, app.Selection{})
+
+			//Assert
+			if app.ErrorCode(err) != tc.err {
+				t.Fatalf("Expected error code %q but got %v", tc.err, err)
+			}
+			if tc.err != "" {
+				return
+			}
+			if !db.filter.Created.From.Equal(tc.expectedCreated.From) || !db.filter.Created.Before.Equal(tc.expectedCreated.Before) {
+				t.Errorf("Expected created range %v but got %v", tc.expectedCreated, db.filter.Created)
+			}
+			if !db.filter.Updated.From.Equal(tc.expectedUpdated.From) || !db.filter.Updated.Before.Equal(tc.expectedUpdated.Before) {
+				t.Errorf("Expected updated range %v but got %v", tc.expectedUpdated, db.filter.Updated)
+			}
+		})
+	}
+}
+
+type StorageMock struct {
+	blobs map[string][]byte
+}
+
+func (s *StorageMock) Put(ctx context.Context, key string, data io.Reader, contentType string) error {
+	content, err := ioutil.ReadAll(data)
+	if err != nil {
+		return err
+	}
+	s.blobs[key] = content
+	return nil
+}
+
+func (s *StorageMock) Get(ctx context.Context, key string) (*storage.Blob, error) {
+	content, ok := s.blobs[key]
+	if !ok {
+		return nil, &app.Error{Op: "storage.Get", Code: app.EMEDIANOTFOUND}
+	}
+	return &storage.Blob{ReadCloser: ioutil.NopCloser(bytes.NewReader(content)), Size: int64(len(content))}, nil
+}
+
+func (s *StorageMock) Delete(ctx context.Context, key string) error {
+	delete(s.blobs, key)
+	return nil
+}
+
+func pngImage(width int, height int) []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height)))
+	return buf.Bytes()
+}
+
+func TestUploadProductImages(t *testing.T) {
+	tests := map[string]struct {
+		productID     int64
+		data          []byte
+		failInserts   bool
+		err           string
+		expectedBlobs int
+	}{
+		"Valid image is stored with thumbnails": {productID: 201, data: pngImage(400, 200), expectedBlobs: 3},
+		"Image too large":                       {productID: 201, data: pngImage(2000, 2000), err: app.EIMAGETOOLARGE},
+		"Image dimensions too large":            {productID: 201, data: pngImage(1000, 1000), err: app.EIMAGETOOLARGE},
+		"Unsupported image type":                {productID: 201, data: []byte("<html></html>"), err: app.EINVALIDIMAGE},
+		"Product not found":                     {productID: 404, data: pngImage(400, 200), err: app.EPRODUCTNOTFOUND},
+		"Failed insert removes stored blobs":    {productID: 201, data: pngImage(400, 200), failInserts: true, err: app.EINTERNAL},
+	}
+	ctx := context.Background()
+	ctx = context.WithValue(ctx, "request_id", uuid.New())
+
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			storageMock := &StorageMock{blobs: make(map[string][]byte)}
+			mockService := &Service{DB: &DBMock{failImageInserts: tc.failInserts}, Storage: storageMock, Images: ImageOptions{
+				MaxSize:        10000,
+				MaxPixels:      500000,
+				ThumbnailSizes: map[string]int{"small": 50, "medium": 100},
+			}}
+			uploads := []repositories.ProductImageUploadModel{{Filename: "image.png", Data: bytes.NewReader(tc.data)}}
+
+			images, err := mockService.UploadProductImages(ctx, tc.productID, uploads)
+
+			if app.ErrorCode(err) != tc.err {
+				t.Errorf("Expected error code %s but got %s", tc.err, app.ErrorCode(err))
+			}
+			if len(storageMock.blobs) != tc.expectedBlobs {
+				t.Errorf("Expected %d stored blobs but got %d", tc.expectedBlobs, len(storageMock.blobs))
+			}
+			if err == nil && len(images[0].Thumbnails) != 2 {
+				t.Errorf("Expected 2 thumbnails but got %d", len(images[0].Thumbnails))
+			}
+		})
+	}
+}
+
+func TestUpdateProductImagesOrder_WhenImageMissing_Fails(t *testing.T) {
+	db := DBMock{}
+	mockService := &Service{DB: &db}
+	ctx := context.Background()
+	ctx = context.WithValue(ctx, "request_id", uuid.New())
+
+	err := mockService.UpdateProductImagesOrder(ctx, 201, repositories.ProductImagesOrderUpdateModel{300, 301})
+	if app.ErrorCode(err) != app.EINVALID {
+		t.Errorf("Expected error code  %s, but got error code %s", app.EINVALID, app.ErrorCode(err))
+	}
+}
+
+func TestUpdateProductImagesOrder_WhenProductNotExists_Fails(t *testing.T) {
+	db := DBMock{}
+	mockService := &Service{DB: &db}
+	ctx := context.Background()
+	ctx = context.WithValue(ctx, "request_id", uuid.New())
+
+	err := mockService.UpdateProductImagesOrder(ctx, 404, repositories.ProductImagesOrderUpdateModel{})
+	if app.ErrorCode(err) != app.EPRODUCTNOTFOUND {
+		t.Errorf("Expected error code  %s, but got error code %s", app.EPRODUCTNOTFOUND, app.ErrorCode(err))
+	}
+}
+
+func TestValidateImageHost(t *testing.T) {
+	tests := map[string]struct {
+		imageURL     string
+		allowedHosts []string
+		err          string
+	}{
+		"All hosts allowed":             {imageURL: "https://images.example.com/image.png"},
+		"Format left to the url rule":   {imageURL: "some image"},
+		"Relative URL":                  {imageURL: "/image.png", allowedHosts: []string{"cdn.example.com"}, err: app.EINVALID},
+		"Unsupported scheme":            {imageURL: "ftp://cdn.example.com/image.png", allowedHosts: []string{"cdn.example.com"}, err: app.EINVALID},
+		"Allowed host":                  {imageURL: "https://cdn.example.com/image.png", allowedHosts: []string{"cdn.example.com"}},
+		"Allowed wildcard host":         {imageURL: "https://eu.cdn.example.com/image.png", allowedHosts: []string{"*.cdn.example.com"}},
+		"Not allowed host":              {imageURL: "https://evil.com/image.png", allowedHosts: []string{"cdn.example.com"}, err: app.EINVALID},
+		"Not allowed host with postfix": {imageURL: "https://evilcdn.example.com/image.png", allowedHosts: []string{"*.cdn.example.com"}, err: app.EINVALID},
+	}
+
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			err := validateImageHost(tc.imageURL, tc.allowedHosts)
+			if app.ErrorCode(err) != tc.err {
+				t.Errorf("Expected error code %s but got %s", tc.err, app.ErrorCode(err))
+			}
+		})
+	}
+}
+
+type HTTPClientMock struct{}
+
+func (c *HTTPClientMock) Do(req *http.Request) (*http.Response, error) {
+	res := &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(""))}
+	switch req.URL.Host + req.URL.Path {
+	case "images.example.com/missing.png":
+		res.StatusCode = http.StatusNotFound
+	case "images.example.com/no-head.png":
+		if req.Method == http.MethodHead {
+			res.StatusCode = http.StatusMethodNotAllowed
+		}
+	case "unreachable.example.com/image.png":
+		return nil, errors.New("no such host")
+	case "verbose.example.com/image.png":
+		return nil, errors.New(strings.Repeat("é", 1500))
+	}
+	return res, nil
+}
+
+func TestLinkChecker_Check(t *testing.T) {
+	//Prepare
+	db := DBMock{}
+	checker := &LinkChecker{DB: &db, Client: &HTTPClientMock{}, Concurrency: 2}
+
+	//Act
+	err := checker.Check(context.Background())
+
+	//Assert
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if len(db.brokenImages) != 2 {
+		t.Fatalf("Expected 2 broken images but got %d", len(db.brokenImages))
+	}
+	if db.brokenImages[0].EntityID != 201 || db.brokenImages[0].StatusCode == nil || *db.brokenImages[0].StatusCode != http.StatusNotFound {
+		t.Errorf("Expected product 201 to be broken with status 404 but got %+v", db.brokenImages[0])
+	}
+	if db.brokenImages[1].EntityType != "category" || db.brokenImages[1].Error == nil {
+		t.Errorf("Expected category 201 to be broken with an error but got %+v", db.brokenImages[1])
+	}
+}
+
+func TestLinkChecker_checkURL_TruncatesErrors(t *testing.T) {
+	//Prepare
+	checker := &LinkChecker{Client: &HTTPClientMock{}}
+	imageURL := &repositories.ImageURLModel{EntityType: "product", EntityID: 201, URL: "https://verbose.example.com/image.png"}
+
+	//Act
+	brokenImage := checker.checkURL(context.Background(), imageURL)
+
+	//Assert
+	if brokenImage == nil || brokenImage.Error == nil {
+		t.Fatalf("Expected a broken image with an error but got %+v", brokenImage)
+	}
+	if *brokenImage.Error != strings.Repeat("é", maxBrokenImageErrorLength) {
+		t.Errorf("Expected the error to be truncated to %d characters but got %d", maxBrokenImageErrorLength, len([]rune(*brokenImage.Error)))
+	}
+}
+
+func TestNewLinkCheckClient_RefusesInternalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+	tests := map[string]string{
+		"Loopback address":    server.URL + "/image.png",
+		"Loopback host":       "http://localhost:" + serverURL.Port() + "/image.png",
+		"IPv6 loopback":       "http://[::1]:" + serverURL.Port() + "/image.png",
+		"Private address":     "http://10.0.0.1/image.png",
+		"Link-local address":  "http://169.254.169.254/latest/meta-data",
+		"Shared address":      "http://100.100.100.200/image.png",
+		"Unspecified address": "http://0.0.0.0:" + serverURL.Port() + "/image.png",
+	}
+	client := NewLinkCheckClient(time.Second)
+
+	for tName, imageURL := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Act
+			checker := &LinkChecker{Client: client}
+			_, err := checker.request(context.Background(), http.MethodHead, imageURL)
+
+			//Assert
+			if err == nil || !strings.Contains(err.Error(), "not allowed") {
+				t.Errorf("Expected the connection to be refused but got %v", err)
+			}
+		})
+	}
+}
+
+func TestCreateProduct_WhenMultipleFieldsInvalid_ReturnsAllViolations(t *testing.T) {
+	db := DBMock{}
+	mockService := &Service{DB: &db, AllowedImageHosts: []string{"cdn.example.com"}}
+	ctx := context.Background()
+	ctx = context.WithValue(ctx, "request_id", uuid.New())
+	productImageURL := "https://evil.com/image.png"
+	categoryID := int64(404)
+	newProduct := repositories.ProductCreateModel{
+		CategoryID: &categoryID,
+		ImageURL:   &productImageURL,
+	}
+
+	_, err := mockService.CreateProduct(ctx, newProduct)
+	if app.ErrorCode(err) != app.EINVALID {
+		t.Errorf("Expected error code  %s, but got error code %s", app.EINVALID, app.ErrorCode(err))
+	}
+	excpectedFields := []string{"title", "price", "image_url", "category_id"}
+	details := app.ErrorDetails(err)
+	if len(details) != len(excpectedFields) {
+		t.Fatalf("Expected %d violations but got %v", len(excpectedFields), details)
+	}
+	for i, field := range excpectedFields {
+		if details[i].Field != field {
+			t.Errorf("Expected violation of field %s but got %s", field, details[i].Field)
+		}
+	}
+}
+
+type CacheMock struct {
+	invalidatedTags []string
+}
+
+func (c *CacheMock) Invalidate(ctx context.Context, tags ...string) error {
+	c.invalidatedTags = append(c.invalidatedTags, tags...)
+	return nil
+}
+
+func TestMutations_InvalidateCachedResponses(t *testing.T) {
+	productTitle := "some random title"
+	productPrice := int64(100)
+	categoryTitle := "some random title"
+	tests := map[string]struct {
+		mutate       func(ctx context.Context, s *Service) error
+		expectedTags []string
+	}{
+		"Update product": {
+			mutate: func(ctx context.Context, s *Service) error {
+				return s.UpdateProduct(ctx, 201, repositories.ProductCreateModel{Title: &productTitle, Price: &productPrice})
+			},
+			expectedTags: []string{cache.ProductTag(201), cache.ProductsTag},
+		},
+		"Update category": {
+			mutate: func(ctx context.Context, s *Service) error {
+				return s.UpdateCategory(ctx, 201, repositories.CategoryCreateModel{Title: &categoryTitle})
+			},
+			expectedTags: []string{cache.CategoryTag(201), cache.CategoriesTag},
+		},
+		"Delete category": {
+			mutate: func(ctx context.Context, s *Service) error {
+				return s.DeleteCategory(ctx, 201)
+			},
+			expectedTags: []string{cache.CategoryTag(201), cache.CategoriesTag, cache.ProductsTag},
+		},
+		"Invalid update": {
+			mutate: func(ctx context.Context, s *Service) error {
+				s.UpdateProduct(ctx, 201, repositories.ProductCreateModel{})
+				return nil
+			},
+			expectedTags: nil,
+		},
+	}
+	ctx := context.Background()
+	ctx = context.WithValue(ctx, "request_id", uuid.New())
+
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			cacheMock := &CacheMock{}
+			mockService := &Service{DB: &DBMock{}, Cache: cacheMock}
+
+			err := tc.mutate(ctx, mockService)
+
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+			if !reflect.DeepEqual(cacheMock.invalidatedTags, tc.expectedTags) {
+				t.Errorf("Expected invalidated tags %v but got %v", tc.expectedTags, cacheMock.invalidatedTags)
+			}
+		})
+	}
+}
+
+func TestSaveProductTranslation(t *testing.T) {
+	title := "Στικάκι 1TB"
+	tests := map[string]struct {
+		productID    int64
+		locale       string
+		err          string
+		expectedTags []string
+	}{
+		"Supported locale":     {productID: 201, locale: "el", expectedTags: []string{cache.ProductTag(201), cache.ProductsTag}},
+		"Locale in other case": {productID: 201, locale: "EL", expectedTags: []string{cache.ProductTag(201), cache.ProductsTag}},
+		"Default locale":       {productID: 201, locale: "en", err: app.EINVALIDLOCALE},
+		"Unsupported locale":   {productID: 201, locale: "fr", err: app.EINVALIDLOCALE},
+		"Product not found":    {productID: 404, locale: "el", err: app.EPRODUCTNOTFOUND},
+	}
+	ctx := context.Background()
+	ctx = context.WithValue(ctx, "request_id", uuid.New())
+
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Prepare
+			cacheMock := &CacheMock{}
+			mockService := &Service{DB: &DBMock{}, Cache: cacheMock, Locales: app.Locales{Default: "en", Supported: []string{"en", "el", "de"}}}
+
+			//Act
+			err := mockService.SaveProductTranslation(ctx, tc.productID, tc.locale, repositories.ProductTranslationModel{Title: &title})
+
+			//Assert
+			if app.ErrorCode(err) != tc.err {
+				t.Errorf("Expected error code %q but got %v", tc.err, err)
+			}
+			if !reflect.DeepEqual(cacheMock.invalidatedTags, tc.expectedTags) {
+				t.Errorf("Expected invalidated tags %v but got %v", tc.expectedTags, cacheMock.invalidatedTags)
+			}
+		})
+	}
+}
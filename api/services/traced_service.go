@@ -0,0 +1,223 @@
+package services
+
+import (
+	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/repositories"
+	"github.com/mzampetakis/prods-api/api/storage"
+	"github.com/mzampetakis/prods-api/api/tracing"
+	"golang.org/x/net/context"
+)
+
+// TracedService decorates the functionalities with a span for each call
+type TracedService struct {
+	FunctionalitiesIface
+}
+
+func (s *TracedService) GetProducts(ctx context.Context, filter app.Filter, selection app.Selection) ([]*repositories.ProductFetchModel, error) {
+	ctx, span := tracing.Start(ctx, "services.GetProducts")
+	defer span.End()
+	products, err := s.FunctionalitiesIface.GetProducts(ctx, filter, selection)
+	span.SetError(err)
+	return products, err
+}
+
+func (s *TracedService) GetProduct(ctx context.Context, productID int64, selection app.Selection) (*repositories.ProductFetchModel, error) {
+	ctx, span := tracing.Start(ctx, "services.GetProduct")
+	defer span.End()
+	span.SetAttribute("product.id", productID)
+	product, err := s.FunctionalitiesIface.GetProduct(ctx, productID, selection)
+	span.SetError(err)
+	return product, err
+}
+
+func (s *TracedService) CreateProduct(ctx context.Context, product repositories.ProductCreateModel) (int64, error) {
+	ctx, span := tracing.Start(ctx, "services.CreateProduct")
+	defer span.End()
+	productID, err := s.FunctionalitiesIface.CreateProduct(ctx, product)
+	span.SetError(err)
+	return productID, err
+}
+
+func (s *TracedService) UpdateProduct(ctx context.Context, productID int64, product repositories.ProductCreateModel) error {
+	ctx, span := tracing.Start(ctx, "services.UpdateProduct")
+	defer span.End()
+	span.SetAttribute("product.id", productID)
+	err := s.FunctionalitiesIface.UpdateProduct(ctx, productID, product)
+	span.SetError(err)
+	return err
+}
+
+func (s *TracedService) ValidateProduct(ctx context.Context, product repositories.ProductCreateModel) error {
+	ctx, span := tracing.Start(ctx, "services.ValidateProduct")
+	defer span.End()
+	err := s.FunctionalitiesIface.ValidateProduct(ctx, product)
+	span.SetError(err)
+	return err
+}
+
+func (s *TracedService) DeleteProduct(ctx context.Context, productID int64) error {
+	ctx, span := tracing.Start(ctx, "services.DeleteProduct")
+	defer span.End()
+	span.SetAttribute("product.id", productID)
+	err := s.FunctionalitiesIface.DeleteProduct(ctx, productID)
+	span.SetError(err)
+	return err
+}
+
+func (s *TracedService) AssignProductsToCategory(ctx context.Context, categoryID int64, productsCategory repositories.ProductsCategoryUpdateModel) error {
+	ctx, span := tracing.Start(ctx, "services.AssignProductsToCategory")
+	defer span.End()
+	span.SetAttribute("category.id", categoryID)
+	err := s.FunctionalitiesIface.AssignProductsToCategory(ctx, categoryID, productsCategory)
+	span.SetError(err)
+	return err
+}
+
+func (s *TracedService) GetProductImages(ctx context.Context, productID int64) ([]*repositories.ProductImageFetchModel, error) {
+	ctx, span := tracing.Start(ctx, "services.GetProductImages")
+	defer span.End()
+	span.SetAttribute("product.id", productID)
+	images, err := s.FunctionalitiesIface.GetProductImages(ctx, productID)
+	span.SetError(err)
+	return images, err
+}
+
+func (s *TracedService) UploadProductImages(ctx context.Context, productID int64, uploads []repositories.ProductImageUploadModel) ([]*repositories.ProductImageFetchModel, error) {
+	ctx, span := tracing.Start(ctx, "services.UploadProductImages")
+	defer span.End()
+	span.SetAttribute("product.id", productID)
+	span.SetAttribute("images.count", len(uploads))
+	images, err := s.FunctionalitiesIface.UploadProductImages(ctx, productID, uploads)
+	span.SetError(err)
+	return images, err
+}
+
+func (s *TracedService) UpdateProductImagesOrder(ctx context.Context, productID int64, order repositories.ProductImagesOrderUpdateModel) error {
+	ctx, span := tracing.Start(ctx, "services.UpdateProductImagesOrder")
+	defer span.End()
+	span.SetAttribute("product.id", productID)
+	err := s.FunctionalitiesIface.UpdateProductImagesOrder(ctx, productID, order)
+	span.SetError(err)
+	return err
+}
+
+func (s *TracedService) DeleteProductImage(ctx context.Context, productID int64, imageID int64) error {
+	ctx, span := tracing.Start(ctx, "services.DeleteProductImage")
+	defer span.End()
+	span.SetAttribute("product.id", productID)
+	span.SetAttribute("image.id", imageID)
+	err := s.FunctionalitiesIface.DeleteProductImage(ctx, productID, imageID)
+	span.SetError(err)
+	return err
+}
+
+func (s *TracedService) GetMedia(ctx context.Context, key string) (*storage.Blob, error) {
+	ctx, span := tracing.Start(ctx, "services.GetMedia")
+	defer span.End()
+	blob, err := s.FunctionalitiesIface.GetMedia(ctx, key)
+	span.SetError(err)
+	return blob, err
+}
+
+func (s *TracedService) GetCategories(ctx context.Context, filter app.Filter, selection app.Selection) ([]*repositories.CategoryFetchModel, error) {
+	ctx, span := tracing.Start(ctx, "services.GetCategories")
+	defer span.End()
+	categories, err := s.FunctionalitiesIface.GetCategories(ctx, filter, selection)
+	span.SetError(err)
+	return categories, err
+}
+
+func (s *TracedService) GetCategory(ctx context.Context, categoryID int64, selection app.Selection) (*repositories.CategoryFetchModel, error) {
+	ctx, span := tracing.Start(ctx, "services.GetCategory")
+	defer span.End()
+	span.SetAttribute("category.id", categoryID)
+	category, err := s.FunctionalitiesIface.GetCategory(ctx, categoryID, selection)
+	span.SetError(err)
+	return category, err
+}
+
+func (s *TracedService) CreateCategory(ctx context.Context, category repositories.CategoryCreateModel) (int64, error) {
+	ctx, span := tracing.Start(ctx, "services.CreateCategory")
+	defer span.End()
+	categoryID, err := s.FunctionalitiesIface.CreateCategory(ctx, category)
+	span.SetError(err)
+	return categoryID, err
+}
+
+func (s *TracedService) UpdateCategory(ctx context.Context, categoryID int64, category repositories.CategoryCreateModel) error {
+	ctx, span := tracing.Start(ctx, "services.UpdateCategory")
+	defer span.End()
+	span.SetAttribute("category.id", categoryID)
+	err := s.FunctionalitiesIface.UpdateCategory(ctx, categoryID, category)
+	span.SetError(err)
+	return err
+}
+
+func (s *TracedService) ValidateCategory(ctx context.Context, category repositories.CategoryCreateModel) error {
+	ctx, span := tracing.Start(ctx, "services.ValidateCategory")
+	defer span.End()
+	err := s.FunctionalitiesIface.ValidateCategory(ctx, category)
+	span.SetError(err)
+	return err
+}
+
+func (s *TracedService) DeleteCategory(ctx context.Context, categoryID int64) error {
+	ctx, span := tracing.Start(ctx, "services.DeleteCategory")
+	defer span.End()
+	span.SetAttribute("category.id", categoryID)
+	err := s.FunctionalitiesIface.DeleteCategory(ctx, categoryID)
+	span.SetError(err)
+	return err
+}
+
+func (s *TracedService) GetBrokenImages(ctx context.Context) ([]*repositories.BrokenImageFetchModel, error) {
+	ctx, span := tracing.Start(ctx, "services.GetBrokenImages")
+	defer span.End()
+	images, err := s.FunctionalitiesIface.GetBrokenImages(ctx)
+	span.SetError(err)
+	return images, err
+}
+
+func (s *TracedService) SaveProductTranslation(ctx context.Context, productID int64, locale string, translation repositories.ProductTranslationModel) error {
+	ctx, span := tracing.Start(ctx, "services.SaveProductTranslation")
+	defer span.End()
+	span.SetAttribute("product.id", productID)
+	err := s.FunctionalitiesIface.SaveProductTranslation(ctx, productID, locale, translation)
+	span.SetError(err)
+	return err
+}
+
+func (s *TracedService) DeleteProductTranslation(ctx context.Context, productID int64, locale string) error {
+	ctx, span := tracing.Start(ctx, "services.DeleteProductTranslation")
+	defer span.End()
+	span.SetAttribute("product.id", productID)
+	err := s.FunctionalitiesIface.DeleteProductTranslation(ctx, productID, locale)
+	span.SetError(err)
+	return err
+}
+
+func (s *TracedService) SaveCategoryTranslation(ctx context.Context, categoryID int64, locale string, translation repositories.CategoryTranslationModel) error {
+	ctx, span := tracing.Start(ctx, "services.SaveCategoryTranslation")
+	defer span.End()
+	span.SetAttribute("category.id", categoryID)
+	err := s.FunctionalitiesIface.SaveCategoryTranslation(ctx, categoryID, locale, translation)
+	span.SetError(err)
+	return err
+}
+
+func (s *TracedService) DeleteCategoryTranslation(ctx context.Context, categoryID int64, locale string) error {
+	ctx, span := tracing.Start(ctx, "services.DeleteCategoryTranslation")
+	defer span.End()
+	span.SetAttribute("category.id", categoryID)
+	err := s.FunctionalitiesIface.DeleteCategoryTranslation(ctx, categoryID, locale)
+	span.SetError(err)
+	return err
+}
+
+func (s *TracedService) GetMissingTranslations(ctx context.Context) ([]*repositories.MissingTranslationModel, error) {
+	ctx, span := tracing.Start(ctx, "services.GetMissingTranslations")
+	defer span.End()
+	missing, err := s.FunctionalitiesIface.GetMissingTranslations(ctx)
+	span.SetError(err)
+	return missing, err
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/cache"
+	"github.com/mzampetakis/prods-api/api/repositories"
+	"golang.org/x/net/context"
+)
+
+func (s *Service) SaveProductTranslation(ctx context.Context, productID int64, locale string, translation repositories.ProductTranslationModel) error {
+	locale, err := s.translationLocale(locale)
+	if err != nil {
+		return &app.Error{Op: "services.SaveProductTranslation", Err: err}
+	}
+	if _, err = s.DB.GetProduct(ctx, productID, app.Selection{Fields: "id"}); err != nil {
+		return &app.Error{Op: "services.SaveProductTranslation", Err: err}
+	}
+	if err = s.DB.SaveProductTranslation(ctx, productID, locale, translation); err != nil {
+		return &app.Error{Op: "services.SaveProductTranslation", Err: err}
+	}
+	invalidateCache(ctx, s.Cache, cache.ProductTag(productID), cache.ProductsTag)
+	return nil
+}
+
+func (s *Service) DeleteProductTranslation(ctx context.Context, productID int64, locale string) error {
+	locale, err := s.translationLocale(locale)
+	if err != nil {
+		return &app.Error{Op: "services.DeleteProductTranslation", Err: err}
+	}
+	if err = s.DB.DeleteProductTranslation(ctx, productID, locale); err != nil {
+		return &app.Error{Op: "services.DeleteProductTranslation", Err: err}
+	}
+	invalidateCache(ctx, s.Cache, cache.ProductTag(productID), cache.ProductsTag)
+	return nil
+}
+
+func (s *Service) SaveCategoryTranslation(ctx context.Context, categoryID int64, locale string, translation repositories.CategoryTranslationModel) error {
+	locale, err := s.translationLocale(locale)
+	if err != nil {
+		return &app.Error{Op: "services.SaveCategoryTranslation", Err: err}
+	}
+	if _, err = s.DB.GetCategory(ctx, categoryID, app.Selection{Fields: "id"}); err != nil {
+		return &app.Error{Op: "services.SaveCategoryTranslation", Err: err}
+	}
+	if err = s.DB.SaveCategoryTranslation(ctx, categoryID, locale, translation); err != nil {
+		return &app.Error{Op: "services.SaveCategoryTranslation", Err: err}
+	}
+	invalidateCache(ctx, s.Cache, cache.CategoryTag(categoryID), cache.CategoriesTag)
+	return nil
+}
+
+func (s *Service) DeleteCategoryTranslation(ctx context.Context, categoryID int64, locale string) error {
+	locale, err := s.translationLocale(locale)
+	if err != nil {
+		return &app.Error{Op: "services.DeleteCategoryTranslation", Err: err}
+	}
+	if err = s.DB.DeleteCategoryTranslation(ctx, categoryID, locale); err != nil {
+		return &app.Error{Op: "services.DeleteCategoryTranslation", Err: err}
+	}
+	invalidateCache(ctx, s.Cache, cache.CategoryTag(categoryID), cache.CategoriesTag)
+	return nil
+}
+
+// GetMissingTranslations returns the untranslated fields of the products and categories for each supported locale
+// other than the default one
+func (s *Service) GetMissingTranslations(ctx context.Context) ([]*repositories.MissingTranslationModel, error) {
+	missing, err := s.DB.GetMissingTranslations(ctx, s.Locales.Translated())
+	if err != nil {
+		return nil, &app.Error{Op: "services.GetMissingTranslations", Err: err}
+	}
+	return missing, nil
+}
+
+// translationLocale returns the supported locale that a translation is written for. The content of the default
+// locale is stored on the entities themselves and has no translations.
+func (s *Service) translationLocale(locale string) (string, error) {
+	supported, ok := s.Locales.Supports(locale)
+	if !ok {
+		return "", &app.Error{Op: "services.translationLocale", Code: app.EINVALIDLOCALE, Message: "Invalid locale: " + locale}
+	}
+	if supported == s.Locales.Default {
+		return "", &app.Error{Op: "services.translationLocale", Code: app.EINVALIDLOCALE,
+			Message: "Invalid locale: " + locale + " is the default locale, whose content is updated on the entity itself"}
+	}
+	return supported, nil
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/mzampetakis/prods-api/api/app"
+)
+
+// LocalStorage stores blobs as files under a root directory of the local filesystem
+type LocalStorage struct {
+	Root string
+}
+
+func NewLocalStorage(root string) (*LocalStorage, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, &app.Error{Op: "storage.NewLocalStorage", Code: app.EINTERNAL, Err: err}
+	}
+	return &LocalStorage{Root: root}, nil
+}
+
+// filePath maps a key to a file path under the root directory rejecting keys that escape it
+func (s *LocalStorage) filePath(key string) (string, error) {
+	cleanKey := path.Clean("/" + key)
+	if cleanKey == "/" || strings.Contains(key, "..") {
+		return "", &app.Error{Op: "storage.filePath", Code: app.EINVALID, Message: "Invalid storage key: " + key}
+	}
+	return filepath.Join(s.Root, filepath.FromSlash(cleanKey)), nil
+}
+
+func (s *LocalStorage) Put(ctx context.Context, key string, data io.Reader, contentType string) error {
+	filePath, err := s.filePath(key)
+	if err != nil {
+		return &app.Error{Op: "storage.LocalStorage.Put", Err: err}
+	}
+	if err = os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return &app.Error{Op: "storage.LocalStorage.Put", Code: app.EINTERNAL, Err: err}
+	}
+	file, err := os.Create(filePath)
+	if err != nil {
+		return &app.Error{Op: "storage.LocalStorage.Put", Code: app.EINTERNAL, Err: err}
+	}
+	defer file.Close()
+	if _, err = io.Copy(file, data); err != nil {
+		return &app.Error{Op: "storage.LocalStorage.Put", Code: app.EINTERNAL, Err: err}
+	}
+	return nil
+}
+
+func (s *LocalStorage) Get(ctx context.Context, key string) (*Blob, error) {
+	filePath, err := s.filePath(key)
+	if err != nil {
+		return nil, &app.Error{Op: "storage.LocalStorage.Get", Err: err}
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, &app.Error{Op: "storage.LocalStorage.Get", Code: app.EMEDIANOTFOUND, Err: err, Message: "Media not found."}
+		}
+		return nil, &app.Error{Op: "storage.LocalStorage.Get", Code: app.EINTERNAL, Err: err}
+	}
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		file.Close()
+		return nil, &app.Error{Op: "storage.LocalStorage.Get", Code: app.EMEDIANOTFOUND, Err: err, Message: "Media not found."}
+	}
+	return &Blob{
+		ReadCloser:  file,
+		ContentType: mime.TypeByExtension(filepath.Ext(filePath)),
+		Size:        info.Size(),
+		ModTime:     info.ModTime(),
+	}, nil
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	filePath, err := s.filePath(key)
+	if err != nil {
+		return &app.Error{Op: "storage.LocalStorage.Delete", Err: err}
+	}
+	if err = os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return &app.Error{Op: "storage.LocalStorage.Delete", Code: app.EINTERNAL, Err: err}
+	}
+	return nil
+}
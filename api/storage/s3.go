@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mzampetakis/prods-api/api/app"
+)
+
+// S3Config holds the connection details of an S3 compatible object storage (AWS S3, MinIO etc)
+type S3Config struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// S3Storage stores blobs in a bucket of an S3 compatible object storage using path style requests
+// signed with AWS Signature Version 4
+type S3Storage struct {
+	Config S3Config
+	Client *http.Client
+}
+
+func NewS3Storage(config S3Config) *S3Storage {
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	return &S3Storage{Config: config, Client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+func (s *S3Storage) Put(ctx context.Context, key string, data io.Reader, contentType string) error {
+	payload, err := ioutil.ReadAll(data)
+	if err != nil {
+		return &app.Error{Op: "storage.S3Storage.Put", Code: app.EINTERNAL, Err: err}
+	}
+	req, err := s.newRequest(ctx, http.MethodPut, key, payload)
+	if err != nil {
+		return &app.Error{Op: "storage.S3Storage.Put", Code: app.EINTERNAL, Err: err}
+	}
+	req.Header.Set("Content-Type", contentType)
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return &app.Error{Op: "storage.S3Storage.Put", Code: app.EINTERNAL, Err: err}
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return &app.Error{Op: "storage.S3Storage.Put", Code: app.EINTERNAL, Err: responseError(res)}
+	}
+	return nil
+}
+
+func (s *S3Storage) Get(ctx context.Context, key string) (*Blob, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, &app.Error{Op: "storage.S3Storage.Get", Code: app.EINTERNAL, Err: err}
+	}
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return nil, &app.Error{Op: "storage.S3Storage.Get", Code: app.EINTERNAL, Err: err}
+	}
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return nil, &app.Error{Op: "storage.S3Storage.Get", Code: app.EMEDIANOTFOUND, Message: "Media not found."}
+	}
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		return nil, &app.Error{Op: "storage.S3Storage.Get", Code: app.EINTERNAL, Err: responseError(res)}
+	}
+	modTime, _ := http.ParseTime(res.Header.Get("Last-Modified"))
+	size, _ := strconv.ParseInt(res.Header.Get("Content-Length"), 10, 64)
+	return &Blob{
+		ReadCloser:  res.Body,
+		ContentType: res.Header.Get("Content-Type"),
+		Size:        size,
+		ModTime:     modTime,
+	}, nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return &app.Error{Op: "storage.S3Storage.Delete", Code: app.EINTERNAL, Err: err}
+	}
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return &app.Error{Op: "storage.S3Storage.Delete", Code: app.EINTERNAL, Err: err}
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNotFound {
+		return &app.Error{Op: "storage.S3Storage.Delete", Code: app.EINTERNAL, Err: responseError(res)}
+	}
+	return nil
+}
+
+// newRequest creates a signed request for the object with the provided key
+func (s *S3Storage) newRequest(ctx context.Context, method string, key string, payload []byte) (*http.Request, error) {
+	objectURL, err := url.Parse(s.Config.Endpoint + "/" + s.Config.Bucket + "/" + strings.TrimPrefix(key, "/"))
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, objectURL.String(), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	signRequest(req, payload, s.Config, time.Now().UTC())
+	return req, nil
+}
+
+// signRequest adds the AWS Signature Version 4 authorization headers to the request
+func signRequest(req *http.Request, payload []byte, config S3Config, now time.Time) {
+	payloadHash := sha256Hex(payload)
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + config.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	signingKey := hmacSHA256([]byte("AWS4"+config.SecretAccessKey), date)
+	signingKey = hmacSHA256(signingKey, config.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func responseError(res *http.Response) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+	return fmt.Errorf("object storage responded with %s: %s", res.Status, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,23 @@
+// Package storage provides pluggable blob storages used to store uploaded media
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// BlobStorage stores binary objects under unique keys
+type BlobStorage interface {
+	Put(ctx context.Context, key string, data io.Reader, contentType string) error
+	Get(ctx context.Context, key string) (*Blob, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Blob is a stored object that should be closed after reading
+type Blob struct {
+	io.ReadCloser
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
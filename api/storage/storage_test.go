@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mzampetakis/prods-api/api/app"
+)
+
+func TestLocalStorage_PutGetDelete(t *testing.T) {
+	//Prepare
+	root, err := ioutil.TempDir("", "prods-api-storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	localStorage, err := NewLocalStorage(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	key := "products/1/image.png"
+	content := []byte("some image content")
+
+	//Act
+	err = localStorage.Put(ctx, key, bytes.NewReader(content), "image/png")
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	blob, err := localStorage.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	stored, _ := ioutil.ReadAll(blob)
+	blob.Close()
+	deleteErr := localStorage.Delete(ctx, key)
+	_, getDeletedErr := localStorage.Get(ctx, key)
+
+	//Assert
+	if !bytes.Equal(stored, content) {
+		t.Errorf("Expected stored content %s but got %s", content, stored)
+	}
+	if blob.ContentType != "image/png" {
+		t.Errorf("Expected content type image/png but got %s", blob.ContentType)
+	}
+	if blob.Size != int64(len(content)) {
+		t.Errorf("Expected size %d but got %d", len(content), blob.Size)
+	}
+	if deleteErr != nil {
+		t.Errorf("Expected no error on delete but got %v", deleteErr)
+	}
+	if app.ErrorCode(getDeletedErr) != app.EMEDIANOTFOUND {
+		t.Errorf("Expected error code %s but got %s", app.EMEDIANOTFOUND, app.ErrorCode(getDeletedErr))
+	}
+}
+
+func TestLocalStorage_WhenKeyEscapesRoot_Fails(t *testing.T) {
+	localStorage := &LocalStorage{Root: os.TempDir()}
+
+	err := localStorage.Put(context.Background(), "../../etc/passwd", strings.NewReader("x"), "text/plain")
+	if app.ErrorCode(err) != app.EINVALID {
+		t.Errorf("Expected error code %s but got %s", app.EINVALID, app.ErrorCode(err))
+	}
+}
+
+func TestS3Storage_PutSignsRequest(t *testing.T) {
+	//Prepare
+	var gotPath, gotAuthorization, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuthorization = r.Header.Get("Authorization")
+		body, _ := ioutil.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	s3Storage := NewS3Storage(S3Config{
+		Endpoint:        server.URL,
+		Region:          "eu-central-1",
+		Bucket:          "media",
+		AccessKeyID:     "access",
+		SecretAccessKey: "secret",
+	})
+
+	//Act
+	err := s3Storage.Put(context.Background(), "products/1/image.png", strings.NewReader("content"), "image/png")
+
+	//Assert
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if gotPath != "/media/products/1/image.png" {
+		t.Errorf("Expected path /media/products/1/image.png but got %s", gotPath)
+	}
+	if !strings.HasPrefix(gotAuthorization, "AWS4-HMAC-SHA256 Credential=access/") ||
+		!strings.Contains(gotAuthorization, "/eu-central-1/s3/aws4_request") {
+		t.Errorf("Unexpected Authorization header %s", gotAuthorization)
+	}
+	if gotBody != "content" {
+		t.Errorf("Expected body content but got %s", gotBody)
+	}
+}
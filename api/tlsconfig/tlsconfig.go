@@ -0,0 +1,172 @@
+// Package tlsconfig builds the TLS configuration of the API server: its certificate, which is reloaded
+// when its files change, HTTP/2 and the optional verification of the client certificates (mTLS).
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mzampetakis/prods-api/api/auth"
+	"github.com/sirupsen/logrus"
+)
+
+// The client authentication modes
+const (
+	// ClientAuthNone does not ask for client certificates
+	ClientAuthNone = "none"
+	// ClientAuthOptional verifies the client certificates that are sent
+	ClientAuthOptional = "optional"
+	// ClientAuthRequire rejects the connections without a valid client certificate
+	ClientAuthRequire = "require"
+)
+
+// Options configures the TLS of the server
+type Options struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile holds the PEM certificates of the CAs that sign the client certificates
+	ClientCAFile string
+	// ClientAuth is the client authentication mode: none, optional or require
+	ClientAuth string
+	// ReloadInterval is how often the certificate files are checked for changes. Zero checks on every handshake.
+	ReloadInterval time.Duration
+	// DisableHTTP2 limits the server to HTTP/1.1
+	DisableHTTP2 bool
+}
+
+// ServerConfig returns the TLS configuration of the server with the certificate of the options
+func ServerConfig(options Options) (*tls.Config, error) {
+	reloader, err := NewCertReloader(options.CertFile, options.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	reloader.Interval = options.ReloadInterval
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+	if options.DisableHTTP2 {
+		config.NextProtos = []string{"http/1.1"}
+	}
+
+	switch options.ClientAuth {
+	case "", ClientAuthNone:
+		return config, nil
+	case ClientAuthOptional:
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("unknown client auth %s", options.ClientAuth)
+	}
+	pem, err := ioutil.ReadFile(options.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the client CA file: %s", err.Error())
+	}
+	config.ClientCAs = x509.NewCertPool()
+	if !config.ClientCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in the client CA file %s", options.ClientCAFile)
+	}
+	return config, nil
+}
+
+// CertReloader serves a certificate and reloads it when its files change, so that renewed certificates are
+// served without restarting the server. A reload that fails keeps serving the previous certificate.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	// Interval is how often the files are checked for changes. Zero checks on every handshake.
+	Interval time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	lastCheck time.Time
+}
+
+// NewCertReloader loads the certificate of the files
+func NewCertReloader(certFile string, keyFile string) (*CertReloader, error) {
+	reloader := &CertReloader{certFile: certFile, keyFile: keyFile, now: time.Now}
+	modTime, err := reloader.filesModTime()
+	if err != nil {
+		return nil, err
+	}
+	if err = reloader.load(modTime); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// GetCertificate returns the current certificate, to be used as the tls.Config GetCertificate
+func (c *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if now.Sub(c.lastCheck) < c.Interval {
+		return c.cert, nil
+	}
+	c.lastCheck = now
+	modTime, err := c.filesModTime()
+	if err != nil {
+		logrus.Warnf("Could not check the TLS certificate files: %s", err.Error())
+		return c.cert, nil
+	}
+	if modTime.Equal(c.modTime) {
+		return c.cert, nil
+	}
+	if err = c.load(modTime); err != nil {
+		logrus.Warnf("Could not reload the TLS certificate, serving the previous one: %s", err.Error())
+		return c.cert, nil
+	}
+	logrus.Infof("Reloaded the TLS certificate %s", c.certFile)
+	return c.cert, nil
+}
+
+func (c *CertReloader) load(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("could not load the TLS certificate: %s", err.Error())
+	}
+	c.cert = &cert
+	c.modTime = modTime
+	return nil
+}
+
+// filesModTime returns the latest modification time of the certificate and key files
+func (c *CertReloader) filesModTime() (time.Time, error) {
+	var modTime time.Time
+	for _, file := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	return modTime, nil
+}
+
+// ClientPrincipal returns the principal of the verified client certificate of the connection. The principal
+// is looked up in principals by the certificate's subject (e.g. CN=orders,O=Shop) and then by its common name.
+// Connections without a verified certificate or with an unknown subject have no principal.
+func ClientPrincipal(state *tls.ConnectionState, principals map[string]string) (auth.Principal, bool) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return auth.Principal{}, false
+	}
+	subject := state.VerifiedChains[0][0].Subject
+	if name, ok := principals[subject.String()]; ok {
+		return auth.Principal{Name: name, Subject: subject.String()}, true
+	}
+	if name, ok := principals[subject.CommonName]; ok && subject.CommonName != "" {
+		return auth.Principal{Name: name, Subject: subject.String()}, true
+	}
+	return auth.Principal{}, false
+}
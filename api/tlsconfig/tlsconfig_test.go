@@ -0,0 +1,268 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mzampetakis/prods-api/api/auth"
+)
+
+// testCert is a locally generated certificate and its key
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCert(t *testing.T, subject pkix.Name, parent *testCert, usage x509.ExtKeyUsage) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      subject,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		DNSNames:     []string{"localhost"},
+	}
+	parentCert, parentKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+		parentCert, parentKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+func (c *testCert) keyPEM(t *testing.T) []byte {
+	der, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+func (c *testCert) tlsCertificate(t *testing.T) tls.Certificate {
+	cert, err := tls.X509KeyPair(c.pem, c.keyPEM(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// writeFiles writes the certificate and key files and sets their modification time
+func (c *testCert) writeFiles(t *testing.T, certFile string, keyFile string, modTime time.Time) {
+	if err := ioutil.WriteFile(certFile, c.pem, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, c.keyPEM(t), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(certFile, modTime, modTime)
+	os.Chtimes(keyFile, modTime, modTime)
+}
+
+func TestServerConfig_ClientCertificates(t *testing.T) {
+	//Prepare
+	dir, err := ioutil.TempDir("", "tlsconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ca := newTestCert(t, pkix.Name{CommonName: "Test CA"}, nil, 0)
+	otherCA := newTestCert(t, pkix.Name{CommonName: "Other CA"}, nil, 0)
+	server := newTestCert(t, pkix.Name{CommonName: "localhost"}, ca, x509.ExtKeyUsageServerAuth)
+	orders := newTestCert(t, pkix.Name{CommonName: "orders", Organization: []string{"Shop"}}, ca, x509.ExtKeyUsageClientAuth)
+	unknown := newTestCert(t, pkix.Name{CommonName: "unknown"}, ca, x509.ExtKeyUsageClientAuth)
+	untrusted := newTestCert(t, pkix.Name{CommonName: "orders"}, otherCA, x509.ExtKeyUsageClientAuth)
+	certFile, keyFile, caFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"), filepath.Join(dir, "ca.crt")
+	server.writeFiles(t, certFile, keyFile, time.Now())
+	if err = ioutil.WriteFile(caFile, ca.pem, 0600); err != nil {
+		t.Fatal(err)
+	}
+	principals := map[string]string{"orders": "orders-service"}
+
+	tests := map[string]struct {
+		clientAuth        string
+		clientCert        *testCert
+		expectedError     bool
+		expectedPrincipal string
+	}{
+		"Mapped client certificate":     {clientAuth: ClientAuthRequire, clientCert: orders, expectedPrincipal: "orders-service"},
+		"Unmapped client certificate":   {clientAuth: ClientAuthRequire, clientCert: unknown},
+		"Untrusted client certificate":  {clientAuth: ClientAuthRequire, clientCert: untrusted, expectedError: true},
+		"Missing client certificate":    {clientAuth: ClientAuthRequire, expectedError: true},
+		"Optional client certificate":   {clientAuth: ClientAuthOptional, clientCert: orders, expectedPrincipal: "orders-service"},
+		"Optional without certificate":  {clientAuth: ClientAuthOptional},
+		"Client certificates not asked": {clientAuth: ClientAuthNone, clientCert: orders},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			config, err := ServerConfig(Options{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, ClientAuth: tc.clientAuth})
+			if err != nil {
+				t.Fatal(err)
+			}
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			server := &http.Server{TLSConfig: config, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				principal, _ := ClientPrincipal(r.TLS, principals)
+				w.Header().Set("Principal", principal.Name)
+				w.Header().Set("Proto", r.Proto)
+			})}
+			go server.ServeTLS(listener, "", "")
+			defer server.Close()
+			clientConfig := &tls.Config{RootCAs: x509.NewCertPool(), ServerName: "localhost", NextProtos: []string{"h2", "http/1.1"}}
+			clientConfig.RootCAs.AddCert(ca.cert)
+			if tc.clientCert != nil {
+				clientConfig.Certificates = []tls.Certificate{tc.clientCert.tlsCertificate(t)}
+			}
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig, ForceAttemptHTTP2: true}}
+
+			//Act
+			resp, err := client.Get("https://" + listener.Addr().String())
+
+			//Assert
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("Expected error %v but got %v", tc.expectedError, err)
+			}
+			if err != nil {
+				return
+			}
+			resp.Body.Close()
+			if principal := resp.Header.Get("Principal"); principal != tc.expectedPrincipal {
+				t.Errorf("Expected principal %q but got %q", tc.expectedPrincipal, principal)
+			}
+			if proto := resp.Header.Get("Proto"); proto != "HTTP/2.0" {
+				t.Errorf("Expected HTTP/2 but got %s", proto)
+			}
+		})
+	}
+}
+
+func TestCertReloader_GetCertificate(t *testing.T) {
+	//Prepare
+	dir, err := ioutil.TempDir("", "tlsconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ca := newTestCert(t, pkix.Name{CommonName: "Test CA"}, nil, 0)
+	first := newTestCert(t, pkix.Name{CommonName: "first"}, ca, x509.ExtKeyUsageServerAuth)
+	renewed := newTestCert(t, pkix.Name{CommonName: "renewed"}, ca, x509.ExtKeyUsageServerAuth)
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	modTime := time.Now().Add(-time.Minute)
+	first.writeFiles(t, certFile, keyFile, modTime)
+	reloader, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	reloader.now = func() time.Time { return now }
+	reloader.Interval = 10 * time.Second
+	servedCN := func() string {
+		cert, err := reloader.GetCertificate(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+		return leaf.Subject.CommonName
+	}
+
+	//Act & Assert
+	if cn := servedCN(); cn != "first" {
+		t.Fatalf("Expected the first certificate but got %s", cn)
+	}
+	renewed.writeFiles(t, certFile, keyFile, modTime.Add(time.Second))
+	if cn := servedCN(); cn != "first" {
+		t.Errorf("Expected the files not to be checked within the interval but got %s", cn)
+	}
+	now = now.Add(10 * time.Second)
+	if cn := servedCN(); cn != "renewed" {
+		t.Errorf("Expected the renewed certificate but got %s", cn)
+	}
+
+	// a broken pair keeps the previous certificate
+	if err = ioutil.WriteFile(keyFile, []byte("broken"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(keyFile, modTime.Add(2*time.Second), modTime.Add(2*time.Second))
+	now = now.Add(10 * time.Second)
+	if cn := servedCN(); cn != "renewed" {
+		t.Errorf("Expected the previous certificate after a failed reload but got %s", cn)
+	}
+}
+
+func TestServerConfig_InvalidOptions(t *testing.T) {
+	tests := map[string]Options{
+		"Missing certificate": {CertFile: "missing.crt", KeyFile: "missing.key"},
+	}
+	for tName, options := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Act
+			_, err := ServerConfig(options)
+
+			//Assert
+			if err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+}
+
+func TestClientPrincipal(t *testing.T) {
+	//Prepare
+	ca := newTestCert(t, pkix.Name{CommonName: "Test CA"}, nil, 0)
+	orders := newTestCert(t, pkix.Name{CommonName: "orders", Organization: []string{"Shop"}}, ca, x509.ExtKeyUsageClientAuth)
+	tests := map[string]struct {
+		state      *tls.ConnectionState
+		principals map[string]string
+		expected   auth.Principal
+		expectedOK bool
+	}{
+		"By subject": {state: &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{orders.cert}}},
+			principals: map[string]string{"CN=orders,O=Shop": "shop-orders", "orders": "orders"},
+			expected:   auth.Principal{Name: "shop-orders", Subject: "CN=orders,O=Shop"}, expectedOK: true},
+		"By common name": {state: &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{orders.cert}}},
+			principals: map[string]string{"orders": "orders"},
+			expected:   auth.Principal{Name: "orders", Subject: "CN=orders,O=Shop"}, expectedOK: true},
+		"Unverified certificate": {state: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{orders.cert}},
+			principals: map[string]string{"orders": "orders"}},
+		"Plain HTTP": {principals: map[string]string{"orders": "orders"}},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Act
+			principal, ok := ClientPrincipal(tc.state, tc.principals)
+
+			//Assert
+			if ok != tc.expectedOK || principal != tc.expected {
+				t.Errorf("Expected principal %+v (%v) but got %+v (%v)", tc.expected, tc.expectedOK, principal, ok)
+			}
+		})
+	}
+}
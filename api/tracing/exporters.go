@@ -0,0 +1,22 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// NewOTLPExporter exports the spans to an OpenTelemetry collector with OTLP/HTTP. The endpoint is the base URL
+// of the collector, e.g. http://localhost:4318.
+func NewOTLPExporter(endpoint string) (sdktrace.SpanExporter, error) {
+	return otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(strings.TrimSuffix(endpoint, "/")+"/v1/traces"))
+}
+
+// NewWriterExporter writes the spans as JSON to a writer (e.g. stdout or a file)
+func NewWriterExporter(w io.Writer) (sdktrace.SpanExporter, error) {
+	return stdouttrace.New(stdouttrace.WithWriter(w))
+}
@@ -0,0 +1,24 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// TraceparentHeader is the W3C Trace Context header that propagates the trace across processes
+const TraceparentHeader = "traceparent"
+
+// propagator propagates the trace context with the W3C traceparent header
+var propagator = propagation.TraceContext{}
+
+// Extract returns a copy of the context whose spans are children of the traceparent of the headers, if any
+func Extract(ctx context.Context, header http.Header) context.Context {
+	return propagator.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// Inject sets the W3C traceparent header of the context's span to the provided headers
+func Inject(ctx context.Context, header http.Header) {
+	propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
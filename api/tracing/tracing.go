@@ -0,0 +1,131 @@
+// Package tracing provides distributed tracing with OpenTelemetry. Trace context is propagated with W3C
+// traceparent headers and the spans are exported by the OpenTelemetry SDK.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/mzampetakis/prods-api/api/app"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the name of the OpenTelemetry tracer of the API
+const instrumentationName = "github.com/mzampetakis/prods-api/api/tracing"
+
+// Kinds of the spans, describing their relationship to their parent
+const (
+	SpanKindInternal = trace.SpanKindInternal
+	SpanKindServer   = trace.SpanKindServer
+	SpanKindClient   = trace.SpanKindClient
+)
+
+// StatusError is the status of the failed spans
+const StatusError = codes.Error
+
+// Span is a timed operation of a trace
+type Span struct {
+	trace.Span
+}
+
+// SetAttribute records an attribute of the span. Values should be strings, bools, ints or floats.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	s.SetAttributes(attributeOf(key, value))
+}
+
+// SetError marks the span as failed with the provided error. A nil error leaves the span unchanged.
+func (s *Span) SetError(err error) {
+	if err == nil {
+		return
+	}
+	s.SetStatus(codes.Error, app.ErrorMessage(err))
+	s.SetAttributes(attribute.String("error.code", app.ErrorCode(err)))
+}
+
+func attributeOf(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case float64:
+		return attribute.Float64(key, v)
+	default:
+		return attribute.String(key, fmt.Sprintf("%v", v))
+	}
+}
+
+// Tracer creates spans and batches the ended ones to its exporter
+type Tracer struct {
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+}
+
+// NewTracer creates a tracer exporting its spans with the provided exporter. A nil exporter creates
+// spans for context propagation without sampling the new traces or exporting any span.
+func NewTracer(serviceName string, exporter sdktrace.SpanExporter) *Tracer {
+	sampler := sdktrace.ParentBased(sdktrace.NeverSample())
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	}
+	if exporter != nil {
+		sampler = sdktrace.ParentBased(sdktrace.AlwaysSample())
+		options = append(options, sdktrace.WithBatcher(exporter))
+	}
+	provider := sdktrace.NewTracerProvider(append(options, sdktrace.WithSampler(sampler))...)
+	return &Tracer{provider: provider, tracer: provider.Tracer(instrumentationName)}
+}
+
+// Start starts a span as a child of the span in the context, or as a root span if there is none
+func (t *Tracer) Start(ctx context.Context, name string, kind trace.SpanKind) (context.Context, *Span) {
+	ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(kind))
+	return ctx, &Span{Span: span}
+}
+
+// Shutdown exports the queued spans and stops the tracer. Spans that end after it are dropped.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	return t.provider.Shutdown(ctx)
+}
+
+var (
+	globalMu     sync.RWMutex
+	globalTracer = NewTracer("prods-api", nil)
+)
+
+// SetTracer sets the tracer used by Start
+func SetTracer(tracer *Tracer) {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	globalTracer = tracer
+}
+
+// Start starts an internal span with the tracer set by SetTracer
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	return StartWithKind(ctx, name, SpanKindInternal)
+}
+
+// StartWithKind starts a span of the provided kind with the tracer set by SetTracer
+func StartWithKind(ctx context.Context, name string, kind trace.SpanKind) (context.Context, *Span) {
+	globalMu.RLock()
+	tracer := globalTracer
+	globalMu.RUnlock()
+	return tracer.Start(ctx, name, kind)
+}
+
+// TraceIDFromContext returns the hex encoded trace id of the context's span or an empty string if there is none
+func TraceIDFromContext(ctx context.Context) string {
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		return spanContext.TraceID().String()
+	}
+	return ""
+}
@@ -0,0 +1,136 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		valid       bool
+		sampled     bool
+	}{
+		{"Sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"Not sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, false},
+		{"Missing", "", false, false},
+		{"Invalid version", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"Zero trace id", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"Short span id", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa-01", false, false},
+		{"Not hex", "00-4bf92f3577b34da6a3ce929d0e0e47zz-00f067aa0ba902b7-01", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//Prepare
+			header := http.Header{}
+			header.Set(TraceparentHeader, tt.traceparent)
+
+			//Act
+			spanContext := trace.SpanContextFromContext(Extract(context.Background(), header))
+
+			//Assert
+			if spanContext.IsValid() != tt.valid {
+				t.Fatalf("Expected valid %v but got %v", tt.valid, spanContext.IsValid())
+			}
+			if tt.valid && spanContext.IsSampled() != tt.sampled {
+				t.Errorf("Expected sampled %v but got %v", tt.sampled, spanContext.IsSampled())
+			}
+			if tt.valid && spanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+				t.Errorf("Unexpected trace id %s", spanContext.TraceID())
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	//Prepare
+	exporter := tracetest.NewInMemoryExporter()
+	tracer := NewTracer("prods-api-test", exporter)
+	SetTracer(tracer)
+	defer SetTracer(NewTracer("prods-api", nil))
+	router := mux.NewRouter()
+	router.Use(Middleware)
+	router.HandleFunc("/products/{productID:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := Start(r.Context(), "services.GetProduct")
+		span.End()
+		w.WriteHeader(http.StatusNotFound)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/products/1", nil)
+	req.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+
+	//Act
+	router.ServeHTTP(w, req)
+	if err := tracer.provider.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	//Assert
+	if traceparent := w.Header().Get(TraceparentHeader); !strings.HasPrefix(traceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-") {
+		t.Errorf("Expected the response traceparent to continue the trace but got %s", traceparent)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans but got %d", len(spans))
+	}
+	serviceSpan, serverSpan := spans[0], spans[1]
+	if serverSpan.Name != "GET /products/{productID:[0-9]+}" || serverSpan.SpanKind != trace.SpanKindServer {
+		t.Errorf("Unexpected server span %s of kind %s", serverSpan.Name, serverSpan.SpanKind)
+	}
+	if serverSpan.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("Expected the server span to be a child of the remote span but got parent %s", serverSpan.Parent.SpanID())
+	}
+	if serviceSpan.Parent.SpanID() != serverSpan.SpanContext.SpanID() || serviceSpan.SpanContext.TraceID() != serverSpan.SpanContext.TraceID() {
+		t.Errorf("Expected the service span to be a child of the server span")
+	}
+	for _, attr := range serverSpan.Attributes {
+		if attr.Key == "http.status_code" && attr.Value != attribute.IntValue(http.StatusNotFound) {
+			t.Errorf("Expected status code 404 but got %v", attr.Value.Emit())
+		}
+	}
+}
+
+func TestTracer_NotSampled(t *testing.T) {
+	//Prepare
+	tracer := NewTracer("prods-api-test", nil)
+
+	//Act
+	ctx, span := tracer.Start(context.Background(), "root", SpanKindInternal)
+	span.End()
+
+	//Assert
+	if span.SpanContext().IsSampled() {
+		t.Errorf("Expected span of a tracer without exporter not to be sampled")
+	}
+	if TraceIDFromContext(ctx) != span.SpanContext().TraceID().String() {
+		t.Errorf("Expected the trace id to be propagated through the context")
+	}
+}
+
+func TestTracer_SpansEndedAfterShutdown_AreDropped(t *testing.T) {
+	//Prepare
+	tracer := NewTracer("prods-api-test", tracetest.NewInMemoryExporter())
+	_, span := tracer.Start(context.Background(), "in-flight request", SpanKindServer)
+
+	//Act
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	span.End()
+	_, late := tracer.Start(context.Background(), "late request", SpanKindServer)
+	late.End()
+
+	//Assert
+	if late.IsRecording() {
+		t.Errorf("Expected the spans started after shutdown not to be recorded")
+	}
+}
@@ -0,0 +1,250 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/controllers/dtos"
+	"github.com/mzampetakis/prods-api/api/services"
+	"github.com/mzampetakis/prods-api/api/validation"
+)
+
+// Formats of the export command
+const (
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+)
+
+// exportPageSize is the number of rows read from the DB at a time while exporting
+const exportPageSize = 100
+
+// productColumns are the CSV columns of the products, in the order of the export.
+// The import reads the columns of the request DTO and ignores the rest, so that an export can be imported.
+var productColumns = []string{"id", "title", "price", "description", "image_url", "category_id", "created_at", "updated_at"}
+
+var categoryColumns = []string{"id", "title", "sort", "image_url", "created_at", "updated_at"}
+
+// RowError is the reason that a row of an imported file was not imported.
+// Rows are numbered from 1 without counting the header row.
+type RowError struct {
+	Row int
+	Err error
+}
+
+func (e *RowError) Error() string {
+	// internal errors, e.g. of the DB, are reported in full as there is no client to hide them from
+	if app.ErrorCode(e.Err) == app.EINTERNAL {
+		return fmt.Sprintf("row %d: %s", e.Row, e.Err.Error())
+	}
+	message := fmt.Sprintf("row %d: %s", e.Row, app.ErrorMessage(e.Err))
+	for _, detail := range app.ErrorDetails(e.Err) {
+		message += " " + detail.Message
+	}
+	return message
+}
+
+// ImportResult reports the number of the imported rows and the errors of the rest
+type ImportResult struct {
+	Imported int
+	Errors   []*RowError
+}
+
+// ImportProducts creates a product for each row of a CSV file with a header row, validating them like the API does.
+// Rows that fail are reported in the result while the rest are still imported.
+// An error is returned only when the file itself cannot be read.
+func ImportProducts(ctx context.Context, sv services.FunctionalitiesIface, r io.Reader) (*ImportResult, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("the file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int)
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, fmt.Errorf("the header row must have a title column")
+	}
+
+	result := &ImportResult{Errors: make([]*RowError, 0)}
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); !ok {
+				return nil, err
+			}
+			result.Errors = append(result.Errors, &RowError{Row: row, Err: err})
+			continue
+		}
+		if err = importProduct(ctx, sv, columns, record); err != nil {
+			result.Errors = append(result.Errors, &RowError{Row: row, Err: err})
+			continue
+		}
+		result.Imported++
+	}
+	return result, nil
+}
+
+func importProduct(ctx context.Context, sv services.FunctionalitiesIface, columns map[string]int, record []string) error {
+	value := func(column string) *string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) || strings.TrimSpace(record[i]) == "" {
+			return nil
+		}
+		v := strings.TrimSpace(record[i])
+		return &v
+	}
+	violations := make([]app.FieldError, 0)
+	number := func(column string) *int64 {
+		v := value(column)
+		if v == nil {
+			return nil
+		}
+		n, err := strconv.ParseInt(*v, 10, 64)
+		if err != nil {
+			violations = append(violations, app.FieldError{Field: column, Rule: "format", Message: column + " must be an integer"})
+			return nil
+		}
+		return &n
+	}
+	product := dtos.ProductRequestDto{
+		Title:       value("title"),
+		Price:       number("price"),
+		Description: value("description"),
+		ImageURL:    value("image_url"),
+		CategoryID:  number("category_id"),
+	}
+	if len(violations) == 0 {
+		var err error
+		if violations, err = validation.Validate(product); err != nil {
+			return err
+		}
+	}
+	if len(violations) > 0 {
+		return validation.Error("api.ImportProducts", violations)
+	}
+	_, err := sv.CreateProduct(ctx, dtos.ConvertProductRequestDtoToModel(product))
+	return err
+}
+
+// ExportProducts writes all products, ordered by id, in the provided format and returns their number
+func ExportProducts(ctx context.Context, sv services.FunctionalitiesIface, format string, w io.Writer) (int, error) {
+	exporter := newExporter(format, w, productColumns)
+	count := 0
+	for {
+		products, err := sv.GetProducts(ctx, exportFilter(count), app.Selection{})
+		if err != nil {
+			return count, err
+		}
+		for _, product := range products {
+			err = exporter.write(dtos.ConvertProductResponseModelToDto(*product), []string{
+				strconv.FormatInt(product.ID, 10), stringCell(product.Title), intCell(product.Price), stringCell(product.Description),
+				stringCell(product.ImageURL), intCell(product.CategoryID), dtos.Timestamp(product.CreatedAt), dtos.Timestamp(product.UpdatedAt),
+			})
+			if err != nil {
+				return count, err
+			}
+		}
+		count += len(products)
+		if len(products) < exportPageSize {
+			return count, exporter.flush()
+		}
+	}
+}
+
+// ExportCategories writes all categories, ordered by id, in the provided format and returns their number
+func ExportCategories(ctx context.Context, sv services.FunctionalitiesIface, format string, w io.Writer) (int, error) {
+	exporter := newExporter(format, w, categoryColumns)
+	count := 0
+	for {
+		categories, err := sv.GetCategories(ctx, exportFilter(count), app.Selection{})
+		if err != nil {
+			return count, err
+		}
+		for _, category := range categories {
+			err = exporter.write(dtos.ConvertCategoryResponseModelToDto(*category), []string{
+				strconv.FormatInt(category.ID, 10), stringCell(category.Title), intCell(category.Sort),
+				stringCell(category.ImageURL), dtos.Timestamp(category.CreatedAt), dtos.Timestamp(category.UpdatedAt),
+			})
+			if err != nil {
+				return count, err
+			}
+		}
+		count += len(categories)
+		if len(categories) < exportPageSize {
+			return count, exporter.flush()
+		}
+	}
+}
+
+func exportFilter(offset int) app.Filter {
+	return app.Filter{Offset: offset, Limit: exportPageSize, SortBy: "id", SortDirection: app.ASC}
+}
+
+// exporter writes each exported entity either as its API DTO in a JSON line or as a CSV row
+type exporter struct {
+	json   *json.Encoder
+	csv    *csv.Writer
+	header []string
+}
+
+func newExporter(format string, w io.Writer, header []string) *exporter {
+	if format == FormatCSV {
+		return &exporter{csv: csv.NewWriter(w), header: header}
+	}
+	return &exporter{json: json.NewEncoder(w)}
+}
+
+func (e *exporter) write(dto interface{}, row []string) error {
+	if e.json != nil {
+		return e.json.Encode(dto)
+	}
+	if e.header != nil {
+		if err := e.csv.Write(e.header); err != nil {
+			return err
+		}
+		e.header = nil
+	}
+	return e.csv.Write(row)
+}
+
+// flush writes any buffered rows and the CSV header of an empty export
+func (e *exporter) flush() error {
+	if e.csv == nil {
+		return nil
+	}
+	if e.header != nil {
+		if err := e.csv.Write(e.header); err != nil {
+			return err
+		}
+	}
+	e.csv.Flush()
+	return e.csv.Error()
+}
+
+func stringCell(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func intCell(value *int64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatInt(*value, 10)
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mzampetakis/prods-api/api/app"
+	"github.com/mzampetakis/prods-api/api/repositories"
+	"github.com/mzampetakis/prods-api/api/services"
+	"github.com/mzampetakis/prods-api/api/validation"
+)
+
+type ServiceMock struct {
+	services.FunctionalitiesIface
+	created  []repositories.ProductCreateModel
+	products []*repositories.ProductFetchModel
+}
+
+func (s *ServiceMock) CreateProduct(ctx context.Context, product repositories.ProductCreateModel) (int64, error) {
+	if violations, _ := validation.Validate(product); len(violations) > 0 {
+		return -1, validation.Error("services.CreateProduct", violations)
+	}
+	if product.CategoryID != nil && *product.CategoryID != 201 {
+		return -1, &app.Error{Op: "services.CreateProduct", Code: app.EINVALID, Message: "Data validation error.",
+			Details: []app.FieldError{{Field: "category_id", Rule: "exists", Message: "Invalid Category."}}}
+	}
+	s.created = append(s.created, product)
+	return int64(len(s.created)), nil
+}
+
+func (s *ServiceMock) GetProducts(ctx context.Context, filter app.Filter, selection app.Selection) ([]*repositories.ProductFetchModel, error) {
+	if filter.Offset >= len(s.products) {
+		return []*repositories.ProductFetchModel{}, nil
+	}
+	end := filter.Offset + filter.Limit
+	if end > len(s.products) {
+		end = len(s.products)
+	}
+	return s.products[filter.Offset:end], nil
+}
+
+func TestImportProducts(t *testing.T) {
+	tests := map[string]struct {
+		file             string
+		expectedImported int
+		expectedErrors   []string
+	}{
+		"Valid products": {
+			file:             "title,price,description,image_url,category_id\nMouse,1050,,,201\n\"Keyboard, wireless\",2000,Silent keys,https://example.com/k.png,\n",
+			expectedImported: 2,
+			expectedErrors:   []string{},
+		},
+		"Exported columns are ignored": {
+			file:             "id,title,price,created_at\n7,Mouse,1050,2020-05-25 21:02:15\n",
+			expectedImported: 1,
+			expectedErrors:   []string{},
+		},
+		"Invalid rows are reported": {
+			file:             "title,price,category_id\n,10,\nMouse,cheap,\nMonitor,100,5\nCable,5\nCharger,30,\n",
+			expectedImported: 1,
+			expectedErrors: []string{
+				"row 1: Data validation error. title is required",
+				"row 2: Data validation error. price must be an integer",
+				"row 3: Data validation error. Invalid Category.",
+				"row 4: record on line 5: wrong number of fields",
+			},
+		},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Prepare
+			sv := &ServiceMock{}
+
+			//Act
+			result, err := ImportProducts(context.Background(), sv, strings.NewReader(tc.file))
+
+			//Assert
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if result.Imported != tc.expectedImported || len(sv.created) != tc.expectedImported {
+				t.Errorf("Expected %d imported products but got %d", tc.expectedImported, result.Imported)
+			}
+			if len(result.Errors) != len(tc.expectedErrors) {
+				t.Fatalf("Expected %d errors but got %v", len(tc.expectedErrors), result.Errors)
+			}
+			for i, expected := range tc.expectedErrors {
+				if result.Errors[i].Error() != expected {
+					t.Errorf("Expected error %q but got %q", expected, result.Errors[i].Error())
+				}
+			}
+		})
+	}
+}
+
+func TestImportProducts_MissingTitleColumn(t *testing.T) {
+	//Act
+	_, err := ImportProducts(context.Background(), &ServiceMock{}, strings.NewReader("name,price\nMouse,10\n"))
+
+	//Assert
+	if err == nil {
+		t.Errorf("Expected an error for the missing title column")
+	}
+}
+
+func TestExportProducts(t *testing.T) {
+	//Prepare
+	sv := &ServiceMock{}
+	for i := 0; i < exportPageSize+1; i++ {
+		title := "Product"
+		sv.products = append(sv.products, &repositories.ProductFetchModel{ID: int64(i + 1), Title: &title})
+	}
+	tests := map[string]struct {
+		format        string
+		expectedLines int
+		expectedFirst string
+	}{
+		"NDJSON": {
+			format:        FormatNDJSON,
+			expectedLines: exportPageSize + 1,
+			expectedFirst: `{"id":1,"category_id":null,"title":"Product","image_url":null,"price":null,"description":null,"created_at":"","updated_at":""}`,
+		},
+		"CSV": {
+			format:        FormatCSV,
+			expectedLines: exportPageSize + 2,
+			expectedFirst: "id,title,price,description,image_url,category_id,created_at,updated_at",
+		},
+	}
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			out := &bytes.Buffer{}
+
+			//Act
+			count, err := ExportProducts(context.Background(), sv, tc.format, out)
+
+			//Assert
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if count != exportPageSize+1 {
+				t.Errorf("Expected all %d products to be exported but got %d", exportPageSize+1, count)
+			}
+			lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+			if len(lines) != tc.expectedLines {
+				t.Fatalf("Expected %d lines but got %d", tc.expectedLines, len(lines))
+			}
+			if lines[0] != tc.expectedFirst {
+				t.Errorf("Expected first line %s but got %s", tc.expectedFirst, lines[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+// Package validation validates structs according to the rules declared in their `validate` struct tags
+//
+// Rules are comma separated and fields are reported by their json name. Supported rules are:
+//
+//	required   the field must be provided and not be empty
+//	minlen=N   string must have at least N characters
+//	maxlen=N   string must have at most N characters
+//	min=N      number must be greater than or equal to N
+//	max=N      number must be less than or equal to N
+//	url        string must be an absolute http or https URL
+//
+// All rules except required are skipped for fields that are not provided (nil).
+package validation
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mzampetakis/prods-api/api/app"
+)
+
+// Validate checks all the fields of the provided struct (or pointer to struct) and returns every violation.
+// An error is returned when a field declares an unknown rule or a rule that does not apply to its type.
+func Validate(v interface{}) ([]app.FieldError, error) {
+	val := reflect.Indirect(reflect.ValueOf(v))
+	if val.Kind() != reflect.Struct {
+		return nil, nil
+	}
+	violations := make([]app.FieldError, 0)
+	for i := 0; i < val.NumField(); i++ {
+		tag := val.Type().Field(i).Tag.Get("validate")
+		if tag == "" {
+			continue
+		}
+		field := fieldName(val.Type().Field(i))
+		for _, rule := range strings.Split(tag, ",") {
+			violation, err := checkRule(field, val.Field(i), rule)
+			if err != nil {
+				return nil, fmt.Errorf("validation: field %s of %s: %v", field, val.Type(), err)
+			}
+			if violation != nil {
+				violations = append(violations, *violation)
+			}
+		}
+	}
+	return violations, nil
+}
+
+// Error wraps the provided violations in an EINVALID error
+func Error(op string, violations []app.FieldError) error {
+	return &app.Error{Op: op, Code: app.EINVALID, Message: "Data validation error.", Details: violations}
+}
+
+// Merge reports the violations of the provided EINVALID errors in a single error, or nil when there are none.
+// The violations of a field that an earlier error already reports are dropped and the first error of any other
+// code is returned as it is.
+func Merge(op string, errs ...error) error {
+	violations := make([]app.FieldError, 0)
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if app.ErrorCode(err) != app.EINVALID {
+			return err
+		}
+		reported := make(map[string]bool)
+		for _, violation := range violations {
+			reported[violation.Field] = true
+		}
+		for _, violation := range app.ErrorDetails(err) {
+			if !reported[violation.Field] {
+				violations = append(violations, violation)
+			}
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return Error(op, violations)
+}
+
+func fieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+func checkRule(field string, value reflect.Value, rule string) (*app.FieldError, error) {
+	ruleName, param := rule, ""
+	if parts := strings.SplitN(rule, "=", 2); len(parts) == 2 {
+		ruleName, param = parts[0], parts[1]
+	}
+	if ruleName == "required" {
+		if isEmpty(value) {
+			return &app.FieldError{Field: field, Rule: ruleName, Message: field + " is required"}, nil
+		}
+		return nil, nil
+	}
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil, nil
+		}
+		value = value.Elem()
+	}
+	switch ruleName {
+	case "minlen", "maxlen":
+		if value.Kind() != reflect.String {
+			return nil, fmt.Errorf("rule %s does not apply to %s values", ruleName, value.Kind())
+		}
+		limit, err := strconv.Atoi(param)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameter of rule %s: %v", ruleName, err)
+		}
+		length := utf8.RuneCountInString(value.String())
+		if ruleName == "minlen" && length < limit {
+			return &app.FieldError{Field: field, Rule: ruleName, Message: fmt.Sprintf("%s must be at least %d characters long", field, limit)}, nil
+		}
+		if ruleName == "maxlen" && length > limit {
+			return &app.FieldError{Field: field, Rule: ruleName, Message: fmt.Sprintf("%s must be at most %d characters long", field, limit)}, nil
+		}
+	case "min", "max":
+		comparison, err := compareNumber(value, param)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %v", ruleName, err)
+		}
+		if ruleName == "min" && comparison < 0 {
+			return &app.FieldError{Field: field, Rule: ruleName, Message: fmt.Sprintf("%s must be greater than or equal to %s", field, param)}, nil
+		}
+		if ruleName == "max" && comparison > 0 {
+			return &app.FieldError{Field: field, Rule: ruleName, Message: fmt.Sprintf("%s must be less than or equal to %s", field, param)}, nil
+		}
+	case "url":
+		if value.Kind() != reflect.String {
+			return nil, fmt.Errorf("rule %s does not apply to %s values", ruleName, value.Kind())
+		}
+		if _, ok := ParseURL(value.String()); !ok {
+			return &app.FieldError{Field: field, Rule: ruleName, Message: field + " must be an absolute http or https URL"}, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown rule %s", ruleName)
+	}
+	return nil, nil
+}
+
+// ParseURL parses the value as an absolute http or https URL, which is what the url rule accepts
+func ParseURL(value string) (*url.URL, bool) {
+	parsedURL, err := url.ParseRequestURI(value)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Hostname() == "" {
+		return nil, false
+	}
+	return parsedURL, true
+}
+
+// compareNumber compares the numeric value with the limit and returns -1, 0 or +1 when the value is less than,
+// equal to or greater than the limit
+func compareNumber(value reflect.Value, limit string) (int, error) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid parameter: %v", err)
+		}
+		return compare(value.Int() < n, value.Int() > n), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid parameter: %v", err)
+		}
+		if n < 0 {
+			return 1, nil
+		}
+		return compare(value.Uint() < uint64(n), value.Uint() > uint64(n)), nil
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(limit, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid parameter: %v", err)
+		}
+		return compare(value.Float() < n, value.Float() > n), nil
+	}
+	return 0, fmt.Errorf("does not apply to %s values", value.Kind())
+}
+
+func compare(less, greater bool) int {
+	if less {
+		return -1
+	} else if greater {
+		return 1
+	}
+	return 0
+}
+
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil() || isEmpty(value.Elem())
+	case reflect.String:
+		return strings.TrimSpace(value.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	}
+	return false
+}
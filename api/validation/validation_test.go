@@ -0,0 +1,176 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mzampetakis/prods-api/api/app"
+)
+
+type requestMock struct {
+	Title    *string `json:"title" validate:"required,minlen=2,maxlen=5"`
+	ImageURL *string `json:"image_url" validate:"url"`
+	Price    *int64  `json:"price" validate:"required,min=0,max=100"`
+	IDs      []int64 `json:"ids" validate:"required"`
+	Ignored  *string `json:"ignored"`
+}
+
+func TestValidate(t *testing.T) {
+	emptyTitle := "  "
+	longTitle := "a very long title"
+	validTitle := "title"
+	invalidURL := "/relative.png"
+	validURL := "https://some.image/image.png"
+	negativePrice := int64(-1)
+	bigPrice := int64(101)
+	validPrice := int64(100)
+	tests := map[string]struct {
+		request    requestMock
+		violations []app.FieldError
+	}{
+		"Valid request": {
+			request:    requestMock{Title: &validTitle, ImageURL: &validURL, Price: &validPrice, IDs: []int64{1}},
+			violations: []app.FieldError{},
+		},
+		"Missing fields": {
+			request: requestMock{},
+			violations: []app.FieldError{
+				{Field: "title", Rule: "required", Message: "title is required"},
+				{Field: "price", Rule: "required", Message: "price is required"},
+				{Field: "ids", Rule: "required", Message: "ids is required"},
+			},
+		},
+		"Empty title": {
+			request: requestMock{Title: &emptyTitle, Price: &validPrice, IDs: []int64{1}},
+			violations: []app.FieldError{
+				{Field: "title", Rule: "required", Message: "title is required"},
+			},
+		},
+		"All violations are collected": {
+			request: requestMock{Title: &longTitle, ImageURL: &invalidURL, Price: &negativePrice, IDs: []int64{1}},
+			violations: []app.FieldError{
+				{Field: "title", Rule: "maxlen", Message: "title must be at most 5 characters long"},
+				{Field: "image_url", Rule: "url", Message: "image_url must be an absolute http or https URL"},
+				{Field: "price", Rule: "min", Message: "price must be greater than or equal to 0"},
+			},
+		},
+		"Number too big": {
+			request: requestMock{Title: &validTitle, Price: &bigPrice, IDs: []int64{1}},
+			violations: []app.FieldError{
+				{Field: "price", Rule: "max", Message: "price must be less than or equal to 100"},
+			},
+		},
+	}
+
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			violations, err := Validate(&tc.request)
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(violations, tc.violations) {
+				t.Errorf("Expected violations %v but got %v", tc.violations, violations)
+			}
+		})
+	}
+}
+
+func TestValidate_NumberKinds(t *testing.T) {
+	type numbersMock struct {
+		Count  *uint32  `json:"count" validate:"min=1,max=10"`
+		Weight *float64 `json:"weight" validate:"min=0.5"`
+	}
+	zeroCount := uint32(0)
+	validCount := uint32(10)
+	lightWeight := 0.25
+	validWeight := 0.5
+	tests := map[string]struct {
+		request        numbersMock
+		expectedFields []string
+	}{
+		"Valid numbers":     {request: numbersMock{Count: &validCount, Weight: &validWeight}, expectedFields: []string{}},
+		"Numbers too small": {request: numbersMock{Count: &zeroCount, Weight: &lightWeight}, expectedFields: []string{"count", "weight"}},
+	}
+
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			violations, err := Validate(&tc.request)
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			fields := make([]string, 0)
+			for _, violation := range violations {
+				fields = append(fields, violation.Field)
+			}
+			if !reflect.DeepEqual(fields, tc.expectedFields) {
+				t.Errorf("Expected violations of fields %v but got %v", tc.expectedFields, violations)
+			}
+		})
+	}
+}
+
+func TestValidate_InvalidRules(t *testing.T) {
+	value := "value"
+	tests := map[string]interface{}{
+		"Unknown rule": struct {
+			Title *string `json:"title" validate:"required,unknown"`
+		}{Title: &value},
+		"Number rule on string": struct {
+			Title *string `json:"title" validate:"min=1"`
+		}{Title: &value},
+		"Length rule on number": struct {
+			Price int64 `json:"price" validate:"maxlen=5"`
+		}{Price: 1},
+		"Invalid rule parameter": struct {
+			Price int64 `json:"price" validate:"max=ten"`
+		}{Price: 1},
+	}
+
+	for tName, request := range tests {
+		t.Run(tName, func(t *testing.T) {
+			_, err := Validate(request)
+			if err == nil {
+				t.Errorf("Expected an error but got none")
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	requestErr := Error("handlers", []app.FieldError{{Field: "title", Rule: "required"}, {Field: "category_id", Rule: "min"}})
+	businessErr := Error("services", []app.FieldError{{Field: "title", Rule: "required"}, {Field: "category_id", Rule: "exists"},
+		{Field: "image_url", Rule: "allowed_host"}})
+	internalErr := &app.Error{Op: "services", Code: app.EINTERNAL}
+	tests := map[string]struct {
+		errs          []error
+		expectedCode  string
+		expectedRules []string
+	}{
+		"No errors":               {errs: []error{nil, nil}, expectedCode: ""},
+		"Single validation error": {errs: []error{nil, businessErr}, expectedCode: app.EINVALID, expectedRules: []string{"required", "exists", "allowed_host"}},
+		"Fields reported once":    {errs: []error{requestErr, businessErr}, expectedCode: app.EINVALID, expectedRules: []string{"required", "min", "allowed_host"}},
+		"Other error":             {errs: []error{requestErr, internalErr}, expectedCode: app.EINTERNAL},
+	}
+
+	for tName, tc := range tests {
+		t.Run(tName, func(t *testing.T) {
+			//Act
+			err := Merge("handlers", tc.errs...)
+
+			//Assert
+			if app.ErrorCode(err) != tc.expectedCode {
+				t.Fatalf("Expected error code %q but got %q", tc.expectedCode, app.ErrorCode(err))
+			}
+			if tc.expectedCode != app.EINVALID {
+				return
+			}
+			rules := make([]string, 0)
+			for _, violation := range app.ErrorDetails(err) {
+				rules = append(rules, violation.Rule)
+			}
+			if !reflect.DeepEqual(rules, tc.expectedRules) {
+				t.Errorf("Expected violations of rules %v but got %v", tc.expectedRules, rules)
+			}
+		})
+	}
+}
@@ -1,36 +1,42 @@
 // GENERATED BY THE COMMAND ABOVE; DO NOT EDIT
 // This file was generated by swaggo/swag at
-// 2020-05-26 21:57:12.021631 +0300 EEST m=+0.065657345
+// 2026-10-19 13:02:33.00389063 +0000 UTC m=+0.107976003
 
 package docs
 
 import (
 	"bytes"
+	"encoding/json"
+	"strings"
 
 	"github.com/alecthomas/template"
 	"github.com/swaggo/swag"
 )
 
 var doc = `{
+    "schemes": {{ marshal .Schemes }},
     "swagger": "2.0",
     "info": {
-        "description": "This is the service that provides the API for prods-api.",
-        "title": "API for prods-api",
+        "description": "{{.Description}}",
+        "title": "{{.Title}}",
         "contact": {
             "name": "Michalis Zampetakis",
             "email": "mzampetakis@gmail.com"
         },
         "license": {},
-        "version": "1.0"
+        "version": "{{.Version}}"
     },
-    "host": "localhost:8080",
-    "basePath": "/api",
+    "host": "{{.Host}}",
+    "basePath": "{{.BasePath}}",
     "paths": {
         "/categories": {
             "get": {
                 "description": "Retrieve a list of Categories",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack",
+                    "text/csv"
                 ],
                 "tags": [
                     "Categories"
@@ -60,27 +66,78 @@ var doc = `{
                         "description": "Sort direction of the results (ASC|DESC)",
                         "name": "sortdirection",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Earliest creation date-time or date (RFC 3339)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Latest creation date-time or date (RFC 3339)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Earliest update date-time or date (RFC 3339)",
+                        "name": "updated_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Latest update date-time or date (RFC 3339)",
+                        "name": "updated_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated fields to return (id is always returned)",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated relationships to embed (products)",
+                        "name": "expand",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Limit the embedded products of each category",
+                        "name": "expand_limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Locale of the titles and descriptions, preferred over the Accept-Language header",
+                        "name": "locale",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Preferred locales of the titles and descriptions",
+                        "name": "Accept-Language",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
                             "$ref": "#/definitions/dtos.CategoriesResponseDto"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
                             "$ref": "#/definitions/dtos.ServeError"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
                             "$ref": "#/definitions/dtos.ServeError"
                         }
                     }
@@ -89,7 +146,9 @@ var doc = `{
             "post": {
                 "description": "Create a new Category",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack"
                 ],
                 "tags": [
                     "Categories"
@@ -105,27 +164,54 @@ var doc = `{
                             "type": "object",
                             "$ref": "#/definitions/dtos.CategoryRequestDto"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unique key of the request, whose retries get its stored response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "type": "object",
                             "$ref": "#/definitions/dtos.CreateCategoryResponseDto"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
                             "$ref": "#/definitions/dtos.ServeError"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
                             "$ref": "#/definitions/dtos.ServeError"
                         }
                     }
@@ -136,7 +222,9 @@ var doc = `{
             "get": {
                 "description": "Retrieves a Category",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack"
                 ],
                 "tags": [
                     "Categories"
@@ -149,34 +237,60 @@ var doc = `{
                         "name": "category_id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated fields to return (id is always returned)",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated relationships to embed (products)",
+                        "name": "expand",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Limit the embedded products",
+                        "name": "expand_limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Locale of the titles and descriptions, preferred over the Accept-Language header",
+                        "name": "locale",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Preferred locales of the titles and descriptions",
+                        "name": "Accept-Language",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
                             "$ref": "#/definitions/dtos.CategoryResponseDto"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
                             "$ref": "#/definitions/dtos.ServeError"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "type": "object",
                             "$ref": "#/definitions/dtos.ServeError"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
                             "$ref": "#/definitions/dtos.ServeError"
                         }
                     }
@@ -185,7 +299,9 @@ var doc = `{
             "put": {
                 "description": "Updates a Category",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack"
                 ],
                 "tags": [
                     "Categories"
@@ -215,21 +331,30 @@ var doc = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
                             "$ref": "#/definitions/dtos.ServeError"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "type": "object",
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
                             "$ref": "#/definitions/dtos.ServeError"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
                             "$ref": "#/definitions/dtos.ServeError"
                         }
                     }
@@ -238,7 +363,9 @@ var doc = `{
             "delete": {
                 "description": "Deletes a Category",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack"
                 ],
                 "tags": [
                     "Categories"
@@ -257,8 +384,135 @@ var doc = `{
                     "204": {},
                     "500": {
                         "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    }
+                }
+            }
+        },
+        "/categories/{category_id}/translations/{locale}": {
+            "put": {
+                "description": "Create or update the title of a Category in a supported locale other than the default one",
+                "produces": [
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack"
+                ],
+                "tags": [
+                    "Translations"
+                ],
+                "summary": "Creates or updates a Category translation",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Category ID to translate",
+                        "name": "category_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Locale of the translation",
+                        "name": "locale",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Category's translated data",
+                        "name": "translation",
+                        "in": "body",
+                        "required": true,
                         "schema": {
                             "type": "object",
+                            "$ref": "#/definitions/dtos.CategoryTranslationRequestDto"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {},
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete the translation of a Category to a locale, whose content then falls back to the default locale",
+                "produces": [
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack"
+                ],
+                "tags": [
+                    "Translations"
+                ],
+                "summary": "Deletes a Category translation",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Category ID of the translation",
+                        "name": "category_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Locale of the translation",
+                        "name": "locale",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {},
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
                             "$ref": "#/definitions/dtos.ServeError"
                         }
                     }
@@ -269,7 +523,10 @@ var doc = `{
             "get": {
                 "description": "Retrieve a list of products",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack",
+                    "text/csv"
                 ],
                 "tags": [
                     "Products"
@@ -299,27 +556,72 @@ var doc = `{
                         "description": "Sort direction of the results (ASC|DESC)",
                         "name": "sortdirection",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Earliest creation date-time or date (RFC 3339)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Latest creation date-time or date (RFC 3339)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Earliest update date-time or date (RFC 3339)",
+                        "name": "updated_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Latest update date-time or date (RFC 3339)",
+                        "name": "updated_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated fields to return (id is always returned)",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated relationships to embed (category)",
+                        "name": "expand",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Locale of the titles and descriptions, preferred over the Accept-Language header",
+                        "name": "locale",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Preferred locales of the titles and descriptions",
+                        "name": "Accept-Language",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
                             "$ref": "#/definitions/dtos.ProductsResponseDto"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
                             "$ref": "#/definitions/dtos.ServeError"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
                             "$ref": "#/definitions/dtos.ServeError"
                         }
                     }
@@ -328,7 +630,9 @@ var doc = `{
             "post": {
                 "description": "Create a new Product",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack"
                 ],
                 "tags": [
                     "Products"
@@ -344,27 +648,54 @@ var doc = `{
                             "type": "object",
                             "$ref": "#/definitions/dtos.ProductRequestDto"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unique key of the request, whose retries get its stored response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "type": "object",
                             "$ref": "#/definitions/dtos.CreateProductResponseDto"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
                             "$ref": "#/definitions/dtos.ServeError"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
                             "$ref": "#/definitions/dtos.ServeError"
                         }
                     }
@@ -375,7 +706,9 @@ var doc = `{
             "put": {
                 "description": "Assing Products to a category",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack"
                 ],
                 "tags": [
                     "Products"
@@ -405,32 +738,43 @@ var doc = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
                             "$ref": "#/definitions/dtos.ServeError"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "type": "object",
                             "$ref": "#/definitions/dtos.ServeError"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "413": {
+                        "description": "Request Entity Too Large",
                         "schema": {
-                            "type": "object",
                             "$ref": "#/definitions/dtos.ServeError"
                         }
-                    }
-                }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    }
+                }
             }
         },
         "/products/{product_id}": {
             "get": {
                 "description": "Retrieve a Product",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack"
                 ],
                 "tags": [
                     "Products"
@@ -443,64 +787,430 @@ var doc = `{
                         "name": "product_id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated fields to return (id is always returned)",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated relationships to embed (category)",
+                        "name": "expand",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Locale of the titles and descriptions, preferred over the Accept-Language header",
+                        "name": "locale",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Preferred locales of the titles and descriptions",
+                        "name": "Accept-Language",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
                             "$ref": "#/definitions/dtos.ProductResponseDto"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update a Product",
+                "produces": [
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Updates a Product",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID to update",
+                        "name": "product_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Product's data to update",
+                        "name": "product",
+                        "in": "body",
+                        "required": true,
                         "schema": {
                             "type": "object",
+                            "$ref": "#/definitions/dtos.ProductRequestDto"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {},
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
                             "$ref": "#/definitions/dtos.ServeError"
                         }
                     },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes a Product",
+                "produces": [
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Deletes a Product",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID to delete",
+                        "name": "product_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {},
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{product_id}/images": {
+            "get": {
+                "description": "Retrieve the ordered images of a Product alongside with their thumbnails",
+                "produces": [
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack"
+                ],
+                "tags": [
+                    "Product Images"
+                ],
+                "summary": "Retrieves Product's Images",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID to retrieve images for",
+                        "name": "product_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ProductImagesResponseDto"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Upload one or more JPEG, PNG or GIF images of a Product. Thumbnails are generated for each image.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack"
+                ],
+                "tags": [
+                    "Product Images"
+                ],
+                "summary": "Uploads Product's Images",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID to upload images for",
+                        "name": "product_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Image files to upload",
+                        "name": "images",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unique key of the request, whose retries get its stored response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ProductImagesResponseDto"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{product_id}/images/order": {
+            "put": {
+                "description": "Set the order of a Product's images. All the images of the Product should be provided.",
+                "produces": [
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack"
+                ],
+                "tags": [
+                    "Product Images"
+                ],
+                "summary": "Orders Product's Images",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID to order images for",
+                        "name": "product_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Images' IDs in the requested order",
+                        "name": "images_order",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/dtos.ProductImagesOrderUpdateRequestDto"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {},
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{product_id}/images/{image_id}": {
+            "delete": {
+                "description": "Delete a Product's image alongside with its thumbnails",
+                "produces": [
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack"
+                ],
+                "tags": [
+                    "Product Images"
+                ],
+                "summary": "Deletes a Product's Image",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID of the image",
+                        "name": "product_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Image ID to delete",
+                        "name": "image_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {},
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "type": "object",
                             "$ref": "#/definitions/dtos.ServeError"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
                             "$ref": "#/definitions/dtos.ServeError"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/products/{product_id}/translations/{locale}": {
             "put": {
-                "description": "Update a Product",
+                "description": "Create or update the title and description of a Product in a supported locale other than the default one",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack"
                 ],
                 "tags": [
-                    "Products"
+                    "Translations"
                 ],
-                "summary": "Updates a Product",
+                "summary": "Creates or updates a Product translation",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Product ID to update",
+                        "description": "Product ID to translate",
                         "name": "product_id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Product's data to update",
-                        "name": "product",
+                        "type": "string",
+                        "description": "Locale of the translation",
+                        "name": "locale",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Product's translated data",
+                        "name": "translation",
                         "in": "body",
                         "required": true,
                         "schema": {
                             "type": "object",
-                            "$ref": "#/definitions/dtos.ProductRequestDto"
+                            "$ref": "#/definitions/dtos.ProductTranslationRequestDto"
                         }
                     }
                 ],
@@ -509,43 +1219,141 @@ var doc = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
                             "$ref": "#/definitions/dtos.ServeError"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
                             "$ref": "#/definitions/dtos.ServeError"
                         }
                     }
                 }
             },
             "delete": {
-                "description": "Deletes a Product",
+                "description": "Delete the translation of a Product to a locale, whose content then falls back to the default locale",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack"
                 ],
                 "tags": [
-                    "Products"
+                    "Translations"
                 ],
-                "summary": "Deletes a Product",
+                "summary": "Deletes a Product translation",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Product ID to delete",
+                        "description": "Product ID of the translation",
                         "name": "product_id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Locale of the translation",
+                        "name": "locale",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "204": {},
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/broken-images": {
+            "get": {
+                "description": "Retrieve the image URLs of Products and Categories that were found broken by the latest link check",
+                "produces": [
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack"
+                ],
+                "tags": [
+                    "Reports"
+                ],
+                "summary": "Retrieves broken image URLs",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.BrokenImagesResponseDto"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ServeError"
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/missing-translations": {
+            "get": {
+                "description": "Retrieve the fields of Products and Categories that are not translated to each supported locale other than the default one",
+                "produces": [
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack"
+                ],
+                "tags": [
+                    "Reports"
+                ],
+                "summary": "Retrieves missing translations",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.MissingTranslationsResponseDto"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
                             "$ref": "#/definitions/dtos.ServeError"
                         }
                     }
@@ -554,6 +1362,55 @@ var doc = `{
         }
     },
     "definitions": {
+        "dtos.BrokenImageResponseDto": {
+            "type": "object",
+            "properties": {
+                "checked_at": {
+                    "type": "string"
+                },
+                "entity_id": {
+                    "type": "integer"
+                },
+                "entity_type": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "status_code": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "dtos.BrokenImagesResponseDto": {
+            "type": "array",
+            "items": {
+                "type": "object",
+                "properties": {
+                    "checked_at": {
+                        "type": "string"
+                    },
+                    "entity_id": {
+                        "type": "integer"
+                    },
+                    "entity_type": {
+                        "type": "string"
+                    },
+                    "error": {
+                        "type": "string"
+                    },
+                    "status_code": {
+                        "type": "integer"
+                    },
+                    "url": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
         "dtos.CategoriesResponseDto": {
             "type": "array",
             "items": {
@@ -568,6 +1425,10 @@ var doc = `{
                     "image_url": {
                         "type": "string"
                     },
+                    "products": {
+                        "type": "object",
+                        "$ref": "#/definitions/dtos.ProductsResponseDto"
+                    },
                     "sort": {
                         "type": "integer"
                     },
@@ -582,6 +1443,9 @@ var doc = `{
         },
         "dtos.CategoryRequestDto": {
             "type": "object",
+            "required": [
+                "title"
+            ],
             "properties": {
                 "image_url": {
                     "type": "string"
@@ -606,6 +1470,10 @@ var doc = `{
                 "image_url": {
                     "type": "string"
                 },
+                "products": {
+                    "type": "object",
+                    "$ref": "#/definitions/dtos.ProductsResponseDto"
+                },
                 "sort": {
                     "type": "integer"
                 },
@@ -617,6 +1485,17 @@ var doc = `{
                 }
             }
         },
+        "dtos.CategoryTranslationRequestDto": {
+            "type": "object",
+            "required": [
+                "title"
+            ],
+            "properties": {
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
         "dtos.CreateCategoryResponseDto": {
             "type": "object",
             "properties": {
@@ -633,8 +1512,144 @@ var doc = `{
                 }
             }
         },
+        "dtos.FieldErrorDto": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "rule": {
+                    "type": "string"
+                }
+            }
+        },
+        "dtos.MissingTranslationResponseDto": {
+            "type": "object",
+            "properties": {
+                "entity_id": {
+                    "type": "integer"
+                },
+                "entity_type": {
+                    "type": "string"
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "locale": {
+                    "type": "string"
+                }
+            }
+        },
+        "dtos.MissingTranslationsResponseDto": {
+            "type": "array",
+            "items": {
+                "type": "object",
+                "properties": {
+                    "entity_id": {
+                        "type": "integer"
+                    },
+                    "entity_type": {
+                        "type": "string"
+                    },
+                    "fields": {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        }
+                    },
+                    "locale": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "dtos.ProductImageResponseDto": {
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "position": {
+                    "type": "integer"
+                },
+                "product_id": {
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "thumbnails": {
+                    "type": "object"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "dtos.ProductImagesOrderUpdateRequestDto": {
+            "type": "object",
+            "required": [
+                "image_ids"
+            ],
+            "properties": {
+                "image_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "dtos.ProductImagesResponseDto": {
+            "type": "array",
+            "items": {
+                "type": "object",
+                "properties": {
+                    "content_type": {
+                        "type": "string"
+                    },
+                    "created_at": {
+                        "type": "string"
+                    },
+                    "id": {
+                        "type": "integer"
+                    },
+                    "position": {
+                        "type": "integer"
+                    },
+                    "product_id": {
+                        "type": "integer"
+                    },
+                    "size": {
+                        "type": "integer"
+                    },
+                    "thumbnails": {
+                        "type": "object"
+                    },
+                    "url": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
         "dtos.ProductRequestDto": {
             "type": "object",
+            "required": [
+                "price",
+                "title"
+            ],
             "properties": {
                 "category_id": {
                     "type": "integer"
@@ -656,6 +1671,10 @@ var doc = `{
         "dtos.ProductResponseDto": {
             "type": "object",
             "properties": {
+                "category": {
+                    "type": "object",
+                    "$ref": "#/definitions/dtos.CategoryResponseDto"
+                },
                 "category_id": {
                     "type": "integer"
                 },
@@ -682,8 +1701,25 @@ var doc = `{
                 }
             }
         },
+        "dtos.ProductTranslationRequestDto": {
+            "type": "object",
+            "required": [
+                "title"
+            ],
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
         "dtos.ProductsCategoryUpdateRequestDto": {
             "type": "object",
+            "required": [
+                "product_ids"
+            ],
             "properties": {
                 "product_ids": {
                     "type": "array",
@@ -698,6 +1734,10 @@ var doc = `{
             "items": {
                 "type": "object",
                 "properties": {
+                    "category": {
+                        "type": "object",
+                        "$ref": "#/definitions/dtos.CategoryResponseDto"
+                    },
                     "category_id": {
                         "type": "integer"
                     },
@@ -731,6 +1771,12 @@ var doc = `{
                 "code": {
                     "type": "string"
                 },
+                "details": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dtos.FieldErrorDto"
+                    }
+                },
                 "http_status": {
                     "type": "string"
                 },
@@ -755,23 +1801,39 @@ type swaggerInfo struct {
 	Version     string
 	Host        string
 	BasePath    string
+	Schemes     []string
 	Title       string
 	Description string
 }
 
 // SwaggerInfo holds exported Swagger Info so clients can modify it
-var SwaggerInfo swaggerInfo
+var SwaggerInfo = swaggerInfo{
+	Version:     "1.0",
+	Host:        "localhost:8080",
+	BasePath:    "/api",
+	Schemes:     []string{},
+	Title:       "API for prods-api",
+	Description: "This is the service that provides the API for prods-api.",
+}
 
 type s struct{}
 
 func (s *s) ReadDoc() string {
-	t, err := template.New("swagger_info").Parse(doc)
+	sInfo := SwaggerInfo
+	sInfo.Description = strings.Replace(sInfo.Description, "\n", "\\n", -1)
+
+	t, err := template.New("swagger_info").Funcs(template.FuncMap{
+		"marshal": func(v interface{}) string {
+			a, _ := json.Marshal(v)
+			return string(a)
+		},
+	}).Parse(doc)
 	if err != nil {
 		return doc
 	}
 
 	var tpl bytes.Buffer
-	if err := t.Execute(&tpl, SwaggerInfo); err != nil {
+	if err := t.Execute(&tpl, sInfo); err != nil {
 		return doc
 	}
 
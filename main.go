@@ -1,6 +1,8 @@
 package main
 
 import (
+	"os"
+
 	"github.com/mzampetakis/prods-api/api"
 )
 
@@ -14,5 +16,5 @@ import (
 // @host localhost:8080
 // @BasePath /api
 func main() {
-	api.Run()
+	os.Exit(api.Main(os.Args[1:]))
 }